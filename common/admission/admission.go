@@ -0,0 +1,162 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrTimeout 排队超过最长等待时间仍未获得名额
+var ErrTimeout = errors.New("admission wait timeout")
+
+// waiter 排队中的请求，等待 key 的并发名额
+type waiter struct {
+	key      string
+	priority int
+	seq      uint64
+	ready    chan struct{}
+	granted  bool
+}
+
+// 并发计数与排队仅在当前节点内生效，请求需要在本节点等待名额
+var (
+	lock     sync.Mutex
+	inflight = make(map[string]int)
+	limits   = make(map[string]int)
+	waiting  = make(map[string]int)
+	queues   = make(map[string][]*waiter) // 队列名（模型） -> 排队中的请求
+	nextSeq  uint64
+)
+
+// Saturated key 的并发数是否已达到 limit，limit 不大于 0 表示不限制
+func Saturated(key string, limit int) bool {
+	if limit <= 0 {
+		return false
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	return inflight[key] >= limit
+}
+
+// TryAcquire 未达到上限且没有排队中的请求时占用一个名额
+func TryAcquire(key string, limit int) bool {
+	lock.Lock()
+	defer lock.Unlock()
+	limits[key] = limit
+	if inflight[key] >= limit || waiting[key] > 0 {
+		return false
+	}
+	inflight[key]++
+	return true
+}
+
+// Wait 在 queue 中排队等待 key 的名额，获得名额时返回 nil；
+// priority 越大越先获得名额，相同优先级先到先得；超时返回 ErrTimeout，ctx 结束时返回 ctx.Err()
+func Wait(ctx context.Context, key string, limit int, queue string, priority int, timeout time.Duration) error {
+	lock.Lock()
+	limits[key] = limit
+	nextSeq++
+	w := &waiter{
+		key:      key,
+		priority: priority,
+		seq:      nextSeq,
+		ready:    make(chan struct{}),
+	}
+	queues[queue] = append(queues[queue], w)
+	waiting[key]++
+	// 上限调大后，已有的空闲名额直接分配给排队中的请求
+	grant(key)
+	lock.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	var err error
+	select {
+	case <-w.ready:
+		return nil
+	case <-timer.C:
+		err = ErrTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	// 超时的同时获得了名额
+	if w.granted {
+		return nil
+	}
+	removeWaiter(queue, w)
+	return err
+}
+
+// Release 释放名额，有排队的请求时按优先级分配给下一个请求
+func Release(key string) {
+	lock.Lock()
+	defer lock.Unlock()
+	inflight[key]--
+	grant(key)
+	if inflight[key] <= 0 && waiting[key] == 0 {
+		delete(inflight, key)
+		delete(limits, key)
+	}
+}
+
+// Depth 返回队列中排队的请求数
+func Depth(queue string) int {
+	lock.Lock()
+	defer lock.Unlock()
+	return len(queues[queue])
+}
+
+// grant 在未达到上限时，依次将名额分配给优先级最高、排队最久的请求，调用方需持有锁
+func grant(key string) {
+	for waiting[key] > 0 && inflight[key] < limits[key] {
+		queue, w := bestWaiter(key)
+		if w == nil {
+			return
+		}
+		removeWaiter(queue, w)
+		inflight[key]++
+		w.granted = true
+		close(w.ready)
+	}
+}
+
+func bestWaiter(key string) (string, *waiter) {
+	var (
+		bestQueue string
+		best      *waiter
+	)
+	for queue, waiters := range queues {
+		for _, w := range waiters {
+			if w.key != key {
+				continue
+			}
+			if best == nil || w.priority > best.priority || w.priority == best.priority && w.seq < best.seq {
+				bestQueue, best = queue, w
+			}
+		}
+	}
+	return bestQueue, best
+}
+
+func removeWaiter(queue string, w *waiter) {
+	waiters := queues[queue]
+	for i, item := range waiters {
+		if item == w {
+			waiters = append(waiters[:i], waiters[i+1:]...)
+			waiting[w.key]--
+			break
+		}
+	}
+	if len(waiters) == 0 {
+		delete(queues, queue)
+	} else {
+		queues[queue] = waiters
+	}
+	if waiting[w.key] <= 0 {
+		delete(waiting, w.key)
+	}
+}
@@ -0,0 +1,90 @@
+package admission
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestPriorityOrder(t *testing.T) {
+	const key = "test:priority"
+	if !TryAcquire(key, 1) {
+		t.Fatal("first acquire should succeed")
+	}
+	if TryAcquire(key, 1) {
+		t.Fatal("acquire over limit should fail")
+	}
+
+	order := make(chan string, 3)
+	start := func(name string, priority int) {
+		go func() {
+			if err := Wait(context.Background(), key, 1, "model", priority, time.Second); err != nil {
+				order <- "error:" + name
+				return
+			}
+			order <- name
+			Release(key)
+		}()
+	}
+	start("low", 0)
+	waitDepth(t, "model", 1)
+	start("high", 10)
+	waitDepth(t, "model", 2)
+	start("low2", 0)
+	waitDepth(t, "model", 3)
+
+	Release(key)
+	for _, want := range []string{"high", "low", "low2"} {
+		if got := <-order; got != want {
+			t.Fatalf("expected %s, got %s", want, got)
+		}
+	}
+}
+
+func TestWaitTimeout(t *testing.T) {
+	const key = "test:timeout"
+	if !TryAcquire(key, 1) {
+		t.Fatal("first acquire should succeed")
+	}
+	defer Release(key)
+
+	err := Wait(context.Background(), key, 1, "timeout", 0, 10*time.Millisecond)
+	if !errors.Is(err, ErrTimeout) {
+		t.Fatalf("expected timeout, got %v", err)
+	}
+	if Depth("timeout") != 0 {
+		t.Fatal("timed out request should leave the queue")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, key, 1, "timeout", 0, time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+}
+
+func TestSaturated(t *testing.T) {
+	const key = "test:saturated"
+	if Saturated(key, 0) {
+		t.Fatal("zero limit means unlimited")
+	}
+	if !TryAcquire(key, 1) || !Saturated(key, 1) {
+		t.Fatal("expected key to be saturated")
+	}
+	Release(key)
+	if Saturated(key, 1) {
+		t.Fatal("expected key to have free slots after release")
+	}
+}
+
+func waitDepth(t *testing.T, queue string, depth int) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for Depth(queue) != depth {
+		if time.Now().After(deadline) {
+			t.Fatalf("queue depth did not reach %d", depth)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
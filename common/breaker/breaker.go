@@ -0,0 +1,157 @@
+package breaker
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lua/breaker_allow.lua
+var allowScriptSource string
+
+//go:embed lua/breaker_report.lua
+var reportScriptSource string
+
+var (
+	allowScript  = redis.NewScript(allowScriptSource)
+	reportScript = redis.NewScript(reportScriptSource)
+)
+
+const (
+	StateClosed   = "closed"
+	StateOpen     = "open"
+	StateHalfOpen = "half_open"
+)
+
+// Config 熔断器参数
+type Config struct {
+	// 连续失败多少次后熔断
+	FailureThreshold int
+	// 熔断后的冷却时间，冷却结束后进入半开状态；半开状态下探测超时也按此时间重新探测
+	Cooldown time.Duration
+	// 半开状态下放行的探测请求数，全部成功后恢复
+	HalfOpenProbes int
+}
+
+func (cfg Config) ttlSeconds() int64 {
+	ttl := int64(cfg.Cooldown.Seconds()) * 10
+	if ttl < 600 {
+		ttl = 600
+	}
+	return ttl
+}
+
+type state struct {
+	state     string
+	failures  int
+	openedAt  time.Time
+	probes    int
+	successes int
+}
+
+var (
+	localStates = make(map[string]*state)
+	localLock   sync.Mutex
+)
+
+// Allow 检查是否放行请求，acquire 为 true 时在半开状态下占用一个探测名额
+// 启用 Redis 时状态在所有节点间共享，Redis 出错时默认放行
+func Allow(key string, cfg Config, acquire bool) bool {
+	if common.RedisEnabled {
+		acquireFlag := 0
+		if acquire {
+			acquireFlag = 1
+		}
+		result, err := allowScript.Run(context.Background(), common.RDB, []string{key},
+			cfg.Cooldown.Milliseconds(), cfg.HalfOpenProbes, acquireFlag).Int()
+		if err != nil {
+			common.SysError(fmt.Sprintf("circuit breaker allow failed: %s", err.Error()))
+			return true
+		}
+		return result == 1
+	}
+
+	localLock.Lock()
+	defer localLock.Unlock()
+	s, ok := localStates[key]
+	if !ok || s.state == StateClosed {
+		return true
+	}
+	now := time.Now()
+	if now.Sub(s.openedAt) >= cfg.Cooldown {
+		if acquire {
+			s.state = StateHalfOpen
+			s.openedAt = now
+			s.probes = 1
+			s.successes = 0
+		}
+		return true
+	}
+	if s.state == StateOpen || s.probes >= cfg.HalfOpenProbes {
+		return false
+	}
+	if acquire {
+		s.probes++
+	}
+	return true
+}
+
+// Report 上报请求结果，返回 true 表示本次上报导致熔断器打开
+func Report(key string, cfg Config, success bool) bool {
+	if common.RedisEnabled {
+		successFlag := 0
+		if success {
+			successFlag = 1
+		}
+		result, err := reportScript.Run(context.Background(), common.RDB, []string{key},
+			successFlag, cfg.FailureThreshold, cfg.HalfOpenProbes, cfg.ttlSeconds()).Int()
+		if err != nil {
+			common.SysError(fmt.Sprintf("circuit breaker report failed: %s", err.Error()))
+			return false
+		}
+		return result == 1
+	}
+
+	localLock.Lock()
+	defer localLock.Unlock()
+	s, ok := localStates[key]
+	if !ok {
+		if success {
+			return false
+		}
+		s = &state{state: StateClosed}
+		localStates[key] = s
+	}
+	if success {
+		switch s.state {
+		case StateClosed:
+			delete(localStates, key)
+		case StateHalfOpen:
+			s.successes++
+			if s.successes >= cfg.HalfOpenProbes {
+				delete(localStates, key)
+			}
+		}
+		return false
+	}
+	switch s.state {
+	case StateOpen:
+		return false
+	case StateClosed:
+		s.failures++
+		if s.failures < cfg.FailureThreshold {
+			return false
+		}
+	}
+	s.state = StateOpen
+	s.openedAt = time.Now()
+	s.failures = 0
+	s.probes = 0
+	s.successes = 0
+	return true
+}
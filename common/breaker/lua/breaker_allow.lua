@@ -0,0 +1,39 @@
+-- 熔断器放行检查
+-- KEYS[1]: 熔断器唯一标识
+-- ARGV[1]: 冷却时间 (毫秒)
+-- ARGV[2]: 半开状态下允许的探测请求数
+-- ARGV[3]: 是否占用探测名额 (1 占用，0 仅检查)
+
+local key = KEYS[1]
+local cooldown = tonumber(ARGV[1])
+local maxProbes = tonumber(ARGV[2])
+local acquire = ARGV[3] == '1'
+
+local now = redis.call('TIME')
+local nowInMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+
+local fields = redis.call('HMGET', key, 'state', 'opened_at', 'probes')
+local state = fields[1]
+local openedAt = tonumber(fields[2]) or 0
+local probes = tonumber(fields[3]) or 0
+
+if not state or state == 'closed' then
+    return 1
+end
+
+-- 冷却结束或探测超时后进入（新一轮）半开状态
+if nowInMs - openedAt >= cooldown then
+    if not acquire then
+        return 1
+    end
+    redis.call('HSET', key, 'state', 'half_open', 'opened_at', nowInMs, 'probes', 1, 'successes', 0)
+    return 1
+end
+
+if state == 'open' or probes >= maxProbes then
+    return 0
+end
+if acquire then
+    redis.call('HINCRBY', key, 'probes', 1)
+end
+return 1
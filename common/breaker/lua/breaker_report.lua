@@ -0,0 +1,47 @@
+-- 熔断器结果上报
+-- KEYS[1]: 熔断器唯一标识
+-- ARGV[1]: 请求是否成功 (1 成功，0 失败)
+-- ARGV[2]: 连续失败多少次后熔断
+-- ARGV[3]: 半开状态下需要连续成功的探测数
+-- ARGV[4]: 状态过期时间 (秒)
+-- 返回 1 表示本次上报导致熔断器打开
+
+local key = KEYS[1]
+local success = ARGV[1] == '1'
+local threshold = tonumber(ARGV[2])
+local probesToClose = tonumber(ARGV[3])
+local ttl = tonumber(ARGV[4])
+
+local state = redis.call('HGET', key, 'state')
+if not state then
+    state = 'closed'
+end
+
+if success then
+    if state == 'closed' then
+        redis.call('DEL', key)
+    elseif state == 'half_open' then
+        local successes = redis.call('HINCRBY', key, 'successes', 1)
+        if successes >= probesToClose then
+            redis.call('DEL', key)
+        end
+    end
+    return 0
+end
+
+if state == 'open' then
+    return 0
+end
+if state == 'closed' then
+    local failures = redis.call('HINCRBY', key, 'failures', 1)
+    redis.call('EXPIRE', key, ttl)
+    if failures < threshold then
+        return 0
+    end
+end
+
+local now = redis.call('TIME')
+local nowInMs = tonumber(now[1]) * 1000 + math.floor(tonumber(now[2]) / 1000)
+redis.call('HSET', key, 'state', 'open', 'opened_at', nowInMs, 'failures', 0, 'probes', 0, 'successes', 0)
+redis.call('EXPIRE', key, ttl)
+return 1
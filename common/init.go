@@ -18,6 +18,14 @@ var (
 	PrintVersion = flag.Bool("version", false, "print version and exit")
 	PrintHelp    = flag.Bool("help", false, "print help and exit")
 	LogDir       = flag.String("log-dir", "./logs", "specify the log directory")
+
+	ExportConfigPath  = flag.String("export-config", "", "export configuration to the given .yaml or .json file and exit")
+	ImportConfigPath  = flag.String("import-config", "", "print the changes of importing the given configuration file and exit")
+	ConfigIncludeKeys = flag.Bool("include-keys", false, "include channel keys when exporting configuration")
+	ConfigApply       = flag.Bool("apply", false, "apply the changes when importing configuration")
+	ConfigPrune       = flag.Bool("prune", false, "delete channels, models, vendors and prefill groups missing from the imported configuration")
+
+	EncryptSecrets = flag.Bool("encrypt-secrets", false, "encrypt plaintext secrets and re-encrypt secrets of previous keys with the current key, then exit")
 )
 
 func printHelp() {
@@ -25,6 +33,9 @@ func printHelp() {
 	fmt.Println("Original Project: OneAPI by JustSong - https://github.com/songquanpeng/one-api")
 	fmt.Println("Maintainer: QuantumNous - https://github.com/QuantumNous/new-api")
 	fmt.Println("Usage: newapi [--port <port>] [--log-dir <log directory>] [--version] [--help]")
+	fmt.Println("       newapi --export-config <file> [--include-keys]")
+	fmt.Println("       newapi --import-config <file> [--apply] [--prune]")
+	fmt.Println("       newapi --encrypt-secrets")
 }
 
 func InitEnv() {
@@ -60,6 +71,9 @@ func InitEnv() {
 	} else {
 		CryptoSecret = SessionSecret
 	}
+	if err := InitSecretEncryption(); err != nil {
+		log.Fatal(err)
+	}
 	if os.Getenv("SQLITE_PATH") != "" {
 		SQLitePath = os.Getenv("SQLITE_PATH")
 	}
@@ -135,6 +149,15 @@ func initConstantEnv() {
 	constant.ErrorLogEnabled = GetEnvOrDefaultBool("ERROR_LOG_ENABLED", false)
 	// 任务轮询时查询的最大数量
 	constant.TaskQueryLimit = GetEnvOrDefault("TASK_QUERY_LIMIT", 1000)
+	// 未配置对象存储时，/v1/files 上传文件的本地保存目录
+	constant.FileStorageDir = GetEnvOrDefaultString("FILE_STORAGE_DIR", "./data/files")
+	// 批处理逐行回放的间隔（毫秒），用于降低批处理对实时请求的影响
+	constant.BatchRequestIntervalMs = GetEnvOrDefault("BATCH_REQUEST_INTERVAL_MS", 100)
+	// 单个批处理允许的最大请求行数
+	constant.BatchMaxRequests = GetEnvOrDefault("BATCH_MAX_REQUESTS", 50000)
+	// 是否开放 Prometheus /metrics 接口，设置 METRICS_TOKEN 后需携带 Bearer 令牌访问
+	constant.MetricsEnabled = GetEnvOrDefaultBool("METRICS_ENABLED", false)
+	constant.MetricsToken = GetEnvOrDefaultString("METRICS_TOKEN", "")
 
 	soraPatchStr := GetEnvOrDefaultString("TASK_PRICE_PATCH", "")
 	if soraPatchStr != "" {
@@ -0,0 +1,68 @@
+package limiter
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"sync"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/go-redis/redis/v8"
+)
+
+//go:embed lua/concurrency_acquire.lua
+var concurrencyAcquireScriptSource string
+
+//go:embed lua/concurrency_release.lua
+var concurrencyReleaseScriptSource string
+
+var (
+	concurrencyAcquireScript = redis.NewScript(concurrencyAcquireScriptSource)
+	concurrencyReleaseScript = redis.NewScript(concurrencyReleaseScriptSource)
+)
+
+// 并发计数的过期时间，需大于单个请求的最长耗时
+const concurrencyTTLSeconds = 3600
+
+var (
+	concurrencyCounter = make(map[string]int)
+	concurrencyLock    sync.Mutex
+)
+
+// AcquireConcurrency 占用一个并发名额，超过 limit 时返回 false
+// 启用 Redis 时计数在所有节点间共享
+func AcquireConcurrency(ctx context.Context, key string, limit int) (bool, error) {
+	if common.RedisEnabled {
+		result, err := concurrencyAcquireScript.Run(ctx, common.RDB, []string{key}, limit, concurrencyTTLSeconds).Int()
+		if err != nil {
+			return false, fmt.Errorf("concurrency acquire failed: %w", err)
+		}
+		return result == 1, nil
+	}
+
+	concurrencyLock.Lock()
+	defer concurrencyLock.Unlock()
+	if concurrencyCounter[key] >= limit {
+		return false, nil
+	}
+	concurrencyCounter[key]++
+	return true, nil
+}
+
+// ReleaseConcurrency 释放 AcquireConcurrency 占用的并发名额
+func ReleaseConcurrency(ctx context.Context, key string) error {
+	if common.RedisEnabled {
+		if err := concurrencyReleaseScript.Run(ctx, common.RDB, []string{key}).Err(); err != nil {
+			return fmt.Errorf("concurrency release failed: %w", err)
+		}
+		return nil
+	}
+
+	concurrencyLock.Lock()
+	defer concurrencyLock.Unlock()
+	concurrencyCounter[key]--
+	if concurrencyCounter[key] <= 0 {
+		delete(concurrencyCounter, key)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package limiter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func TestConcurrencyAcquireRelease(t *testing.T) {
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = redisEnabled })
+
+	ctx := context.Background()
+	tests := []struct {
+		name  string
+		limit int
+		steps []string
+		want  []bool
+	}{
+		{"within limit", 2, []string{"acquire", "acquire"}, []bool{true, true}},
+		{"over limit", 1, []string{"acquire", "acquire"}, []bool{true, false}},
+		{"release frees slot", 1, []string{"acquire", "release", "acquire"}, []bool{true, true}},
+		{"zero limit", 0, []string{"acquire"}, []bool{false}},
+	}
+	for _, tt := range tests {
+		key := "test:concurrency:" + tt.name
+		concurrencyLock.Lock()
+		delete(concurrencyCounter, key)
+		concurrencyLock.Unlock()
+		results := make([]bool, 0, len(tt.want))
+		for _, step := range tt.steps {
+			if step == "release" {
+				if err := ReleaseConcurrency(ctx, key); err != nil {
+					t.Fatal(err)
+				}
+				continue
+			}
+			allowed, err := AcquireConcurrency(ctx, key, tt.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+			results = append(results, allowed)
+		}
+		for i := range tt.want {
+			if results[i] != tt.want[i] {
+				t.Fatalf("%s: step %d expected %v, got %v", tt.name, i, tt.want[i], results[i])
+			}
+		}
+	}
+}
+
+func TestConcurrencyReleaseClearsCounter(t *testing.T) {
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = redisEnabled })
+
+	const key = "test:concurrency:clear"
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		if allowed, _ := AcquireConcurrency(ctx, key, 3); !allowed {
+			t.Fatalf("acquire %d should succeed", i)
+		}
+	}
+	for i := 0; i < 3; i++ {
+		_ = ReleaseConcurrency(ctx, key)
+	}
+	concurrencyLock.Lock()
+	_, ok := concurrencyCounter[key]
+	concurrencyLock.Unlock()
+	if ok {
+		t.Fatal("expected counter to be removed after all slots are released")
+	}
+}
@@ -13,9 +13,13 @@ import (
 //go:embed lua/rate_limit.lua
 var rateLimitScript string
 
+//go:embed lua/consume.lua
+var consumeScript string
+
 type RedisLimiter struct {
-	client         *redis.Client
-	limitScriptSHA string
+	client           *redis.Client
+	limitScriptSHA   string
+	consumeScriptSHA string
 }
 
 var (
@@ -30,9 +34,14 @@ func New(ctx context.Context, r *redis.Client) *RedisLimiter {
 		if err != nil {
 			common.SysLog(fmt.Sprintf("Failed to load rate limit script: %v", err))
 		}
+		consumeSHA, err := r.ScriptLoad(ctx, consumeScript).Result()
+		if err != nil {
+			common.SysLog(fmt.Sprintf("Failed to load consume script: %v", err))
+		}
 		instance = &RedisLimiter{
-			client:         r,
-			limitScriptSHA: limitSHA,
+			client:           r,
+			limitScriptSHA:   limitSHA,
+			consumeScriptSHA: consumeSHA,
 		}
 	})
 
@@ -68,6 +77,31 @@ func (rl *RedisLimiter) Allow(ctx context.Context, key string, opts ...Option) (
 	return result == 1, nil
 }
 
+// Consume 强制扣减令牌，余额允许为负，Requested 为负数时表示退还
+func (rl *RedisLimiter) Consume(ctx context.Context, key string, opts ...Option) error {
+	config := &Config{
+		Capacity:  10,
+		Rate:      1,
+		Requested: 1,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	err := rl.client.EvalSha(
+		ctx,
+		rl.consumeScriptSHA,
+		[]string{key},
+		config.Requested,
+		config.Rate,
+		config.Capacity,
+	).Err()
+	if err != nil {
+		return fmt.Errorf("rate limit consume failed: %w", err)
+	}
+	return nil
+}
+
 // Config 配置选项模式
 type Config struct {
 	Capacity  int64
@@ -0,0 +1,16 @@
+-- 并发数限制
+-- KEYS[1]: 计数器唯一标识
+-- ARGV[1]: 最大并发数
+-- ARGV[2]: 过期时间 (秒)，避免节点异常退出后计数无法释放
+
+local key = KEYS[1]
+local limit = tonumber(ARGV[1])
+local ttl = tonumber(ARGV[2])
+
+local current = redis.call('INCR', key)
+if current > limit then
+    redis.call('DECR', key)
+    return 0
+end
+redis.call('EXPIRE', key, ttl)
+return 1
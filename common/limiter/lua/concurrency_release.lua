@@ -0,0 +1,9 @@
+-- 释放并发计数
+-- KEYS[1]: 计数器唯一标识
+
+local key = KEYS[1]
+local current = redis.call('DECR', key)
+if current <= 0 then
+    redis.call('DEL', key)
+end
+return 1
@@ -0,0 +1,33 @@
+-- 令牌桶强制扣减
+-- 用于请求结束后按实际用量校正，余额允许为负，requested 为负数时表示退还
+-- KEYS[1]: 限流器唯一标识
+-- ARGV[1]: 扣减令牌数
+-- ARGV[2]: 令牌生成速率 (每秒)
+-- ARGV[3]: 桶容量
+
+local key = KEYS[1]
+local requested = tonumber(ARGV[1])
+local rate = tonumber(ARGV[2])
+local capacity = tonumber(ARGV[3])
+
+local now = redis.call('TIME')
+local nowInSeconds = tonumber(now[1])
+
+local bucket = redis.call('HMGET', key, 'tokens', 'last_time')
+local tokens = tonumber(bucket[1])
+local last_time = tonumber(bucket[2])
+
+if not tokens or not last_time then
+    tokens = capacity
+    last_time = nowInSeconds
+else
+    local elapsed = nowInSeconds - last_time
+    tokens = math.min(capacity, tokens + elapsed * rate)
+    last_time = nowInSeconds
+end
+
+tokens = math.min(capacity, tokens - requested)
+
+redis.call('HMSET', key, 'tokens', tokens, 'last_time', last_time)
+
+return 1
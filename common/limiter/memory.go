@@ -0,0 +1,86 @@
+package limiter
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+type memoryBucket struct {
+	tokens   float64
+	lastTime time.Time
+}
+
+// MemoryLimiter 未启用 Redis 时使用的令牌桶，语义与 RedisLimiter 一致
+type MemoryLimiter struct {
+	mutex   sync.Mutex
+	buckets map[string]*memoryBucket
+}
+
+var (
+	memoryInstance *MemoryLimiter
+	memoryOnce     sync.Once
+)
+
+func NewMemory() *MemoryLimiter {
+	memoryOnce.Do(func() {
+		memoryInstance = &MemoryLimiter{
+			buckets: make(map[string]*memoryBucket),
+		}
+	})
+	return memoryInstance
+}
+
+func (ml *MemoryLimiter) refill(key string, config *Config) *memoryBucket {
+	now := time.Now()
+	bucket, ok := ml.buckets[key]
+	if !ok {
+		bucket = &memoryBucket{
+			tokens:   float64(config.Capacity),
+			lastTime: now,
+		}
+		ml.buckets[key] = bucket
+		return bucket
+	}
+	elapsed := now.Sub(bucket.lastTime).Seconds()
+	bucket.tokens = math.Min(float64(config.Capacity), bucket.tokens+elapsed*float64(config.Rate))
+	bucket.lastTime = now
+	return bucket
+}
+
+func (ml *MemoryLimiter) Allow(key string, opts ...Option) bool {
+	config := &Config{
+		Capacity:  10,
+		Rate:      1,
+		Requested: 1,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	bucket := ml.refill(key, config)
+	if bucket.tokens < float64(config.Requested) {
+		return false
+	}
+	bucket.tokens -= float64(config.Requested)
+	return true
+}
+
+// Consume 强制扣减令牌，余额允许为负，Requested 为负数时表示退还
+func (ml *MemoryLimiter) Consume(key string, opts ...Option) {
+	config := &Config{
+		Capacity:  10,
+		Rate:      1,
+		Requested: 1,
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+
+	ml.mutex.Lock()
+	defer ml.mutex.Unlock()
+	bucket := ml.refill(key, config)
+	bucket.tokens = math.Min(float64(config.Capacity), bucket.tokens-float64(config.Requested))
+}
@@ -0,0 +1,66 @@
+package limiter
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestMemoryLimiterTPMWindow(t *testing.T) {
+	// 与 TPM 限制相同的换算：容量 tpm*60，每秒补充 tpm，每个 token 消耗 60
+	const tpm = 100
+	opts := func(tokens int64) []Option {
+		return []Option{WithCapacity(tpm * 60), WithRate(tpm), WithRequested(tokens * 60)}
+	}
+	tests := []struct {
+		name    string
+		used    int64
+		elapsed time.Duration
+		tokens  int64
+		allowed bool
+	}{
+		{"full window", 0, 0, tpm, true},
+		{"over window", 0, 0, tpm + 1, false},
+		{"window used up", tpm, 0, 1, false},
+		{"partial refill", tpm, 30 * time.Second, tpm / 2, true},
+		{"partial refill exceeded", tpm, 30 * time.Second, tpm/2 + 1, false},
+		{"refill capped at capacity", 0, 10 * time.Minute, tpm + 1, false},
+	}
+	ml := &MemoryLimiter{buckets: make(map[string]*memoryBucket)}
+	for _, tt := range tests {
+		key := "test:tpm:" + tt.name
+		ml.Consume(key, opts(tt.used)...)
+		ml.buckets[key].lastTime = ml.buckets[key].lastTime.Add(-tt.elapsed)
+		if got := ml.Allow(key, opts(tt.tokens)...); got != tt.allowed {
+			t.Fatalf("%s: expected allowed=%v, got %v", tt.name, tt.allowed, got)
+		}
+	}
+}
+
+func TestMemoryLimiterConsume(t *testing.T) {
+	const tpm = 100
+	opts := func(tokens int64) []Option {
+		return []Option{WithCapacity(tpm * 60), WithRate(tpm), WithRequested(tokens * 60)}
+	}
+	tests := []struct {
+		name     string
+		consumed []int64
+		want     float64
+	}{
+		{"debit", []int64{40}, (tpm - 40) * 60},
+		{"overdraw goes negative", []int64{tpm + 50}, -50 * 60},
+		{"refund", []int64{80, -30}, (tpm - 50) * 60},
+		{"refund capped at capacity", []int64{10, -50}, tpm * 60},
+	}
+	ml := &MemoryLimiter{buckets: make(map[string]*memoryBucket)}
+	for _, tt := range tests {
+		key := "test:consume:" + tt.name
+		for _, tokens := range tt.consumed {
+			ml.Consume(key, opts(tokens)...)
+		}
+		// 两次扣减之间的补充量远小于 1
+		if got := ml.buckets[key].tokens; math.Abs(got-tt.want) >= 1 {
+			t.Fatalf("%s: expected %v tokens, got %v", tt.name, tt.want, got)
+		}
+	}
+}
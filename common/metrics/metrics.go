@@ -0,0 +1,154 @@
+package metrics
+
+import (
+	"strconv"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const namespace = "new_api"
+
+var (
+	relayRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_requests_total",
+		Help:      "Number of relay attempts sent to upstream channels.",
+	}, []string{"model", "channel", "group", "status_code"})
+
+	relayUpstreamLatency = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_upstream_latency_seconds",
+		Help:      "Duration of relay attempts, from sending the upstream request until the response is finished.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120, 300},
+	}, []string{"model", "channel", "group"})
+
+	relayFirstToken = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "relay_first_token_seconds",
+		Help:      "Time to first token of successful relay attempts.",
+		Buckets:   []float64{0.1, 0.25, 0.5, 1, 2, 4, 8, 15, 30, 60},
+	}, []string{"model", "channel", "group"})
+
+	relayRetries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_retries_total",
+		Help:      "Number of relay retries after a failed attempt.",
+	}, []string{"model", "group"})
+
+	relayHedges = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "relay_hedges_total",
+		Help:      "Number of hedged requests sent to a second channel, by which attempt won.",
+	}, []string{"model", "group", "winner"})
+
+	admissionQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "admission_queue_depth",
+		Help:      "Number of requests waiting for a saturated channel, by model and priority class.",
+	}, []string{"model", "class"})
+
+	admissionWait = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "admission_wait_seconds",
+		Help:      "Time requests waited for a saturated channel, by result (admitted, timeout, canceled or rejected).",
+		Buckets:   []float64{0.01, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60, 120},
+	}, []string{"model", "class", "result"})
+
+	promptTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "prompt_tokens_total",
+		Help:      "Billed prompt tokens.",
+	}, []string{"model", "channel", "group"})
+
+	completionTokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "completion_tokens_total",
+		Help:      "Billed completion tokens.",
+	}, []string{"model", "channel", "group"})
+
+	quotaConsumed = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "quota_consumed_total",
+		Help:      "Quota consumed by relay requests.",
+	}, []string{"model", "channel", "group"})
+
+	channelAutoDisabled = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "channel_auto_disabled_total",
+		Help:      "Number of times a channel was automatically disabled.",
+	}, []string{"channel"})
+
+	batchUpdateQueueDepth = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "batch_update_queue_depth",
+		Help:      "Number of pending records in the batch updater.",
+	}, []string{"type"})
+)
+
+func init() {
+	prometheus.MustRegister(
+		relayRequests,
+		relayUpstreamLatency,
+		relayFirstToken,
+		relayRetries,
+		relayHedges,
+		admissionQueueDepth,
+		admissionWait,
+		promptTokens,
+		completionTokens,
+		quotaConsumed,
+		channelAutoDisabled,
+		batchUpdateQueueDepth,
+	)
+}
+
+// RelayAttempt 记录一次转发尝试，ttftSeconds 为 0 时表示未知
+func RelayAttempt(modelName string, channelId int, group string, statusCode int, latencySeconds float64, ttftSeconds float64) {
+	channel := strconv.Itoa(channelId)
+	relayRequests.WithLabelValues(modelName, channel, group, strconv.Itoa(statusCode)).Inc()
+	relayUpstreamLatency.WithLabelValues(modelName, channel, group).Observe(latencySeconds)
+	if ttftSeconds > 0 {
+		relayFirstToken.WithLabelValues(modelName, channel, group).Observe(ttftSeconds)
+	}
+}
+
+func RelayRetry(modelName string, group string) {
+	relayRetries.WithLabelValues(modelName, group).Inc()
+}
+
+// RelayHedge 记录一次对冲请求，winner 为 primary、hedge 或 failed（两个请求都失败）
+func RelayHedge(modelName string, group string, winner string) {
+	relayHedges.WithLabelValues(modelName, group, winner).Inc()
+}
+
+// AddAdmissionQueueDepth 请求开始或结束排队时调整队列长度
+func AddAdmissionQueueDepth(modelName string, class string, delta int) {
+	admissionQueueDepth.WithLabelValues(modelName, class).Add(float64(delta))
+}
+
+// AdmissionWait 记录一次排队，result 为 admitted、timeout、canceled 或 rejected（不排队直接拒绝）
+func AdmissionWait(modelName string, class string, result string, waitSeconds float64) {
+	admissionWait.WithLabelValues(modelName, class, result).Observe(waitSeconds)
+}
+
+// Consume 记录一次请求最终计费的 token 数与额度
+func Consume(modelName string, channelId int, group string, prompt int, completion int, quota int) {
+	channel := strconv.Itoa(channelId)
+	if prompt > 0 {
+		promptTokens.WithLabelValues(modelName, channel, group).Add(float64(prompt))
+	}
+	if completion > 0 {
+		completionTokens.WithLabelValues(modelName, channel, group).Add(float64(completion))
+	}
+	if quota > 0 {
+		quotaConsumed.WithLabelValues(modelName, channel, group).Add(float64(quota))
+	}
+}
+
+func ChannelAutoDisabled(channelId int) {
+	channelAutoDisabled.WithLabelValues(strconv.Itoa(channelId)).Inc()
+}
+
+func SetBatchUpdateQueueDepth(updateType string, depth int) {
+	batchUpdateQueueDepth.WithLabelValues(updateType).Set(float64(depth))
+}
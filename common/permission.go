@@ -0,0 +1,70 @@
+package common
+
+// 管理员可访问的资源，读操作与写操作分别授权
+const (
+	PermissionUser    = "user"    // 用户管理
+	PermissionBilling = "billing" // 兑换码、充值订单
+	PermissionChannel = "channel" // 渠道、模型、分组与部署
+	PermissionLog     = "log"     // 使用日志、数据看板、任务记录与审核日志
+)
+
+// 管理角色，仅对管理员生效，为空表示拥有全部权限
+const (
+	AdminRoleBillingOperator = "billing_operator"
+	AdminRoleChannelOperator = "channel_operator"
+	AdminRoleSupport         = "support"
+	AdminRoleAuditor         = "auditor"
+)
+
+type adminPermission struct {
+	read  bool
+	write bool
+}
+
+var adminRolePermissions = map[string]map[string]adminPermission{
+	AdminRoleBillingOperator: {
+		PermissionUser:    {read: true},
+		PermissionBilling: {read: true, write: true},
+		PermissionLog:     {read: true},
+	},
+	AdminRoleChannelOperator: {
+		PermissionChannel: {read: true, write: true},
+		PermissionLog:     {read: true},
+	},
+	AdminRoleSupport: {
+		PermissionUser: {read: true},
+		PermissionLog:  {read: true},
+	},
+	AdminRoleAuditor: {
+		PermissionUser:    {read: true},
+		PermissionBilling: {read: true},
+		PermissionChannel: {read: true},
+		PermissionLog:     {read: true},
+	},
+}
+
+func IsValidAdminRole(adminRole string) bool {
+	if adminRole == "" {
+		return true
+	}
+	_, ok := adminRolePermissions[adminRole]
+	return ok
+}
+
+// HasAdminPermission 超级管理员与未设置管理角色的管理员拥有全部权限
+func HasAdminPermission(role int, adminRole string, permission string, write bool) bool {
+	if role >= RoleRootUser {
+		return true
+	}
+	if role < RoleAdminUser {
+		return false
+	}
+	if adminRole == "" {
+		return true
+	}
+	granted := adminRolePermissions[adminRole][permission]
+	if write {
+		return granted.write
+	}
+	return granted.read
+}
@@ -0,0 +1,57 @@
+package common
+
+import "testing"
+
+func TestHasAdminPermission(t *testing.T) {
+	permissions := []string{PermissionUser, PermissionBilling, PermissionChannel, PermissionLog}
+	tests := []struct {
+		name      string
+		role      int
+		adminRole string
+		// 资源 -> 授权，"r" 只读，"rw" 读写，缺省表示无权限
+		granted map[string]string
+	}{
+		{"root", RoleRootUser, "", map[string]string{PermissionUser: "rw", PermissionBilling: "rw", PermissionChannel: "rw", PermissionLog: "rw"}},
+		{"root ignores admin role", RoleRootUser, AdminRoleSupport, map[string]string{PermissionUser: "rw", PermissionBilling: "rw", PermissionChannel: "rw", PermissionLog: "rw"}},
+		{"admin without role", RoleAdminUser, "", map[string]string{PermissionUser: "rw", PermissionBilling: "rw", PermissionChannel: "rw", PermissionLog: "rw"}},
+		{"billing operator", RoleAdminUser, AdminRoleBillingOperator, map[string]string{PermissionUser: "r", PermissionBilling: "rw", PermissionLog: "r"}},
+		{"channel operator", RoleAdminUser, AdminRoleChannelOperator, map[string]string{PermissionChannel: "rw", PermissionLog: "r"}},
+		{"support", RoleAdminUser, AdminRoleSupport, map[string]string{PermissionUser: "r", PermissionLog: "r"}},
+		{"auditor", RoleAdminUser, AdminRoleAuditor, map[string]string{PermissionUser: "r", PermissionBilling: "r", PermissionChannel: "r", PermissionLog: "r"}},
+		{"unknown admin role", RoleAdminUser, "unknown", map[string]string{}},
+		{"common user", RoleCommonUser, "", map[string]string{}},
+		{"common user with admin role", RoleCommonUser, AdminRoleAuditor, map[string]string{}},
+		{"guest", RoleGuestUser, "", map[string]string{}},
+	}
+	for _, tt := range tests {
+		for _, permission := range permissions {
+			wantRead := tt.granted[permission] != ""
+			wantWrite := tt.granted[permission] == "rw"
+			if got := HasAdminPermission(tt.role, tt.adminRole, permission, false); got != wantRead {
+				t.Fatalf("%s: read %s expected %v, got %v", tt.name, permission, wantRead, got)
+			}
+			if got := HasAdminPermission(tt.role, tt.adminRole, permission, true); got != wantWrite {
+				t.Fatalf("%s: write %s expected %v, got %v", tt.name, permission, wantWrite, got)
+			}
+		}
+	}
+}
+
+func TestIsValidAdminRole(t *testing.T) {
+	tests := []struct {
+		adminRole string
+		want      bool
+	}{
+		{"", true},
+		{AdminRoleBillingOperator, true},
+		{AdminRoleChannelOperator, true},
+		{AdminRoleSupport, true},
+		{AdminRoleAuditor, true},
+		{"root", false},
+	}
+	for _, tt := range tests {
+		if got := IsValidAdminRole(tt.adminRole); got != tt.want {
+			t.Fatalf("%q: expected %v, got %v", tt.adminRole, tt.want, got)
+		}
+	}
+}
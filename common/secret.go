@@ -0,0 +1,229 @@
+package common
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// 密文格式：enc:v1:<主密钥 id>:<被主密钥加密的数据密钥>:<被数据密钥加密的内容>
+const secretPrefix = "enc:v1:"
+
+var (
+	secretMasterKeys   = map[string][]byte{}
+	secretCurrentKeyId = ""
+)
+
+// InitSecretEncryption 从环境变量或文件加载静态加密主密钥，未配置时不加密
+// SECRET_ENCRYPTION_KEY 为当前主密钥，SECRET_ENCRYPTION_PREVIOUS_KEYS 为逗号分隔的旧主密钥，仅用于解密；
+// 使用 SECRET_ENCRYPTION_KEY_FILE 时文件第一行为当前主密钥，其余各行为旧主密钥
+func InitSecretEncryption() error {
+	var keys []string
+	if path := os.Getenv("SECRET_ENCRYPTION_KEY_FILE"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read secret encryption key file: %w", err)
+		}
+		keys = strings.Split(string(data), "\n")
+	} else if key := os.Getenv("SECRET_ENCRYPTION_KEY"); key != "" {
+		keys = append([]string{key}, strings.Split(os.Getenv("SECRET_ENCRYPTION_PREVIOUS_KEYS"), ",")...)
+	}
+	masterKeys := map[string][]byte{}
+	currentKeyId := ""
+	for _, key := range keys {
+		key = strings.TrimSpace(key)
+		if key == "" {
+			continue
+		}
+		sum := sha256.Sum256([]byte(key))
+		keyId := secretKeyId(sum[:])
+		masterKeys[keyId] = sum[:]
+		if currentKeyId == "" {
+			currentKeyId = keyId
+		}
+	}
+	secretMasterKeys = masterKeys
+	secretCurrentKeyId = currentKeyId
+	if currentKeyId != "" {
+		SysLog(fmt.Sprintf("secret encryption enabled, current key id: %s, %d keys loaded", currentKeyId, len(masterKeys)))
+	}
+	return nil
+}
+
+func secretKeyId(masterKey []byte) string {
+	sum := sha256.Sum256(masterKey)
+	return hex.EncodeToString(sum[:4])
+}
+
+// SecretEncryptionEnabled 是否配置了静态加密主密钥
+func SecretEncryptionEnabled() bool {
+	return secretCurrentKeyId != ""
+}
+
+// SecretCurrentKeyId 当前主密钥 id
+func SecretCurrentKeyId() string {
+	return secretCurrentKeyId
+}
+
+func IsEncryptedSecret(value string) bool {
+	return strings.HasPrefix(value, secretPrefix)
+}
+
+func sealSecret(key []byte, plaintext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+func openSecret(key []byte, sealed []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, errors.New("invalid secret ciphertext")
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+}
+
+// parseSecret 拆分密文并用对应的主密钥解出数据密钥
+func parseSecret(value string) (dataKey []byte, payload []byte, keyId string, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, secretPrefix), ":")
+	if len(parts) != 3 {
+		return nil, nil, "", errors.New("invalid secret ciphertext")
+	}
+	keyId = parts[0]
+	masterKey, ok := secretMasterKeys[keyId]
+	if !ok {
+		return nil, nil, "", fmt.Errorf("secret encryption key %s is not configured", keyId)
+	}
+	wrappedKey, err := base64.RawStdEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, nil, "", err
+	}
+	payload, err = base64.RawStdEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, nil, "", err
+	}
+	dataKey, err = openSecret(masterKey, wrappedKey)
+	if err != nil {
+		return nil, nil, "", fmt.Errorf("failed to unwrap secret data key: %w", err)
+	}
+	return dataKey, payload, keyId, nil
+}
+
+func formatSecret(dataKey []byte, payload []byte) (string, error) {
+	wrappedKey, err := sealSecret(secretMasterKeys[secretCurrentKeyId], dataKey)
+	if err != nil {
+		return "", err
+	}
+	return secretPrefix + secretCurrentKeyId + ":" + base64.RawStdEncoding.EncodeToString(wrappedKey) + ":" +
+		base64.RawStdEncoding.EncodeToString(payload), nil
+}
+
+// EncryptSecret 使用随机数据密钥加密内容，数据密钥由当前主密钥加密；未启用加密、空值或已是密文时原样返回
+func EncryptSecret(plaintext string) (string, error) {
+	if !SecretEncryptionEnabled() || plaintext == "" || IsEncryptedSecret(plaintext) {
+		return plaintext, nil
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return "", err
+	}
+	payload, err := sealSecret(dataKey, []byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return formatSecret(dataKey, payload)
+}
+
+// DecryptSecret 解密内容，明文（尚未迁移的数据）原样返回
+func DecryptSecret(value string) (string, error) {
+	if !IsEncryptedSecret(value) {
+		return value, nil
+	}
+	dataKey, payload, _, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	plaintext, err := openSecret(dataKey, payload)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt secret: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// SecretNeedsRewrap 判断内容是否为明文或由旧主密钥加密
+func SecretNeedsRewrap(value string) bool {
+	if !SecretEncryptionEnabled() || value == "" {
+		return false
+	}
+	if !IsEncryptedSecret(value) {
+		return true
+	}
+	return !strings.HasPrefix(value, secretPrefix+secretCurrentKeyId+":")
+}
+
+// RewrapSecret 加密明文，或用当前主密钥重新加密旧密文的数据密钥，内容本身不需要重新加密
+func RewrapSecret(value string) (string, error) {
+	if !SecretNeedsRewrap(value) {
+		return value, nil
+	}
+	if !IsEncryptedSecret(value) {
+		return EncryptSecret(value)
+	}
+	dataKey, payload, _, err := parseSecret(value)
+	if err != nil {
+		return "", err
+	}
+	return formatSecret(dataKey, payload)
+}
+
+func secretLookupHash(masterKey []byte, value string) string {
+	lookupKey := sha256.Sum256(append([]byte("lookup:"), masterKey...))
+	mac := hmac.New(sha256.New, lookupKey[:])
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SecretLookupHash 返回内容在当前主密钥下的 HMAC，用于按明文等值查询已加密的字段；未启用加密或空值时返回空字符串
+func SecretLookupHash(value string) string {
+	if !SecretEncryptionEnabled() || value == "" {
+		return ""
+	}
+	return secretLookupHash(secretMasterKeys[secretCurrentKeyId], value)
+}
+
+// SecretLookupHashes 返回内容在所有已配置主密钥下的 HMAC，轮换主密钥后尚未重新加密的数据也能查到
+func SecretLookupHashes(value string) []string {
+	if !SecretEncryptionEnabled() || value == "" {
+		return nil
+	}
+	hashes := make([]string, 0, len(secretMasterKeys))
+	for _, masterKey := range secretMasterKeys {
+		hashes = append(hashes, secretLookupHash(masterKey, value))
+	}
+	return hashes
+}
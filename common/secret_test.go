@@ -0,0 +1,143 @@
+package common
+
+import (
+	"strings"
+	"testing"
+)
+
+func setSecretKeys(t *testing.T, current string, previous string) {
+	t.Helper()
+	t.Setenv("SECRET_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("SECRET_ENCRYPTION_KEY", current)
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", previous)
+	if err := InitSecretEncryption(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		secretMasterKeys = map[string][]byte{}
+		secretCurrentKeyId = ""
+	})
+}
+
+func TestSecretRoundTrip(t *testing.T) {
+	setSecretKeys(t, "current-key", "")
+	for _, plaintext := range []string{"sk-test", "line1\nline2", `{"key":"value"}`} {
+		encrypted, err := EncryptSecret(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !IsEncryptedSecret(encrypted) || strings.Contains(encrypted, plaintext) {
+			t.Fatalf("expected %q to be encrypted, got %q", plaintext, encrypted)
+		}
+		decrypted, err := DecryptSecret(encrypted)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decrypted != plaintext {
+			t.Fatalf("expected %q, got %q", plaintext, decrypted)
+		}
+	}
+}
+
+func TestSecretRewrapPreviousKey(t *testing.T) {
+	setSecretKeys(t, "old-key", "")
+	encrypted, err := EncryptSecret("sk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldKeyId := SecretCurrentKeyId()
+
+	setSecretKeys(t, "new-key", "old-key")
+	if SecretCurrentKeyId() == oldKeyId {
+		t.Fatal("expected key id to change after rotation")
+	}
+	decrypted, err := DecryptSecret(encrypted)
+	if err != nil || decrypted != "sk-test" {
+		t.Fatalf("expected previous key to decrypt, got %q, %v", decrypted, err)
+	}
+	if !SecretNeedsRewrap(encrypted) {
+		t.Fatal("secret encrypted by previous key should need rewrap")
+	}
+	rewrapped, err := RewrapSecret(encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(rewrapped, secretPrefix+SecretCurrentKeyId()+":") || SecretNeedsRewrap(rewrapped) {
+		t.Fatalf("expected rewrapped secret under current key, got %q", rewrapped)
+	}
+	if decrypted, err = DecryptSecret(rewrapped); err != nil || decrypted != "sk-test" {
+		t.Fatalf("expected rewrapped secret to decrypt, got %q, %v", decrypted, err)
+	}
+
+	setSecretKeys(t, "new-key", "")
+	if _, err := DecryptSecret(encrypted); err == nil {
+		t.Fatal("expected decrypt to fail once previous key is removed")
+	}
+}
+
+func TestSecretTampered(t *testing.T) {
+	setSecretKeys(t, "current-key", "")
+	encrypted, err := EncryptSecret("sk-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := strings.Split(strings.TrimPrefix(encrypted, secretPrefix), ":")
+	flip := func(s string) string {
+		b := []byte(s)
+		if b[len(b)/2] == 'A' {
+			b[len(b)/2] = 'B'
+		} else {
+			b[len(b)/2] = 'A'
+		}
+		return string(b)
+	}
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"payload", secretPrefix + parts[0] + ":" + parts[1] + ":" + flip(parts[2])},
+		{"wrapped key", secretPrefix + parts[0] + ":" + flip(parts[1]) + ":" + parts[2]},
+		{"unknown key id", secretPrefix + "00000000:" + parts[1] + ":" + parts[2]},
+		{"truncated", secretPrefix + parts[0] + ":" + parts[1]},
+	}
+	for _, tt := range tests {
+		if _, err := DecryptSecret(tt.value); err == nil {
+			t.Fatalf("%s: expected decrypt to fail", tt.name)
+		}
+	}
+}
+
+func TestSecretPlaintextPassthrough(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		if enabled {
+			setSecretKeys(t, "current-key", "")
+		}
+		for _, value := range []string{"", "sk-test", "enc:v2:not-ours"} {
+			decrypted, err := DecryptSecret(value)
+			if err != nil || decrypted != value {
+				t.Fatalf("expected %q to pass through, got %q, %v", value, decrypted, err)
+			}
+		}
+	}
+	encrypted, err := EncryptSecret("")
+	if err != nil || encrypted != "" {
+		t.Fatalf("expected empty value to stay empty, got %q, %v", encrypted, err)
+	}
+}
+
+func TestSecretLookupHash(t *testing.T) {
+	if SecretLookupHash("sk-test") != "" {
+		t.Fatal("expected no lookup hash without encryption")
+	}
+	setSecretKeys(t, "old-key", "")
+	oldHash := SecretLookupHash("sk-test")
+	setSecretKeys(t, "new-key", "old-key")
+	hash := SecretLookupHash("sk-test")
+	if hash == "" || hash == oldHash || hash == SecretLookupHash("sk-other") {
+		t.Fatal("expected lookup hash to depend on key and value")
+	}
+	hashes := SecretLookupHashes("sk-test")
+	if len(hashes) != 2 || !strings.Contains(strings.Join(hashes, ","), oldHash) {
+		t.Fatalf("expected hashes under all keys, got %v", hashes)
+	}
+}
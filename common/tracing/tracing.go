@@ -0,0 +1,106 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/QuantumNous/new-api"
+
+// Init 根据环境变量初始化 OTLP 链路追踪，未启用时使用 otel 默认的空实现，所有埋点均无开销
+// 采集端地址、请求头等使用 otel 标准环境变量配置，如 OTEL_EXPORTER_OTLP_ENDPOINT、OTEL_EXPORTER_OTLP_HEADERS
+func Init(ctx context.Context) (func(context.Context) error, error) {
+	if !common.GetEnvOrDefaultBool("OTEL_ENABLED", false) {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var client otlptrace.Client
+	protocol := common.GetEnvOrDefaultString("OTEL_EXPORTER_OTLP_PROTOCOL", "http/protobuf")
+	switch protocol {
+	case "grpc":
+		client = otlptracegrpc.NewClient()
+	case "http/protobuf":
+		client = otlptracehttp.NewClient()
+	default:
+		return nil, fmt.Errorf("unsupported OTEL_EXPORTER_OTLP_PROTOCOL: %s", protocol)
+	}
+	exporter, err := otlptrace.New(ctx, client)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", "new-api"), attribute.String("service.version", common.Version)),
+		// OTEL_SERVICE_NAME、OTEL_RESOURCE_ATTRIBUTES 优先
+		resource.WithFromEnv(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	common.SysLog(fmt.Sprintf("opentelemetry tracing enabled, protocol: %s", protocol))
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartServer 从请求头中提取 W3C traceparent，创建服务端 span
+func StartServer(c *gin.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	ctx := otel.GetTextMapPropagator().Extract(c.Request.Context(), propagation.HeaderCarrier(c.Request.Header))
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+}
+
+// StartClient 创建上游请求的 span，并将 traceparent 写入上游请求头
+func StartClient(ctx context.Context, req *http.Request, name string) trace.Span {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(
+		attribute.String("http.request.method", req.Method),
+		attribute.String("server.address", req.URL.Host),
+		// 不记录 query，部分渠道会在 query 中携带密钥
+		attribute.String("url.path", req.URL.Path),
+	))
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+	return span
+}
+
+// EndWithGin 结束 gin 中间件中的 span，请求被中止时标记为错误
+func EndWithGin(c *gin.Context, span trace.Span) {
+	if c.IsAborted() {
+		span.SetStatus(codes.Error, strings.TrimSpace(c.Errors.String()))
+		span.SetAttributes(attribute.Int("http.response.status_code", c.Writer.Status()))
+	}
+	span.End()
+}
+
+// RecordError 记录错误并将 span 标记为失败，err 为 nil 时不做处理
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
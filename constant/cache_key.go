@@ -11,4 +11,8 @@ const (
 const (
 	TokenFiledRemainQuota = "RemainQuota"
 	TokenFieldGroup       = "Group"
+
+	TokenFieldBudgetUsed        = "BudgetUsed"
+	TokenFieldBudgetPeriodStart = "BudgetPeriodStart"
+	TokenFieldOrgId             = "OrgId"
 )
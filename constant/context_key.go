@@ -9,6 +9,15 @@ const (
 
 	ContextKeyOriginalModel    ContextKey = "original_model"
 	ContextKeyRequestStartTime ContextKey = "request_start_time"
+	// 发生模型降级时记录用户请求的模型
+	ContextKeyModelFallbackFrom ContextKey = "model_fallback_from"
+	// 对冲请求中当前请求的状态，见 service.HedgeAttempt
+	ContextKeyHedgeAttempt ContextKey = "hedge_attempt"
+	// 会话亲和：会话标识的哈希，以及命中的渠道与密钥索引，见 service.ChannelAffinity
+	ContextKeyChannelAffinitySession ContextKey = "channel_affinity_session"
+	ContextKeyChannelAffinity        ContextKey = "channel_affinity"
+	// 渠道并发已满时排队等待的毫秒数
+	ContextKeyAdmissionWaitMs ContextKey = "admission_wait_ms"
 
 	/* token related keys */
 	ContextKeyTokenUnlimited         ContextKey = "token_unlimited_quota"
@@ -19,6 +28,14 @@ const (
 	ContextKeyTokenModelLimitEnabled ContextKey = "token_model_limit_enabled"
 	ContextKeyTokenModelLimit        ContextKey = "token_model_limit"
 	ContextKeyTokenCrossGroupRetry   ContextKey = "token_cross_group_retry"
+	ContextKeyTokenTPMLimit          ContextKey = "token_tpm_limit"
+	ContextKeyTokenConcurrencyLimit  ContextKey = "token_concurrency_limit"
+	ContextKeyTokenOrgId             ContextKey = "token_org_id"
+	ContextKeyTokenFallbackDisabled  ContextKey = "token_fallback_disabled"
+	ContextKeyTokenHedgeDelay        ContextKey = "token_hedge_delay"
+	ContextKeyTokenPriorityClass     ContextKey = "token_priority_class"
+	// 批处理后台回放的请求
+	ContextKeyBatchReplay ContextKey = "batch_replay"
 
 	/* channel related keys */
 	ContextKeyChannelId                ContextKey = "channel_id"
@@ -52,6 +69,9 @@ const (
 	ContextKeyUsingGroup  ContextKey = "group"
 	ContextKeyUserName    ContextKey = "username"
 
+	ContextKeyUserTPMLimit         ContextKey = "user_tpm_limit"
+	ContextKeyUserConcurrencyLimit ContextKey = "user_concurrency_limit"
+
 	ContextKeyLocalCountTokens ContextKey = "local_count_tokens"
 
 	ContextKeySystemPromptOverride ContextKey = "system_prompt_override"
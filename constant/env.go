@@ -17,6 +17,11 @@ var NotificationLimitDurationMinute int
 var GenerateDefaultToken bool
 var ErrorLogEnabled bool
 var TaskQueryLimit int
+var FileStorageDir string
+var BatchRequestIntervalMs int
+var BatchMaxRequests int
+var MetricsEnabled bool
+var MetricsToken string
 
 // temporary variable for sora patch, will be removed in future
 var TaskPricePatches []string
@@ -3,6 +3,7 @@ package constant
 type MultiKeyMode string
 
 const (
-	MultiKeyModeRandom  MultiKeyMode = "random"  // 随机
-	MultiKeyModePolling MultiKeyMode = "polling" // 轮询
+	MultiKeyModeRandom      MultiKeyMode = "random"       // 随机
+	MultiKeyModePolling     MultiKeyMode = "polling"      // 轮询
+	MultiKeyModeLeastLoaded MultiKeyMode = "least_loaded" // 最低负载，按各密钥用量与上游限流信息选择
 )
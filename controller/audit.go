@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// getAuditSnapshot 读取实体修改前的快照，失败时返回 nil，不影响修改本身
+func getAuditSnapshot(entityType string, entityId string) map[string]any {
+	snapshot, err := model.GetAuditEntitySnapshot(entityType, entityId)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to get audit snapshot of %s %s: %s", entityType, entityId, err.Error()))
+		return nil
+	}
+	return snapshot
+}
+
+// recordAuditVersion 在实体修改成功后记录新版本，before 为修改前的快照
+func recordAuditVersion(c *gin.Context, entityType string, entityId string, before map[string]any, action string) {
+	if before == nil {
+		return
+	}
+	after := getAuditSnapshot(entityType, entityId)
+	if after == nil {
+		return
+	}
+	err := model.RecordAuditVersion(entityType, entityId, before, after, action, c.GetInt("id"), c.GetString("username"), c.ClientIP())
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to record audit version of %s %s: %s", entityType, entityId, err.Error()))
+	}
+}
+
+// GetAuditVersions 渠道版本包含渠道配置，没有渠道读权限时不返回
+func GetAuditVersions(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	entityType := c.Query("entity_type")
+	var hiddenTypes []string
+	role, adminRole, err := model.GetUserRoles(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !common.HasAdminPermission(role, adminRole, common.PermissionChannel, false) {
+		if entityType == model.AuditEntityChannel {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无权进行此操作，管理角色权限不足",
+			})
+			return
+		}
+		hiddenTypes = append(hiddenTypes, model.AuditEntityChannel)
+	}
+	versions, total, err := model.GetAuditVersions(entityType, c.Query("entity_id"), hiddenTypes, pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(versions)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// canRollbackAuditEntity 回滚需要对应资源的写权限，系统设置只有超级管理员可以回滚
+func canRollbackAuditEntity(c *gin.Context, entityType string) bool {
+	role, adminRole, err := model.GetUserRoles(c.GetInt("id"))
+	if err != nil {
+		return false
+	}
+	switch entityType {
+	case model.AuditEntityOption:
+		return role >= common.RoleRootUser
+	case model.AuditEntityChannel:
+		return common.HasAdminPermission(role, adminRole, common.PermissionChannel, true)
+	default:
+		return common.HasAdminPermission(role, adminRole, common.PermissionUser, true)
+	}
+}
+
+// RollbackAuditVersion 将实体恢复到指定版本，回滚本身也会记录为新版本
+func RollbackAuditVersion(c *gin.Context) {
+	id, _ := strconv.Atoi(c.Param("id"))
+	version, err := model.GetAuditVersionById(id)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "版本不存在",
+		})
+		return
+	}
+	if !canRollbackAuditEntity(c, version.EntityType) {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "无权进行此操作，管理角色权限不足",
+		})
+		return
+	}
+	before := getAuditSnapshot(version.EntityType, version.EntityId)
+	if err := model.ApplyAuditVersion(version); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if version.EntityType == model.AuditEntityChannel {
+		model.InitChannelCache()
+		service.ResetProxyClientCache()
+	}
+	recordAuditVersion(c, version.EntityType, version.EntityId, before, model.AuditActionRollback)
+	common.ApiSuccess(c, nil)
+}
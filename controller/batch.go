@@ -0,0 +1,668 @@
+package controller
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	storageService "github.com/QuantumNous/new-api/service/storage"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 批处理支持的端点及其对应的转发格式
+var batchEndpoints = map[string]types.RelayFormat{
+	"/v1/chat/completions": types.RelayFormatOpenAI,
+	"/v1/completions":      types.RelayFormatOpenAI,
+	"/v1/embeddings":       types.RelayFormatEmbedding,
+	"/v1/responses":        types.RelayFormatOpenAIResponses,
+	"/v1/moderations":      types.RelayFormatOpenAI,
+}
+
+const batchScanMaxLineSize = 64 << 20
+
+type batchCreateRequest struct {
+	InputFileId      string          `json:"input_file_id"`
+	Endpoint         string          `json:"endpoint"`
+	CompletionWindow string          `json:"completion_window"`
+	Metadata         json.RawMessage `json:"metadata,omitempty"`
+}
+
+// batchRequestLine 输入文件中的单行请求
+type batchRequestLine struct {
+	CustomId string          `json:"custom_id"`
+	Method   string          `json:"method"`
+	Url      string          `json:"url"`
+	Body     json.RawMessage `json:"body"`
+}
+
+type batchResponseBody struct {
+	StatusCode int             `json:"status_code"`
+	RequestId  string          `json:"request_id"`
+	Body       json.RawMessage `json:"body"`
+}
+
+type batchLineError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// batchResultLine 输出文件与错误文件中的单行结果
+type batchResultLine struct {
+	Id       string             `json:"id"`
+	CustomId string             `json:"custom_id"`
+	Response *batchResponseBody `json:"response"`
+	Error    *batchLineError    `json:"error"`
+}
+
+func CreateBatch(c *gin.Context) {
+	var req batchCreateRequest
+	if err := common.UnmarshalBodyReusable(c, &req); err != nil {
+		fileError(c, http.StatusBadRequest, "invalid request body")
+		return
+	}
+	if _, ok := batchEndpoints[req.Endpoint]; !ok {
+		fileError(c, http.StatusBadRequest, fmt.Sprintf("unsupported endpoint: %s", req.Endpoint))
+		return
+	}
+	if req.CompletionWindow == "" {
+		req.CompletionWindow = "24h"
+	}
+	window, err := time.ParseDuration(req.CompletionWindow)
+	if err != nil || window <= 0 {
+		fileError(c, http.StatusBadRequest, fmt.Sprintf("invalid completion_window: %s", req.CompletionWindow))
+		return
+	}
+	// 令牌的接口限制在创建时按批处理端点检查一次，回放时按实际请求再次检查
+	token, err := model.GetTokenById(c.GetInt("token_id"))
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to query token")
+		return
+	}
+	policy := token.GetPolicy()
+	if !model.TokenPolicyAllowsEndpoint(&policy, req.Endpoint) {
+		fileError(c, http.StatusForbidden, fmt.Sprintf("this token is not allowed to access endpoint: %s", req.Endpoint))
+		return
+	}
+	userId := c.GetInt("id")
+	inputFile, exist, err := model.GetUserFile(userId, req.InputFileId)
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to query input file")
+		return
+	}
+	if !exist || inputFile.Purpose != model.FilePurposeBatch {
+		fileError(c, http.StatusBadRequest, fmt.Sprintf("invalid input_file_id: %s", req.InputFileId))
+		return
+	}
+
+	now := common.GetTimestamp()
+	batch := &model.Batch{
+		BatchId:          "batch_" + common.GetUUID(),
+		UserId:           userId,
+		TokenId:          c.GetInt("token_id"),
+		Endpoint:         req.Endpoint,
+		InputFileId:      req.InputFileId,
+		CompletionWindow: req.CompletionWindow,
+		Status:           model.BatchStatusValidating,
+		Metadata:         req.Metadata,
+		ClientIp:         c.ClientIP(),
+		CreatedAt:        now,
+		ExpiresAt:        now + int64(window.Seconds()),
+	}
+	if err := batch.Insert(); err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to create batch")
+		return
+	}
+	c.JSON(http.StatusOK, batch.Normalize())
+}
+
+func ListBatches(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	// 多查一条用于判断 has_more
+	batches, err := model.GetUserBatches(c.GetInt("id"), c.Query("after"), limit+1)
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to list batches")
+		return
+	}
+	hasMore := len(batches) > limit
+	if hasMore {
+		batches = batches[:limit]
+	}
+	response := gin.H{
+		"object":   "list",
+		"data":     batches,
+		"has_more": hasMore,
+	}
+	if len(batches) > 0 {
+		response["first_id"] = batches[0].BatchId
+		response["last_id"] = batches[len(batches)-1].BatchId
+	}
+	for _, batch := range batches {
+		batch.Normalize()
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func getUserBatchOrAbort(c *gin.Context) *model.Batch {
+	batchId := c.Param("id")
+	batch, exist, err := model.GetUserBatch(c.GetInt("id"), batchId)
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to query batch")
+		return nil
+	}
+	if !exist {
+		fileError(c, http.StatusNotFound, fmt.Sprintf("No such Batch object: %s", batchId))
+		return nil
+	}
+	return batch
+}
+
+func GetBatch(c *gin.Context) {
+	batch := getUserBatchOrAbort(c)
+	if batch == nil {
+		return
+	}
+	c.JSON(http.StatusOK, batch.Normalize())
+}
+
+func CancelBatch(c *gin.Context) {
+	batch := getUserBatchOrAbort(c)
+	if batch == nil {
+		return
+	}
+	if batch.Status.IsFinished() || batch.Status == model.BatchStatusCancelling {
+		fileError(c, http.StatusConflict, fmt.Sprintf("Cannot cancel a batch with status '%s'", batch.Status))
+		return
+	}
+	cancellingAt := common.GetTimestamp()
+	// 只取消仍在校验或执行中的批处理，避免覆盖后台同时写入的结束状态
+	result := model.DB.Model(&model.Batch{}).Where("id = ? AND status IN ?", batch.Id,
+		[]model.BatchStatus{model.BatchStatusValidating, model.BatchStatusInProgress}).Updates(map[string]any{
+		"status":        model.BatchStatusCancelling,
+		"cancelling_at": cancellingAt,
+	})
+	if result.Error != nil {
+		fileError(c, http.StatusInternalServerError, "failed to cancel batch")
+		return
+	}
+	if result.RowsAffected == 0 {
+		status, _ := model.GetBatchStatus(batch.Id)
+		fileError(c, http.StatusConflict, fmt.Sprintf("Cannot cancel a batch with status '%s'", status))
+		return
+	}
+	batch.Status = model.BatchStatusCancelling
+	batch.CancellingAt = cancellingAt
+	c.JSON(http.StatusOK, batch.Normalize())
+}
+
+var (
+	batchRelayEngine     *gin.Engine
+	batchRelayEngineOnce sync.Once
+)
+
+// getBatchRelayEngine 构建批处理回放使用的内部路由，复用与 /v1 相同的鉴权、渠道选择与转发流程
+func getBatchRelayEngine() *gin.Engine {
+	batchRelayEngineOnce.Do(func() {
+		engine := gin.New()
+		engine.Use(gin.Recovery(), middleware.RequestId(), func(c *gin.Context) {
+			common.SetContextKey(c, constant.ContextKeyBatchReplay, true)
+		})
+		relayRouter := engine.Group("")
+		relayRouter.Use(middleware.TokenAuth(), middleware.Distribute())
+		for endpoint, relayFormat := range batchEndpoints {
+			format := relayFormat
+			relayRouter.POST(endpoint, func(c *gin.Context) {
+				Relay(c, format)
+			})
+		}
+		batchRelayEngine = engine
+	})
+	return batchRelayEngine
+}
+
+// RunBatchJobs 后台按创建顺序逐个执行批处理任务，逐行串行回放以降低对实时请求的影响
+func RunBatchJobs() {
+	for {
+		time.Sleep(10 * time.Second)
+		batches, err := model.GetUnfinishedBatches(10)
+		if err != nil {
+			common.SysError("failed to query unfinished batches: " + err.Error())
+			continue
+		}
+		for _, batch := range batches {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						common.SysError(fmt.Sprintf("batch %s panic: %v", batch.BatchId, r))
+					}
+				}()
+				processBatch(batch)
+			}()
+		}
+	}
+}
+
+func processBatch(batch *model.Batch) {
+	ctx := context.Background()
+	switch batch.Status {
+	case model.BatchStatusCancelling:
+		if batch.InProgressAt == 0 {
+			batch.Status = model.BatchStatusCancelled
+			batch.CancelledAt = common.GetTimestamp()
+			_ = batch.Update()
+			return
+		}
+		finalizeBatch(ctx, batch, model.BatchStatusCancelled)
+		return
+	case model.BatchStatusValidating:
+		if !validateBatch(ctx, batch) {
+			return
+		}
+	case model.BatchStatusFinalizing:
+		finalizeBatch(ctx, batch, model.BatchStatusCompleted)
+		return
+	}
+	runBatch(ctx, batch)
+}
+
+func failBatch(batch *model.Batch, code string, message string, line *int) {
+	batch.Status = model.BatchStatusFailed
+	batch.FailedAt = common.GetTimestamp()
+	batch.Errors = model.BatchErrors{
+		Object: "list",
+		Data: []model.BatchErrorDetail{
+			{Code: code, Message: message, Line: line},
+		},
+	}
+	if err := batch.Update(); err != nil {
+		common.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+	}
+}
+
+func openBatchInput(ctx context.Context, batch *model.Batch) (io.ReadCloser, error) {
+	inputFile, exist, err := model.GetUserFile(batch.UserId, batch.InputFileId)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return nil, fmt.Errorf("input file %s not found", batch.InputFileId)
+	}
+	return storageService.OpenFile(ctx, inputFile.StorageType, inputFile.StorageKey)
+}
+
+func newBatchScanner(reader io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), batchScanMaxLineSize)
+	return scanner
+}
+
+// validateBatch 校验输入文件的每一行，通过后进入执行阶段
+func validateBatch(ctx context.Context, batch *model.Batch) bool {
+	reader, err := openBatchInput(ctx, batch)
+	if err != nil {
+		failBatch(batch, "invalid_input_file", err.Error(), nil)
+		return false
+	}
+	defer reader.Close()
+
+	total := 0
+	customIds := make(map[string]struct{})
+	scanner := newBatchScanner(reader)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		total++
+		lineNo := total
+		var line batchRequestLine
+		if err := common.Unmarshal(scanner.Bytes(), &line); err != nil {
+			failBatch(batch, "invalid_json_line", "This line is not parseable as valid JSON.", &lineNo)
+			return false
+		}
+		if line.CustomId == "" {
+			failBatch(batch, "missing_required_parameter", "custom_id is required", &lineNo)
+			return false
+		}
+		if _, ok := customIds[line.CustomId]; ok {
+			failBatch(batch, "duplicate_custom_id", fmt.Sprintf("The custom_id %s is duplicated.", line.CustomId), &lineNo)
+			return false
+		}
+		customIds[line.CustomId] = struct{}{}
+		if line.Method != http.MethodPost {
+			failBatch(batch, "invalid_method", "Only POST method is supported.", &lineNo)
+			return false
+		}
+		if line.Url != batch.Endpoint {
+			failBatch(batch, "mismatched_endpoint", fmt.Sprintf("The url %s does not match the batch endpoint %s.", line.Url, batch.Endpoint), &lineNo)
+			return false
+		}
+		if common.GetJsonType(line.Body) != "object" {
+			failBatch(batch, "invalid_request", "body must be a JSON object", &lineNo)
+			return false
+		}
+		if total > constant.BatchMaxRequests {
+			failBatch(batch, "too_many_requests", fmt.Sprintf("The input file contains more than %d requests.", constant.BatchMaxRequests), nil)
+			return false
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		failBatch(batch, "invalid_input_file", err.Error(), nil)
+		return false
+	}
+	if total == 0 {
+		failBatch(batch, "empty_file", "The input file is empty.", nil)
+		return false
+	}
+
+	batch.Status = model.BatchStatusInProgress
+	batch.InProgressAt = common.GetTimestamp()
+	batch.RequestTotal = total
+	if err := batch.Update(); err != nil {
+		common.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+		return false
+	}
+	return true
+}
+
+// batchWorkDir 批处理执行期间的本地结果目录，服务重启后据此续跑
+func batchWorkDir(batch *model.Batch) string {
+	return filepath.Join(constant.FileStorageDir, "batches", batch.BatchId)
+}
+
+// loadBatchResults 读取结果文件中已处理的 custom_id，返回结果行数
+func loadBatchResults(path string, done map[string]struct{}) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+	count := 0
+	scanner := newBatchScanner(file)
+	for scanner.Scan() {
+		var result batchResultLine
+		if err := common.Unmarshal(scanner.Bytes(), &result); err != nil || result.CustomId == "" {
+			continue
+		}
+		if _, ok := done[result.CustomId]; ok {
+			continue
+		}
+		done[result.CustomId] = struct{}{}
+		count++
+	}
+	return count, scanner.Err()
+}
+
+func runBatch(ctx context.Context, batch *model.Batch) {
+	token, err := model.GetTokenById(batch.TokenId)
+	if err != nil {
+		failBatch(batch, "invalid_token", "the token used to create this batch is no longer available", nil)
+		return
+	}
+
+	reader, err := openBatchInput(ctx, batch)
+	if err != nil {
+		failBatch(batch, "invalid_input_file", err.Error(), nil)
+		return
+	}
+	defer reader.Close()
+
+	workDir := batchWorkDir(batch)
+	if err := os.MkdirAll(workDir, 0755); err != nil {
+		common.SysError(fmt.Sprintf("failed to create batch work dir %s: %s", workDir, err.Error()))
+		return
+	}
+	outputWriter, err := os.OpenFile(filepath.Join(workDir, "output.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to open batch output file: %s", err.Error()))
+		return
+	}
+	defer outputWriter.Close()
+	errorWriter, err := os.OpenFile(filepath.Join(workDir, "error.jsonl"), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to open batch error file: %s", err.Error()))
+		return
+	}
+	defer errorWriter.Close()
+
+	// 以结果文件中的 custom_id 为准跳过已处理的请求：写入结果后、更新进度前中断时不会重复执行
+	done := make(map[string]struct{})
+	completed, err := loadBatchResults(filepath.Join(workDir, "output.jsonl"), done)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to read batch %s output file: %s", batch.BatchId, err.Error()))
+		return
+	}
+	failed, err := loadBatchResults(filepath.Join(workDir, "error.jsonl"), done)
+	if err != nil {
+		common.SysError(fmt.Sprintf("failed to read batch %s error file: %s", batch.BatchId, err.Error()))
+		return
+	}
+	// 执行中的请求在回放前记录，中断时该请求可能已经计费，不再重试，记为失败
+	inflightPath := filepath.Join(workDir, "inflight")
+	if data, err := os.ReadFile(inflightPath); err == nil {
+		customId := string(data)
+		if _, ok := done[customId]; customId != "" && !ok {
+			resultBytes, _ := common.Marshal(&batchResultLine{
+				Id:       "batch_req_" + common.GetUUID(),
+				CustomId: customId,
+				Error: &batchLineError{
+					Code:    "request_interrupted",
+					Message: "The request was interrupted by a server restart and was not retried to avoid duplicate charges.",
+				},
+			})
+			_, _ = errorWriter.Write(append(resultBytes, '\n'))
+			done[customId] = struct{}{}
+			failed++
+		}
+	}
+	if completed != batch.RequestCompleted || failed != batch.RequestFailed {
+		batch.RequestCompleted = completed
+		batch.RequestFailed = failed
+		if err := batch.UpdateProgress(); err != nil {
+			common.SysError(fmt.Sprintf("failed to update batch %s progress: %s", batch.BatchId, err.Error()))
+		}
+	}
+
+	scanner := newBatchScanner(reader)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var line batchRequestLine
+		_ = common.Unmarshal(scanner.Bytes(), &line)
+		if _, ok := done[line.CustomId]; ok {
+			continue
+		}
+
+		status, err := model.GetBatchStatus(batch.Id)
+		if err == nil && status == model.BatchStatusCancelling {
+			finalizeBatch(ctx, batch, model.BatchStatusCancelled)
+			return
+		}
+		if common.GetTimestamp() > batch.ExpiresAt {
+			finalizeBatch(ctx, batch, model.BatchStatusExpired)
+			return
+		}
+
+		if err := os.WriteFile(inflightPath, []byte(line.CustomId), 0644); err != nil {
+			common.SysError(fmt.Sprintf("failed to mark batch %s request %s in flight: %s", batch.BatchId, line.CustomId, err.Error()))
+			return
+		}
+		result := replayBatchLine(token, batch, line)
+		resultBytes, _ := common.Marshal(result)
+		resultBytes = append(resultBytes, '\n')
+		if result.Error == nil {
+			_, _ = outputWriter.Write(resultBytes)
+			batch.RequestCompleted++
+		} else {
+			_, _ = errorWriter.Write(resultBytes)
+			batch.RequestFailed++
+		}
+		if err := batch.UpdateProgress(); err != nil {
+			common.SysError(fmt.Sprintf("failed to update batch %s progress: %s", batch.BatchId, err.Error()))
+		}
+
+		if constant.BatchRequestIntervalMs > 0 {
+			time.Sleep(time.Duration(constant.BatchRequestIntervalMs) * time.Millisecond)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		common.SysError(fmt.Sprintf("failed to read batch %s input: %s", batch.BatchId, err.Error()))
+		return
+	}
+	_ = os.Remove(inflightPath)
+
+	batch.Status = model.BatchStatusFinalizing
+	batch.FinalizingAt = common.GetTimestamp()
+	if err := model.DB.Model(&model.Batch{}).Where("id = ?", batch.Id).Updates(map[string]any{
+		"status":        batch.Status,
+		"finalizing_at": batch.FinalizingAt,
+	}).Error; err != nil {
+		common.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+		return
+	}
+	finalizeBatch(ctx, batch, model.BatchStatusCompleted)
+}
+
+// replayBatchLine 通过内部路由回放单行请求，计费由正常转发流程按实际用量结算
+func replayBatchLine(token *model.Token, batch *model.Batch, line batchRequestLine) *batchResultLine {
+	result := &batchResultLine{
+		Id:       "batch_req_" + common.GetUUID(),
+		CustomId: line.CustomId,
+	}
+
+	// 批处理不支持流式输出
+	var body map[string]any
+	if err := common.Unmarshal(line.Body, &body); err != nil {
+		result.Error = &batchLineError{Code: "invalid_request", Message: err.Error()}
+		return result
+	}
+	delete(body, "stream")
+	delete(body, "stream_options")
+	bodyBytes, _ := common.Marshal(body)
+
+	req := httptest.NewRequest(http.MethodPost, line.Url, bytes.NewReader(bodyBytes))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer sk-"+token.Key)
+	if batch.ClientIp != "" {
+		req.RemoteAddr = net.JoinHostPort(batch.ClientIp, "0")
+	}
+	recorder := httptest.NewRecorder()
+	getBatchRelayEngine().ServeHTTP(recorder, req)
+
+	responseBody := recorder.Body.Bytes()
+	if !json.Valid(responseBody) {
+		responseBody, _ = common.Marshal(string(responseBody))
+	}
+	result.Response = &batchResponseBody{
+		StatusCode: recorder.Code,
+		RequestId:  recorder.Header().Get(common.RequestIdKey),
+		Body:       responseBody,
+	}
+	if recorder.Code != http.StatusOK {
+		var errorResponse struct {
+			Error types.OpenAIError `json:"error"`
+		}
+		_ = common.Unmarshal(recorder.Body.Bytes(), &errorResponse)
+		result.Error = &batchLineError{
+			Code:    "request_failed",
+			Message: errorResponse.Error.Message,
+		}
+		if code := common.Interface2String(errorResponse.Error.Code); code != "" {
+			result.Error.Code = code
+		}
+		if result.Error.Message == "" {
+			result.Error.Message = http.StatusText(recorder.Code)
+		}
+	}
+	return result
+}
+
+// finalizeBatch 上传输出文件与错误文件并结束批处理
+func finalizeBatch(ctx context.Context, batch *model.Batch, finalStatus model.BatchStatus) {
+	workDir := batchWorkDir(batch)
+	outputFileId, err := saveBatchResultFile(ctx, batch, filepath.Join(workDir, "output.jsonl"), "output")
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to save batch %s output file: %s", batch.BatchId, err.Error()))
+		return
+	}
+	errorFileId, err := saveBatchResultFile(ctx, batch, filepath.Join(workDir, "error.jsonl"), "error")
+	if err != nil {
+		logger.LogError(ctx, fmt.Sprintf("failed to save batch %s error file: %s", batch.BatchId, err.Error()))
+		return
+	}
+
+	now := common.GetTimestamp()
+	batch.OutputFileId = outputFileId
+	batch.ErrorFileId = errorFileId
+	batch.Status = finalStatus
+	switch finalStatus {
+	case model.BatchStatusCompleted:
+		batch.CompletedAt = now
+	case model.BatchStatusCancelled:
+		batch.CancelledAt = now
+	case model.BatchStatusExpired:
+		batch.ExpiredAt = now
+	}
+	if err := batch.Update(); err != nil {
+		common.SysError(fmt.Sprintf("failed to update batch %s: %s", batch.BatchId, err.Error()))
+		return
+	}
+	_ = os.RemoveAll(workDir)
+}
+
+// saveBatchResultFile 将本地结果文件保存为用户文件，无内容时返回空 id
+func saveBatchResultFile(ctx context.Context, batch *model.Batch, path string, kind string) (string, error) {
+	stat, err := os.Stat(path)
+	if os.IsNotExist(err) || (err == nil && stat.Size() == 0) {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	reader, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer reader.Close()
+
+	file := &model.File{
+		FileId:   "file-" + common.GetUUID(),
+		UserId:   batch.UserId,
+		Filename: fmt.Sprintf("%s_%s.jsonl", batch.BatchId, kind),
+		Purpose:  model.FilePurposeBatchOutput,
+		Bytes:    stat.Size(),
+		Status:   model.FileStatusProcessed,
+	}
+	file.StorageKey = storageService.GetFileStorageKey(batch.UserId, file.FileId)
+	file.StorageType, err = storageService.SaveFile(ctx, file.StorageKey, reader, stat.Size(), "application/jsonl")
+	if err != nil {
+		return "", err
+	}
+	if err := file.Insert(); err != nil {
+		return "", err
+	}
+	return file.FileId, nil
+}
@@ -2,6 +2,7 @@ package controller
 
 import (
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/model"
 	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
@@ -20,6 +21,13 @@ func GetSubscription(c *gin.Context) {
 		expiredTime = token.ExpiredTime
 		remainQuota = token.RemainQuota
 		usedQuota = token.UsedQuota
+	} else if orgId := common.GetContextKeyInt(c, constant.ContextKeyTokenOrgId); orgId != 0 {
+		var org *model.Organization
+		org, err = model.GetOrganizationById(orgId)
+		if err == nil {
+			remainQuota = org.Quota
+			usedQuota = org.UsedQuota
+		}
 	} else {
 		userId := c.GetInt("id")
 		remainQuota, err = model.GetUserQuota(userId, false)
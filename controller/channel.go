@@ -488,6 +488,12 @@ func GetChannel(c *gin.Context) {
 	return
 }
 
+// GetChannelStats 获取渠道在各模型上的自适应选择统计（当前节点），可通过 channel_id 过滤
+func GetChannelStats(c *gin.Context) {
+	channelId, _ := strconv.Atoi(c.Query("channel_id"))
+	common.ApiSuccess(c, model.GetChannelStats(channelId))
+}
+
 // GetChannelKey 获取渠道密钥（需要通过安全验证中间件）
 // 此函数依赖 SecureVerificationRequired 中间件，确保用户已通过安全验证
 func GetChannelKey(c *gin.Context) {
@@ -921,6 +927,8 @@ func UpdateChannel(c *gin.Context) {
 		return
 	}
 
+	auditBefore := getAuditSnapshot(model.AuditEntityChannel, strconv.Itoa(channel.Id))
+
 	// Always copy the original ChannelInfo so that fields like IsMultiKey and MultiKeySize are retained.
 	channel.ChannelInfo = originChannel.ChannelInfo
 
@@ -1016,6 +1024,7 @@ func UpdateChannel(c *gin.Context) {
 	}
 	model.InitChannelCache()
 	service.ResetProxyClientCache()
+	recordAuditVersion(c, model.AuditEntityChannel, strconv.Itoa(channel.Id), auditBefore, model.AuditActionUpdate)
 	channel.Key = ""
 	clearChannelInfo(&channel.Channel)
 	c.JSON(http.StatusOK, gin.H{
@@ -1273,6 +1282,8 @@ type KeyStatus struct {
 	DisabledTime int64  `json:"disabled_time,omitempty"`
 	Reason       string `json:"reason,omitempty"`
 	KeyPreview   string `json:"key_preview"` // first 10 chars of key for identification
+	// 当前节点记录的密钥用量，密钥尚未被使用时为空
+	Usage *model.ChannelKeyUsage `json:"usage,omitempty"`
 }
 
 // ManageMultiKeys handles multi-key management operations
@@ -1322,6 +1333,8 @@ func ManageMultiKeys(c *gin.Context) {
 		// Statistics for all keys (unchanged by filtering)
 		var enabledCount, manualDisabledCount, autoDisabledCount int
 
+		keyUsage := model.GetChannelKeyUsage(channel.Id)
+
 		// Build all key status data first
 		var allKeyStatusList []KeyStatus
 		for i, key := range keys {
@@ -1360,13 +1373,17 @@ func ManageMultiKeys(c *gin.Context) {
 				keyPreview = key[:10] + "..."
 			}
 
-			allKeyStatusList = append(allKeyStatusList, KeyStatus{
+			keyStatus := KeyStatus{
 				Index:        i,
 				Status:       status,
 				DisabledTime: disabledTime,
 				Reason:       reason,
 				KeyPreview:   keyPreview,
-			})
+			}
+			if usage, ok := keyUsage[i]; ok {
+				keyStatus.Usage = &usage
+			}
+			allKeyStatusList = append(allKeyStatusList, keyStatus)
 		}
 
 		// Apply status filter if specified
@@ -1649,6 +1666,8 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		// 删除后密钥索引发生变化，原有用量不再对应
+		model.ResetChannelKeyUsage(channel.Id)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -1717,6 +1736,7 @@ func ManageMultiKeys(c *gin.Context) {
 			return
 		}
 
+		model.ResetChannelKeyUsage(channel.Id)
 		model.InitChannelCache()
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
@@ -0,0 +1,81 @@
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+func exportConfig(c *gin.Context, includeKeys bool) {
+	doc, err := model.ExportConfigDocument(includeKeys)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	format := c.DefaultQuery("format", "yaml")
+	data, err := model.MarshalConfigDocument(doc, format)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	contentType := "application/yaml"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	filename := fmt.Sprintf("new-api-config-%s.%s", time.Now().Format("20060102150405"), format)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// ExportConfig 导出不含渠道密钥的配置文档
+func ExportConfig(c *gin.Context) {
+	exportConfig(c, false)
+}
+
+// ExportConfigWithKeys 导出包含渠道密钥的配置文档，需要通过安全验证
+func ExportConfigWithKeys(c *gin.Context) {
+	exportConfig(c, true)
+}
+
+// ImportConfig 导入配置文档，默认只返回变更预览，dry_run=false 时才会应用
+func ImportConfig(c *gin.Context) {
+	data, err := c.GetRawData()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	doc, err := model.ParseConfigDocument(data)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	opts := model.ConfigImportOptions{
+		DryRun:    c.Query("dry_run") != "false",
+		Prune:     c.Query("prune") == "true",
+		ActorId:   c.GetInt("id"),
+		ActorName: c.GetString("username"),
+		Ip:        c.ClientIP(),
+	}
+	changes, err := model.ImportConfigDocument(doc, opts)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if !opts.DryRun && len(changes) > 0 {
+		// 渠道与价格缓存已在提交后刷新，代理配置可能随渠道设置变化
+		service.ResetProxyClientCache()
+	}
+	if changes == nil {
+		changes = []model.ConfigChange{}
+	}
+	common.ApiSuccess(c, gin.H{
+		"dry_run": opts.DryRun,
+		"changes": changes,
+	})
+}
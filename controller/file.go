@@ -0,0 +1,157 @@
+package controller
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	storageService "github.com/QuantumNous/new-api/service/storage"
+
+	"github.com/gin-gonic/gin"
+)
+
+func fileError(c *gin.Context, statusCode int, message string) {
+	errType := "invalid_request_error"
+	if statusCode >= http.StatusInternalServerError {
+		errType = "server_error"
+	}
+	c.JSON(statusCode, gin.H{
+		"error": gin.H{
+			"message": common.MessageWithRequestId(message, c.GetString(common.RequestIdKey)),
+			"type":    errType,
+		},
+	})
+}
+
+// UploadFile 上传文件，目前仅支持 purpose=batch
+func UploadFile(c *gin.Context) {
+	userId := c.GetInt("id")
+	purpose := c.PostForm("purpose")
+	if purpose != model.FilePurposeBatch {
+		fileError(c, http.StatusBadRequest, fmt.Sprintf("unsupported purpose: %s, only %s is supported", purpose, model.FilePurposeBatch))
+		return
+	}
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		fileError(c, http.StatusBadRequest, "file is required")
+		return
+	}
+	if fileHeader.Size > int64(constant.MaxRequestBodyMB)<<20 {
+		fileError(c, http.StatusRequestEntityTooLarge, fmt.Sprintf("file is too large, max size is %d MB", constant.MaxRequestBodyMB))
+		return
+	}
+	reader, err := fileHeader.Open()
+	if err != nil {
+		fileError(c, http.StatusBadRequest, "failed to open file")
+		return
+	}
+	defer reader.Close()
+
+	file := &model.File{
+		FileId:   "file-" + common.GetUUID(),
+		UserId:   userId,
+		Filename: fileHeader.Filename,
+		Purpose:  purpose,
+		Bytes:    fileHeader.Size,
+		Status:   model.FileStatusProcessed,
+	}
+	file.StorageKey = storageService.GetFileStorageKey(userId, file.FileId)
+	file.StorageType, err = storageService.SaveFile(c.Request.Context(), file.StorageKey, reader, fileHeader.Size, "application/jsonl")
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to save file %s: %s", file.FileId, err.Error()))
+		fileError(c, http.StatusInternalServerError, "failed to save file")
+		return
+	}
+	if err := file.Insert(); err != nil {
+		_ = storageService.DeleteFile(c.Request.Context(), file.StorageType, file.StorageKey)
+		fileError(c, http.StatusInternalServerError, "failed to save file")
+		return
+	}
+	c.JSON(http.StatusOK, file.Normalize())
+}
+
+func ListFiles(c *gin.Context) {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "100"))
+	if limit <= 0 || limit > 10000 {
+		limit = 100
+	}
+	files, err := model.GetUserFiles(c.GetInt("id"), c.Query("purpose"), limit)
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to list files")
+		return
+	}
+	for _, file := range files {
+		file.Normalize()
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"object":   "list",
+		"data":     files,
+		"has_more": false,
+	})
+}
+
+func getUserFileOrAbort(c *gin.Context) *model.File {
+	fileId := c.Param("id")
+	file, exist, err := model.GetUserFile(c.GetInt("id"), fileId)
+	if err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to query file")
+		return nil
+	}
+	if !exist {
+		fileError(c, http.StatusNotFound, fmt.Sprintf("No such File object: %s", fileId))
+		return nil
+	}
+	return file
+}
+
+func GetFile(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	c.JSON(http.StatusOK, file.Normalize())
+}
+
+func GetFileContent(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	reader, err := storageService.OpenFile(c.Request.Context(), file.StorageType, file.StorageKey)
+	if err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to open file %s: %s", file.FileId, err.Error()))
+		fileError(c, http.StatusInternalServerError, "failed to read file content")
+		return
+	}
+	defer reader.Close()
+	c.Header("Content-Type", "application/octet-stream")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", file.Filename))
+	c.Status(http.StatusOK)
+	if _, err := io.Copy(c.Writer, reader); err != nil {
+		logger.LogError(c, fmt.Sprintf("failed to write file %s: %s", file.FileId, err.Error()))
+	}
+}
+
+func DeleteFile(c *gin.Context) {
+	file := getUserFileOrAbort(c)
+	if file == nil {
+		return
+	}
+	if err := storageService.DeleteFile(c.Request.Context(), file.StorageType, file.StorageKey); err != nil {
+		logger.LogWarn(c, fmt.Sprintf("failed to delete file %s from storage: %s", file.FileId, err.Error()))
+	}
+	if err := file.Delete(); err != nil {
+		fileError(c, http.StatusInternalServerError, "failed to delete file")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"id":      file.FileId,
+		"object":  "file",
+		"deleted": true,
+	})
+}
@@ -4,7 +4,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"strings"
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
@@ -20,11 +19,7 @@ func GetOptions(c *gin.Context) {
 	var options []*model.Option
 	common.OptionMapRWMutex.Lock()
 	for k, v := range common.OptionMap {
-		if strings.HasSuffix(k, "Token") ||
-			strings.HasSuffix(k, "Secret") ||
-			strings.HasSuffix(k, "Key") ||
-			strings.HasSuffix(k, "secret") ||
-			strings.HasSuffix(k, "api_key") {
+		if model.IsSensitiveOptionKey(k) {
 			continue
 		}
 		options = append(options, &model.Option{
@@ -177,6 +172,15 @@ func UpdateOption(c *gin.Context) {
 			})
 			return
 		}
+	case "GroupRelayLimit":
+		err = setting.CheckGroupRelayLimit(option.Value.(string))
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
 	case "console_setting.api_info":
 		err = console_setting.ValidateConsoleSettings(option.Value.(string), "ApiInfo")
 		if err != nil {
@@ -214,11 +218,16 @@ func UpdateOption(c *gin.Context) {
 			return
 		}
 	}
+	var auditBefore map[string]any
+	if !model.IsSensitiveOptionKey(option.Key) {
+		auditBefore = getAuditSnapshot(model.AuditEntityOption, option.Key)
+	}
 	err = model.UpdateOption(option.Key, option.Value.(string))
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordAuditVersion(c, model.AuditEntityOption, option.Key, auditBefore, model.AuditActionUpdate)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -0,0 +1,431 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+type organizationRequest struct {
+	Id     int    `json:"id"`
+	Name   string `json:"name"`
+	Status int    `json:"status"`
+	Quota  *int   `json:"quota"`
+}
+
+type organizationMemberRequest struct {
+	UserId         int    `json:"user_id"`
+	Role           string `json:"role"`
+	QuotaLimit     int    `json:"quota_limit"`
+	ResetUsedQuota bool   `json:"reset_used_quota"`
+}
+
+type organizationInvitationRequest struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+func organizationError(c *gin.Context, message string) {
+	c.JSON(http.StatusOK, gin.H{
+		"success": false,
+		"message": message,
+	})
+}
+
+// getOrganizationMembership 读取路径中的组织，并确认当前用户是组织成员
+func getOrganizationMembership(c *gin.Context, requireManage bool) (*model.Organization, *model.OrganizationMember, bool) {
+	orgId, _ := strconv.Atoi(c.Param("id"))
+	org, err := model.GetOrganizationById(orgId)
+	if err != nil {
+		organizationError(c, "组织不存在")
+		return nil, nil, false
+	}
+	member, err := model.GetOrganizationMember(org.Id, c.GetInt("id"))
+	if err != nil {
+		organizationError(c, "你不是该组织的成员")
+		return nil, nil, false
+	}
+	if requireManage && !member.CanManageOrganization() {
+		organizationError(c, "只有组织所有者或管理员可以进行此操作")
+		return nil, nil, false
+	}
+	return org, member, true
+}
+
+func validateOrganizationName(name string) error {
+	if name == "" {
+		return errors.New("组织名称不能为空")
+	}
+	if len(name) > 64 {
+		return errors.New("组织名称过长")
+	}
+	return nil
+}
+
+func GetAllOrganizations(c *gin.Context) {
+	pageInfo := common.GetPageQuery(c)
+	orgs, total, err := model.GetAllOrganizations(c.Query("keyword"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(orgs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+// AdminUpdateOrganization 管理员修改组织名称、状态与额度
+func AdminUpdateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	org, err := model.GetOrganizationById(req.Id)
+	if err != nil {
+		organizationError(c, "组织不存在")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateOrganizationName(req.Name); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	if req.Status != model.OrganizationStatusEnabled && req.Status != model.OrganizationStatusDisabled {
+		organizationError(c, "无效的组织状态")
+		return
+	}
+	org.Name = req.Name
+	org.Status = req.Status
+	if err := org.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.Quota != nil && *req.Quota != org.Quota {
+		if err := model.SetOrganizationQuota(org.Id, *req.Quota); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		model.RecordLog(org.OwnerId, model.LogTypeManage, fmt.Sprintf("管理员将组织 %s 的额度从 %s修改为 %s", org.Name, logger.LogQuota(org.Quota), logger.LogQuota(*req.Quota)))
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetSelfOrganizations(c *gin.Context) {
+	orgs, err := model.GetUserOrganizations(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, orgs)
+}
+
+func CreateOrganization(c *gin.Context) {
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateOrganizationName(req.Name); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	org, err := model.CreateOrganization(req.Name, c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+func UpdateOrganization(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	var req organizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	req.Name = strings.TrimSpace(req.Name)
+	if err := validateOrganizationName(req.Name); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	org.Name = req.Name
+	if err := org.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, org)
+}
+
+func DeleteOrganization(c *gin.Context) {
+	org, member, ok := getOrganizationMembership(c, false)
+	if !ok {
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		organizationError(c, "只有组织所有者可以删除组织")
+		return
+	}
+	if err := model.DeleteOrganization(org); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+// TransferOrganizationQuota 所有者将个人额度转入组织，quota 为负数时从组织转回个人
+func TransferOrganizationQuota(c *gin.Context) {
+	org, member, ok := getOrganizationMembership(c, false)
+	if !ok {
+		return
+	}
+	if member.Role != model.OrganizationRoleOwner {
+		organizationError(c, "只有组织所有者可以转移额度")
+		return
+	}
+	var req struct {
+		Quota int `json:"quota"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil || req.Quota == 0 {
+		organizationError(c, "无效的参数")
+		return
+	}
+	if err := model.TransferUserQuotaToOrganization(member.UserId, org.Id, req.Quota); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	if req.Quota > 0 {
+		model.RecordLog(member.UserId, model.LogTypeManage, fmt.Sprintf("向组织 %s 转入额度 %s", org.Name, logger.LogQuota(req.Quota)))
+	} else {
+		model.RecordLog(member.UserId, model.LogTypeManage, fmt.Sprintf("从组织 %s 转出额度 %s", org.Name, logger.LogQuota(-req.Quota)))
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationMembers(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, false)
+	if !ok {
+		return
+	}
+	members, err := model.GetOrganizationMembers(org.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, members)
+}
+
+// UpdateOrganizationMember 修改成员角色与消费上限，只有所有者可以修改管理员
+func UpdateOrganizationMember(c *gin.Context) {
+	org, operator, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	var req organizationMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	if !model.IsValidOrganizationRole(req.Role) || req.QuotaLimit < 0 {
+		organizationError(c, "无效的角色或消费上限")
+		return
+	}
+	member, err := model.GetOrganizationMember(org.Id, req.UserId)
+	if err != nil {
+		organizationError(c, "成员不存在")
+		return
+	}
+	if member.Role == model.OrganizationRoleOwner {
+		organizationError(c, "无法修改组织所有者")
+		return
+	}
+	if operator.Role != model.OrganizationRoleOwner && (member.Role == model.OrganizationRoleAdmin || req.Role == model.OrganizationRoleAdmin) {
+		organizationError(c, "只有组织所有者可以管理组织管理员")
+		return
+	}
+	member.Role = req.Role
+	member.QuotaLimit = req.QuotaLimit
+	if err := member.Update(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	if req.ResetUsedQuota {
+		if err := member.ResetUsedQuota(); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+	}
+	common.ApiSuccess(c, member)
+}
+
+// RemoveOrganizationMember 移除成员，成员也可以主动退出组织
+func RemoveOrganizationMember(c *gin.Context) {
+	org, operator, ok := getOrganizationMembership(c, false)
+	if !ok {
+		return
+	}
+	userId, _ := strconv.Atoi(c.Param("user_id"))
+	member, err := model.GetOrganizationMember(org.Id, userId)
+	if err != nil {
+		organizationError(c, "成员不存在")
+		return
+	}
+	if member.Role == model.OrganizationRoleOwner {
+		organizationError(c, "组织所有者无法退出组织")
+		return
+	}
+	if member.UserId != operator.UserId {
+		if !operator.CanManageOrganization() {
+			organizationError(c, "只有组织所有者或管理员可以进行此操作")
+			return
+		}
+		if operator.Role != model.OrganizationRoleOwner && member.Role == model.OrganizationRoleAdmin {
+			organizationError(c, "只有组织所有者可以管理组织管理员")
+			return
+		}
+	}
+	if err := member.Delete(); err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationInvitations(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	invitations, err := model.GetOrganizationInvitations(org.Id)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, invitations)
+}
+
+func CreateOrganizationInvitation(c *gin.Context) {
+	org, operator, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	var req organizationInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	if !model.IsValidOrganizationRole(req.Role) {
+		organizationError(c, "无效的角色")
+		return
+	}
+	if req.Role == model.OrganizationRoleAdmin && operator.Role != model.OrganizationRoleOwner {
+		organizationError(c, "只有组织所有者可以邀请管理员")
+		return
+	}
+	inviteeId, err := model.GetUserIdByUsername(strings.TrimSpace(req.Username))
+	if err != nil || inviteeId == 0 {
+		organizationError(c, "用户不存在")
+		return
+	}
+	if err := model.CreateOrganizationInvitation(org.Id, operator.UserId, inviteeId, req.Role); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func CancelOrganizationInvitation(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	invitationId, _ := strconv.Atoi(c.Param("invitation_id"))
+	if err := model.CancelOrganizationInvitation(org.Id, invitationId); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetSelfOrganizationInvitations(c *gin.Context) {
+	invitations, err := model.GetUserOrganizationInvitations(c.GetInt("id"))
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, invitations)
+}
+
+// RespondOrganizationInvitation 被邀请用户接受或拒绝邀请
+func RespondOrganizationInvitation(c *gin.Context) {
+	var req struct {
+		Accept bool `json:"accept"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		organizationError(c, "无效的参数")
+		return
+	}
+	invitationId, _ := strconv.Atoi(c.Param("id"))
+	invitation, err := model.GetOrganizationInvitationById(invitationId)
+	if err != nil || invitation.InviteeId != c.GetInt("id") {
+		organizationError(c, "邀请不存在")
+		return
+	}
+	if err := model.RespondOrganizationInvitation(invitation, req.Accept); err != nil {
+		organizationError(c, err.Error())
+		return
+	}
+	common.ApiSuccess(c, nil)
+}
+
+func GetOrganizationLogs(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	pageInfo := common.GetPageQuery(c)
+	logType, _ := strconv.Atoi(c.Query("type"))
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	logs, total, err := model.GetOrganizationLogs(org.Id, logType, startTimestamp, endTimestamp, c.Query("model_name"), c.Query("username"), c.Query("token_name"), pageInfo.GetStartIdx(), pageInfo.GetPageSize())
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	pageInfo.SetTotal(int(total))
+	pageInfo.SetItems(logs)
+	common.ApiSuccess(c, pageInfo)
+}
+
+func GetOrganizationQuotaDates(c *gin.Context) {
+	org, _, ok := getOrganizationMembership(c, true)
+	if !ok {
+		return
+	}
+	startTimestamp, _ := strconv.ParseInt(c.Query("start_timestamp"), 10, 64)
+	endTimestamp, _ := strconv.ParseInt(c.Query("end_timestamp"), 10, 64)
+	// 判断时间跨度是否超过 1 个月
+	if endTimestamp-startTimestamp > 2592000 {
+		organizationError(c, "时间跨度不能超过 1 个月")
+		return
+	}
+	dates, err := model.GetQuotaDataByOrgId(org.Id, startTimestamp, endTimestamp)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, dates)
+}
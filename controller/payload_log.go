@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/service"
+	storageService "github.com/QuantumNous/new-api/service/storage"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// savePayloadLog 异步压缩并保存完整内容
+func savePayloadLog(log *model.PayloadLog, transcript *service.PayloadTranscript) {
+	if log == nil {
+		return
+	}
+	gopool.Go(func() {
+		data, size, err := service.EncodePayloadTranscript(transcript)
+		if err != nil {
+			common.SysError("failed to encode payload log: " + err.Error())
+			return
+		}
+		log.Size = size
+		if operation_setting.GetPayloadLogSetting().Storage == operation_setting.PayloadLogStorageObject {
+			// 路径格式：payloads/YYYY/MM/DD/requestId.json.gz
+			log.StorageKey = fmt.Sprintf("payloads/%s/%s.json.gz", time.Now().Format("2006/01/02"), log.RequestId)
+			log.StorageType, err = storageService.SaveFile(context.Background(), log.StorageKey, bytes.NewReader(data), int64(len(data)), "application/gzip")
+			if err != nil {
+				common.SysError("failed to save payload log: " + err.Error())
+				return
+			}
+		} else {
+			log.Data = data
+		}
+		if err := model.CreatePayloadLog(log); err != nil {
+			common.SysError("failed to create payload log: " + err.Error())
+		}
+	})
+}
+
+func getPayloadTranscript(c *gin.Context, userId int) {
+	log, err := model.GetPayloadLogByRequestId(c.Param("request_id"), userId)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			common.ApiErrorMsg(c, "未找到该请求的完整内容，可能未开启记录或已过期")
+			return
+		}
+		common.ApiError(c, err)
+		return
+	}
+	var reader io.Reader = bytes.NewReader(log.Data)
+	if log.StorageKey != "" {
+		file, err := storageService.OpenFile(c.Request.Context(), log.StorageType, log.StorageKey)
+		if err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		defer file.Close()
+		reader = file
+	}
+	transcript, err := service.DecodePayloadTranscript(reader)
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, transcript)
+}
+
+func GetPayloadLog(c *gin.Context) {
+	getPayloadTranscript(c, 0)
+}
+
+func GetSelfPayloadLog(c *gin.Context) {
+	getPayloadTranscript(c, c.GetInt("id"))
+}
+
+// RunPayloadLogCleanup 按保留天数清理过期的完整内容记录
+func RunPayloadLogCleanup() {
+	for {
+		time.Sleep(time.Hour)
+		retentionDays := operation_setting.GetPayloadLogSetting().RetentionDays
+		if retentionDays <= 0 {
+			continue
+		}
+		before := time.Now().AddDate(0, 0, -retentionDays).Unix()
+		for {
+			logs, err := model.GetExpiredPayloadLogs(before, 100)
+			if err != nil {
+				common.SysError("failed to query expired payload logs: " + err.Error())
+				break
+			}
+			if len(logs) == 0 {
+				break
+			}
+			ids := make([]int, 0, len(logs))
+			for _, log := range logs {
+				if log.StorageKey != "" {
+					if err := storageService.DeleteFile(context.Background(), log.StorageType, log.StorageKey); err != nil {
+						common.SysError(fmt.Sprintf("failed to delete payload log %s: %s", log.StorageKey, err.Error()))
+					}
+				}
+				ids = append(ids, log.Id)
+			}
+			if err := model.DeletePayloadLogsByIds(ids); err != nil {
+				common.SysError("failed to delete expired payload logs: " + err.Error())
+				break
+			}
+			if len(logs) < 100 {
+				break
+			}
+		}
+	}
+}
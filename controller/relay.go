@@ -8,8 +8,11 @@ import (
 	"log"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/metrics"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
@@ -21,12 +24,14 @@ import (
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 	"github.com/QuantumNous/new-api/types"
 
 	"github.com/bytedance/gopkg/util/gopool"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func relayHandler(c *gin.Context, info *relaycommon.RelayInfo) *types.NewAPIError {
@@ -120,11 +125,26 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		return
 	}
 
+	// 完整内容记录保存脱敏前的原始请求体，发送给上游的请求体为脱敏后的内容
+	payloadCapture := service.StartPayloadCapture(c, relayInfo)
+	defer func() {
+		savePayloadLog(payloadCapture.Finish(newAPIError))
+	}()
+
+	if operation_setting.ShouldRedactPIIForGroup(relayInfo.UsingGroup) {
+		if err := service.RedactRequestPII(c, relayInfo); err != nil {
+			newAPIError = types.NewError(err, types.ErrorCodeInvalidRequest)
+			return
+		}
+		request = relayInfo.Request
+	}
+
 	needSensitiveCheck := setting.ShouldCheckPromptSensitive()
+	needGuardrailCheck := operation_setting.GetGuardrailAction(relayInfo.UsingGroup) != operation_setting.GuardrailActionOff
 	needCountToken := constant.CountToken
 	// Avoid building huge CombineText (strings.Join) when token counting and sensitive check are both disabled.
 	var meta *types.TokenCountMeta
-	if needSensitiveCheck || needCountToken {
+	if needSensitiveCheck || needGuardrailCheck || needCountToken {
 		meta = request.GetTokenCountMeta()
 	} else {
 		meta = fastTokenCountMetaForPricing(request)
@@ -139,6 +159,13 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 	}
 
+	if needGuardrailCheck && meta != nil {
+		newAPIError = service.CheckPromptGuardrail(c, relayInfo, meta.CombineText)
+		if newAPIError != nil {
+			return
+		}
+	}
+
 	tokens, err := service.EstimateRequestToken(c, meta, relayInfo)
 	if err != nil {
 		newAPIError = types.NewError(err, types.ErrorCodeCountTokenFailed)
@@ -155,6 +182,14 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 
 	// common.SetContextKey(c, constant.ContextKeyTokenCountMeta, meta)
 
+	relayLimit, newAPIError := service.AcquireRelayLimit(c, relayInfo, tokens)
+	if newAPIError != nil {
+		return
+	}
+	defer func() {
+		relayLimit.Release(relayInfo, newAPIError == nil)
+	}()
+
 	if priceData.FreeModel {
 		logger.LogInfo(c, fmt.Sprintf("模型 %s 免费，跳过预扣费", relayInfo.OriginModelName))
 	} else {
@@ -178,7 +213,11 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		Retry:      common.GetPointer(0),
 	}
 
+	hedgeDelay := service.GetHedgeDelay(c, relayInfo)
 	for ; retryParam.GetRetry() <= common.RetryTimes; retryParam.IncreaseRetry() {
+		if retryParam.GetRetry() > 0 {
+			metrics.RelayRetry(relayInfo.OriginModelName, relayInfo.UsingGroup)
+		}
 		channel, channelErr := getChannel(c, relayInfo, retryParam)
 		if channelErr != nil {
 			logger.LogError(c, channelErr.Error())
@@ -186,7 +225,20 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 			break
 		}
 
+		if retryParam.ModelName != relayInfo.OriginModelName {
+			newAPIError = switchFallbackModel(c, relayInfo, retryParam.ModelName, meta)
+			if newAPIError != nil {
+				break
+			}
+		}
+
 		addUsedChannel(c, channel.Id)
+		if channel.GetSetting().PIIRedactionEnabled {
+			if err := service.RedactRequestPII(c, relayInfo); err != nil {
+				newAPIError = types.NewError(err, types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
+				break
+			}
+		}
 		requestBody, bodyErr := common.GetRequestBody(c)
 		if bodyErr != nil {
 			// Ensure consistent 413 for oversized bodies even when error occurs later (e.g., retry path)
@@ -199,25 +251,27 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 		}
 		c.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
 
-		switch relayFormat {
-		case types.RelayFormatOpenAIRealtime:
-			newAPIError = relay.WssHelper(c, relayInfo)
-		case types.RelayFormatClaude:
-			newAPIError = relay.ClaudeHelper(c, relayInfo)
-		case types.RelayFormatGemini:
-			newAPIError = geminiRelayHandler(c, relayInfo)
-		default:
-			newAPIError = relayHandler(c, relayInfo)
+		if hedgeDelay > 0 {
+			// 只有首次请求发出对冲请求，两个渠道的错误在 relayHedged 中分别处理
+			newAPIError = relayHedged(c, relayFormat, relayInfo, channel, retryParam, hedgeDelay)
+			hedgeDelay = 0
+		} else {
+			newAPIError = relayAttempt(c, relayFormat, relayInfo, channel, retryParam.GetRetry())
+			if newAPIError != nil {
+				processChannelError(c, newChannelError(c, channel), newAPIError)
+			}
 		}
 
 		if newAPIError == nil {
+			service.SaveChannelAffinity(c, relayInfo)
 			return
 		}
 
-		processChannelError(c, *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan()), newAPIError)
-
 		if !shouldRetry(c, newAPIError, common.RetryTimes-retryParam.GetRetry()) {
-			break
+			// 当前模型的重试次数已用完时，尝试按降级链切换到下一个模型
+			if !shouldRetry(c, newAPIError, 1) || !retryParam.PrepareModelFallback() {
+				break
+			}
 		}
 	}
 
@@ -228,6 +282,61 @@ func Relay(c *gin.Context, relayFormat types.RelayFormat) {
 	}
 }
 
+// relayAttempt 向已选择的渠道发出一次请求，并记录渠道统计
+func relayAttempt(c *gin.Context, relayFormat types.RelayFormat, relayInfo *relaycommon.RelayInfo, channel *model.Channel, retry int) *types.NewAPIError {
+	requestCtx := c.Request.Context()
+	// 每次尝试单独创建 span，上游请求与计费的 span 挂在其下
+	attemptCtx, attemptSpan := tracing.Start(requestCtx, "relay_attempt",
+		attribute.Int("retry", retry),
+		attribute.Int("channel.id", channel.Id),
+		attribute.Int("channel.type", channel.Type),
+	)
+	c.Request = c.Request.WithContext(attemptCtx)
+	// 渠道并发已满时排队，排队超时的请求未发往上游，不计入渠道统计
+	channelAdmission, newAPIError := service.AcquireChannelAdmission(c, relayInfo, channel)
+	if newAPIError != nil {
+		c.Request = c.Request.WithContext(requestCtx)
+		tracing.RecordError(attemptSpan, newAPIError)
+		attemptSpan.End()
+		return newAPIError
+	}
+	defer channelAdmission.Release()
+	attemptStart := time.Now()
+	guardrailCapture := service.StartGuardrailCapture(c, relayInfo)
+	outputFilter := service.StartOutputFilter(c, relayInfo)
+	switch relayFormat {
+	case types.RelayFormatOpenAIRealtime:
+		newAPIError = relay.WssHelper(c, relayInfo)
+	case types.RelayFormatClaude:
+		newAPIError = relay.ClaudeHelper(c, relayInfo)
+	case types.RelayFormatGemini:
+		newAPIError = geminiRelayHandler(c, relayInfo)
+	default:
+		newAPIError = relayHandler(c, relayInfo)
+	}
+	outputFilter.Finish()
+	// 对冲落选的请求被主动取消，不审核输出也不计入渠道统计
+	hedgeLost := service.HedgeLost(c)
+	guardrailCapture.Finish(newAPIError == nil && !hedgeLost)
+	c.Request = c.Request.WithContext(requestCtx)
+	if newAPIError != nil {
+		attemptSpan.SetAttributes(attribute.Int("http.response.status_code", newAPIError.StatusCode))
+		tracing.RecordError(attemptSpan, newAPIError)
+	}
+	attemptSpan.End()
+	// 命中响应缓存时未请求上游，不计入渠道统计
+	if !relayInfo.ResponseCacheHit && !hedgeLost {
+		service.RecordChannelRelayResult(relayInfo, channel.Id, attemptStart, newAPIError)
+		service.ReportChannelRelayBreaker(c, channel.Id, newAPIError)
+		service.RecordRelayMetrics(c, relayInfo, channel.Id, attemptStart, newAPIError)
+	}
+	return newAPIError
+}
+
+func newChannelError(c *gin.Context, channel *model.Channel) types.ChannelError {
+	return *types.NewChannelError(channel.Id, channel.Type, channel.Name, channel.ChannelInfo.IsMultiKey, common.GetContextKeyString(c, constant.ContextKeyChannelKey), channel.GetAutoBan())
+}
+
 var upgrader = websocket.Upgrader{
 	Subprotocols: []string{"realtime"}, // WS 握手支持的协议，如果有使用 Sec-WebSocket-Protocol，则必须在此声明对应的 Protocol TODO add other protocol
 	CheckOrigin: func(r *http.Request) bool {
@@ -282,7 +391,11 @@ func getChannel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service
 			AutoBan: &autoBanInt,
 		}, nil
 	}
-	channel, selectGroup, err := service.CacheGetRandomSatisfiedChannel(retryParam)
+	channel, selectGroup := service.SelectAffinityChannel(retryParam)
+	var err error
+	if channel == nil {
+		channel, selectGroup, err = service.CacheGetRandomSatisfiedChannel(retryParam)
+	}
 
 	info.PriceData.GroupRatioInfo = helper.HandleGroupRatio(c, info)
 
@@ -293,13 +406,25 @@ func getChannel(c *gin.Context, info *relaycommon.RelayInfo, retryParam *service
 		return nil, types.NewError(fmt.Errorf("分组 %s 下模型 %s 的可用渠道不存在（retry）", selectGroup, info.OriginModelName), types.ErrorCodeGetChannelFailed, types.ErrOptionWithSkipRetry())
 	}
 
-	newAPIError := middleware.SetupContextForSelectedChannel(c, channel, info.OriginModelName)
+	newAPIError := middleware.SetupContextForSelectedChannel(c, channel, retryParam.ModelName)
 	if newAPIError != nil {
 		return nil, newAPIError
 	}
 	return channel, nil
 }
 
+// switchFallbackModel 降级到其他模型后，按新模型重新计算价格，实际扣费在结算时按新价格补扣或返还
+func switchFallbackModel(c *gin.Context, info *relaycommon.RelayInfo, modelName string, meta *types.TokenCountMeta) *types.NewAPIError {
+	fromModel := info.OriginModelName
+	info.OriginModelName = modelName
+	if _, err := helper.ModelPriceHelper(c, info, info.GetEstimatePromptTokens(), meta); err != nil {
+		return types.NewError(err, types.ErrorCodeModelPriceError, types.ErrOptionWithSkipRetry())
+	}
+	service.MarkModelFallback(c, fromModel, modelName)
+	logger.LogInfo(c, fmt.Sprintf("模型 %s 降级到 %s", fromModel, modelName))
+	return nil
+}
+
 func shouldRetry(c *gin.Context, openaiErr *types.NewAPIError, retryTimes int) bool {
 	if openaiErr == nil {
 		return false
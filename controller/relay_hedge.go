@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/metrics"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/middleware"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/relay/helper"
+	"github.com/QuantumNous/new-api/service"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// hedgeAttempt 超时后发给另一个渠道的对冲请求
+type hedgeAttempt struct {
+	c       *gin.Context
+	info    *relaycommon.RelayInfo
+	channel *model.Channel
+	attempt *service.HedgeAttempt
+	done    <-chan *types.NewAPIError
+}
+
+// relayHedged 向已选择的渠道发出请求，超过 delay 仍未向客户端写出首字节时，向另一个渠道发出相同的请求；
+// 先开始输出的请求胜出并计费，另一个请求被取消，只记录其用量。都失败时返回首个请求的错误
+func relayHedged(c *gin.Context, relayFormat types.RelayFormat, relayInfo *relaycommon.RelayInfo, channel *model.Channel, retryParam *service.RetryParam, delay time.Duration) *types.NewAPIError {
+	// 对冲请求使用独立的 RelayInfo、gin.Context 与 http.Request，必须在首个请求开始前复制
+	hedgeInfo, err := relayInfo.CloneForHedge()
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("skip hedging: %s", err.Error()))
+		newAPIError := relayAttempt(c, relayFormat, relayInfo, channel, retryParam.GetRetry())
+		if newAPIError != nil {
+			processChannelError(c, newChannelError(c, channel), newAPIError)
+		}
+		return newAPIError
+	}
+	hc := c.Copy()
+	requestCtx := c.Request.Context()
+	hedgeRequest := c.Request.Clone(requestCtx)
+	group := service.NewHedgeGroup(c.Writer)
+
+	primaryCtx, cancelPrimary := context.WithCancel(requestCtx)
+	defer cancelPrimary()
+	primary := group.Attach(c, cancelPrimary, false)
+	c.Request = c.Request.WithContext(primaryCtx)
+	primaryDone := goRelayAttempt(func() *types.NewAPIError {
+		return relayAttempt(c, relayFormat, relayInfo, channel, retryParam.GetRetry())
+	})
+
+	var (
+		primaryErr      *types.NewAPIError
+		primaryFinished bool
+		hedge           *hedgeAttempt
+	)
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case primaryErr = <-primaryDone:
+		primaryFinished = true
+	case <-group.Won():
+	case <-timer.C:
+		if group.Winner() == nil {
+			hedge = startHedgeAttempt(hc, hedgeRequest, hedgeInfo, group, relayFormat, channel, retryParam, delay)
+		}
+	}
+	if !primaryFinished {
+		primaryErr = <-primaryDone
+	}
+	var hedgeErr *types.NewAPIError
+	if hedge != nil {
+		hedgeErr = <-hedge.done
+	}
+	group.Detach(c)
+	c.Request = c.Request.WithContext(requestCtx)
+
+	if primaryErr != nil && !primary.Lost() {
+		processChannelError(c, newChannelError(c, channel), primaryErr)
+	}
+	if hedge == nil {
+		return primaryErr
+	}
+
+	addUsedChannel(c, hedge.channel.Id)
+	if hedgeErr != nil && !hedge.attempt.Lost() {
+		processChannelError(hedge.c, newChannelError(hedge.c, hedge.channel), hedgeErr)
+	}
+	settleHedgeLoser(c, relayInfo, channel.Id, primary)
+	settleHedgeLoser(hedge.c, hedge.info, hedge.channel.Id, hedge.attempt)
+
+	winner := group.Winner()
+	switch {
+	case winner == hedge.attempt:
+		metrics.RelayHedge(relayInfo.OriginModelName, relayInfo.UsingGroup, "hedge")
+		logger.LogInfo(c, fmt.Sprintf("对冲请求胜出，使用渠道 #%d 的响应", hedge.channel.Id))
+		// 之后的限流校正与额度返还使用胜出请求的信息
+		*relayInfo = *hedge.info
+		return hedgeErr
+	case winner == primary:
+		metrics.RelayHedge(relayInfo.OriginModelName, relayInfo.UsingGroup, "primary")
+	default:
+		metrics.RelayHedge(relayInfo.OriginModelName, relayInfo.UsingGroup, "failed")
+	}
+	return primaryErr
+}
+
+// startHedgeAttempt 为对冲请求选择另一个渠道并发出请求，没有可用渠道时返回 nil
+func startHedgeAttempt(hc *gin.Context, hedgeRequest *http.Request, hedgeInfo *relaycommon.RelayInfo, group *service.HedgeGroup, relayFormat types.RelayFormat, channel *model.Channel, retryParam *service.RetryParam, delay time.Duration) *hedgeAttempt {
+	hedgeParam := &service.RetryParam{
+		Ctx:        hc,
+		TokenGroup: retryParam.TokenGroup,
+		ModelName:  retryParam.ModelName,
+		Retry:      common.GetPointer(0),
+	}
+	hedgeChannel := service.SelectHedgeChannel(hedgeParam, channel.Id)
+	if hedgeChannel == nil {
+		logger.LogInfo(hc, fmt.Sprintf("渠道 #%d 超过 %dms 未开始输出，但没有其他可用渠道，不发出对冲请求", channel.Id, delay.Milliseconds()))
+		return nil
+	}
+	hedgeInfo.PriceData.GroupRatioInfo = helper.HandleGroupRatio(hc, hedgeInfo)
+	if newAPIError := middleware.SetupContextForSelectedChannel(hc, hedgeChannel, hedgeParam.ModelName); newAPIError != nil {
+		logger.LogWarn(hc, fmt.Sprintf("skip hedging on channel #%d: %s", hedgeChannel.Id, newAPIError.Error()))
+		return nil
+	}
+	hedgeCtx, cancelHedge := context.WithCancel(hedgeRequest.Context())
+	hc.Request = hedgeRequest.WithContext(hedgeCtx)
+	if hedgeChannel.GetSetting().PIIRedactionEnabled {
+		if err := service.RedactRequestPII(hc, hedgeInfo); err != nil {
+			cancelHedge()
+			logger.LogWarn(hc, fmt.Sprintf("skip hedging on channel #%d: %s", hedgeChannel.Id, err.Error()))
+			return nil
+		}
+	}
+	requestBody, err := common.GetRequestBody(hc)
+	if err != nil {
+		cancelHedge()
+		logger.LogWarn(hc, fmt.Sprintf("skip hedging on channel #%d: %s", hedgeChannel.Id, err.Error()))
+		return nil
+	}
+	hc.Request.Body = io.NopCloser(bytes.NewBuffer(requestBody))
+
+	logger.LogInfo(hc, fmt.Sprintf("渠道 #%d 超过 %dms 未开始输出，向渠道 #%d 发出对冲请求", channel.Id, delay.Milliseconds(), hedgeChannel.Id))
+	attempt := group.Attach(hc, cancelHedge, true)
+	return &hedgeAttempt{
+		c:       hc,
+		info:    hedgeInfo,
+		channel: hedgeChannel,
+		attempt: attempt,
+		done: goRelayAttempt(func() *types.NewAPIError {
+			defer cancelHedge()
+			return relayAttempt(hc, relayFormat, hedgeInfo, hedgeChannel, 0)
+		}),
+	}
+}
+
+// settleHedgeLoser 落选请求被取消时未进入结算，按预估的输入 token 记录用量
+func settleHedgeLoser(c *gin.Context, info *relaycommon.RelayInfo, channelId int, attempt *service.HedgeAttempt) {
+	if !attempt.Lost() || attempt.Settled {
+		return
+	}
+	service.RecordHedgeLoserUsage(c, info, channelId, info.GetEstimatePromptTokens(), 0, 0, "对冲请求落选，请求已取消，上游未返回用量")
+}
+
+func goRelayAttempt(attempt func() *types.NewAPIError) <-chan *types.NewAPIError {
+	done := make(chan *types.NewAPIError, 1)
+	gopool.Go(func() {
+		defer func() {
+			if r := recover(); r != nil {
+				common.SysError(fmt.Sprintf("hedged relay attempt panic: %v", r))
+				done <- types.NewError(fmt.Errorf("hedged relay attempt panic: %v", r), types.ErrorCodeDoRequestFailed)
+			}
+		}()
+		done <- attempt()
+	})
+	return done
+}
@@ -0,0 +1,25 @@
+package controller
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+
+	"github.com/gin-gonic/gin"
+)
+
+func GetSecretEncryptionStatus(c *gin.Context) {
+	common.ApiSuccess(c, gin.H{
+		"enabled": common.SecretEncryptionEnabled(),
+		"key_id":  common.SecretCurrentKeyId(),
+	})
+}
+
+// RewrapSecrets 加密存量明文，并在轮换主密钥后将旧密文切换到当前主密钥
+func RewrapSecrets(c *gin.Context) {
+	result, err := model.RewrapSecrets()
+	if err != nil {
+		common.ApiError(c, err)
+		return
+	}
+	common.ApiSuccess(c, result)
+}
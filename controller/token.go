@@ -8,6 +8,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
 
 	"github.com/gin-gonic/gin"
 )
@@ -150,6 +151,34 @@ func AddToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.TPMLimit < 0 || token.ConcurrencyLimit < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "TPM 与并发限制不能为负数",
+		})
+		return
+	}
+	if token.HedgeDelay < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "对冲等待时间不能为负数",
+		})
+		return
+	}
+	if _, ok := operation_setting.GetAdmissionSetting().Classes[token.PriorityClass]; token.PriorityClass != "" && !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "排队优先级不存在",
+		})
+		return
+	}
+	if !model.IsValidTokenBudgetPeriod(token.BudgetPeriod) || token.BudgetLimit < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "预算周期无效或预算额度为负数",
+		})
+		return
+	}
 	// 非无限额度时，检查额度值是否超出有效范围
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
@@ -168,6 +197,9 @@ func AddToken(c *gin.Context) {
 			return
 		}
 	}
+	if !checkTokenOrganization(c, token.OrgId, c.GetInt("id")) {
+		return
+	}
 	key, err := common.GenerateKey()
 	if err != nil {
 		c.JSON(http.StatusOK, gin.H{
@@ -189,8 +221,24 @@ func AddToken(c *gin.Context) {
 		ModelLimitsEnabled: token.ModelLimitsEnabled,
 		ModelLimits:        token.ModelLimits,
 		AllowIps:           token.AllowIps,
+		Policy:             token.Policy,
 		Group:              token.Group,
 		CrossGroupRetry:    token.CrossGroupRetry,
+		FallbackDisabled:   token.FallbackDisabled,
+		HedgeDelay:         token.HedgeDelay,
+		PriorityClass:      token.PriorityClass,
+		TPMLimit:           token.TPMLimit,
+		ConcurrencyLimit:   token.ConcurrencyLimit,
+		BudgetPeriod:       token.BudgetPeriod,
+		BudgetLimit:        token.BudgetLimit,
+		OrgId:              token.OrgId,
+	}
+	if err := cleanToken.NormalizePolicy(); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": err.Error(),
+		})
+		return
 	}
 	err = cleanToken.Insert()
 	if err != nil {
@@ -235,6 +283,34 @@ func UpdateToken(c *gin.Context) {
 		})
 		return
 	}
+	if token.TPMLimit < 0 || token.ConcurrencyLimit < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "TPM 与并发限制不能为负数",
+		})
+		return
+	}
+	if token.HedgeDelay < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "对冲等待时间不能为负数",
+		})
+		return
+	}
+	if _, ok := operation_setting.GetAdmissionSetting().Classes[token.PriorityClass]; token.PriorityClass != "" && !ok {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "排队优先级不存在",
+		})
+		return
+	}
+	if !model.IsValidTokenBudgetPeriod(token.BudgetPeriod) || token.BudgetLimit < 0 {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "预算周期无效或预算额度为负数",
+		})
+		return
+	}
 	if !token.UnlimitedQuota {
 		if token.RemainQuota < 0 {
 			c.JSON(http.StatusOK, gin.H{
@@ -257,6 +333,9 @@ func UpdateToken(c *gin.Context) {
 		common.ApiError(c, err)
 		return
 	}
+	if statusOnly == "" && token.OrgId != cleanToken.OrgId && !checkTokenOrganization(c, token.OrgId, userId) {
+		return
+	}
 	if token.Status == common.TokenStatusEnabled {
 		if cleanToken.Status == common.TokenStatusExpired && cleanToken.ExpiredTime <= common.GetTimestamp() && cleanToken.ExpiredTime != -1 {
 			c.JSON(http.StatusOK, gin.H{
@@ -273,6 +352,7 @@ func UpdateToken(c *gin.Context) {
 			return
 		}
 	}
+	auditBefore := getAuditSnapshot(model.AuditEntityToken, strconv.Itoa(cleanToken.Id))
 	if statusOnly != "" {
 		cleanToken.Status = token.Status
 	} else {
@@ -284,14 +364,44 @@ func UpdateToken(c *gin.Context) {
 		cleanToken.ModelLimitsEnabled = token.ModelLimitsEnabled
 		cleanToken.ModelLimits = token.ModelLimits
 		cleanToken.AllowIps = token.AllowIps
+		cleanToken.Policy = token.Policy
 		cleanToken.Group = token.Group
 		cleanToken.CrossGroupRetry = token.CrossGroupRetry
+		cleanToken.FallbackDisabled = token.FallbackDisabled
+		cleanToken.HedgeDelay = token.HedgeDelay
+		cleanToken.PriorityClass = token.PriorityClass
+		cleanToken.TPMLimit = token.TPMLimit
+		cleanToken.ConcurrencyLimit = token.ConcurrencyLimit
+		budgetChanged := cleanToken.BudgetPeriod != token.BudgetPeriod || cleanToken.BudgetLimit != token.BudgetLimit
+		cleanToken.BudgetPeriod = token.BudgetPeriod
+		cleanToken.BudgetLimit = token.BudgetLimit
+		if err := cleanToken.NormalizePolicy(); err != nil {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": err.Error(),
+			})
+			return
+		}
+		if budgetChanged {
+			// 预算额度或周期变更后重新开始计算
+			if err := model.ResetTokenBudget(cleanToken); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+		}
+		if token.OrgId != cleanToken.OrgId {
+			if err := model.SetTokenOrganization(cleanToken, token.OrgId); err != nil {
+				common.ApiError(c, err)
+				return
+			}
+		}
 	}
 	err = cleanToken.Update()
 	if err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordAuditVersion(c, model.AuditEntityToken, strconv.Itoa(cleanToken.Id), auditBefore, model.AuditActionUpdate)
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
 		"message": "",
@@ -324,3 +434,18 @@ func DeleteTokenBatch(c *gin.Context) {
 		"data":    count,
 	})
 }
+
+// checkTokenOrganization 只有组织成员可以在组织下创建令牌
+func checkTokenOrganization(c *gin.Context, orgId int, userId int) bool {
+	if orgId == 0 {
+		return true
+	}
+	if _, err := model.GetOrganizationMember(orgId, userId); err != nil {
+		c.JSON(http.StatusOK, gin.H{
+			"success": false,
+			"message": "你不是该组织的成员",
+		})
+		return false
+	}
+	return true
+}
@@ -442,6 +442,8 @@ func GetSelf(c *gin.Context) {
 
 	// 获取用户设置并提取sidebar_modules
 	userSetting := user.GetSetting()
+	// 返回解密后的设置，数据库中的通知密钥为密文
+	settingBytes, _ := common.Marshal(userSetting)
 
 	// 构建响应数据，包含用户信息和权限
 	responseData := map[string]interface{}{
@@ -466,7 +468,7 @@ func GetSelf(c *gin.Context) {
 		"aff_history_quota": user.AffHistoryQuota,
 		"inviter_id":        user.InviterId,
 		"linux_do_id":       user.LinuxDOId,
-		"setting":           user.Setting,
+		"setting":           string(settingBytes),
 		"stripe_customer":   user.StripeCustomer,
 		"sidebar_modules":   userSetting.SidebarModules, // 正确提取sidebar_modules字段
 		"permissions":       permissions,                // 新增权限字段
@@ -531,9 +533,10 @@ func generateDefaultSidebarConfig(userRole int) string {
 
 	// 个人中心区域 - 所有用户都可以访问
 	defaultConfig["personal"] = map[string]interface{}{
-		"enabled":  true,
-		"topup":    true,
-		"personal": true,
+		"enabled":      true,
+		"topup":        true,
+		"organization": true,
+		"personal":     true,
 	}
 
 	// 管理员区域 - 根据角色决定
@@ -641,10 +644,12 @@ func UpdateUser(c *gin.Context) {
 		updatedUser.Password = "" // rollback to what it should be
 	}
 	updatePassword := updatedUser.Password != ""
+	auditBefore := getAuditSnapshot(model.AuditEntityUser, strconv.Itoa(updatedUser.Id))
 	if err := updatedUser.Edit(updatePassword); err != nil {
 		common.ApiError(c, err)
 		return
 	}
+	recordAuditVersion(c, model.AuditEntityUser, strconv.Itoa(updatedUser.Id), auditBefore, model.AuditActionUpdate)
 	if originUser.Quota != updatedUser.Quota {
 		model.RecordLog(originUser.Id, model.LogTypeManage, fmt.Sprintf("管理员将用户额度从 %s修改为 %s", logger.LogQuota(originUser.Quota), logger.LogQuota(updatedUser.Quota)))
 	}
@@ -879,8 +884,9 @@ func CreateUser(c *gin.Context) {
 }
 
 type ManageRequest struct {
-	Id     int    `json:"id"`
-	Action string `json:"action"`
+	Id        int    `json:"id"`
+	Action    string `json:"action"`
+	AdminRole string `json:"admin_role"`
 }
 
 // ManageUser Only admin user can do this
@@ -974,6 +980,39 @@ func ManageUser(c *gin.Context) {
 			return
 		}
 		user.Role = common.RoleCommonUser
+		// 降级后清除管理角色
+		if err := model.SetUserAdminRole(user.Id, ""); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		user.AdminRole = ""
+	case "set_admin_role":
+		if myRole != common.RoleRootUser {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "只有超级管理员可以设置管理角色",
+			})
+			return
+		}
+		if user.Role != common.RoleAdminUser {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "只能为管理员设置管理角色",
+			})
+			return
+		}
+		if !common.IsValidAdminRole(req.AdminRole) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无效的管理角色",
+			})
+			return
+		}
+		if err := model.SetUserAdminRole(user.Id, req.AdminRole); err != nil {
+			common.ApiError(c, err)
+			return
+		}
+		user.AdminRole = req.AdminRole
 	}
 
 	if err := user.Update(false); err != nil {
@@ -981,8 +1020,9 @@ func ManageUser(c *gin.Context) {
 		return
 	}
 	clearUser := model.User{
-		Role:   user.Role,
-		Status: user.Status,
+		Role:      user.Role,
+		Status:    user.Status,
+		AdminRole: user.AdminRole,
 	}
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -1,5 +1,7 @@
 package dto
 
+import "strings"
+
 type ChannelSettings struct {
 	ForceFormat            bool   `json:"force_format,omitempty"`
 	ThinkingToContent      bool   `json:"thinking_to_content,omitempty"`
@@ -7,6 +9,8 @@ type ChannelSettings struct {
 	PassThroughBodyEnabled bool   `json:"pass_through_body_enabled,omitempty"`
 	SystemPrompt           string `json:"system_prompt,omitempty"`
 	SystemPromptOverride   bool   `json:"system_prompt_override,omitempty"`
+	PIIRedactionEnabled    bool   `json:"pii_redaction_enabled,omitempty"` // 发送给上游前脱敏请求中的个人信息
+	MaxConcurrency         int    `json:"max_concurrency,omitempty"`       // 渠道并发上限，0 表示使用默认配置
 }
 
 type VertexKeyType string
@@ -31,6 +35,9 @@ type ChannelOtherSettings struct {
 	DisableStore          bool          `json:"disable_store,omitempty"`           // 是否禁用 store 透传（默认允许透传，禁用后可能导致 Codex 无法使用）
 	AllowSafetyIdentifier bool          `json:"allow_safety_identifier,omitempty"` // 是否允许 safety_identifier 透传（默认过滤以保护用户隐私）
 	AwsKeyType            AwsKeyType    `json:"aws_key_type,omitempty"`
+	// Chat Completions 请求改走上游 /v1/responses，开启后对渠道内所有模型生效，否则仅对列表中的模型生效
+	ChatCompletionsToResponses       bool     `json:"chat_completions_to_responses,omitempty"`
+	ChatCompletionsToResponsesModels []string `json:"chat_completions_to_responses_models,omitempty"`
 }
 
 func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
@@ -39,3 +46,19 @@ func (s *ChannelOtherSettings) IsOpenRouterEnterprise() bool {
 	}
 	return *s.OpenRouterEnterprise
 }
+
+// ShouldConvertChatToResponses 判断该模型的 Chat Completions 请求是否需要转换为 Responses API 请求
+func (s *ChannelOtherSettings) ShouldConvertChatToResponses(modelName string) bool {
+	if s == nil {
+		return false
+	}
+	if s.ChatCompletionsToResponses {
+		return true
+	}
+	for _, model := range s.ChatCompletionsToResponsesModels {
+		if strings.TrimSpace(model) == modelName {
+			return true
+		}
+	}
+	return false
+}
@@ -341,6 +341,12 @@ type ResponsesOutput struct {
 	Content []ResponsesOutputContent `json:"content"`
 	Quality string                   `json:"quality"`
 	Size    string                   `json:"size"`
+	// function_call
+	CallId    string `json:"call_id,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Arguments string `json:"arguments,omitempty"`
+	// reasoning
+	Summary []ResponsesOutputContent `json:"summary,omitempty"`
 }
 
 type ResponsesOutputContent struct {
@@ -363,12 +369,42 @@ const (
 	ResponsesOutputTypeItemDone  = "response.output_item.done"
 )
 
+const (
+	ResponsesStreamTypeCreated                  = "response.created"
+	ResponsesStreamTypeInProgress               = "response.in_progress"
+	ResponsesStreamTypeCompleted                = "response.completed"
+	ResponsesStreamTypeContentPartAdded         = "response.content_part.added"
+	ResponsesStreamTypeContentPartDone          = "response.content_part.done"
+	ResponsesStreamTypeOutputTextDelta          = "response.output_text.delta"
+	ResponsesStreamTypeOutputTextDone           = "response.output_text.done"
+	ResponsesStreamTypeFunctionCallArgsDelta    = "response.function_call_arguments.delta"
+	ResponsesStreamTypeFunctionCallArgsDone     = "response.function_call_arguments.done"
+	ResponsesStreamTypeReasoningSummaryAdded    = "response.reasoning_summary_part.added"
+	ResponsesStreamTypeReasoningSummaryDone     = "response.reasoning_summary_part.done"
+	ResponsesStreamTypeReasoningSummaryDelta    = "response.reasoning_summary_text.delta"
+	ResponsesStreamTypeReasoningSummaryTextDone = "response.reasoning_summary_text.done"
+)
+
+const (
+	ResponsesItemTypeMessage      = "message"
+	ResponsesItemTypeFunctionCall = "function_call"
+	ResponsesItemTypeReasoning    = "reasoning"
+)
+
 // ResponsesStreamResponse 用于处理 /v1/responses 流式响应
 type ResponsesStreamResponse struct {
-	Type     string                   `json:"type"`
-	Response *OpenAIResponsesResponse `json:"response,omitempty"`
-	Delta    string                   `json:"delta,omitempty"`
-	Item     *ResponsesOutput         `json:"item,omitempty"`
+	Type           string                   `json:"type"`
+	SequenceNumber int                      `json:"sequence_number,omitempty"`
+	Response       *OpenAIResponsesResponse `json:"response,omitempty"`
+	Delta          string                   `json:"delta,omitempty"`
+	Item           *ResponsesOutput         `json:"item,omitempty"`
+	ItemId         string                   `json:"item_id,omitempty"`
+	OutputIndex    *int                     `json:"output_index,omitempty"`
+	ContentIndex   *int                     `json:"content_index,omitempty"`
+	SummaryIndex   *int                     `json:"summary_index,omitempty"`
+	Part           *ResponsesOutputContent  `json:"part,omitempty"`
+	Text           string                   `json:"text,omitempty"`
+	Arguments      string                   `json:"arguments,omitempty"`
 }
 
 // GetOpenAIError 从动态错误类型中提取OpenAIError结构
@@ -0,0 +1,17 @@
+package dto
+
+// TokenPolicy 令牌访问策略，各项为空表示不限制
+type TokenPolicy struct {
+	AllowIps       []string          `json:"allow_ips,omitempty"`       // 允许的 IP 或 CIDR，支持 IPv6
+	AllowReferers  []string          `json:"allow_referers,omitempty"`  // 允许的 Origin/Referer，支持 * 通配，如 https://*.example.com
+	TimeWindows    []TokenTimeWindow `json:"time_windows,omitempty"`    // 允许访问的时间段
+	TimeZone       string            `json:"time_zone,omitempty"`       // 时间段所在时区，如 Asia/Shanghai，为空使用服务器时区
+	AllowEndpoints []string          `json:"allow_endpoints,omitempty"` // 允许访问的接口路径，支持 * 通配，如 /v1/embeddings
+}
+
+// TokenTimeWindow 允许访问的时间段，End 早于 Start 时表示跨越午夜
+type TokenTimeWindow struct {
+	Weekdays []int  `json:"weekdays,omitempty"` // 0 表示周日，为空表示每天
+	Start    string `json:"start"`              // HH:MM
+	End      string `json:"end"`                // HH:MM
+}
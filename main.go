@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"embed"
 	"fmt"
 	"log"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/logger"
@@ -46,6 +48,26 @@ func main() {
 		return
 	}
 
+	if *common.EncryptSecrets {
+		result, err := model.RewrapSecrets()
+		_ = model.CloseDB()
+		if err != nil {
+			common.FatalLog("failed to encrypt secrets: " + err.Error())
+		}
+		fmt.Printf("secrets encrypted with key %s: %d channels, %d tasks, %d users, %d options\n",
+			common.SecretCurrentKeyId(), result.Channels, result.Tasks, result.Users, result.Options)
+		return
+	}
+
+	if *common.ExportConfigPath != "" || *common.ImportConfigPath != "" {
+		err = runConfigCommand()
+		_ = model.CloseDB()
+		if err != nil {
+			common.FatalLog(err.Error())
+		}
+		return
+	}
+
 	common.SysLog("New API " + common.Version + " started")
 	if os.Getenv("GIN_MODE") != "debug" {
 		gin.SetMode(gin.ReleaseMode)
@@ -111,6 +133,14 @@ func main() {
 			controller.UpdateTaskBulk()
 		})
 	}
+	if common.IsMasterNode {
+		gopool.Go(func() {
+			controller.RunBatchJobs()
+		})
+		gopool.Go(func() {
+			controller.RunPayloadLogCleanup()
+		})
+	}
 	if os.Getenv("BATCH_UPDATE_ENABLED") == "true" {
 		common.BatchUpdateEnabled = true
 		common.SysLog("batch update enabled with interval " + strconv.Itoa(common.BatchUpdateInterval) + "s")
@@ -130,6 +160,14 @@ func main() {
 		common.SysError(fmt.Sprintf("start pyroscope error : %v", err))
 	}
 
+	shutdownTracing, err := tracing.Init(context.Background())
+	if err != nil {
+		common.FatalLog("failed to initialize tracing: " + err.Error())
+	}
+	defer func() {
+		_ = shutdownTracing(context.Background())
+	}()
+
 	// Initialize HTTP server
 	server := gin.New()
 	server.Use(gin.CustomRecovery(func(c *gin.Context, err any) {
@@ -274,3 +312,57 @@ func InitResources() error {
 
 	return nil
 }
+
+// runConfigCommand 命令行模式下导出或导入配置，导入默认只打印变更，指定 --apply 后才会应用
+func runConfigCommand() error {
+	if path := *common.ExportConfigPath; path != "" {
+		doc, err := model.ExportConfigDocument(*common.ConfigIncludeKeys)
+		if err != nil {
+			return err
+		}
+		format := "yaml"
+		if strings.HasSuffix(path, ".json") {
+			format = "json"
+		}
+		data, err := model.MarshalConfigDocument(doc, format)
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return err
+		}
+		fmt.Printf("configuration exported to %s\n", path)
+		return nil
+	}
+	data, err := os.ReadFile(*common.ImportConfigPath)
+	if err != nil {
+		return err
+	}
+	doc, err := model.ParseConfigDocument(data)
+	if err != nil {
+		return err
+	}
+	changes, err := model.ImportConfigDocument(doc, model.ConfigImportOptions{
+		DryRun:    !*common.ConfigApply,
+		Prune:     *common.ConfigPrune,
+		ActorName: "cli",
+	})
+	for _, change := range changes {
+		fmt.Printf("%s %s %s\n", change.Action, change.Section, change.Name)
+		for field, diff := range change.Diff {
+			fmt.Printf("    %s: %v -> %v\n", field, diff.Old, diff.New)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if !*common.ConfigApply {
+		fmt.Printf("%d changes, run with --apply to apply them\n", len(changes))
+	} else {
+		fmt.Printf("%d changes applied\n", len(changes))
+		if len(changes) > 0 {
+			fmt.Printf("running servers pick up the changes at their next sync (every %d seconds, see SYNC_FREQUENCY); restart them to apply immediately\n", common.SyncFrequency)
+		}
+	}
+	return nil
+}
@@ -1,13 +1,16 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net"
 	"net/http"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
@@ -16,6 +19,7 @@ import (
 
 	"github.com/gin-contrib/sessions"
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 func validUserInfo(username string, role int) bool {
@@ -30,6 +34,15 @@ func validUserInfo(username string, role int) bool {
 }
 
 func authHelper(c *gin.Context, minRole int) {
+	if !authenticate(c, minRole) {
+		return
+	}
+	c.Next()
+	recordAdminAction(c, minRole)
+}
+
+// authenticate 校验登录状态与角色，失败时写入错误响应并返回 false
+func authenticate(c *gin.Context, minRole int) bool {
 	session := sessions.Default(c)
 	username := session.Get("username")
 	role := session.Get("role")
@@ -45,7 +58,7 @@ func authHelper(c *gin.Context, minRole int) {
 				"message": "无权进行此操作，未登录且未提供 access token",
 			})
 			c.Abort()
-			return
+			return false
 		}
 		user := model.ValidateAccessToken(accessToken)
 		if user != nil && user.Username != "" {
@@ -55,7 +68,7 @@ func authHelper(c *gin.Context, minRole int) {
 					"message": "无权进行此操作，用户信息无效",
 				})
 				c.Abort()
-				return
+				return false
 			}
 			// Token is valid
 			username = user.Username
@@ -69,7 +82,7 @@ func authHelper(c *gin.Context, minRole int) {
 				"message": "无权进行此操作，access token 无效",
 			})
 			c.Abort()
-			return
+			return false
 		}
 	}
 	// get header New-Api-User
@@ -80,7 +93,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "无权进行此操作，未提供 New-Api-User",
 		})
 		c.Abort()
-		return
+		return false
 	}
 	apiUserId, err := strconv.Atoi(apiUserIdStr)
 	if err != nil {
@@ -89,7 +102,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "无权进行此操作，New-Api-User 格式错误",
 		})
 		c.Abort()
-		return
+		return false
 
 	}
 	if id != apiUserId {
@@ -98,7 +111,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "无权进行此操作，New-Api-User 与登录用户不匹配",
 		})
 		c.Abort()
-		return
+		return false
 	}
 	if status.(int) == common.UserStatusDisabled {
 		c.JSON(http.StatusOK, gin.H{
@@ -106,7 +119,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "用户已被封禁",
 		})
 		c.Abort()
-		return
+		return false
 	}
 	if role.(int) < minRole {
 		c.JSON(http.StatusOK, gin.H{
@@ -114,7 +127,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "无权进行此操作，权限不足",
 		})
 		c.Abort()
-		return
+		return false
 	}
 	if !validUserInfo(username.(string), role.(int)) {
 		c.JSON(http.StatusOK, gin.H{
@@ -122,7 +135,7 @@ func authHelper(c *gin.Context, minRole int) {
 			"message": "无权进行此操作，用户信息无效",
 		})
 		c.Abort()
-		return
+		return false
 	}
 	c.Set("username", username)
 	c.Set("role", role)
@@ -141,8 +154,42 @@ func authHelper(c *gin.Context, minRole int) {
 	//	return
 	//}
 	//userCache.WriteContext(c)
+	return true
+}
 
-	c.Next()
+func isWriteMethod(method string) bool {
+	return method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+}
+
+// recordAdminAction 记录管理员的写操作
+func recordAdminAction(c *gin.Context, minRole int) {
+	if minRole < common.RoleAdminUser || !isWriteMethod(c.Request.Method) {
+		return
+	}
+	model.RecordLog(c.GetInt("id"), model.LogTypeManage, fmt.Sprintf("管理员 %s 执行操作 %s %s，状态码 %d",
+		c.GetString("username"), c.Request.Method, c.Request.URL.Path, c.Writer.Status()))
+}
+
+// PermissionAuth 校验管理员对资源的权限，GET 请求需要读权限，其他请求需要写权限
+func PermissionAuth(permission string) func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if !authenticate(c, common.RoleAdminUser) {
+			return
+		}
+		// 从数据库读取角色，修改管理角色后无需重新登录即可生效
+		role, adminRole, err := model.GetUserRoles(c.GetInt("id"))
+		if err != nil || !common.HasAdminPermission(role, adminRole, permission, isWriteMethod(c.Request.Method)) {
+			c.JSON(http.StatusOK, gin.H{
+				"success": false,
+				"message": "无权进行此操作，管理角色权限不足",
+			})
+			c.Abort()
+			return
+		}
+		c.Set("admin_role", adminRole)
+		c.Next()
+		recordAdminAction(c, common.RoleAdminUser)
+	}
 }
 
 func TryUserAuth() func(c *gin.Context) {
@@ -178,128 +225,174 @@ func WssAuth(c *gin.Context) {
 
 }
 
+// MetricsAuth 校验 /metrics 接口的 Bearer 令牌，未设置 METRICS_TOKEN 时不校验
+func MetricsAuth() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		if constant.MetricsToken == "" {
+			c.Next()
+			return
+		}
+		auth := strings.TrimPrefix(c.Request.Header.Get("Authorization"), "Bearer ")
+		if subtle.ConstantTimeCompare([]byte(auth), []byte(constant.MetricsToken)) != 1 {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Next()
+	}
+}
+
 func TokenAuth() func(c *gin.Context) {
 	return func(c *gin.Context) {
-		// 先检测是否为ws
-		if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
-			// Sec-WebSocket-Protocol: realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1
-			// read sk from Sec-WebSocket-Protocol
-			key := c.Request.Header.Get("Sec-WebSocket-Protocol")
-			parts := strings.Split(key, ",")
-			for _, part := range parts {
-				part = strings.TrimSpace(part)
-				if strings.HasPrefix(part, "openai-insecure-api-key") {
-					key = strings.TrimPrefix(part, "openai-insecure-api-key.")
-					break
-				}
+		// span 仅覆盖鉴权本身，在进入后续处理前结束
+		authenticateToken(c)
+		c.Next()
+	}
+}
+
+func authenticateToken(c *gin.Context) {
+	_, span := tracing.Start(c.Request.Context(), "token_auth")
+	defer tracing.EndWithGin(c, span)
+	// 先检测是否为ws
+	if c.Request.Header.Get("Sec-WebSocket-Protocol") != "" {
+		// Sec-WebSocket-Protocol: realtime, openai-insecure-api-key.sk-xxx, openai-beta.realtime-v1
+		// read sk from Sec-WebSocket-Protocol
+		key := c.Request.Header.Get("Sec-WebSocket-Protocol")
+		parts := strings.Split(key, ",")
+		for _, part := range parts {
+			part = strings.TrimSpace(part)
+			if strings.HasPrefix(part, "openai-insecure-api-key") {
+				key = strings.TrimPrefix(part, "openai-insecure-api-key.")
+				break
 			}
-			c.Request.Header.Set("Authorization", "Bearer "+key)
 		}
-		// 检查path包含/v1/messages 或 /v1/models 
-		if strings.Contains(c.Request.URL.Path, "/v1/messages") || strings.Contains(c.Request.URL.Path, "/v1/models") {
-			anthropicKey := c.Request.Header.Get("x-api-key")
-			if anthropicKey != "" {
-				c.Request.Header.Set("Authorization", "Bearer "+anthropicKey)
-			}
+		c.Request.Header.Set("Authorization", "Bearer "+key)
+	}
+	// 检查path包含/v1/messages 或 /v1/models 
+	if strings.Contains(c.Request.URL.Path, "/v1/messages") || strings.Contains(c.Request.URL.Path, "/v1/models") {
+		anthropicKey := c.Request.Header.Get("x-api-key")
+		if anthropicKey != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+anthropicKey)
 		}
-		// gemini api 从query中获取key
-		if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models") ||
-			strings.HasPrefix(c.Request.URL.Path, "/v1beta/openai/models") ||
-			strings.HasPrefix(c.Request.URL.Path, "/v1/models/") {
-			skKey := c.Query("key")
-			if skKey != "" {
-				c.Request.Header.Set("Authorization", "Bearer "+skKey)
-			}
-			// 从x-goog-api-key header中获取key
-			xGoogKey := c.Request.Header.Get("x-goog-api-key")
-			if xGoogKey != "" {
-				c.Request.Header.Set("Authorization", "Bearer "+xGoogKey)
-			}
+	}
+	// gemini api 从query中获取key
+	if strings.HasPrefix(c.Request.URL.Path, "/v1beta/models") ||
+		strings.HasPrefix(c.Request.URL.Path, "/v1beta/openai/models") ||
+		strings.HasPrefix(c.Request.URL.Path, "/v1/models/") {
+		skKey := c.Query("key")
+		if skKey != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+skKey)
 		}
-		key := c.Request.Header.Get("Authorization")
-		parts := make([]string, 0)
+		// 从x-goog-api-key header中获取key
+		xGoogKey := c.Request.Header.Get("x-goog-api-key")
+		if xGoogKey != "" {
+			c.Request.Header.Set("Authorization", "Bearer "+xGoogKey)
+		}
+	}
+	key := c.Request.Header.Get("Authorization")
+	parts := make([]string, 0)
+	if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
+		key = strings.TrimSpace(key[7:])
+	}
+	if key == "" || key == "midjourney-proxy" {
+		key = c.Request.Header.Get("mj-api-secret")
 		if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
 			key = strings.TrimSpace(key[7:])
 		}
-		if key == "" || key == "midjourney-proxy" {
-			key = c.Request.Header.Get("mj-api-secret")
-			if strings.HasPrefix(key, "Bearer ") || strings.HasPrefix(key, "bearer ") {
-				key = strings.TrimSpace(key[7:])
-			}
-			key = strings.TrimPrefix(key, "sk-")
-			parts = strings.Split(key, "-")
-			key = parts[0]
-		} else {
-			key = strings.TrimPrefix(key, "sk-")
-			parts = strings.Split(key, "-")
-			key = parts[0]
-		}
-		token, err := model.ValidateUserToken(key)
-		if token != nil {
-			id := c.GetInt("id")
-			if id == 0 {
-				c.Set("id", token.UserId)
-			}
-		}
-		if err != nil {
-			abortWithOpenAiMessage(c, http.StatusUnauthorized, err.Error())
-			return
+		key = strings.TrimPrefix(key, "sk-")
+		parts = strings.Split(key, "-")
+		key = parts[0]
+	} else {
+		key = strings.TrimPrefix(key, "sk-")
+		parts = strings.Split(key, "-")
+		key = parts[0]
+	}
+	token, err := model.ValidateUserToken(key)
+	if token != nil {
+		id := c.GetInt("id")
+		if id == 0 {
+			c.Set("id", token.UserId)
 		}
+	}
+	if err != nil {
+		abortWithOpenAiMessage(c, http.StatusUnauthorized, err.Error())
+		return
+	}
 
-		allowIps := token.GetIpLimits()
-		if len(allowIps) > 0 {
-			clientIp := c.ClientIP()
-			logger.LogDebug(c, "Token has IP restrictions, checking client IP %s", clientIp)
-			ip := net.ParseIP(clientIp)
-			if ip == nil {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "无法解析客户端 IP 地址")
-				return
-			}
-			if common.IsIpInCIDRList(ip, allowIps) == false {
-				abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中")
-				return
-			}
-			logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
-		}
+	if !checkTokenPolicy(c, token) {
+		return
+	}
 
-		userCache, err := model.GetUserCache(token.UserId)
-		if err != nil {
-			abortWithOpenAiMessage(c, http.StatusInternalServerError, err.Error())
-			return
-		}
-		userEnabled := userCache.Status == common.UserStatusEnabled
-		if !userEnabled {
-			abortWithOpenAiMessage(c, http.StatusForbidden, "用户已被封禁")
-			return
-		}
+	userCache, err := model.GetUserCache(token.UserId)
+	if err != nil {
+		abortWithOpenAiMessage(c, http.StatusInternalServerError, err.Error())
+		return
+	}
+	userEnabled := userCache.Status == common.UserStatusEnabled
+	if !userEnabled {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "用户已被封禁")
+		return
+	}
 
-		userCache.WriteContext(c)
+	userCache.WriteContext(c)
 
-		userGroup := userCache.Group
-		tokenGroup := token.Group
-		if tokenGroup != "" {
-			// check common.UserUsableGroups[userGroup]
-			if _, ok := service.GetUserUsableGroups(userGroup)[tokenGroup]; !ok {
-				abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("无权访问 %s 分组", tokenGroup))
+	userGroup := userCache.Group
+	tokenGroup := token.Group
+	if tokenGroup != "" {
+		// check common.UserUsableGroups[userGroup]
+		if _, ok := service.GetUserUsableGroups(userGroup)[tokenGroup]; !ok {
+			abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("无权访问 %s 分组", tokenGroup))
+			return
+		}
+		// check group in common.GroupRatio
+		if !ratio_setting.ContainsGroupRatio(tokenGroup) {
+			if tokenGroup != "auto" {
+				abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("分组 %s 已被弃用", tokenGroup))
 				return
 			}
-			// check group in common.GroupRatio
-			if !ratio_setting.ContainsGroupRatio(tokenGroup) {
-				if tokenGroup != "auto" {
-					abortWithOpenAiMessage(c, http.StatusForbidden, fmt.Sprintf("分组 %s 已被弃用", tokenGroup))
-					return
-				}
-			}
-			userGroup = tokenGroup
 		}
-		common.SetContextKey(c, constant.ContextKeyUsingGroup, userGroup)
+		userGroup = tokenGroup
+	}
+	common.SetContextKey(c, constant.ContextKeyUsingGroup, userGroup)
 
-		err = SetupContextForToken(c, token, parts...)
-		if err != nil {
-			return
+	err = SetupContextForToken(c, token, parts...)
+	if err != nil {
+		return
+	}
+	span.SetAttributes(attribute.Int("user.id", token.UserId), attribute.Int("token.id", token.Id), attribute.String("group", userGroup))
+}
+
+// checkTokenPolicy 检查令牌访问策略中的 IP、来源、时间段与接口限制
+func checkTokenPolicy(c *gin.Context, token *model.Token) bool {
+	policy := token.GetPolicy()
+	if len(policy.AllowIps) > 0 {
+		clientIp := c.ClientIP()
+		logger.LogDebug(c, "Token has IP restrictions, checking client IP %s", clientIp)
+		ip := net.ParseIP(clientIp)
+		if ip == nil {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "无法解析客户端 IP 地址")
+			return false
 		}
-		c.Next()
+		if !model.TokenPolicyAllowsIp(&policy, ip) {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "您的 IP 不在令牌允许访问的列表中")
+			return false
+		}
+		logger.LogDebug(c, "Client IP %s passed the token IP restrictions check", clientIp)
+	}
+	// 批处理回放已在创建时检查来源与时间段，回放时只重新检查 IP 与接口
+	replay := common.GetContextKeyBool(c, constant.ContextKeyBatchReplay)
+	if !replay && !model.TokenPolicyAllowsReferer(&policy, c.Request.Header.Get("Origin"), c.Request.Referer()) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "请求来源不在令牌允许访问的列表中")
+		return false
+	}
+	if !replay && !model.TokenPolicyAllowsTime(&policy, time.Now()) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "当前时间不在令牌允许访问的时间段内")
+		return false
 	}
+	if !model.TokenPolicyAllowsEndpoint(&policy, c.Request.URL.Path) {
+		abortWithOpenAiMessage(c, http.StatusForbidden, "该令牌无权访问此接口")
+		return false
+	}
+	return true
 }
 
 func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) error {
@@ -322,6 +415,24 @@ func SetupContextForToken(c *gin.Context, token *model.Token, parts ...string) e
 	}
 	common.SetContextKey(c, constant.ContextKeyTokenGroup, token.Group)
 	common.SetContextKey(c, constant.ContextKeyTokenCrossGroupRetry, token.CrossGroupRetry)
+	common.SetContextKey(c, constant.ContextKeyTokenFallbackDisabled, token.FallbackDisabled)
+	common.SetContextKey(c, constant.ContextKeyTokenHedgeDelay, token.HedgeDelay)
+	common.SetContextKey(c, constant.ContextKeyTokenPriorityClass, token.PriorityClass)
+	common.SetContextKey(c, constant.ContextKeyTokenTPMLimit, token.TPMLimit)
+	common.SetContextKey(c, constant.ContextKeyTokenConcurrencyLimit, token.ConcurrencyLimit)
+	if token.OrgId != 0 {
+		// 组织令牌需要组织可用且令牌所有者仍是组织成员
+		org, err := model.GetOrganizationCache(token.OrgId)
+		if err != nil || org.Status != model.OrganizationStatusEnabled {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "令牌所属组织不可用")
+			return fmt.Errorf("令牌所属组织不可用")
+		}
+		if _, err := model.GetOrganizationMemberCache(token.OrgId, token.UserId); err != nil {
+			abortWithOpenAiMessage(c, http.StatusForbidden, "令牌所有者已不是组织成员")
+			return fmt.Errorf("令牌所有者已不是组织成员")
+		}
+		common.SetContextKey(c, constant.ContextKeyTokenOrgId, token.OrgId)
+	}
 	if len(parts) > 1 {
 		if model.IsAdmin(token.UserId) {
 			c.Set("specific_channel_id", parts[1])
@@ -0,0 +1,44 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+)
+
+// Tracing 为每个请求创建服务端 span，请求头中带有 W3C traceparent 时沿用上游的链路
+func Tracing() func(c *gin.Context) {
+	return func(c *gin.Context) {
+		route := c.FullPath()
+		if route == "" {
+			route = c.Request.URL.Path
+		}
+		ctx, span := tracing.StartServer(c, c.Request.Method+" "+route,
+			attribute.String("http.request.method", c.Request.Method),
+			attribute.String("http.route", route),
+			attribute.String("request.id", c.GetString(common.RequestIdKey)),
+		)
+		defer span.End()
+		c.Request = c.Request.WithContext(ctx)
+
+		c.Next()
+
+		statusCode := c.Writer.Status()
+		span.SetAttributes(
+			attribute.Int("http.response.status_code", statusCode),
+			attribute.Int("user.id", c.GetInt("id")),
+			attribute.Int("token.id", c.GetInt("token_id")),
+			attribute.Int("channel.id", common.GetContextKeyInt(c, constant.ContextKeyChannelId)),
+			attribute.String("model", common.GetContextKeyString(c, constant.ContextKeyOriginalModel)),
+		)
+		if statusCode >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(statusCode))
+		}
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting"
 
 	"github.com/samber/lo"
 	"gorm.io/gorm"
@@ -58,89 +59,89 @@ func GetAllEnableAbilities() []Ability {
 	return abilities
 }
 
-func getPriority(group string, model string, retry int) (int, error) {
-
-	var priorities []int
-	err := DB.Model(&Ability{}).
-		Select("DISTINCT(priority)").
-		Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
-		Order("priority DESC").              // 按优先级降序排序
-		Pluck("priority", &priorities).Error // Pluck用于将查询的结果直接扫描到一个切片中
-
+func GetChannel(group string, model string, retry int) (*Channel, error) {
+	var abilities []Ability
+	err := DB.Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
+		Order("priority DESC, weight DESC").Find(&abilities).Error
 	if err != nil {
-		// 处理错误
-		return 0, err
+		return nil, err
 	}
-
-	if len(priorities) == 0 {
-		// 如果没有查询到优先级，则返回错误
-		return 0, errors.New("数据库一致性被破坏")
+	if len(abilities) == 0 {
+		return nil, nil
 	}
-
-	// 确定要使用的优先级
-	var priorityToUse int
-	if retry >= len(priorities) {
-		// 如果重试次数大于优先级数，则使用最小的优先级
-		priorityToUse = priorities[len(priorities)-1]
-	} else {
-		priorityToUse = priorities[retry]
+	var channels []*Channel
+	channelIds := lo.Uniq(lo.Map(abilities, func(ability Ability, _ int) int { return ability.ChannelId }))
+	if err = DB.Where("id IN ?", channelIds).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	id2channel := make(map[int]*Channel, len(channels))
+	for _, channel := range channels {
+		id2channel[channel.Id] = channel
 	}
-	return priorityToUse, nil
-}
 
-func getChannelQuery(group string, model string, retry int) (*gorm.DB, error) {
-	maxPrioritySubQuery := DB.Model(&Ability{}).Select("MAX(priority)").Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true)
-	channelQuery := DB.Where(commonGroupCol+" = ? and model = ? and enabled = ? and priority = (?)", group, model, true, maxPrioritySubQuery)
-	if retry != 0 {
-		priority, err := getPriority(group, model, retry)
-		if err != nil {
-			return nil, err
-		} else {
-			channelQuery = DB.Where(commonGroupCol+" = ? and model = ? and enabled = ? and priority = ?", group, model, true, priority)
+	// 按优先级从高到低分组
+	var tiers [][]*Channel
+	var lastPriority int64
+	for _, ability := range abilities {
+		channel, ok := id2channel[ability.ChannelId]
+		if !ok {
+			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", ability.ChannelId)
+		}
+		var priority int64
+		if ability.Priority != nil {
+			priority = *ability.Priority
 		}
+		if len(tiers) == 0 || priority != lastPriority {
+			tiers = append(tiers, nil)
+			lastPriority = priority
+		}
+		tiers[len(tiers)-1] = append(tiers[len(tiers)-1], channel)
 	}
+	retry = min(retry, len(tiers)-1)
 
-	return channelQuery, nil
-}
+	// 跳过熔断中的渠道与并发已满的渠道
+	channels = filterBreakerTiers(tiers, retry)
+	channels = filterSaturatedChannels(channels)
 
-func GetChannel(group string, model string, retry int) (*Channel, error) {
-	var abilities []Ability
+	var channel *Channel
+	if setting.IsAdaptiveSelectGroup(group) {
+		channel = pickAdaptiveChannel(channels, model)
+	} else {
+		channel = pickWeightedAbilityChannel(abilities, channels)
+	}
+	if channel == nil {
+		return nil, errors.New("channel not found")
+	}
+	acquireChannelBreaker(channel.Id)
+	return channel, nil
+}
 
-	var err error = nil
-	channelQuery, err := getChannelQuery(group, model, retry)
-	if err != nil {
-		return nil, err
+// pickWeightedAbilityChannel 在可用渠道中按能力的权重随机选择，每个能力的权重额外加 10
+func pickWeightedAbilityChannel(abilities []Ability, channels []*Channel) *Channel {
+	id2channel := make(map[int]*Channel, len(channels))
+	for _, channel := range channels {
+		id2channel[channel.Id] = channel
 	}
-	if common.UsingSQLite || common.UsingPostgreSQL {
-		err = channelQuery.Order("weight DESC").Find(&abilities).Error
-	} else {
-		err = channelQuery.Order("weight DESC").Find(&abilities).Error
+	candidates := lo.Filter(abilities, func(ability Ability, _ int) bool {
+		_, ok := id2channel[ability.ChannelId]
+		return ok
+	})
+	if len(candidates) == 0 {
+		return nil
 	}
-	if err != nil {
-		return nil, err
+	weightSum := uint(0)
+	for _, ability_ := range candidates {
+		weightSum += ability_.Weight + 10
 	}
-	channel := Channel{}
-	if len(abilities) > 0 {
-		// Randomly choose one
-		weightSum := uint(0)
-		for _, ability_ := range abilities {
-			weightSum += ability_.Weight + 10
+	// Randomly choose one
+	weight := common.GetRandomInt(int(weightSum))
+	for _, ability_ := range candidates {
+		weight -= int(ability_.Weight) + 10
+		if weight <= 0 {
+			return id2channel[ability_.ChannelId]
 		}
-		// Randomly choose one
-		weight := common.GetRandomInt(int(weightSum))
-		for _, ability_ := range abilities {
-			weight -= int(ability_.Weight) + 10
-			//log.Printf("weight: %d, ability weight: %d", weight, *ability_.Weight)
-			if weight <= 0 {
-				channel.Id = ability_.ChannelId
-				break
-			}
-		}
-	} else {
-		return nil, nil
 	}
-	err = DB.First(&channel, "id = ?", channel.Id).Error
-	return &channel, err
+	return id2channel[candidates[len(candidates)-1].ChannelId]
 }
 
 func (channel *Channel) AddAbilities(tx *gorm.DB) error {
@@ -0,0 +1,42 @@
+package model
+
+import "testing"
+
+func TestPickWeightedAbilityChannel(t *testing.T) {
+	abilities := []Ability{{ChannelId: 1, Weight: 90}, {ChannelId: 2, Weight: 0}, {ChannelId: 3, Weight: 0}}
+	channels := []*Channel{{Id: 1}, {Id: 2}, {Id: 3}}
+	tests := []struct {
+		name      string
+		available []*Channel
+		want      map[int]bool
+	}{
+		{"only available channels", []*Channel{channels[1]}, map[int]bool{2: true}},
+		{"no available channels", nil, map[int]bool{}},
+		{"all channels", channels, map[int]bool{1: true, 2: true, 3: true}},
+	}
+	for _, tt := range tests {
+		picked := make(map[int]int)
+		for n := 0; n < 1000; n++ {
+			if channel := pickWeightedAbilityChannel(abilities, tt.available); channel != nil {
+				picked[channel.Id]++
+			}
+		}
+		for id := range picked {
+			if !tt.want[id] {
+				t.Fatalf("%s: unexpected channel %d", tt.name, id)
+			}
+		}
+		if len(picked) != len(tt.want) {
+			t.Fatalf("%s: expected channels %v, got %v", tt.name, tt.want, picked)
+		}
+	}
+
+	// 权重 90 的能力按 100:10:10 的比例被选中
+	picked := make(map[int]int)
+	for n := 0; n < 1200; n++ {
+		picked[pickWeightedAbilityChannel(abilities, channels).Id]++
+	}
+	if picked[1] < 700 || picked[2] > 250 || picked[3] > 250 {
+		t.Fatalf("expected ability weights to drive selection, got %v", picked)
+	}
+}
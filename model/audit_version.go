@@ -0,0 +1,276 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"gorm.io/gorm"
+)
+
+const (
+	AuditEntityChannel = "channel"
+	AuditEntityOption  = "option"
+	AuditEntityToken   = "token"
+	AuditEntityUser    = "user"
+)
+
+const (
+	AuditActionBaseline = "baseline"
+	AuditActionUpdate   = "update"
+	AuditActionRollback = "rollback"
+)
+
+// 密钥、可能携带凭据的覆盖与设置字段以及运行时自动维护的字段不纳入快照，回滚时保持当前值
+var auditExcludedFields = map[string][]string{
+	AuditEntityChannel: {"id", "key", "status", "created_time", "test_time", "response_time", "balance",
+		"balance_updated_time", "used_quota", "other_info", "channel_info",
+		"other", "setting", "settings", "param_override", "header_override"},
+	AuditEntityToken: {"id", "user_id", "key", "status", "created_time", "accessed_time", "remain_quota",
+		"used_quota", "budget_used", "budget_period_start", "DeletedAt"},
+}
+
+// AuditVersion 配置变更版本，Snapshot 为变更后的完整快照，Diff 为相对上一状态的差异
+type AuditVersion struct {
+	Id          int    `json:"id"`
+	EntityType  string `json:"entity_type" gorm:"type:varchar(32);uniqueIndex:idx_audit_entity_version"`
+	EntityId    string `json:"entity_id" gorm:"type:varchar(128);uniqueIndex:idx_audit_entity_version"`
+	Version     int    `json:"version" gorm:"uniqueIndex:idx_audit_entity_version"`
+	Action      string `json:"action" gorm:"type:varchar(16)"`
+	Snapshot    string `json:"snapshot" gorm:"type:text"`
+	Diff        string `json:"diff" gorm:"type:text"`
+	ActorId     int    `json:"actor_id" gorm:"index"`
+	ActorName   string `json:"actor_name" gorm:"type:varchar(64)"`
+	Ip          string `json:"ip" gorm:"type:varchar(64)"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint;index"`
+}
+
+// AuditChange 单个字段的变更
+type AuditChange struct {
+	Old any `json:"old"`
+	New any `json:"new"`
+}
+
+func IsValidAuditEntity(entityType string) bool {
+	switch entityType {
+	case AuditEntityChannel, AuditEntityOption, AuditEntityToken, AuditEntityUser:
+		return true
+	}
+	return false
+}
+
+func toAuditSnapshot(entityType string, v any) (map[string]any, error) {
+	data, err := common.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var snapshot map[string]any
+	if err := decoder.Decode(&snapshot); err != nil {
+		return nil, err
+	}
+	for _, field := range auditExcludedFields[entityType] {
+		delete(snapshot, field)
+	}
+	return snapshot, nil
+}
+
+// GetAuditEntitySnapshot 读取实体当前状态的快照
+func GetAuditEntitySnapshot(entityType string, entityId string) (map[string]any, error) {
+	switch entityType {
+	case AuditEntityOption:
+		common.OptionMapRWMutex.RLock()
+		value := common.Interface2String(common.OptionMap[entityId])
+		common.OptionMapRWMutex.RUnlock()
+		return map[string]any{"value": value}, nil
+	}
+	id, err := strconv.Atoi(entityId)
+	if err != nil {
+		return nil, errors.New("无效的实体 id")
+	}
+	switch entityType {
+	case AuditEntityChannel:
+		channel, err := GetChannelById(id, false)
+		if err != nil {
+			return nil, err
+		}
+		return toAuditSnapshot(entityType, channel)
+	case AuditEntityToken:
+		token, err := GetTokenById(id)
+		if err != nil {
+			return nil, err
+		}
+		return toAuditSnapshot(entityType, token)
+	case AuditEntityUser:
+		group, err := GetUserGroup(id, true)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"group": group}, nil
+	}
+	return nil, errors.New("不支持的实体类型")
+}
+
+func diffAuditSnapshots(before map[string]any, after map[string]any) map[string]AuditChange {
+	diff := make(map[string]AuditChange)
+	for key, newValue := range after {
+		oldValue, ok := before[key]
+		if !ok || !reflect.DeepEqual(oldValue, newValue) {
+			diff[key] = AuditChange{Old: oldValue, New: newValue}
+		}
+	}
+	for key, oldValue := range before {
+		if _, ok := after[key]; !ok {
+			diff[key] = AuditChange{Old: oldValue}
+		}
+	}
+	return diff
+}
+
+// RecordAuditVersion 记录一次变更，实体首次变更时先保存变更前的状态作为基线版本
+func RecordAuditVersion(entityType string, entityId string, before map[string]any, after map[string]any, action string, actorId int, actorName string, ip string) error {
+	diff := diffAuditSnapshots(before, after)
+	if len(diff) == 0 {
+		return nil
+	}
+	diffJson, err := common.Marshal(diff)
+	if err != nil {
+		return err
+	}
+	afterJson, err := common.Marshal(after)
+	if err != nil {
+		return err
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		var latest int
+		if err := tx.Model(&AuditVersion{}).Where("entity_type = ? AND entity_id = ?", entityType, entityId).
+			Select("COALESCE(MAX(version), 0)").Scan(&latest).Error; err != nil {
+			return err
+		}
+		now := common.GetTimestamp()
+		if latest == 0 {
+			beforeJson, err := common.Marshal(before)
+			if err != nil {
+				return err
+			}
+			latest++
+			if err := tx.Create(&AuditVersion{
+				EntityType:  entityType,
+				EntityId:    entityId,
+				Version:     latest,
+				Action:      AuditActionBaseline,
+				Snapshot:    string(beforeJson),
+				Diff:        "{}",
+				CreatedTime: now,
+			}).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Create(&AuditVersion{
+			EntityType:  entityType,
+			EntityId:    entityId,
+			Version:     latest + 1,
+			Action:      action,
+			Snapshot:    string(afterJson),
+			Diff:        string(diffJson),
+			ActorId:     actorId,
+			ActorName:   actorName,
+			Ip:          ip,
+			CreatedTime: now,
+		}).Error
+	})
+}
+
+// GetAuditVersions 分页查询变更版本，hiddenTypes 中的实体类型不返回
+func GetAuditVersions(entityType string, entityId string, hiddenTypes []string, startIdx int, num int) (versions []*AuditVersion, total int64, err error) {
+	tx := DB.Model(&AuditVersion{})
+	if entityType != "" {
+		tx = tx.Where("entity_type = ?", entityType)
+	}
+	if len(hiddenTypes) > 0 {
+		tx = tx.Where("entity_type NOT IN ?", hiddenTypes)
+	}
+	if entityId != "" {
+		tx = tx.Where("entity_id = ?", entityId)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&versions).Error
+	return versions, total, err
+}
+
+func GetAuditVersionById(id int) (*AuditVersion, error) {
+	var version AuditVersion
+	err := DB.First(&version, "id = ?", id).Error
+	return &version, err
+}
+
+// auditFieldNames 将快照中的 json 字段名转换为结构体字段名，用于指定需要更新的列
+func auditFieldNames(v any, snapshot map[string]any) []string {
+	t := reflect.TypeOf(v).Elem()
+	fields := make([]string, 0, len(snapshot))
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := strings.Split(field.Tag.Get("json"), ",")[0]
+		if name == "" {
+			name = field.Name
+		}
+		if _, ok := snapshot[name]; ok {
+			fields = append(fields, field.Name)
+		}
+	}
+	return fields
+}
+
+// ApplyAuditVersion 将实体恢复为指定版本的快照
+func ApplyAuditVersion(version *AuditVersion) error {
+	var snapshot map[string]any
+	if err := common.UnmarshalJsonStr(version.Snapshot, &snapshot); err != nil {
+		return err
+	}
+	switch version.EntityType {
+	case AuditEntityOption:
+		value, _ := snapshot["value"].(string)
+		return UpdateOption(version.EntityId, value)
+	}
+	id, err := strconv.Atoi(version.EntityId)
+	if err != nil {
+		return errors.New("无效的实体 id")
+	}
+	switch version.EntityType {
+	case AuditEntityChannel:
+		channel, err := GetChannelById(id, true)
+		if err != nil {
+			return err
+		}
+		if err := common.UnmarshalJsonStr(version.Snapshot, channel); err != nil {
+			return err
+		}
+		if err := DB.Model(channel).Select(auditFieldNames(channel, snapshot)).Updates(channel).Error; err != nil {
+			return err
+		}
+		return channel.UpdateAbilities(nil)
+	case AuditEntityToken:
+		token, err := GetTokenById(id)
+		if err != nil {
+			return err
+		}
+		if err := common.UnmarshalJsonStr(version.Snapshot, token); err != nil {
+			return err
+		}
+		return token.Update()
+	case AuditEntityUser:
+		group, _ := snapshot["group"].(string)
+		if err := DB.Model(&User{}).Where("id = ?", id).Update("group", group).Error; err != nil {
+			return err
+		}
+		return updateUserGroupCache(id, group)
+	}
+	return errors.New("不支持的实体类型")
+}
@@ -0,0 +1,166 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+type BatchStatus string
+
+const (
+	BatchStatusValidating BatchStatus = "validating"
+	BatchStatusFailed     BatchStatus = "failed"
+	BatchStatusInProgress BatchStatus = "in_progress"
+	BatchStatusFinalizing BatchStatus = "finalizing"
+	BatchStatusCompleted  BatchStatus = "completed"
+	BatchStatusExpired    BatchStatus = "expired"
+	BatchStatusCancelling BatchStatus = "cancelling"
+	BatchStatusCancelled  BatchStatus = "cancelled"
+)
+
+func (s BatchStatus) IsFinished() bool {
+	switch s {
+	case BatchStatusFailed, BatchStatusCompleted, BatchStatusExpired, BatchStatusCancelled:
+		return true
+	}
+	return false
+}
+
+type BatchRequestCounts struct {
+	Total     int `json:"total"`
+	Completed int `json:"completed"`
+	Failed    int `json:"failed"`
+}
+
+type BatchErrors struct {
+	Object string             `json:"object"`
+	Data   []BatchErrorDetail `json:"data"`
+}
+
+type BatchErrorDetail struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Line    *int   `json:"line,omitempty"`
+}
+
+func (e *BatchErrors) Scan(val interface{}) error {
+	bytesValue, _ := val.([]byte)
+	if len(bytesValue) == 0 {
+		return nil
+	}
+	return json.Unmarshal(bytesValue, e)
+}
+
+func (e BatchErrors) Value() (driver.Value, error) {
+	if len(e.Data) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(e)
+}
+
+// Batch 对应 /v1/batches 的批处理任务，由后台按行回放到正常的转发流程
+type Batch struct {
+	Id               int             `json:"-"`
+	BatchId          string          `json:"id" gorm:"type:varchar(64);uniqueIndex"`
+	Object           string          `json:"object" gorm:"-"`
+	UserId           int             `json:"-" gorm:"index"`
+	TokenId          int             `json:"-" gorm:"index"`
+	Endpoint         string          `json:"endpoint" gorm:"type:varchar(64)"`
+	InputFileId      string          `json:"input_file_id" gorm:"type:varchar(64)"`
+	OutputFileId     string          `json:"output_file_id,omitempty" gorm:"type:varchar(64)"`
+	ErrorFileId      string          `json:"error_file_id,omitempty" gorm:"type:varchar(64)"`
+	CompletionWindow string          `json:"completion_window" gorm:"type:varchar(16)"`
+	Status           BatchStatus     `json:"status" gorm:"type:varchar(20);index"`
+	Errors           BatchErrors     `json:"errors,omitempty" gorm:"type:json"`
+	RequestTotal     int             `json:"-"`
+	RequestCompleted int             `json:"-"`
+	RequestFailed    int             `json:"-"`
+	Metadata         json.RawMessage `json:"metadata,omitempty" gorm:"type:json"`
+	// 创建批处理时的客户端 IP，回放时用于通过令牌的 IP 限制
+	ClientIp     string `json:"-" gorm:"type:varchar(64)"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+	InProgressAt int64  `json:"in_progress_at,omitempty" gorm:"bigint"`
+	FinalizingAt int64  `json:"finalizing_at,omitempty" gorm:"bigint"`
+	CompletedAt  int64  `json:"completed_at,omitempty" gorm:"bigint"`
+	FailedAt     int64  `json:"failed_at,omitempty" gorm:"bigint"`
+	ExpiresAt    int64  `json:"expires_at,omitempty" gorm:"bigint"`
+	ExpiredAt    int64  `json:"expired_at,omitempty" gorm:"bigint"`
+	CancellingAt int64  `json:"cancelling_at,omitempty" gorm:"bigint"`
+	CancelledAt  int64  `json:"cancelled_at,omitempty" gorm:"bigint"`
+
+	RequestCounts BatchRequestCounts `json:"request_counts" gorm:"-"`
+}
+
+// Normalize 填充仅用于响应的字段
+func (b *Batch) Normalize() *Batch {
+	b.Object = "batch"
+	b.RequestCounts = BatchRequestCounts{
+		Total:     b.RequestTotal,
+		Completed: b.RequestCompleted,
+		Failed:    b.RequestFailed,
+	}
+	if b.Errors.Object == "" && len(b.Errors.Data) > 0 {
+		b.Errors.Object = "list"
+	}
+	return b
+}
+
+func (b *Batch) Insert() error {
+	if b.CreatedAt == 0 {
+		b.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(b).Error
+}
+
+func (b *Batch) Update() error {
+	return DB.Save(b).Error
+}
+
+// UpdateProgress 仅更新计数，避免覆盖并发的取消操作
+func (b *Batch) UpdateProgress() error {
+	return DB.Model(&Batch{}).Where("id = ?", b.Id).Updates(map[string]any{
+		"request_total":     b.RequestTotal,
+		"request_completed": b.RequestCompleted,
+		"request_failed":    b.RequestFailed,
+	}).Error
+}
+
+func GetUserBatch(userId int, batchId string) (*Batch, bool, error) {
+	var batch Batch
+	err := DB.Where("user_id = ? and batch_id = ?", userId, batchId).First(&batch).Error
+	exist, err := RecordExist(err)
+	if err != nil || !exist {
+		return nil, exist, err
+	}
+	return &batch, true, nil
+}
+
+func GetUserBatches(userId int, afterId string, limit int) ([]*Batch, error) {
+	var batches []*Batch
+	query := DB.Where("user_id = ?", userId)
+	if afterId != "" {
+		var after Batch
+		if err := DB.Select("id").Where("user_id = ? and batch_id = ?", userId, afterId).First(&after).Error; err == nil {
+			query = query.Where("id < ?", after.Id)
+		}
+	}
+	err := query.Order("id desc").Limit(limit).Find(&batches).Error
+	return batches, err
+}
+
+func GetBatchStatus(id int) (BatchStatus, error) {
+	var batch Batch
+	err := DB.Select("status").Where("id = ?", id).First(&batch).Error
+	return batch.Status, err
+}
+
+// GetUnfinishedBatches 获取待执行或执行中的批处理，按创建顺序处理
+func GetUnfinishedBatches(limit int) ([]*Batch, error) {
+	var batches []*Batch
+	err := DB.Where("status in (?)", []BatchStatus{
+		BatchStatusValidating, BatchStatusInProgress, BatchStatusFinalizing, BatchStatusCancelling,
+	}).Order("id").Limit(limit).Find(&batches).Error
+	return batches, err
+}
@@ -21,7 +21,8 @@ import (
 type Channel struct {
 	Id                 int     `json:"id"`
 	Type               int     `json:"type" gorm:"default:0"`
-	Key                string  `json:"key" gorm:"not null"`
+	Key                string  `json:"key" gorm:"not null;serializer:secret"`
+	KeyHash            string  `json:"-" gorm:"type:varchar(64);index"` // 启用静态加密时密钥的 HMAC，用于按密钥搜索
 	OpenAIOrganization *string `json:"openai_organization"`
 	TestModel          *string `json:"test_model"`
 	Status             int     `json:"status" gorm:"default:1"`
@@ -144,11 +145,26 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	if len(enabledIdx) == 0 {
 		return "", 0, types.NewError(errors.New("no enabled keys"), types.ErrorCodeChannelNoAvailableKey)
 	}
+	// 跳过熔断中的密钥；全部熔断时改用其他渠道，渠道本身也已熔断（其他渠道同样不可用）时仍使用全部密钥
+	if available := filterBreakerKeys(channel.Id, enabledIdx); len(available) > 0 {
+		enabledIdx = available
+	} else if channelBreakerAllows(channel.Id) {
+		return "", 0, types.NewError(errors.New("all enabled keys are circuit broken"), types.ErrorCodeChannelNoAvailableKey)
+	}
+	selectable := make(map[int]bool, len(enabledIdx))
+	for _, idx := range enabledIdx {
+		selectable[idx] = true
+	}
 
 	switch channel.ChannelInfo.MultiKeyMode {
 	case constant.MultiKeyModeRandom:
 		// Randomly pick one enabled key
 		selectedIdx := enabledIdx[rand.Intn(len(enabledIdx))]
+		useChannelKey(channel.Id, selectedIdx)
+		return keys[selectedIdx], selectedIdx, nil
+	case constant.MultiKeyModeLeastLoaded:
+		selectedIdx := pickLeastLoadedKey(channel.Id, enabledIdx)
+		useChannelKey(channel.Id, selectedIdx)
 		return keys[selectedIdx], selectedIdx, nil
 	case constant.MultiKeyModePolling:
 		// Use channel-specific lock to ensure thread-safe polling
@@ -175,9 +191,10 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 		}
 		for i := 0; i < len(keys); i++ {
 			idx := (start + i) % len(keys)
-			if getStatus(idx) == common.ChannelStatusEnabled {
+			if selectable[idx] {
 				// update polling index for next call (point to the next position)
 				channel.ChannelInfo.MultiKeyPollingIndex = (idx + 1) % len(keys)
+				useChannelKey(channel.Id, idx)
 				return keys[idx], idx, nil
 			}
 		}
@@ -189,6 +206,31 @@ func (channel *Channel) GetNextEnabledKey() (string, int, *types.NewAPIError) {
 	}
 }
 
+// GetEnabledKeyAt 使用指定索引的密钥，密钥已禁用、熔断中或冷却中时返回 false
+func (channel *Channel) GetEnabledKeyAt(index int) (string, bool) {
+	if !channel.ChannelInfo.IsMultiKey {
+		return channel.Key, index == 0
+	}
+	keys := channel.GetKeys()
+	if index < 0 || index >= len(keys) {
+		return "", false
+	}
+	if status, ok := channel.ChannelInfo.MultiKeyStatusList[index]; ok && status != common.ChannelStatusEnabled {
+		return "", false
+	}
+	if !channelKeyBreakerAllows(channel.Id, index) || channelKeyCoolingDown(channel.Id, index) {
+		return "", false
+	}
+	useChannelKey(channel.Id, index)
+	return keys[index], true
+}
+
+// useChannelKey 占用选中密钥的熔断探测名额并记录用量
+func useChannelKey(channelId int, keyIndex int) {
+	acquireChannelKeyBreaker(channelId, keyIndex)
+	recordChannelKeyRequest(channelId, keyIndex)
+}
+
 func (channel *Channel) SaveChannelInfo() error {
 	return DB.Model(channel).Update("channel_info", channel.ChannelInfo).Error
 }
@@ -257,7 +299,24 @@ func (channel *Channel) SaveWithoutKey() error {
 	if channel.Id == 0 {
 		return errors.New("channel ID is 0")
 	}
-	return DB.Omit("key").Save(channel).Error
+	return DB.Omit("key", "key_hash").Save(channel).Error
+}
+
+// BeforeSave 写入密钥时同步更新密钥的 HMAC
+func (channel *Channel) BeforeSave(tx *gorm.DB) error {
+	if channel.Key != "" {
+		channel.KeyHash = common.SecretLookupHash(channel.Key)
+	}
+	return nil
+}
+
+// channelKeySearchCondition 按密钥精确搜索的条件；启用静态加密后密钥列为密文，改为匹配密钥的 HMAC，
+// 启用加密前创建的渠道需要执行一次重新加密（RewrapSecrets）后才能按密钥搜索到
+func channelKeySearchCondition(keyword string) (string, []interface{}) {
+	if hashes := common.SecretLookupHashes(keyword); len(hashes) > 0 {
+		return commonKeyCol + " = ? OR key_hash IN ?", []interface{}{keyword, hashes}
+	}
+	return commonKeyCol + " = ?", []interface{}{keyword}
 }
 
 func GetAllChannels(startIdx int, num int, selectAll bool, idSort bool) ([]*Channel, error) {
@@ -315,6 +374,7 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 	// 构造WHERE子句
 	var whereClause string
 	var args []interface{}
+	keyCondition, keyArgs := channelKeySearchCondition(keyword)
 	if group != "" && group != "null" {
 		var groupCondition string
 		if common.UsingMySQL {
@@ -323,11 +383,15 @@ func SearchChannels(keyword string, group string, model string, idSort bool) ([]
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%")
 	}
 
 	// 执行查询
@@ -810,6 +874,7 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 	// 构造WHERE子句
 	var whereClause string
 	var args []interface{}
+	keyCondition, keyArgs := channelKeySearchCondition(keyword)
 	if group != "" && group != "null" {
 		var groupCondition string
 		if common.UsingMySQL {
@@ -818,11 +883,15 @@ func SearchTags(keyword string, group string, model string, idSort bool) ([]*str
 			// sqlite, PostgreSQL
 			groupCondition = `(',' || ` + commonGroupCol + ` || ',') LIKE ?`
 		}
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + ` LIKE ? AND ` + groupCondition
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%", "%,"+group+",%")
 	} else {
-		whereClause = "(id = ? OR name LIKE ? OR " + commonKeyCol + " = ? OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
-		args = append(args, common.String2Int(keyword), "%"+keyword+"%", keyword, "%"+keyword+"%", "%"+model+"%")
+		whereClause = "(id = ? OR name LIKE ? OR " + keyCondition + " OR " + baseURLCol + " LIKE ?) AND " + modelsCol + " LIKE ?"
+		args = append(args, common.String2Int(keyword), "%"+keyword+"%")
+		args = append(args, keyArgs...)
+		args = append(args, "%"+keyword+"%", "%"+model+"%")
 	}
 
 	subQuery := baseQuery.Where(whereClause, args...).
@@ -0,0 +1,38 @@
+package model
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/common/admission"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+// ChannelAdmissionKey 渠道并发计数的 key
+func ChannelAdmissionKey(channelId int) string {
+	return fmt.Sprintf("channel:%d", channelId)
+}
+
+// GetConcurrencyLimit 返回渠道的并发上限，0 表示不限制
+func (channel *Channel) GetConcurrencyLimit() int {
+	if !operation_setting.GetAdmissionSetting().Enabled {
+		return 0
+	}
+	return operation_setting.GetChannelConcurrency(channel.GetSetting().MaxConcurrency)
+}
+
+// filterSaturatedChannels 过滤掉并发已满的渠道，全部已满时返回原列表，请求在选中的渠道排队
+func filterSaturatedChannels(channels []*Channel) []*Channel {
+	if !operation_setting.GetAdmissionSetting().Enabled {
+		return channels
+	}
+	available := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if !admission.Saturated(ChannelAdmissionKey(channel.Id), channel.GetConcurrencyLimit()) {
+			available = append(available, channel)
+		}
+	}
+	if len(available) == 0 {
+		return channels
+	}
+	return available
+}
@@ -0,0 +1,113 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/breaker"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+func getChannelBreakerConfig() (breaker.Config, bool) {
+	setting := operation_setting.GetCircuitBreakerSetting()
+	cfg := breaker.Config{
+		FailureThreshold: max(setting.FailureThreshold, 1),
+		Cooldown:         time.Duration(max(setting.CooldownSeconds, 1)) * time.Second,
+		HalfOpenProbes:   max(setting.HalfOpenProbes, 1),
+	}
+	return cfg, setting.Enabled
+}
+
+func channelBreakerKey(channelId int) string {
+	return fmt.Sprintf("channel_breaker:%d", channelId)
+}
+
+func channelKeyBreakerKey(channelId int, keyIndex int) string {
+	return fmt.Sprintf("channel_breaker:%d:%d", channelId, keyIndex)
+}
+
+// filterBreakerChannels 过滤掉熔断中的渠道，全部熔断时返回空列表，由调用方改用下一优先级
+func filterBreakerChannels(channels []*Channel) []*Channel {
+	cfg, enabled := getChannelBreakerConfig()
+	if !enabled {
+		return channels
+	}
+	available := make([]*Channel, 0, len(channels))
+	for _, channel := range channels {
+		if breaker.Allow(channelBreakerKey(channel.Id), cfg, false) {
+			available = append(available, channel)
+		}
+	}
+	return available
+}
+
+// filterBreakerTiers 从 retry 对应的优先级开始，返回第一个存在未熔断渠道的优先级中未熔断的渠道；
+// 之后的优先级全部熔断时返回 retry 对应优先级的全部渠道，避免请求无渠道可用
+func filterBreakerTiers(tiers [][]*Channel, retry int) []*Channel {
+	for _, tier := range tiers[retry:] {
+		if available := filterBreakerChannels(tier); len(available) > 0 {
+			return available
+		}
+	}
+	return tiers[retry]
+}
+
+// filterBreakerKeys 过滤掉熔断中的多密钥索引，全部熔断时返回空列表
+func filterBreakerKeys(channelId int, keyIndexes []int) []int {
+	cfg, enabled := getChannelBreakerConfig()
+	if !enabled {
+		return keyIndexes
+	}
+	available := make([]int, 0, len(keyIndexes))
+	for _, idx := range keyIndexes {
+		if breaker.Allow(channelKeyBreakerKey(channelId, idx), cfg, false) {
+			available = append(available, idx)
+		}
+	}
+	return available
+}
+
+// tryAcquireChannelBreaker 渠道未熔断时占用探测名额并返回 true，熔断中返回 false
+func tryAcquireChannelBreaker(channelId int) bool {
+	cfg, enabled := getChannelBreakerConfig()
+	return !enabled || breaker.Allow(channelBreakerKey(channelId), cfg, true)
+}
+
+// channelBreakerAllows 渠道是否未熔断，不占用探测名额
+func channelBreakerAllows(channelId int) bool {
+	cfg, enabled := getChannelBreakerConfig()
+	return !enabled || breaker.Allow(channelBreakerKey(channelId), cfg, false)
+}
+
+func channelKeyBreakerAllows(channelId int, keyIndex int) bool {
+	cfg, enabled := getChannelBreakerConfig()
+	return !enabled || breaker.Allow(channelKeyBreakerKey(channelId, keyIndex), cfg, false)
+}
+
+// acquireChannelBreaker 选中渠道后占用半开状态的探测名额
+func acquireChannelBreaker(channelId int) {
+	if cfg, enabled := getChannelBreakerConfig(); enabled {
+		breaker.Allow(channelBreakerKey(channelId), cfg, true)
+	}
+}
+
+func acquireChannelKeyBreaker(channelId int, keyIndex int) {
+	if cfg, enabled := getChannelBreakerConfig(); enabled {
+		breaker.Allow(channelKeyBreakerKey(channelId, keyIndex), cfg, true)
+	}
+}
+
+// ReportChannelBreaker 上报渠道请求结果，多密钥渠道同时上报对应密钥
+func ReportChannelBreaker(channelId int, isMultiKey bool, keyIndex int, success bool) {
+	cfg, enabled := getChannelBreakerConfig()
+	if !enabled {
+		return
+	}
+	if breaker.Report(channelBreakerKey(channelId), cfg, success) {
+		common.SysLog(fmt.Sprintf("channel #%d circuit breaker opened, cooldown %s", channelId, cfg.Cooldown))
+	}
+	if isMultiKey && breaker.Report(channelKeyBreakerKey(channelId, keyIndex), cfg, success) {
+		common.SysLog(fmt.Sprintf("channel #%d key #%d circuit breaker opened, cooldown %s", channelId, keyIndex, cfg.Cooldown))
+	}
+}
@@ -0,0 +1,58 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+)
+
+func enableTestBreaker(t *testing.T) {
+	t.Helper()
+	setting := operation_setting.GetCircuitBreakerSetting()
+	old, redisEnabled := *setting, common.RedisEnabled
+	setting.Enabled, setting.FailureThreshold, setting.CooldownSeconds, setting.HalfOpenProbes = true, 1, 60, 1
+	common.RedisEnabled = false
+	t.Cleanup(func() {
+		*setting, common.RedisEnabled = old, redisEnabled
+	})
+}
+
+func TestFilterBreakerTiers(t *testing.T) {
+	enableTestBreaker(t)
+	// 910001、910002 熔断中
+	for _, id := range []int{910001, 910002} {
+		ReportChannelBreaker(id, false, 0, false)
+	}
+	ch := func(id int) *Channel { return &Channel{Id: id} }
+	tests := []struct {
+		name  string
+		tiers [][]*Channel
+		retry int
+		want  []int
+	}{
+		{"skip open channel", [][]*Channel{{ch(910001), ch(910003)}}, 0, []int{910003}},
+		{"open tier yields to next tier", [][]*Channel{{ch(910001)}, {ch(910004), ch(910002)}}, 0, []int{910004}},
+		{"retry starts at lower tier", [][]*Channel{{ch(910003)}, {ch(910001)}, {ch(910004)}}, 1, []int{910004}},
+		{"all tiers open fails open", [][]*Channel{{ch(910001)}, {ch(910002)}}, 0, []int{910001}},
+		{"last tier open fails open", [][]*Channel{{ch(910003)}, {ch(910001), ch(910002)}}, 1, []int{910001, 910002}},
+	}
+	for _, tt := range tests {
+		got := filterBreakerTiers(tt.tiers, tt.retry)
+		if len(got) != len(tt.want) {
+			t.Fatalf("%s: expected %v, got %d channels", tt.name, tt.want, len(got))
+		}
+		for i, channel := range got {
+			if channel.Id != tt.want[i] {
+				t.Fatalf("%s: expected %v, got channel %d at %d", tt.name, tt.want, channel.Id, i)
+			}
+		}
+	}
+	if keys := filterBreakerKeys(910001, []int{0}); len(keys) != 1 {
+		t.Fatalf("expected closed key to pass, got %v", keys)
+	}
+	ReportChannelBreaker(910005, true, 0, false)
+	if keys := filterBreakerKeys(910005, []int{0}); len(keys) != 0 {
+		t.Fatalf("expected open key to be filtered, got %v", keys)
+	}
+}
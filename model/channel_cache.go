@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math/rand"
+	"slices"
 	"sort"
 	"strings"
 	"sync"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/setting"
 	"github.com/QuantumNous/new-api/setting/ratio_setting"
 )
 
@@ -115,13 +117,6 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 		return nil, nil
 	}
 
-	if len(channels) == 1 {
-		if channel, ok := channelsIDM[channels[0]]; ok {
-			return channel, nil
-		}
-		return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channels[0])
-	}
-
 	uniquePriorities := make(map[int]bool)
 	for _, channelId := range channels {
 		if channel, ok := channelsIDM[channelId]; ok {
@@ -139,24 +134,82 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	if retry >= len(uniquePriorities) {
 		retry = len(uniquePriorities) - 1
 	}
-	targetPriority := int64(sortedUniquePriorities[retry])
 
-	// get the priority for the given retry number
-	var sumWeight = 0
-	var targetChannels []*Channel
+	// 按优先级从高到低分组
+	tiers := make([][]*Channel, len(sortedUniquePriorities))
 	for _, channelId := range channels {
-		if channel, ok := channelsIDM[channelId]; ok {
-			if channel.GetPriority() == targetPriority {
-				sumWeight += channel.GetWeight()
-				targetChannels = append(targetChannels, channel)
+		channel := channelsIDM[channelId]
+		for i, priority := range sortedUniquePriorities {
+			if channel.GetPriority() == int64(priority) {
+				tiers[i] = append(tiers[i], channel)
+				break
 			}
-		} else {
-			return nil, fmt.Errorf("数据库一致性错误，渠道# %d 不存在，请联系管理员修复", channelId)
 		}
 	}
 
+	// 跳过熔断中的渠道与并发已满的渠道
+	targetChannels := filterBreakerTiers(tiers, retry)
+	targetChannels = filterSaturatedChannels(targetChannels)
+
+	var channel *Channel
+	switch {
+	case len(targetChannels) == 1:
+		// 熔断与并发过滤之后只剩一个渠道时直接使用
+		channel = targetChannels[0]
+	case setting.IsAdaptiveSelectGroup(group):
+		channel = pickAdaptiveChannel(targetChannels, model)
+	default:
+		channel = pickWeightedChannel(targetChannels)
+	}
+	if channel == nil {
+		// return null if no channel is not found
+		return nil, errors.New("channel not found")
+	}
+	acquireChannelBreaker(channel.Id)
+	return channel, nil
+}
+
+// GetSatisfiedChannelIds 获取分组下可用于该模型的全部已启用渠道 id
+func GetSatisfiedChannelIds(group string, model string) ([]int, error) {
+	if !common.MemoryCacheEnabled {
+		var channelIds []int
+		err := DB.Model(&Ability{}).Where(commonGroupCol+" = ? and model = ? and enabled = ?", group, model, true).
+			Pluck("channel_id", &channelIds).Error
+		return channelIds, err
+	}
+
+	channelSyncLock.RLock()
+	defer channelSyncLock.RUnlock()
+	channels := group2model2channels[group][model]
+	if len(channels) == 0 {
+		channels = group2model2channels[group][ratio_setting.FormatMatchingModelName(model)]
+	}
+	return append([]int(nil), channels...), nil
+}
+
+// GetAffinityChannel 返回会话上次使用的渠道，渠道已禁用、不再属于该分组与模型或熔断中时返回 nil
+func GetAffinityChannel(group string, model string, channelId int) *Channel {
+	channelIds, err := GetSatisfiedChannelIds(group, model)
+	if err != nil || !slices.Contains(channelIds, channelId) {
+		return nil
+	}
+	channel, err := CacheGetChannel(channelId)
+	if err != nil || channel.Status != common.ChannelStatusEnabled {
+		return nil
+	}
+	if !tryAcquireChannelBreaker(channelId) {
+		return nil
+	}
+	return channel
+}
+
+func pickWeightedChannel(targetChannels []*Channel) *Channel {
 	if len(targetChannels) == 0 {
-		return nil, errors.New(fmt.Sprintf("no channel found, group: %s, model: %s, priority: %d", group, model, targetPriority))
+		return nil
+	}
+	var sumWeight = 0
+	for _, channel := range targetChannels {
+		sumWeight += channel.GetWeight()
 	}
 
 	// smoothing factor and adjustment
@@ -183,11 +236,10 @@ func GetRandomSatisfiedChannel(group string, model string, retry int) (*Channel,
 	for _, channel := range targetChannels {
 		randomWeight -= channel.GetWeight()*smoothingFactor + smoothingAdjustment
 		if randomWeight < 0 {
-			return channel, nil
+			return channel
 		}
 	}
-	// return null if no channel is not found
-	return nil, errors.New("channel not found")
+	return nil
 }
 
 func CacheGetChannel(id int) (*Channel, error) {
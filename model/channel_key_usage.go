@@ -0,0 +1,290 @@
+package model
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+const (
+	// 统计最近负载的时间窗口
+	channelKeyUsageWindow = time.Minute
+	// 收到 429 但上游未返回重置时间时的默认冷却时间
+	channelKeyDefaultCooldown = 30 * time.Second
+	// 冷却时间上限，避免异常的重置时间导致密钥长期不可用
+	channelKeyMaxCooldown = 10 * time.Minute
+)
+
+// ChannelKeyRateLimit 上游响应头中的限流信息，数值为 -1、时间为零值时表示未知
+type ChannelKeyRateLimit struct {
+	LimitRequests     int64
+	RemainingRequests int64
+	ResetRequests     time.Time
+	LimitTokens       int64
+	RemainingTokens   int64
+	ResetTokens       time.Time
+	RetryAfter        time.Time
+}
+
+// ChannelKeyUsage 多密钥渠道中单个密钥的用量，仅保存在当前节点内存中
+type ChannelKeyUsage struct {
+	Requests          int64 `json:"requests"`
+	PromptTokens      int64 `json:"prompt_tokens"`
+	CompletionTokens  int64 `json:"completion_tokens"`
+	RateLimited       int64 `json:"rate_limited"`
+	RecentRequests    int64 `json:"recent_requests"`
+	RecentTokens      int64 `json:"recent_tokens"`
+	LimitRequests     int64 `json:"limit_requests"`
+	RemainingRequests int64 `json:"remaining_requests"`
+	LimitTokens       int64 `json:"limit_tokens"`
+	RemainingTokens   int64 `json:"remaining_tokens"`
+	CooldownUntil     int64 `json:"cooldown_until"`
+	LastUsedTime      int64 `json:"last_used_time"`
+}
+
+type channelKeyUsage struct {
+	requests         int64
+	promptTokens     int64
+	completionTokens int64
+	rateLimited      int64
+
+	windowStart    time.Time
+	windowRequests int64
+	windowTokens   int64
+
+	limitRequests     int64
+	remainingRequests int64
+	requestsResetAt   time.Time
+	limitTokens       int64
+	remainingTokens   int64
+	tokensResetAt     time.Time
+
+	cooldownUntil time.Time
+	lastUsed      time.Time
+}
+
+var channelKeyUsages = make(map[int]map[int]*channelKeyUsage)
+var channelKeyUsagesLock sync.Mutex
+
+func getChannelKeyUsage(channelId int, keyIndex int) *channelKeyUsage {
+	idx2usage, ok := channelKeyUsages[channelId]
+	if !ok {
+		idx2usage = make(map[int]*channelKeyUsage)
+		channelKeyUsages[channelId] = idx2usage
+	}
+	usage, ok := idx2usage[keyIndex]
+	if !ok {
+		usage = &channelKeyUsage{
+			limitRequests:     -1,
+			remainingRequests: -1,
+			limitTokens:       -1,
+			remainingTokens:   -1,
+		}
+		idx2usage[keyIndex] = usage
+	}
+	return usage
+}
+
+// rollWindow 超出统计窗口后重新计数
+func (u *channelKeyUsage) rollWindow(now time.Time) {
+	if now.Sub(u.windowStart) >= channelKeyUsageWindow {
+		u.windowStart = now
+		u.windowRequests = 0
+		u.windowTokens = 0
+	}
+}
+
+// remaining 返回仍然有效的剩余额度，重置时间已过时视为未知
+func (u *channelKeyUsage) remaining(now time.Time) (requests int64, tokens int64) {
+	requests, tokens = -1, -1
+	if u.remainingRequests >= 0 && now.Before(u.requestsResetAt) {
+		requests = u.remainingRequests
+	}
+	if u.remainingTokens >= 0 && now.Before(u.tokensResetAt) {
+		tokens = u.remainingTokens
+	}
+	return requests, tokens
+}
+
+// headroom 剩余额度占上限的比例，取请求数与 token 数中较小者，未知时为 1
+func (u *channelKeyUsage) headroom(now time.Time) float64 {
+	result := 1.0
+	requests, tokens := u.remaining(now)
+	if requests >= 0 && u.limitRequests > 0 {
+		result = min(result, float64(requests)/float64(u.limitRequests))
+	}
+	if tokens >= 0 && u.limitTokens > 0 {
+		result = min(result, float64(tokens)/float64(u.limitTokens))
+	}
+	return result
+}
+
+// recordChannelKeyRequest 记录密钥被选中一次
+func recordChannelKeyRequest(channelId int, keyIndex int) {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+
+	now := time.Now()
+	usage := getChannelKeyUsage(channelId, keyIndex)
+	usage.rollWindow(now)
+	usage.requests++
+	usage.windowRequests++
+	usage.lastUsed = now
+}
+
+// RecordChannelKeyTokens 记录密钥完成一次请求消耗的 token
+func RecordChannelKeyTokens(channelId int, keyIndex int, promptTokens int, completionTokens int) {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+
+	usage := getChannelKeyUsage(channelId, keyIndex)
+	usage.rollWindow(time.Now())
+	usage.promptTokens += int64(promptTokens)
+	usage.completionTokens += int64(completionTokens)
+	usage.windowTokens += int64(promptTokens + completionTokens)
+}
+
+// UpdateChannelKeyRateLimit 根据上游返回的限流信息更新密钥剩余额度；
+// 被限流或额度耗尽时，密钥冷却到上游给出的重置时间
+func UpdateChannelKeyRateLimit(channelId int, keyIndex int, limit ChannelKeyRateLimit, rateLimited bool) {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+
+	now := time.Now()
+	usage := getChannelKeyUsage(channelId, keyIndex)
+	if limit.RemainingRequests >= 0 {
+		usage.limitRequests = limit.LimitRequests
+		usage.remainingRequests = limit.RemainingRequests
+		usage.requestsResetAt = limit.ResetRequests
+		if usage.requestsResetAt.IsZero() {
+			usage.requestsResetAt = now.Add(channelKeyUsageWindow)
+		}
+	}
+	if limit.RemainingTokens >= 0 {
+		usage.limitTokens = limit.LimitTokens
+		usage.remainingTokens = limit.RemainingTokens
+		usage.tokensResetAt = limit.ResetTokens
+		if usage.tokensResetAt.IsZero() {
+			usage.tokensResetAt = now.Add(channelKeyUsageWindow)
+		}
+	}
+
+	var cooldownUntil time.Time
+	if limit.RemainingRequests == 0 {
+		cooldownUntil = usage.requestsResetAt
+	}
+	if limit.RemainingTokens == 0 && usage.tokensResetAt.After(cooldownUntil) {
+		cooldownUntil = usage.tokensResetAt
+	}
+	if rateLimited {
+		usage.rateLimited++
+		if limit.RetryAfter.After(cooldownUntil) {
+			cooldownUntil = limit.RetryAfter
+		}
+		if cooldownUntil.IsZero() {
+			cooldownUntil = now.Add(channelKeyDefaultCooldown)
+		}
+	}
+	if cooldownUntil.After(now.Add(channelKeyMaxCooldown)) {
+		cooldownUntil = now.Add(channelKeyMaxCooldown)
+	}
+	if cooldownUntil.After(usage.cooldownUntil) {
+		usage.cooldownUntil = cooldownUntil
+	}
+}
+
+// pickLeastLoadedKey 跳过冷却中的密钥，优先选择剩余额度比例最高的密钥，
+// 相同时选择最近请求数与 token 数更少的密钥；全部冷却时选择最早结束冷却的密钥
+func pickLeastLoadedKey(channelId int, keyIndexes []int) int {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+
+	now := time.Now()
+	idx2usage := channelKeyUsages[channelId]
+	available := make([]int, 0, len(keyIndexes))
+	earliest := keyIndexes[0]
+	for _, idx := range keyIndexes {
+		usage, ok := idx2usage[idx]
+		if !ok || !now.Before(usage.cooldownUntil) {
+			available = append(available, idx)
+			continue
+		}
+		if other, ok := idx2usage[earliest]; ok && usage.cooldownUntil.Before(other.cooldownUntil) {
+			earliest = idx
+		}
+	}
+	if len(available) == 0 {
+		return earliest
+	}
+
+	// 从随机位置开始比较，负载相同的密钥之间均匀分配
+	offset := rand.Intn(len(available))
+	best := -1
+	var bestHeadroom float64
+	var bestRequests, bestTokens int64
+	for i := range available {
+		idx := available[(offset+i)%len(available)]
+		headroom := 1.0
+		var requests, tokens int64
+		if usage, ok := idx2usage[idx]; ok {
+			usage.rollWindow(now)
+			headroom = usage.headroom(now)
+			requests = usage.windowRequests
+			tokens = usage.windowTokens
+		}
+		better := best < 0 || headroom > bestHeadroom ||
+			headroom == bestHeadroom && (requests < bestRequests || requests == bestRequests && tokens < bestTokens)
+		if better {
+			best, bestHeadroom, bestRequests, bestTokens = idx, headroom, requests, tokens
+		}
+	}
+	return best
+}
+
+// channelKeyCoolingDown 密钥是否因限流处于冷却中
+func channelKeyCoolingDown(channelId int, keyIndex int) bool {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+	usage, ok := channelKeyUsages[channelId][keyIndex]
+	return ok && time.Now().Before(usage.cooldownUntil)
+}
+
+// GetChannelKeyUsage 获取多密钥渠道各密钥的用量
+func GetChannelKeyUsage(channelId int) map[int]ChannelKeyUsage {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+
+	now := time.Now()
+	result := make(map[int]ChannelKeyUsage)
+	for idx, usage := range channelKeyUsages[channelId] {
+		usage.rollWindow(now)
+		remainingRequests, remainingTokens := usage.remaining(now)
+		item := ChannelKeyUsage{
+			Requests:          usage.requests,
+			PromptTokens:      usage.promptTokens,
+			CompletionTokens:  usage.completionTokens,
+			RateLimited:       usage.rateLimited,
+			RecentRequests:    usage.windowRequests,
+			RecentTokens:      usage.windowTokens,
+			LimitRequests:     usage.limitRequests,
+			RemainingRequests: remainingRequests,
+			LimitTokens:       usage.limitTokens,
+			RemainingTokens:   remainingTokens,
+		}
+		if now.Before(usage.cooldownUntil) {
+			item.CooldownUntil = usage.cooldownUntil.Unix()
+		}
+		if !usage.lastUsed.IsZero() {
+			item.LastUsedTime = usage.lastUsed.Unix()
+		}
+		result[idx] = item
+	}
+	return result
+}
+
+// ResetChannelKeyUsage 清空渠道的密钥用量，密钥被删除导致索引变化时调用
+func ResetChannelKeyUsage(channelId int) {
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+	delete(channelKeyUsages, channelId)
+}
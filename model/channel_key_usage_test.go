@@ -0,0 +1,81 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+type testKeyUsage struct {
+	cooldown  time.Duration // 相对当前时间的冷却结束时间，0 表示未冷却
+	requests  int64
+	tokens    int64
+	limit     int64
+	remaining int64
+}
+
+func setTestKeyUsages(t *testing.T, channelId int, usages map[int]testKeyUsage) {
+	t.Helper()
+	ResetChannelKeyUsage(channelId)
+	t.Cleanup(func() { ResetChannelKeyUsage(channelId) })
+	now := time.Now()
+	channelKeyUsagesLock.Lock()
+	defer channelKeyUsagesLock.Unlock()
+	for idx, item := range usages {
+		usage := getChannelKeyUsage(channelId, idx)
+		usage.windowStart = now
+		usage.windowRequests = item.requests
+		usage.windowTokens = item.tokens
+		if item.cooldown != 0 {
+			usage.cooldownUntil = now.Add(item.cooldown)
+		}
+		if item.limit > 0 {
+			usage.limitRequests = item.limit
+			usage.remainingRequests = item.remaining
+			usage.requestsResetAt = now.Add(time.Minute)
+		}
+	}
+}
+
+func TestPickLeastLoadedKey(t *testing.T) {
+	tests := []struct {
+		name   string
+		usages map[int]testKeyUsage
+		keys   []int
+		want   int
+	}{
+		{"skip cooling key", map[int]testKeyUsage{0: {cooldown: time.Minute}}, []int{0, 1}, 1},
+		{"expired cooldown", map[int]testKeyUsage{0: {cooldown: -time.Second}, 1: {cooldown: time.Minute}}, []int{0, 1}, 0},
+		{"all cooling picks earliest", map[int]testKeyUsage{0: {cooldown: 3 * time.Minute}, 1: {cooldown: time.Minute}, 2: {cooldown: 2 * time.Minute}}, []int{0, 1, 2}, 1},
+		{"all cooling first is earliest", map[int]testKeyUsage{0: {cooldown: time.Minute}, 1: {cooldown: 2 * time.Minute}}, []int{0, 1}, 0},
+		{"higher headroom", map[int]testKeyUsage{0: {limit: 100, remaining: 10}, 1: {limit: 100, remaining: 80, requests: 50}}, []int{0, 1}, 1},
+		{"headroom tie picks fewer requests", map[int]testKeyUsage{0: {requests: 5}, 1: {requests: 2, tokens: 9000}, 2: {requests: 3}}, []int{0, 1, 2}, 1},
+		{"request tie picks fewer tokens", map[int]testKeyUsage{0: {requests: 2, tokens: 500}, 1: {requests: 2, tokens: 100}}, []int{0, 1}, 1},
+		{"unused key", map[int]testKeyUsage{0: {requests: 1}}, []int{0, 1}, 1},
+	}
+	for i, tt := range tests {
+		channelId := 900000 + i
+		setTestKeyUsages(t, channelId, tt.usages)
+		for n := 0; n < 20; n++ {
+			if got := pickLeastLoadedKey(channelId, tt.keys); got != tt.want {
+				t.Fatalf("%s: expected key %d, got %d", tt.name, tt.want, got)
+			}
+		}
+	}
+}
+
+func TestPickLeastLoadedKeySpreadsTies(t *testing.T) {
+	const channelId = 900100
+	setTestKeyUsages(t, channelId, map[int]testKeyUsage{0: {requests: 1}, 1: {requests: 1}, 2: {requests: 1}, 3: {cooldown: time.Minute}})
+	picked := make(map[int]int)
+	for n := 0; n < 300; n++ {
+		picked[pickLeastLoadedKey(channelId, []int{0, 1, 2, 3})]++
+	}
+	if picked[3] != 0 {
+		t.Fatal("cooling key must not be picked")
+	}
+	for _, idx := range []int{0, 1, 2} {
+		if picked[idx] == 0 {
+			t.Fatalf("expected tied keys to share the load, got %v", picked)
+		}
+	}
+}
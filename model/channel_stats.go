@@ -0,0 +1,184 @@
+package model
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// EWMA 平滑系数，越大越偏向最近的请求
+	channelStatsAlpha = 0.2
+	// 渠道空闲后失败率的恢复时间常数（秒），避免降级渠道因长期无流量而无法恢复
+	channelStatsRecoverSeconds = 300.0
+	// 首字时间与输出速度未知时使用的参考值，对应的得分因子均为 0.5
+	channelStatsRefTTFTMs = 1000.0
+	channelStatsRefTPS    = 20.0
+	// 以小概率选择得分较低的候选渠道，使降级渠道仍有少量流量来刷新统计
+	channelStatsExploreRate = 0.05
+)
+
+// ChannelModelStats 渠道在某个模型上的滚动统计，仅保存在当前节点内存中
+type ChannelModelStats struct {
+	Model           string  `json:"model"`
+	Requests        int64   `json:"requests"`
+	Failures        int64   `json:"failures"`
+	SuccessRate     float64 `json:"success_rate"`
+	TTFTMs          float64 `json:"ttft_ms"`
+	TokensPerSecond float64 `json:"tokens_per_second"`
+	Score           float64 `json:"score"`
+	UpdatedAt       int64   `json:"updated_at"`
+}
+
+type channelModelStats struct {
+	requests    int64
+	failures    int64
+	successRate float64
+	ttftMs      float64
+	tps         float64
+	updatedAt   time.Time
+}
+
+var channelStats = make(map[int]map[string]*channelModelStats)
+var channelStatsLock sync.RWMutex
+
+func ewma(old float64, value float64) float64 {
+	return old + channelStatsAlpha*(value-old)
+}
+
+// RecordChannelStats 记录一次请求结果，ttft 与 tokensPerSecond 为 0 时表示未知，不参与统计
+func RecordChannelStats(channelId int, modelName string, success bool, ttft time.Duration, tokensPerSecond float64) {
+	channelStatsLock.Lock()
+	defer channelStatsLock.Unlock()
+
+	model2stats, ok := channelStats[channelId]
+	if !ok {
+		model2stats = make(map[string]*channelModelStats)
+		channelStats[channelId] = model2stats
+	}
+	stats, ok := model2stats[modelName]
+	if !ok {
+		stats = &channelModelStats{successRate: 1}
+		model2stats[modelName] = stats
+	}
+
+	now := time.Now()
+	// 先将空闲期间的恢复计入，再叠加本次结果
+	stats.successRate = stats.effectiveSuccessRate(now)
+	stats.requests++
+	if success {
+		stats.successRate = ewma(stats.successRate, 1)
+		if ttft > 0 {
+			ttftMs := float64(ttft.Milliseconds())
+			if stats.ttftMs == 0 {
+				stats.ttftMs = ttftMs
+			} else {
+				stats.ttftMs = ewma(stats.ttftMs, ttftMs)
+			}
+		}
+		if tokensPerSecond > 0 {
+			if stats.tps == 0 {
+				stats.tps = tokensPerSecond
+			} else {
+				stats.tps = ewma(stats.tps, tokensPerSecond)
+			}
+		}
+	} else {
+		stats.failures++
+		stats.successRate = ewma(stats.successRate, 0)
+	}
+	stats.updatedAt = now
+}
+
+func (s *channelModelStats) effectiveSuccessRate(now time.Time) float64 {
+	idle := now.Sub(s.updatedAt).Seconds()
+	if idle <= 0 {
+		return s.successRate
+	}
+	return 1 - (1-s.successRate)*math.Exp(-idle/channelStatsRecoverSeconds)
+}
+
+// score 综合成功率、首字时间与输出速度计算得分，成功率权重最高
+func (s *channelModelStats) score(now time.Time) float64 {
+	successRate := 1.0
+	ttftMs := channelStatsRefTTFTMs
+	tps := channelStatsRefTPS
+	if s != nil {
+		successRate = s.effectiveSuccessRate(now)
+		if s.ttftMs > 0 {
+			ttftMs = s.ttftMs
+		}
+		if s.tps > 0 {
+			tps = s.tps
+		}
+	}
+	latencyFactor := channelStatsRefTTFTMs / (channelStatsRefTTFTMs + ttftMs)
+	throughputFactor := tps / (tps + channelStatsRefTPS)
+	return successRate * successRate * latencyFactor * throughputFactor
+}
+
+func getChannelScore(channelId int, modelName string) float64 {
+	channelStatsLock.RLock()
+	defer channelStatsLock.RUnlock()
+	return channelStats[channelId][modelName].score(time.Now())
+}
+
+// GetChannelStats 获取渠道在各模型上的统计，channelId 为 0 时返回全部渠道
+func GetChannelStats(channelId int) map[int][]ChannelModelStats {
+	channelStatsLock.RLock()
+	defer channelStatsLock.RUnlock()
+
+	now := time.Now()
+	result := make(map[int][]ChannelModelStats)
+	for id, model2stats := range channelStats {
+		if channelId != 0 && id != channelId {
+			continue
+		}
+		list := make([]ChannelModelStats, 0, len(model2stats))
+		for modelName, stats := range model2stats {
+			list = append(list, ChannelModelStats{
+				Model:           modelName,
+				Requests:        stats.requests,
+				Failures:        stats.failures,
+				SuccessRate:     stats.effectiveSuccessRate(now),
+				TTFTMs:          stats.ttftMs,
+				TokensPerSecond: stats.tps,
+				Score:           stats.score(now),
+				UpdatedAt:       stats.updatedAt.Unix(),
+			})
+		}
+		sort.Slice(list, func(i, j int) bool {
+			return list[i].Model < list[j].Model
+		})
+		result[id] = list
+	}
+	return result
+}
+
+// pickAdaptiveChannel 按权重随机抽取两个渠道，选择得分更高的一个（power of two choices）
+func pickAdaptiveChannel(channels []*Channel, modelName string) *Channel {
+	first := pickWeightedChannel(channels)
+	if first == nil || len(channels) < 2 {
+		return first
+	}
+	rest := make([]*Channel, 0, len(channels)-1)
+	for _, channel := range channels {
+		if channel.Id != first.Id {
+			rest = append(rest, channel)
+		}
+	}
+	second := pickWeightedChannel(rest)
+	if second == nil {
+		return first
+	}
+	better, worse := first, second
+	if getChannelScore(second.Id, modelName) > getChannelScore(first.Id, modelName) {
+		better, worse = second, first
+	}
+	if rand.Float64() < channelStatsExploreRate {
+		return worse
+	}
+	return better
+}
@@ -0,0 +1,822 @@
+package model
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/setting/config"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+const ConfigDocumentVersion = 1
+
+const (
+	ConfigSectionChannels      = "channels"
+	ConfigSectionRatios        = "ratios"
+	ConfigSectionSettings      = "settings"
+	ConfigSectionOptions       = "options"
+	ConfigSectionVendors       = "vendors"
+	ConfigSectionModels        = "models"
+	ConfigSectionPrefillGroups = "prefill_groups"
+)
+
+const (
+	ConfigActionCreate = "create"
+	ConfigActionUpdate = "update"
+	ConfigActionDelete = "delete"
+)
+
+// 模型倍率与分组倍率，以对象形式导出，便于阅读与比对
+var configRatioKeys = []string{
+	"ModelRatio", "ModelPrice", "CompletionRatio", "CacheRatio", "ImageRatio", "AudioRatio",
+	"AudioCompletionRatio", "ParamRatioConfig", "GroupRatio", "GroupGroupRatio", "TopupGroupRatio", "UserUsableGroups",
+}
+
+// ConfigDocument 可导入导出的完整配置，实体按名称匹配，未出现在文档中的配置项保持不变；
+// 渠道名称在数据库中不强制唯一，存在同名渠道时导入会失败，需要先重命名
+type ConfigDocument struct {
+	Version       int              `json:"version" yaml:"version"`
+	Channels      []map[string]any `json:"channels,omitempty" yaml:"channels,omitempty"`
+	Ratios        map[string]any   `json:"ratios,omitempty" yaml:"ratios,omitempty"`
+	Settings      map[string]any   `json:"settings,omitempty" yaml:"settings,omitempty"`
+	Options       map[string]any   `json:"options,omitempty" yaml:"options,omitempty"`
+	Vendors       []map[string]any `json:"vendors,omitempty" yaml:"vendors,omitempty"`
+	Models        []map[string]any `json:"models,omitempty" yaml:"models,omitempty"`
+	PrefillGroups []map[string]any `json:"prefill_groups,omitempty" yaml:"prefill_groups,omitempty"`
+}
+
+// ConfigChange 导入时的单项变更
+type ConfigChange struct {
+	Section string                 `json:"section"`
+	Name    string                 `json:"name"`
+	Action  string                 `json:"action"`
+	Diff    map[string]AuditChange `json:"diff,omitempty"`
+}
+
+type ConfigImportOptions struct {
+	DryRun    bool
+	Prune     bool // 删除文档中不存在的渠道、模型、供应商与预填组
+	ActorId   int
+	ActorName string
+	Ip        string
+}
+
+// IsSensitiveOptionKey 密钥类配置不导出、不返回给前端，也不记录变更历史
+func IsSensitiveOptionKey(key string) bool {
+	return strings.HasSuffix(key, "Token") ||
+		strings.HasSuffix(key, "Secret") ||
+		strings.HasSuffix(key, "Key") ||
+		strings.HasSuffix(key, "secret") ||
+		strings.HasSuffix(key, "api_key")
+}
+
+func isConfigRatioKey(key string) bool {
+	for _, ratioKey := range configRatioKeys {
+		if ratioKey == key {
+			return true
+		}
+	}
+	return false
+}
+
+func decodeConfigJson(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
+// normalizeConfigValue 统一 JSON 与 YAML 解析出的数值类型，便于比较
+func normalizeConfigValue(v any) any {
+	data, err := common.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var normalized any
+	if err := decodeConfigJson(data, &normalized); err != nil {
+		return v
+	}
+	return normalized
+}
+
+// configValueToString 将文档中的值转换为配置项的字符串形式
+func configValueToString(v any) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case json.Number:
+		return value.String(), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	}
+	data, err := common.Marshal(v)
+	return string(data), err
+}
+
+// yamlReadyValue 将 json.Number 转换为数值，避免 YAML 输出为字符串
+func yamlReadyValue(v any) any {
+	switch value := v.(type) {
+	case json.Number:
+		if i, err := value.Int64(); err == nil {
+			return i
+		}
+		f, _ := value.Float64()
+		return f
+	case map[string]any:
+		for key, item := range value {
+			value[key] = yamlReadyValue(item)
+		}
+	case []any:
+		for i, item := range value {
+			value[i] = yamlReadyValue(item)
+		}
+	case []map[string]any:
+		for _, item := range value {
+			yamlReadyValue(item)
+		}
+	}
+	return v
+}
+
+// MarshalConfigDocument 按格式序列化配置文档，format 为 yaml 或 json
+func MarshalConfigDocument(doc *ConfigDocument, format string) ([]byte, error) {
+	if format == "json" {
+		return json.MarshalIndent(doc, "", "  ")
+	}
+	yamlReadyValue(doc.Channels)
+	yamlReadyValue(doc.Ratios)
+	yamlReadyValue(doc.Settings)
+	yamlReadyValue(doc.Options)
+	yamlReadyValue(doc.Vendors)
+	yamlReadyValue(doc.Models)
+	yamlReadyValue(doc.PrefillGroups)
+	return yaml.Marshal(doc)
+}
+
+// ParseConfigDocument 解析 JSON 或 YAML 格式的配置文档
+func ParseConfigDocument(data []byte) (*ConfigDocument, error) {
+	var doc ConfigDocument
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 {
+		return nil, errors.New("配置文档为空")
+	}
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		if err := decodeConfigJson(trimmed, &doc); err != nil {
+			return nil, fmt.Errorf("解析 JSON 配置失败: %w", err)
+		}
+	} else if err := yaml.Unmarshal(trimmed, &doc); err != nil {
+		return nil, fmt.Errorf("解析 YAML 配置失败: %w", err)
+	}
+	if doc.Version != ConfigDocumentVersion {
+		return nil, fmt.Errorf("不支持的配置版本 %d", doc.Version)
+	}
+	return &doc, nil
+}
+
+// 渠道导出的字段与变更历史的快照一致，密钥按需导出
+func channelConfigMap(channel *Channel, includeKeys bool) (map[string]any, error) {
+	snapshot, err := toAuditSnapshot(AuditEntityChannel, channel)
+	if err != nil {
+		return nil, err
+	}
+	if includeKeys {
+		snapshot["key"] = channel.Key
+	}
+	return snapshot, nil
+}
+
+func vendorConfigMap(vendor *Vendor) map[string]any {
+	return map[string]any{
+		"name":        vendor.Name,
+		"description": vendor.Description,
+		"icon":        vendor.Icon,
+		"status":      vendor.Status,
+	}
+}
+
+// modelConfigMap 供应商以名称导出，不同实例之间的 id 可能不同
+func modelConfigMap(m *Model, vendorNames map[int]string) map[string]any {
+	return map[string]any{
+		"model_name":    m.ModelName,
+		"description":   m.Description,
+		"icon":          m.Icon,
+		"tags":          m.Tags,
+		"vendor":        vendorNames[m.VendorID],
+		"endpoints":     m.Endpoints,
+		"status":        m.Status,
+		"sync_official": m.SyncOfficial,
+		"name_rule":     m.NameRule,
+	}
+}
+
+func prefillGroupConfigMap(group *PrefillGroup) (map[string]any, error) {
+	var items any
+	if len(group.Items) > 0 {
+		if err := decodeConfigJson(group.Items, &items); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]any{
+		"name":        group.Name,
+		"type":        group.Type,
+		"items":       items,
+		"description": group.Description,
+	}, nil
+}
+
+// configAllowedFields 各类实体在文档中可以出现的字段
+func configAllowedFields(section string) map[string]bool {
+	var fields map[string]any
+	switch section {
+	case ConfigSectionChannels:
+		fields, _ = channelConfigMap(&Channel{}, true)
+	case ConfigSectionVendors:
+		fields = vendorConfigMap(&Vendor{})
+	case ConfigSectionModels:
+		fields = modelConfigMap(&Model{}, nil)
+	case ConfigSectionPrefillGroups:
+		fields, _ = prefillGroupConfigMap(&PrefillGroup{})
+	}
+	allowed := make(map[string]bool, len(fields))
+	for field := range fields {
+		allowed[field] = true
+	}
+	return allowed
+}
+
+func exportConfigChannels(includeKeys bool) ([]map[string]any, error) {
+	var channels []*Channel
+	if err := DB.Order("id asc").Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(channels))
+	for _, channel := range channels {
+		item, err := channelConfigMap(channel, includeKeys)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func exportConfigVendors() ([]map[string]any, error) {
+	var vendors []*Vendor
+	if err := DB.Order("id asc").Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(vendors))
+	for _, vendor := range vendors {
+		result = append(result, vendorConfigMap(vendor))
+	}
+	return result, nil
+}
+
+func getVendorNames() (map[int]string, error) {
+	var vendors []*Vendor
+	if err := DB.Find(&vendors).Error; err != nil {
+		return nil, err
+	}
+	names := make(map[int]string, len(vendors))
+	for _, vendor := range vendors {
+		names[vendor.Id] = vendor.Name
+	}
+	return names, nil
+}
+
+func exportConfigModels() ([]map[string]any, error) {
+	var models []*Model
+	if err := DB.Order("id asc").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	vendorNames, err := getVendorNames()
+	if err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(models))
+	for _, m := range models {
+		result = append(result, modelConfigMap(m, vendorNames))
+	}
+	return result, nil
+}
+
+func exportConfigPrefillGroups() ([]map[string]any, error) {
+	var groups []*PrefillGroup
+	if err := DB.Order("id asc").Find(&groups).Error; err != nil {
+		return nil, err
+	}
+	result := make([]map[string]any, 0, len(groups))
+	for _, group := range groups {
+		item, err := prefillGroupConfigMap(group)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, item)
+	}
+	return result, nil
+}
+
+func hasSettingKey(settingKeys map[string]string, key string) bool {
+	_, ok := settingKeys[key]
+	return ok
+}
+
+// exportConfigOptions 按倍率、系统设置与其他配置项分组导出，密钥类配置不导出
+func exportConfigOptions() (ratios map[string]any, settings map[string]any, options map[string]any) {
+	settingKeys := config.GlobalConfig.ExportAllConfigs()
+	ratios = make(map[string]any)
+	settings = make(map[string]any)
+	options = make(map[string]any)
+	common.OptionMapRWMutex.RLock()
+	defer common.OptionMapRWMutex.RUnlock()
+	for key, value := range common.OptionMap {
+		if IsSensitiveOptionKey(key) {
+			continue
+		}
+		str := common.Interface2String(value)
+		switch {
+		case isConfigRatioKey(key):
+			var parsed any
+			if err := decodeConfigJson([]byte(str), &parsed); err != nil {
+				parsed = str
+			}
+			ratios[key] = parsed
+		case hasSettingKey(settingKeys, key):
+			settings[key] = str
+		default:
+			options[key] = str
+		}
+	}
+	return ratios, settings, options
+}
+
+// ExportConfigDocument 导出当前实例的完整配置，includeKeys 为 false 时不导出渠道密钥
+func ExportConfigDocument(includeKeys bool) (*ConfigDocument, error) {
+	doc := &ConfigDocument{Version: ConfigDocumentVersion}
+	var err error
+	if doc.Channels, err = exportConfigChannels(includeKeys); err != nil {
+		return nil, err
+	}
+	if doc.Vendors, err = exportConfigVendors(); err != nil {
+		return nil, err
+	}
+	if doc.Models, err = exportConfigModels(); err != nil {
+		return nil, err
+	}
+	if doc.PrefillGroups, err = exportConfigPrefillGroups(); err != nil {
+		return nil, err
+	}
+	doc.Ratios, doc.Settings, doc.Options = exportConfigOptions()
+	return doc, nil
+}
+
+// diffConfigFields 只比较文档中出现的字段，未出现的字段保持不变
+func diffConfigFields(current map[string]any, desired map[string]any) map[string]AuditChange {
+	diff := make(map[string]AuditChange)
+	for key, value := range desired {
+		newValue := normalizeConfigValue(value)
+		oldValue := normalizeConfigValue(current[key])
+		if !reflect.DeepEqual(oldValue, newValue) {
+			if key == "key" {
+				// 密钥不出现在差异中
+				diff[key] = AuditChange{Old: "***", New: "***"}
+				continue
+			}
+			diff[key] = AuditChange{Old: oldValue, New: newValue}
+		}
+	}
+	return diff
+}
+
+// planConfigEntities 按名称字段比较实体列表，返回需要创建、更新与删除的实体
+func planConfigEntities(section string, nameField string, current []map[string]any, desired []map[string]any, prune bool) ([]ConfigChange, error) {
+	currentByName := make(map[string]map[string]any, len(current))
+	for _, item := range current {
+		name := fmt.Sprint(item[nameField])
+		if _, ok := currentByName[name]; ok {
+			return nil, fmt.Errorf("%s 中存在重复的名称 %s，无法按名称匹配", section, name)
+		}
+		currentByName[name] = item
+	}
+	allowed := configAllowedFields(section)
+	var changes []ConfigChange
+	seen := make(map[string]bool, len(desired))
+	for _, item := range desired {
+		name, _ := item[nameField].(string)
+		if name == "" {
+			return nil, fmt.Errorf("%s 中存在缺少 %s 的条目", section, nameField)
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("配置文档的 %s 中存在重复的名称 %s", section, name)
+		}
+		seen[name] = true
+		for field := range item {
+			if !allowed[field] {
+				return nil, fmt.Errorf("%s %s 包含不支持的字段 %s", section, name, field)
+			}
+		}
+		existing, ok := currentByName[name]
+		if !ok {
+			changes = append(changes, ConfigChange{Section: section, Name: name, Action: ConfigActionCreate})
+			continue
+		}
+		if diff := diffConfigFields(existing, item); len(diff) > 0 {
+			changes = append(changes, ConfigChange{Section: section, Name: name, Action: ConfigActionUpdate, Diff: diff})
+		}
+	}
+	if prune {
+		for _, item := range current {
+			name := fmt.Sprint(item[nameField])
+			if !seen[name] {
+				changes = append(changes, ConfigChange{Section: section, Name: name, Action: ConfigActionDelete})
+			}
+		}
+	}
+	return changes, nil
+}
+
+func planConfigOptions(section string, current map[string]any, desired map[string]any, validate func(key string) error) ([]ConfigChange, error) {
+	keys := make([]string, 0, len(desired))
+	for key := range desired {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var changes []ConfigChange
+	for _, key := range keys {
+		if err := validate(key); err != nil {
+			return nil, err
+		}
+		if diff := diffConfigFields(map[string]any{"value": current[key]}, map[string]any{"value": desired[key]}); len(diff) > 0 {
+			changes = append(changes, ConfigChange{Section: section, Name: key, Action: ConfigActionUpdate, Diff: diff})
+		}
+	}
+	return changes, nil
+}
+
+// PlanConfigDocument 计算导入配置文档需要进行的变更
+func PlanConfigDocument(doc *ConfigDocument, prune bool) ([]ConfigChange, error) {
+	// 与包含密钥的当前配置比较，密钥为空或未导出时保持原密钥
+	current, err := ExportConfigDocument(true)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range doc.Channels {
+		if key, _ := item["key"].(string); key == "" {
+			delete(item, "key")
+		}
+	}
+	var changes []ConfigChange
+	addChanges := func(sectionChanges []ConfigChange, err error) error {
+		if err != nil {
+			return err
+		}
+		changes = append(changes, sectionChanges...)
+		return nil
+	}
+	if err := addChanges(planConfigEntities(ConfigSectionVendors, "name", current.Vendors, doc.Vendors, prune)); err != nil {
+		return nil, err
+	}
+	if err := addChanges(planConfigEntities(ConfigSectionModels, "model_name", current.Models, doc.Models, prune)); err != nil {
+		return nil, err
+	}
+	if err := addChanges(planConfigEntities(ConfigSectionPrefillGroups, "name", current.PrefillGroups, doc.PrefillGroups, prune)); err != nil {
+		return nil, err
+	}
+	if err := addChanges(planConfigEntities(ConfigSectionChannels, "name", current.Channels, doc.Channels, prune)); err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if change.Section == ConfigSectionChannels && change.Action == ConfigActionCreate {
+			for _, item := range doc.Channels {
+				if _, ok := item["key"]; item["name"] == change.Name && !ok {
+					return nil, fmt.Errorf("新建渠道 %s 缺少密钥", change.Name)
+				}
+			}
+		}
+	}
+	validateRatio := func(key string) error {
+		if !isConfigRatioKey(key) {
+			return fmt.Errorf("未知的倍率配置 %s", key)
+		}
+		return nil
+	}
+	if err := addChanges(planConfigOptions(ConfigSectionRatios, current.Ratios, doc.Ratios, validateRatio)); err != nil {
+		return nil, err
+	}
+	validateSetting := func(key string) error {
+		if _, ok := current.Settings[key]; !ok {
+			return fmt.Errorf("未知的系统设置 %s", key)
+		}
+		return nil
+	}
+	if err := addChanges(planConfigOptions(ConfigSectionSettings, current.Settings, doc.Settings, validateSetting)); err != nil {
+		return nil, err
+	}
+	validateOption := func(key string) error {
+		if _, ok := current.Options[key]; !ok {
+			return fmt.Errorf("未知或不支持导入的配置项 %s", key)
+		}
+		return nil
+	}
+	if err := addChanges(planConfigOptions(ConfigSectionOptions, current.Options, doc.Options, validateOption)); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+func findConfigEntity(items []map[string]any, nameField string, name string) map[string]any {
+	for _, item := range items {
+		if item[nameField] == name {
+			return item
+		}
+	}
+	return nil
+}
+
+// applyConfigSnapshot 将文档中的字段写入实体，只更新文档中出现的字段
+func applyConfigSnapshot(entity any, snapshot map[string]any) ([]string, error) {
+	data, err := common.Marshal(snapshot)
+	if err != nil {
+		return nil, err
+	}
+	if err := common.Unmarshal(data, entity); err != nil {
+		return nil, err
+	}
+	return auditFieldNames(entity, snapshot), nil
+}
+
+// configAuditRecord 导入时产生的变更历史，事务提交后写入
+type configAuditRecord struct {
+	entityType string
+	entityId   string
+	before     map[string]any
+	after      map[string]any
+}
+
+// configImport 在同一个事务中应用配置变更，缓存与变更历史在事务提交后更新
+type configImport struct {
+	tx      *gorm.DB
+	opts    ConfigImportOptions
+	audits  []configAuditRecord
+	options map[string]string
+}
+
+// findConfigChannel 按名称查找渠道，同名渠道存在多个时无法按名称匹配
+func (im *configImport) findConfigChannel(name string) (*Channel, error) {
+	var channels []*Channel
+	if err := im.tx.Where("name = ?", name).Limit(2).Find(&channels).Error; err != nil {
+		return nil, err
+	}
+	switch len(channels) {
+	case 0:
+		return nil, nil
+	case 1:
+		return channels[0], nil
+	}
+	return nil, fmt.Errorf("存在多个名为 %s 的渠道，无法按名称匹配，请先重命名", name)
+}
+
+func (im *configImport) applyChannel(change ConfigChange, item map[string]any) error {
+	channel, err := im.findConfigChannel(change.Name)
+	if err != nil {
+		return err
+	}
+	if change.Action == ConfigActionCreate {
+		if channel != nil {
+			return fmt.Errorf("渠道 %s 已存在", change.Name)
+		}
+		channel = &Channel{CreatedTime: common.GetTimestamp(), Status: common.ChannelStatusEnabled}
+		if _, err := applyConfigSnapshot(channel, item); err != nil {
+			return err
+		}
+		if err := im.tx.Create(channel).Error; err != nil {
+			return err
+		}
+		return channel.AddAbilities(im.tx)
+	}
+	if channel == nil {
+		return fmt.Errorf("渠道 %s 不存在", change.Name)
+	}
+	if change.Action == ConfigActionDelete {
+		if err := im.tx.Delete(channel).Error; err != nil {
+			return err
+		}
+		return im.tx.Where("channel_id = ?", channel.Id).Delete(&Ability{}).Error
+	}
+	before, err := toAuditSnapshot(AuditEntityChannel, channel)
+	if err != nil {
+		return err
+	}
+	fields, err := applyConfigSnapshot(channel, item)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(fields, "Key") {
+		fields = append(fields, "KeyHash")
+	}
+	if err := im.tx.Model(channel).Select(fields).Updates(channel).Error; err != nil {
+		return err
+	}
+	if err := channel.UpdateAbilities(im.tx); err != nil {
+		return err
+	}
+	var updated Channel
+	if err := im.tx.Omit("key").First(&updated, "id = ?", channel.Id).Error; err != nil {
+		return err
+	}
+	after, err := toAuditSnapshot(AuditEntityChannel, &updated)
+	if err != nil {
+		return err
+	}
+	im.audits = append(im.audits, configAuditRecord{
+		entityType: AuditEntityChannel,
+		entityId:   strconv.Itoa(channel.Id),
+		before:     before,
+		after:      after,
+	})
+	return nil
+}
+
+func (im *configImport) getVendorIdByName(name string) (int, error) {
+	if name == "" {
+		return 0, nil
+	}
+	var vendor Vendor
+	if err := im.tx.First(&vendor, "name = ?", name).Error; err != nil {
+		return 0, fmt.Errorf("供应商 %s 不存在", name)
+	}
+	return vendor.Id, nil
+}
+
+func (im *configImport) applyModel(change ConfigChange, item map[string]any) error {
+	m := &Model{}
+	if change.Action != ConfigActionCreate {
+		if err := im.tx.First(m, "model_name = ?", change.Name).Error; err != nil {
+			return err
+		}
+		if change.Action == ConfigActionDelete {
+			return im.tx.Delete(m).Error
+		}
+	}
+	if _, err := applyConfigSnapshot(m, item); err != nil {
+		return err
+	}
+	if vendor, ok := item["vendor"]; ok {
+		vendorId, err := im.getVendorIdByName(fmt.Sprint(vendor))
+		if err != nil {
+			return err
+		}
+		m.VendorID = vendorId
+	}
+	now := common.GetTimestamp()
+	m.UpdatedTime = now
+	if change.Action == ConfigActionCreate {
+		m.CreatedTime = now
+		return im.tx.Create(m).Error
+	}
+	return im.tx.Model(&Model{}).Where("id = ?", m.Id).Omit("created_time").Select("*").Updates(m).Error
+}
+
+func (im *configImport) applyVendor(change ConfigChange, item map[string]any) error {
+	vendor := &Vendor{}
+	if change.Action != ConfigActionCreate {
+		if err := im.tx.First(vendor, "name = ?", change.Name).Error; err != nil {
+			return err
+		}
+		if change.Action == ConfigActionDelete {
+			return im.tx.Delete(vendor).Error
+		}
+	}
+	if _, err := applyConfigSnapshot(vendor, item); err != nil {
+		return err
+	}
+	now := common.GetTimestamp()
+	vendor.UpdatedTime = now
+	if change.Action == ConfigActionCreate {
+		vendor.CreatedTime = now
+		return im.tx.Create(vendor).Error
+	}
+	return im.tx.Save(vendor).Error
+}
+
+func (im *configImport) applyPrefillGroup(change ConfigChange, item map[string]any) error {
+	group := &PrefillGroup{}
+	if change.Action != ConfigActionCreate {
+		if err := im.tx.First(group, "name = ?", change.Name).Error; err != nil {
+			return err
+		}
+		if change.Action == ConfigActionDelete {
+			return im.tx.Delete(&PrefillGroup{}, group.Id).Error
+		}
+	}
+	if _, err := applyConfigSnapshot(group, item); err != nil {
+		return err
+	}
+	now := common.GetTimestamp()
+	group.UpdatedTime = now
+	if change.Action == ConfigActionCreate {
+		group.CreatedTime = now
+		return im.tx.Create(group).Error
+	}
+	return im.tx.Save(group).Error
+}
+
+func (im *configImport) applyOption(key string, value any) error {
+	str, err := configValueToString(value)
+	if err != nil {
+		return err
+	}
+	option := Option{Key: key}
+	if err := im.tx.FirstOrCreate(&option, Option{Key: key}).Error; err != nil {
+		return err
+	}
+	if option.Value, err = encryptOptionValue(key, str); err != nil {
+		return err
+	}
+	if err := im.tx.Save(&option).Error; err != nil {
+		return err
+	}
+	before, _ := GetAuditEntitySnapshot(AuditEntityOption, key)
+	im.options[key] = str
+	im.audits = append(im.audits, configAuditRecord{
+		entityType: AuditEntityOption,
+		entityId:   key,
+		before:     before,
+		after:      map[string]any{"value": str},
+	})
+	return nil
+}
+
+func (im *configImport) apply(doc *ConfigDocument, changes []ConfigChange) error {
+	for _, change := range changes {
+		var err error
+		switch change.Section {
+		case ConfigSectionVendors:
+			err = im.applyVendor(change, findConfigEntity(doc.Vendors, "name", change.Name))
+		case ConfigSectionModels:
+			err = im.applyModel(change, findConfigEntity(doc.Models, "model_name", change.Name))
+		case ConfigSectionPrefillGroups:
+			err = im.applyPrefillGroup(change, findConfigEntity(doc.PrefillGroups, "name", change.Name))
+		case ConfigSectionChannels:
+			err = im.applyChannel(change, findConfigEntity(doc.Channels, "name", change.Name))
+		case ConfigSectionRatios:
+			err = im.applyOption(change.Name, doc.Ratios[change.Name])
+		case ConfigSectionSettings:
+			err = im.applyOption(change.Name, doc.Settings[change.Name])
+		case ConfigSectionOptions:
+			err = im.applyOption(change.Name, doc.Options[change.Name])
+		}
+		if err != nil {
+			return fmt.Errorf("应用 %s %s 失败: %w", change.Section, change.Name, err)
+		}
+	}
+	return nil
+}
+
+// ImportConfigDocument 计算并应用配置变更，DryRun 时只返回变更列表；重复导入同一文档不会产生变更
+// 所有变更在同一个事务中应用，任一变更失败时全部回滚；提交后刷新当前进程的配置、渠道与价格缓存，
+// 其他节点在下次同步时生效
+func ImportConfigDocument(doc *ConfigDocument, opts ConfigImportOptions) ([]ConfigChange, error) {
+	changes, err := PlanConfigDocument(doc, opts.Prune)
+	if err != nil || opts.DryRun || len(changes) == 0 {
+		return changes, err
+	}
+	im := &configImport{opts: opts, options: make(map[string]string)}
+	err = DB.Transaction(func(tx *gorm.DB) error {
+		im.tx = tx
+		return im.apply(doc, changes)
+	})
+	if err != nil {
+		return changes, err
+	}
+	for key, value := range im.options {
+		if err := updateOptionMap(key, value); err != nil {
+			common.SysError(fmt.Sprintf("failed to update option %s: %v", key, err))
+		}
+	}
+	InitChannelCache()
+	RefreshPricing()
+	for _, record := range im.audits {
+		if err := RecordAuditVersion(record.entityType, record.entityId, record.before, record.after, AuditActionUpdate, opts.ActorId, opts.ActorName, opts.Ip); err != nil {
+			common.SysError(fmt.Sprintf("failed to record audit version for %s %s: %v", record.entityType, record.entityId, err))
+		}
+	}
+	return changes, nil
+}
@@ -0,0 +1,67 @@
+package model
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	FilePurposeBatch       = "batch"
+	FilePurposeBatchOutput = "batch_output"
+)
+
+const (
+	FileStatusUploaded  = "uploaded"
+	FileStatusProcessed = "processed"
+)
+
+// File 用户通过 /v1/files 上传的文件以及批处理生成的结果文件
+type File struct {
+	Id          int    `json:"-"`
+	FileId      string `json:"id" gorm:"type:varchar(64);uniqueIndex"`
+	Object      string `json:"object" gorm:"-"`
+	UserId      int    `json:"-" gorm:"index"`
+	Filename    string `json:"filename" gorm:"type:varchar(255)"`
+	Purpose     string `json:"purpose" gorm:"type:varchar(32);index"`
+	Bytes       int64  `json:"bytes"`
+	Status      string `json:"status" gorm:"type:varchar(20)"`
+	StorageType string `json:"-" gorm:"type:varchar(20)"` // local 或对象存储类型
+	StorageKey  string `json:"-" gorm:"type:varchar(512)"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+}
+
+// Normalize 填充仅用于响应的字段
+func (f *File) Normalize() *File {
+	f.Object = "file"
+	return f
+}
+
+func (f *File) Insert() error {
+	if f.CreatedAt == 0 {
+		f.CreatedAt = common.GetTimestamp()
+	}
+	return DB.Create(f).Error
+}
+
+func (f *File) Delete() error {
+	return DB.Delete(f).Error
+}
+
+func GetUserFile(userId int, fileId string) (*File, bool, error) {
+	var file File
+	err := DB.Where("user_id = ? and file_id = ?", userId, fileId).First(&file).Error
+	exist, err := RecordExist(err)
+	if err != nil || !exist {
+		return nil, exist, err
+	}
+	return &file, true, nil
+}
+
+func GetUserFiles(userId int, purpose string, limit int) ([]*File, error) {
+	var files []*File
+	query := DB.Where("user_id = ?", userId)
+	if purpose != "" {
+		query = query.Where("purpose = ?", purpose)
+	}
+	err := query.Order("id desc").Limit(limit).Find(&files).Error
+	return files, err
+}
@@ -0,0 +1,90 @@
+package model
+
+import (
+	"errors"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+const (
+	GuardrailStagePrompt     = "prompt"
+	GuardrailStageCompletion = "completion"
+)
+
+const (
+	GuardrailReviewPending  = "pending"
+	GuardrailReviewApproved = "approved"
+	GuardrailReviewRejected = "rejected"
+)
+
+// GuardrailLog 外部审核命中记录，供管理员复核
+type GuardrailLog struct {
+	Id           int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	CreatedAt    int64  `json:"created_at" gorm:"bigint;index"`
+	RequestId    string `json:"request_id" gorm:"type:varchar(64);index"`
+	UserId       int    `json:"user_id" gorm:"index"`
+	Username     string `json:"username" gorm:"type:varchar(64)"`
+	TokenId      int    `json:"token_id"`
+	TokenName    string `json:"token_name" gorm:"type:varchar(64)"`
+	Group        string `json:"group" gorm:"type:varchar(64)"`
+	ModelName    string `json:"model_name" gorm:"type:varchar(128)"`
+	Stage        string `json:"stage" gorm:"type:varchar(16)"`
+	Action       string `json:"action" gorm:"type:varchar(16)"`
+	Blocked      bool   `json:"blocked"`
+	Categories   string `json:"categories" gorm:"type:text"` // 命中的审核类别，以逗号分隔
+	Content      string `json:"content" gorm:"type:text"`    // 被审核的内容（截断）
+	ReviewStatus string `json:"review_status" gorm:"type:varchar(16);index;default:'pending'"`
+	ReviewedBy   int    `json:"reviewed_by"`
+	ReviewedAt   int64  `json:"reviewed_at" gorm:"bigint"`
+	ReviewRemark string `json:"review_remark" gorm:"type:varchar(255)"`
+}
+
+func (GuardrailLog) TableName() string {
+	return "guardrail_logs"
+}
+
+func CreateGuardrailLog(log *GuardrailLog) error {
+	log.CreatedAt = common.GetTimestamp()
+	if log.ReviewStatus == "" {
+		log.ReviewStatus = GuardrailReviewPending
+	}
+	return DB.Create(log).Error
+}
+
+func GetGuardrailLogs(reviewStatus string, stage string, username string, startIdx int, num int) (logs []*GuardrailLog, total int64, err error) {
+	tx := DB.Model(&GuardrailLog{})
+	if reviewStatus != "" {
+		tx = tx.Where("review_status = ?", reviewStatus)
+	}
+	if stage != "" {
+		tx = tx.Where("stage = ?", stage)
+	}
+	if username != "" {
+		tx = tx.Where("username = ?", username)
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&logs).Error
+	return logs, total, err
+}
+
+// ReviewGuardrailLog 管理员复核审核记录
+func ReviewGuardrailLog(id int, status string, reviewerId int, remark string) error {
+	if status != GuardrailReviewApproved && status != GuardrailReviewRejected && status != GuardrailReviewPending {
+		return errors.New("无效的复核状态")
+	}
+	result := DB.Model(&GuardrailLog{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"review_status": status,
+		"reviewed_by":   reviewerId,
+		"reviewed_at":   common.GetTimestamp(),
+		"review_remark": remark,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("记录不存在")
+	}
+	return nil
+}
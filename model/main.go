@@ -268,6 +268,13 @@ func migrateDB() error {
 		&TwoFA{},
 		&TwoFABackupCode{},
 		&Checkin{},
+		&File{},
+		&Batch{},
+		&GuardrailLog{},
+		&Organization{},
+		&OrganizationMember{},
+		&OrganizationInvitation{},
+		&AuditVersion{},
 	)
 	if err != nil {
 		return err
@@ -302,6 +309,13 @@ func migrateDBFast() error {
 		{&TwoFA{}, "TwoFA"},
 		{&TwoFABackupCode{}, "TwoFABackupCode"},
 		{&Checkin{}, "Checkin"},
+		{&File{}, "File"},
+		{&Batch{}, "Batch"},
+		{&GuardrailLog{}, "GuardrailLog"},
+		{&Organization{}, "Organization"},
+		{&OrganizationMember{}, "OrganizationMember"},
+		{&OrganizationInvitation{}, "OrganizationInvitation"},
+		{&AuditVersion{}, "AuditVersion"},
 	}
 	// 动态计算migration数量，确保errChan缓冲区足够大
 	errChan := make(chan error, len(migrations))
@@ -335,6 +349,9 @@ func migrateLOGDB() error {
 	if err = LOG_DB.AutoMigrate(&Log{}); err != nil {
 		return err
 	}
+	if err = LOG_DB.AutoMigrate(&PayloadLog{}); err != nil {
+		return err
+	}
 	return nil
 }
 
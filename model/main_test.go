@@ -0,0 +1,32 @@
+package model
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 使用临时的 SQLite 数据库并关闭 Redis，测试结束后恢复
+func setupTestDB(t *testing.T, models ...any) {
+	t.Helper()
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = db.AutoMigrate(models...); err != nil {
+		t.Fatal(err)
+	}
+	oldDB, usingSQLite, redisEnabled := DB, common.UsingSQLite, common.RedisEnabled
+	DB, common.UsingSQLite, common.RedisEnabled = db, true, false
+	initCol()
+	t.Cleanup(func() {
+		DB, common.UsingSQLite, common.RedisEnabled = oldDB, usingSQLite, redisEnabled
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+	})
+}
@@ -23,6 +23,7 @@ type Midjourney struct {
 	Quota       int    `json:"quota"`
 	Buttons     string `json:"buttons"`
 	Properties  string `json:"properties"`
+	OrgId       int    `json:"-" gorm:"default:0"` // 组织令牌提交的任务，退款时退还到组织
 }
 
 // TaskQueryParams 用于包含所有搜索条件的结构体，可以根据需求添加更多字段
@@ -0,0 +1,504 @@
+package model
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"gorm.io/gorm"
+)
+
+const (
+	OrganizationStatusEnabled  = 1
+	OrganizationStatusDisabled = 2
+)
+
+const (
+	OrganizationRoleOwner  = "owner"
+	OrganizationRoleAdmin  = "admin"
+	OrganizationRoleMember = "member"
+)
+
+const (
+	OrganizationInvitationPending  = "pending"
+	OrganizationInvitationAccepted = "accepted"
+	OrganizationInvitationDeclined = "declined"
+	OrganizationInvitationCanceled = "canceled"
+)
+
+// Organization 组织，成员在组织下创建的令牌共用组织额度
+type Organization struct {
+	Id          int            `json:"id"`
+	Name        string         `json:"name" gorm:"type:varchar(64);index"`
+	OwnerId     int            `json:"owner_id" gorm:"index"`
+	Status      int            `json:"status" gorm:"default:1"`
+	Quota       int            `json:"quota" gorm:"default:0"`
+	UsedQuota   int            `json:"used_quota" gorm:"default:0"`
+	CreatedTime int64          `json:"created_time" gorm:"bigint"`
+	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// OrganizationMember 组织成员，QuotaLimit 为该成员在组织内的累计消费上限，0 表示不限制
+type OrganizationMember struct {
+	Id          int    `json:"id"`
+	OrgId       int    `json:"org_id" gorm:"uniqueIndex:idx_org_member"`
+	UserId      int    `json:"user_id" gorm:"uniqueIndex:idx_org_member;index"`
+	Username    string `json:"username" gorm:"-:all"`
+	Role        string `json:"role" gorm:"type:varchar(16)"`
+	QuotaLimit  int    `json:"quota_limit" gorm:"default:0"`
+	UsedQuota   int    `json:"used_quota" gorm:"default:0"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationInvitation 组织邀请，被邀请用户接受后成为成员
+type OrganizationInvitation struct {
+	Id          int    `json:"id"`
+	OrgId       int    `json:"org_id" gorm:"index"`
+	OrgName     string `json:"org_name" gorm:"-:all"`
+	InviterId   int    `json:"inviter_id"`
+	InviteeId   int    `json:"invitee_id" gorm:"index"`
+	Username    string `json:"username" gorm:"-:all"`
+	Role        string `json:"role" gorm:"type:varchar(16)"`
+	Status      string `json:"status" gorm:"type:varchar(16);index"`
+	CreatedTime int64  `json:"created_time" gorm:"bigint"`
+}
+
+// OrganizationWithRole 当前用户所在的组织及其角色
+type OrganizationWithRole struct {
+	Organization
+	Role            string `json:"role"`
+	QuotaLimit      int    `json:"quota_limit"`
+	MemberUsedQuota int    `json:"member_used_quota"`
+}
+
+func IsValidOrganizationRole(role string) bool {
+	return role == OrganizationRoleAdmin || role == OrganizationRoleMember
+}
+
+// CanManageOrganization 组织所有者与管理员可以管理成员
+func (member *OrganizationMember) CanManageOrganization() bool {
+	return member.Role == OrganizationRoleOwner || member.Role == OrganizationRoleAdmin
+}
+
+func CreateOrganization(name string, ownerId int) (*Organization, error) {
+	org := &Organization{
+		Name:        name,
+		OwnerId:     ownerId,
+		Status:      OrganizationStatusEnabled,
+		CreatedTime: common.GetTimestamp(),
+	}
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		return tx.Create(&OrganizationMember{
+			OrgId:       org.Id,
+			UserId:      ownerId,
+			Role:        OrganizationRoleOwner,
+			CreatedTime: common.GetTimestamp(),
+		}).Error
+	})
+	return org, err
+}
+
+func GetOrganizationById(id int) (*Organization, error) {
+	if id == 0 {
+		return nil, errors.New("id 为空！")
+	}
+	var org Organization
+	err := DB.First(&org, "id = ?", id).Error
+	return &org, err
+}
+
+func GetAllOrganizations(keyword string, startIdx int, num int) (orgs []*Organization, total int64, err error) {
+	tx := DB.Model(&Organization{})
+	if keyword != "" {
+		tx = tx.Where("name LIKE ?", "%"+keyword+"%")
+	}
+	if err = tx.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	err = tx.Order("id desc").Limit(num).Offset(startIdx).Find(&orgs).Error
+	return orgs, total, err
+}
+
+func GetUserOrganizations(userId int) ([]*OrganizationWithRole, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("user_id = ?", userId).Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return []*OrganizationWithRole{}, nil
+	}
+	orgIds := make([]int, 0, len(members))
+	for _, member := range members {
+		orgIds = append(orgIds, member.OrgId)
+	}
+	var orgs []*Organization
+	if err := DB.Where("id IN ?", orgIds).Order("id desc").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	memberMap := make(map[int]*OrganizationMember, len(members))
+	for _, member := range members {
+		memberMap[member.OrgId] = member
+	}
+	result := make([]*OrganizationWithRole, 0, len(orgs))
+	for _, org := range orgs {
+		member := memberMap[org.Id]
+		result = append(result, &OrganizationWithRole{
+			Organization:    *org,
+			Role:            member.Role,
+			QuotaLimit:      member.QuotaLimit,
+			MemberUsedQuota: member.UsedQuota,
+		})
+	}
+	return result, nil
+}
+
+// Update 更新组织名称与状态，额度通过 SetOrganizationQuota 等方法修改
+func (org *Organization) Update() error {
+	if err := DB.Model(org).Select("name", "status").Updates(org).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationCache(org.Id)
+}
+
+// DeleteOrganization 删除组织，剩余额度退还给所有者
+func DeleteOrganization(org *Organization) error {
+	var memberIds []int
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		var current Organization
+		if err := tx.First(&current, "id = ?", org.Id).Error; err != nil {
+			return err
+		}
+		if current.Quota > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", current.OwnerId).Update("quota", gorm.Expr("quota + ?", current.Quota)).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Model(&OrganizationMember{}).Where("org_id = ?", org.Id).Pluck("user_id", &memberIds).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("org_id = ?", org.Id).Delete(&OrganizationMember{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&OrganizationInvitation{}).Where("org_id = ? AND status = ?", org.Id, OrganizationInvitationPending).
+			Update("status", OrganizationInvitationCanceled).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&current).Error
+	})
+	if err != nil {
+		return err
+	}
+	for _, userId := range memberIds {
+		if err := invalidateOrganizationMemberCache(org.Id, userId); err != nil {
+			return err
+		}
+	}
+	if err := invalidateOrganizationCache(org.Id); err != nil {
+		return err
+	}
+	return invalidateUserCache(org.OwnerId)
+}
+
+func GetOrganizationMember(orgId int, userId int) (*OrganizationMember, error) {
+	var member OrganizationMember
+	err := DB.First(&member, "org_id = ? AND user_id = ?", orgId, userId).Error
+	return &member, err
+}
+
+func GetOrganizationMembers(orgId int) ([]*OrganizationMember, error) {
+	var members []*OrganizationMember
+	if err := DB.Where("org_id = ?", orgId).Order("id asc").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	if len(members) == 0 {
+		return members, nil
+	}
+	userIds := make([]int, 0, len(members))
+	for _, member := range members {
+		userIds = append(userIds, member.UserId)
+	}
+	usernames, err := getUsernamesByIds(userIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, member := range members {
+		member.Username = usernames[member.UserId]
+	}
+	return members, nil
+}
+
+func (member *OrganizationMember) Update() error {
+	if err := DB.Model(member).Select("role", "quota_limit").Updates(member).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationMemberCache(member.OrgId, member.UserId)
+}
+
+// ResetUsedQuota 清零成员已用额度，重新开始计算消费上限
+func (member *OrganizationMember) ResetUsedQuota() error {
+	member.UsedQuota = 0
+	if err := DB.Model(member).Update("used_quota", 0).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationMemberCache(member.OrgId, member.UserId)
+}
+
+func (member *OrganizationMember) Delete() error {
+	if err := DB.Delete(member).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationMemberCache(member.OrgId, member.UserId)
+}
+
+// SetTokenOrganization 修改令牌所属组织，非 0 时令牌所有者必须是该组织成员
+func SetTokenOrganization(token *Token, orgId int) error {
+	if orgId != 0 {
+		if _, err := GetOrganizationMember(orgId, token.UserId); err != nil {
+			return errors.New("令牌所有者不是该组织的成员")
+		}
+	}
+	if err := DB.Model(&Token{}).Where("id = ?", token.Id).UpdateColumn("org_id", orgId).Error; err != nil {
+		return err
+	}
+	token.OrgId = orgId
+	if common.RedisEnabled {
+		if err := cacheSetTokenField(token.Key, constant.TokenFieldOrgId, strconv.Itoa(orgId)); err != nil {
+			common.SysLog("failed to update token organization cache: " + err.Error())
+		}
+	}
+	return nil
+}
+
+func getUsernamesByIds(userIds []int) (map[int]string, error) {
+	var users []struct {
+		Id       int
+		Username string
+	}
+	if err := DB.Model(&User{}).Select("id, username").Where("id IN ?", userIds).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	usernames := make(map[int]string, len(users))
+	for _, user := range users {
+		usernames[user.Id] = user.Username
+	}
+	return usernames, nil
+}
+
+func CreateOrganizationInvitation(orgId int, inviterId int, inviteeId int, role string) error {
+	if _, err := GetOrganizationMember(orgId, inviteeId); err == nil {
+		return errors.New("该用户已经是组织成员")
+	}
+	var count int64
+	if err := DB.Model(&OrganizationInvitation{}).Where("org_id = ? AND invitee_id = ? AND status = ?", orgId, inviteeId, OrganizationInvitationPending).
+		Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return errors.New("已向该用户发送过邀请")
+	}
+	return DB.Create(&OrganizationInvitation{
+		OrgId:       orgId,
+		InviterId:   inviterId,
+		InviteeId:   inviteeId,
+		Role:        role,
+		Status:      OrganizationInvitationPending,
+		CreatedTime: common.GetTimestamp(),
+	}).Error
+}
+
+func GetOrganizationInvitations(orgId int) ([]*OrganizationInvitation, error) {
+	var invitations []*OrganizationInvitation
+	if err := DB.Where("org_id = ? AND status = ?", orgId, OrganizationInvitationPending).Order("id desc").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	if len(invitations) == 0 {
+		return invitations, nil
+	}
+	userIds := make([]int, 0, len(invitations))
+	for _, invitation := range invitations {
+		userIds = append(userIds, invitation.InviteeId)
+	}
+	usernames, err := getUsernamesByIds(userIds)
+	if err != nil {
+		return nil, err
+	}
+	for _, invitation := range invitations {
+		invitation.Username = usernames[invitation.InviteeId]
+	}
+	return invitations, nil
+}
+
+func GetUserOrganizationInvitations(userId int) ([]*OrganizationInvitation, error) {
+	var invitations []*OrganizationInvitation
+	if err := DB.Where("invitee_id = ? AND status = ?", userId, OrganizationInvitationPending).Order("id desc").Find(&invitations).Error; err != nil {
+		return nil, err
+	}
+	for _, invitation := range invitations {
+		if org, err := GetOrganizationById(invitation.OrgId); err == nil {
+			invitation.OrgName = org.Name
+		}
+	}
+	return invitations, nil
+}
+
+func GetOrganizationInvitationById(id int) (*OrganizationInvitation, error) {
+	var invitation OrganizationInvitation
+	err := DB.First(&invitation, "id = ?", id).Error
+	return &invitation, err
+}
+
+// RespondOrganizationInvitation 接受或拒绝邀请，接受时加入组织
+func RespondOrganizationInvitation(invitation *OrganizationInvitation, accept bool) error {
+	status := OrganizationInvitationDeclined
+	if accept {
+		status = OrganizationInvitationAccepted
+	}
+	return DB.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&OrganizationInvitation{}).Where("id = ? AND status = ?", invitation.Id, OrganizationInvitationPending).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("邀请已失效")
+		}
+		if !accept {
+			return nil
+		}
+		var org Organization
+		if err := tx.First(&org, "id = ?", invitation.OrgId).Error; err != nil {
+			return errors.New("组织不存在")
+		}
+		return tx.Create(&OrganizationMember{
+			OrgId:       invitation.OrgId,
+			UserId:      invitation.InviteeId,
+			Role:        invitation.Role,
+			CreatedTime: common.GetTimestamp(),
+		}).Error
+	})
+}
+
+func CancelOrganizationInvitation(orgId int, id int) error {
+	result := DB.Model(&OrganizationInvitation{}).Where("id = ? AND org_id = ? AND status = ?", id, orgId, OrganizationInvitationPending).
+		Update("status", OrganizationInvitationCanceled)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("邀请不存在")
+	}
+	return nil
+}
+
+func GetOrganizationQuota(orgId int) (int, error) {
+	var quota int
+	err := DB.Model(&Organization{}).Where("id = ?", orgId).Select("quota").Find(&quota).Error
+	return quota, err
+}
+
+// SetOrganizationQuota 管理员直接设置组织额度
+func SetOrganizationQuota(orgId int, quota int) error {
+	if quota < 0 {
+		return errors.New("quota 不能为负数！")
+	}
+	if err := DB.Model(&Organization{}).Where("id = ?", orgId).Update("quota", quota).Error; err != nil {
+		return err
+	}
+	return invalidateOrganizationCache(orgId)
+}
+
+// TransferUserQuotaToOrganization 所有者在个人额度与组织额度之间转移，quota 为负数时从组织转回个人
+func TransferUserQuotaToOrganization(userId int, orgId int, quota int) error {
+	err := DB.Transaction(func(tx *gorm.DB) error {
+		if quota > 0 {
+			result := tx.Model(&User{}).Where("id = ? AND quota >= ?", userId, quota).Update("quota", gorm.Expr("quota - ?", quota))
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				return errors.New("个人额度不足")
+			}
+			return tx.Model(&Organization{}).Where("id = ?", orgId).Update("quota", gorm.Expr("quota + ?", quota)).Error
+		}
+		result := tx.Model(&Organization{}).Where("id = ? AND quota >= ?", orgId, -quota).Update("quota", gorm.Expr("quota + ?", quota))
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("组织额度不足")
+		}
+		return tx.Model(&User{}).Where("id = ?", userId).Update("quota", gorm.Expr("quota - ?", quota)).Error
+	})
+	if err != nil {
+		return err
+	}
+	if err := invalidateOrganizationCache(orgId); err != nil {
+		return err
+	}
+	return invalidateUserCache(userId)
+}
+
+// ConsumeOrganizationQuota 按消费额度扣减组织额度并累计成员用量，quota 为负数时退还
+func ConsumeOrganizationQuota(orgId int, userId int, quota int) error {
+	if quota == 0 {
+		return nil
+	}
+	gopool.Go(func() {
+		if err := cacheConsumeOrganizationQuota(orgId, userId, quota); err != nil {
+			common.SysLog("failed to update organization quota cache: " + err.Error())
+		}
+	})
+	if common.BatchUpdateEnabled {
+		addNewRecord(BatchUpdateTypeOrganizationQuota, orgId, quota)
+		member, err := GetOrganizationMemberCache(orgId, userId)
+		if err != nil {
+			// 成员已被移除时只扣减组织额度
+			return nil
+		}
+		addNewRecord(BatchUpdateTypeOrganizationMemberUsedQuota, member.Id, quota)
+		return nil
+	}
+	return consumeOrganizationQuota(orgId, userId, quota)
+}
+
+func consumeOrganizationQuota(orgId int, userId int, quota int) error {
+	if err := updateOrganizationUsedQuota(orgId, quota); err != nil {
+		return err
+	}
+	return DB.Model(&OrganizationMember{}).Where("org_id = ? AND user_id = ?", orgId, userId).
+		Update("used_quota", gorm.Expr("used_quota + ?", quota)).Error
+}
+
+func updateOrganizationUsedQuota(orgId int, quota int) error {
+	return DB.Model(&Organization{}).Where("id = ?", orgId).Updates(map[string]interface{}{
+		"quota":      gorm.Expr("quota - ?", quota),
+		"used_quota": gorm.Expr("used_quota + ?", quota),
+	}).Error
+}
+
+func updateOrganizationMemberUsedQuota(memberId int, quota int) error {
+	return DB.Model(&OrganizationMember{}).Where("id = ?", memberId).
+		Update("used_quota", gorm.Expr("used_quota + ?", quota)).Error
+}
+
+// IncreasePayerQuota 退还额度，组织令牌产生的费用退还到组织
+func IncreasePayerQuota(userId int, orgId int, quota int) error {
+	if orgId != 0 {
+		return ConsumeOrganizationQuota(orgId, userId, -quota)
+	}
+	return IncreaseUserQuota(userId, quota, false)
+}
+
+// DecreasePayerQuota 扣减额度，组织令牌产生的费用从组织扣除
+func DecreasePayerQuota(userId int, orgId int, quota int) error {
+	if orgId != 0 {
+		return ConsumeOrganizationQuota(orgId, userId, quota)
+	}
+	return DecreaseUserQuota(userId, quota)
+}
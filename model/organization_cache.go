@@ -0,0 +1,114 @@
+package model
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+
+	"github.com/bytedance/gopkg/util/gopool"
+)
+
+// OrganizationBase 组织缓存，只包含转发请求需要的字段
+type OrganizationBase struct {
+	Id     int `json:"id"`
+	Status int `json:"status"`
+	Quota  int `json:"quota"`
+}
+
+// OrganizationMemberBase 组织成员缓存，用于检查成员身份与消费上限
+type OrganizationMemberBase struct {
+	Id         int    `json:"id"`
+	Role       string `json:"role"`
+	QuotaLimit int    `json:"quota_limit"`
+	UsedQuota  int    `json:"used_quota"`
+}
+
+func getOrganizationCacheKey(orgId int) string {
+	return fmt.Sprintf("org:%d", orgId)
+}
+
+func getOrganizationMemberCacheKey(orgId int, userId int) string {
+	return fmt.Sprintf("org_member:%d:%d", orgId, userId)
+}
+
+func invalidateOrganizationCache(orgId int) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisDelKey(getOrganizationCacheKey(orgId))
+}
+
+func invalidateOrganizationMemberCache(orgId int, userId int) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	return common.RedisDelKey(getOrganizationMemberCacheKey(orgId, userId))
+}
+
+// GetOrganizationCache 优先从缓存读取组织，缓存不存在时从数据库读取并异步写入缓存
+func GetOrganizationCache(orgId int) (orgCache *OrganizationBase, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) {
+			cache := *orgCache
+			gopool.Go(func() {
+				err := common.RedisHSetObj(getOrganizationCacheKey(orgId), &cache, time.Duration(common.RedisKeyCacheSeconds())*time.Second)
+				if err != nil {
+					common.SysLog("failed to update organization cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cache OrganizationBase
+		if err := common.RedisHGetObj(getOrganizationCacheKey(orgId), &cache); err == nil {
+			return &cache, nil
+		}
+	}
+	fromDB = true
+	org, err := GetOrganizationById(orgId)
+	if err != nil {
+		return nil, err
+	}
+	return &OrganizationBase{Id: org.Id, Status: org.Status, Quota: org.Quota}, nil
+}
+
+// GetOrganizationMemberCache 优先从缓存读取组织成员，缓存不存在时从数据库读取并异步写入缓存
+func GetOrganizationMemberCache(orgId int, userId int) (memberCache *OrganizationMemberBase, err error) {
+	var fromDB bool
+	defer func() {
+		if shouldUpdateRedis(fromDB, err) {
+			cache := *memberCache
+			gopool.Go(func() {
+				err := common.RedisHSetObj(getOrganizationMemberCacheKey(orgId, userId), &cache, time.Duration(common.RedisKeyCacheSeconds())*time.Second)
+				if err != nil {
+					common.SysLog("failed to update organization member cache: " + err.Error())
+				}
+			})
+		}
+	}()
+	if common.RedisEnabled {
+		var cache OrganizationMemberBase
+		if err := common.RedisHGetObj(getOrganizationMemberCacheKey(orgId, userId), &cache); err == nil {
+			return &cache, nil
+		}
+	}
+	fromDB = true
+	member, err := GetOrganizationMember(orgId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return &OrganizationMemberBase{Id: member.Id, Role: member.Role, QuotaLimit: member.QuotaLimit, UsedQuota: member.UsedQuota}, nil
+}
+
+// cacheConsumeOrganizationQuota 同步扣减缓存中的组织额度并累计成员用量，缓存不存在时不做处理
+func cacheConsumeOrganizationQuota(orgId int, userId int, quota int) error {
+	if !common.RedisEnabled {
+		return nil
+	}
+	if err := common.RedisHIncrBy(getOrganizationCacheKey(orgId), "Quota", -int64(quota)); err != nil {
+		return err
+	}
+	return common.RedisHIncrBy(getOrganizationMemberCacheKey(orgId, userId), "UsedQuota", int64(quota))
+}
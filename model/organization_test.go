@@ -0,0 +1,129 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func setupOrganizationTest(t *testing.T) (*User, *Organization) {
+	t.Helper()
+	setupTestDB(t, &User{}, &Organization{}, &OrganizationMember{})
+	user := &User{Username: "org-owner", Quota: 1000}
+	if err := DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	org, err := CreateOrganization("test-org", user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = SetOrganizationQuota(org.Id, 500); err != nil {
+		t.Fatal(err)
+	}
+	return user, org
+}
+
+func TestPayerQuota(t *testing.T) {
+	tests := []struct {
+		name          string
+		useOrg        bool
+		decrease      int
+		increase      int
+		wantUserQuota int
+		wantOrgQuota  int
+		wantOrgUsed   int
+	}{
+		{"personal consume", false, 300, 0, 700, 500, 0},
+		{"personal refund", false, 300, 100, 800, 500, 0},
+		{"organization consume", true, 300, 0, 1000, 200, 300},
+		{"organization refund", true, 300, 100, 1000, 300, 200},
+		{"organization overdraw", true, 600, 0, 1000, -100, 600},
+	}
+	for _, tt := range tests {
+		user, org := setupOrganizationTest(t)
+		orgId := 0
+		if tt.useOrg {
+			orgId = org.Id
+		}
+		if err := DecreasePayerQuota(user.Id, orgId, tt.decrease); err != nil {
+			t.Fatal(err)
+		}
+		if tt.increase > 0 {
+			if err := IncreasePayerQuota(user.Id, orgId, tt.increase); err != nil {
+				t.Fatal(err)
+			}
+		}
+		userQuota, err := GetUserQuota(user.Id, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current, err := GetOrganizationById(org.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		member, err := GetOrganizationMember(org.Id, user.Id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if userQuota != tt.wantUserQuota || current.Quota != tt.wantOrgQuota || current.UsedQuota != tt.wantOrgUsed || member.UsedQuota != tt.wantOrgUsed {
+			t.Fatalf("%s: expected user %d, org %d used %d, got user %d, org %d used %d, member used %d", tt.name,
+				tt.wantUserQuota, tt.wantOrgQuota, tt.wantOrgUsed, userQuota, current.Quota, current.UsedQuota, member.UsedQuota)
+		}
+	}
+}
+
+func TestTransferUserQuotaToOrganization(t *testing.T) {
+	tests := []struct {
+		name          string
+		quota         int
+		wantErr       bool
+		wantUserQuota int
+		wantOrgQuota  int
+	}{
+		{"to organization", 400, false, 600, 900},
+		{"back to owner", -200, false, 1200, 300},
+		{"personal quota not enough", 1001, true, 1000, 500},
+		{"organization quota not enough", -501, true, 1000, 500},
+	}
+	for _, tt := range tests {
+		user, org := setupOrganizationTest(t)
+		err := TransferUserQuotaToOrganization(user.Id, org.Id, tt.quota)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+		userQuota, _ := GetUserQuota(user.Id, true)
+		orgQuota, _ := GetOrganizationQuota(org.Id)
+		if userQuota != tt.wantUserQuota || orgQuota != tt.wantOrgQuota {
+			t.Fatalf("%s: expected user %d, org %d, got user %d, org %d", tt.name, tt.wantUserQuota, tt.wantOrgQuota, userQuota, orgQuota)
+		}
+	}
+}
+
+func TestConsumeOrganizationQuotaBatchUpdate(t *testing.T) {
+	user, org := setupOrganizationTest(t)
+	batchUpdateEnabled := common.BatchUpdateEnabled
+	common.BatchUpdateEnabled = true
+	t.Cleanup(func() { common.BatchUpdateEnabled = batchUpdateEnabled })
+
+	if err := DecreasePayerQuota(user.Id, org.Id, 300); err != nil {
+		t.Fatal(err)
+	}
+	if err := IncreasePayerQuota(user.Id, org.Id, 100); err != nil {
+		t.Fatal(err)
+	}
+	if orgQuota, _ := GetOrganizationQuota(org.Id); orgQuota != 500 {
+		t.Fatalf("expected organization quota unchanged before batch update, got %d", orgQuota)
+	}
+	batchUpdate()
+	current, err := GetOrganizationById(org.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	member, err := GetOrganizationMember(org.Id, user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if current.Quota != 300 || current.UsedQuota != 200 || member.UsedQuota != 200 {
+		t.Fatalf("expected org 300 used 200, got org %d used %d, member used %d", current.Quota, current.UsedQuota, member.UsedQuota)
+	}
+}
@@ -0,0 +1,52 @@
+package model
+
+// PayloadLog 完整的请求与响应内容，按请求 ID 与消费日志关联
+// 内容为 gzip 压缩后的 JSON，保存在 Data 中或对象存储的 StorageKey 下
+type PayloadLog struct {
+	Id          int    `json:"id" gorm:"primaryKey;autoIncrement"`
+	RequestId   string `json:"request_id" gorm:"type:varchar(64);index"`
+	CreatedAt   int64  `json:"created_at" gorm:"bigint;index"`
+	UserId      int    `json:"user_id" gorm:"index"`
+	TokenId     int    `json:"token_id"`
+	Group       string `json:"group" gorm:"type:varchar(64)"`
+	ModelName   string `json:"model_name" gorm:"type:varchar(128)"`
+	Size        int    `json:"size"` // 压缩前的大小
+	StorageType string `json:"storage_type" gorm:"type:varchar(32)"`
+	StorageKey  string `json:"storage_key" gorm:"type:varchar(255)"`
+	Data        []byte `json:"-"`
+}
+
+func (PayloadLog) TableName() string {
+	return "payload_logs"
+}
+
+func CreatePayloadLog(log *PayloadLog) error {
+	return LOG_DB.Create(log).Error
+}
+
+// GetPayloadLogByRequestId userId 为 0 时不限制用户
+func GetPayloadLogByRequestId(requestId string, userId int) (*PayloadLog, error) {
+	var log PayloadLog
+	tx := LOG_DB.Where("request_id = ?", requestId)
+	if userId != 0 {
+		tx = tx.Where("user_id = ?", userId)
+	}
+	err := tx.Order("id desc").First(&log).Error
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// GetExpiredPayloadLogs 返回早于指定时间的记录，不加载内容
+func GetExpiredPayloadLogs(before int64, limit int) (logs []*PayloadLog, err error) {
+	err = LOG_DB.Omit("data").Where("created_at < ?", before).Order("id asc").Limit(limit).Find(&logs).Error
+	return logs, err
+}
+
+func DeletePayloadLogsByIds(ids []int) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return LOG_DB.Where("id in ?", ids).Delete(&PayloadLog{}).Error
+}
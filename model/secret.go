@@ -0,0 +1,251 @@
+package model
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("secret", secretSerializer{})
+}
+
+// secretSerializer 字符串字段的静态加密，写入数据库前加密，读取后解密
+type secretSerializer struct{}
+
+func (secretSerializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	var value string
+	switch v := dbValue.(type) {
+	case nil:
+	case []byte:
+		value = string(v)
+	case string:
+		value = v
+	default:
+		return fmt.Errorf("failed to scan secret field %s: unsupported type %T", field.Name, dbValue)
+	}
+	plaintext, err := common.DecryptSecret(value)
+	if err != nil {
+		return fmt.Errorf("failed to decrypt field %s: %w", field.Name, err)
+	}
+	return field.Set(ctx, dst, plaintext)
+}
+
+func (secretSerializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	value, _ := fieldValue.(string)
+	return common.EncryptSecret(value)
+}
+
+// encryptUserSettingSecrets 加密用户设置中的通知密钥
+func encryptUserSettingSecrets(setting *dto.UserSetting) (err error) {
+	if setting.WebhookSecret, err = common.EncryptSecret(setting.WebhookSecret); err != nil {
+		return err
+	}
+	setting.GotifyToken, err = common.EncryptSecret(setting.GotifyToken)
+	return err
+}
+
+// decryptUserSettingSecrets 解密用户设置中的通知密钥，失败时清空对应字段
+func decryptUserSettingSecrets(setting *dto.UserSetting) {
+	var err error
+	if setting.WebhookSecret, err = common.DecryptSecret(setting.WebhookSecret); err != nil {
+		common.SysError("failed to decrypt webhook secret: " + err.Error())
+	}
+	if setting.GotifyToken, err = common.DecryptSecret(setting.GotifyToken); err != nil {
+		common.SysError("failed to decrypt gotify token: " + err.Error())
+	}
+}
+
+// SecretRewrapResult 各表重新加密的记录数
+type SecretRewrapResult struct {
+	Channels int `json:"channels"`
+	Tasks    int `json:"tasks"`
+	Users    int `json:"users"`
+	Options  int `json:"options"`
+}
+
+const secretRewrapBatchSize = 100
+
+// RewrapSecrets 加密存量明文，并将旧主密钥加密的数据切换到当前主密钥，可在服务运行期间执行
+// 更新时以原值为条件，期间被修改的记录会跳过，写入时已由当前主密钥加密
+func RewrapSecrets() (*SecretRewrapResult, error) {
+	if !common.SecretEncryptionEnabled() {
+		return nil, errors.New("未配置加密主密钥")
+	}
+	result := &SecretRewrapResult{}
+	var err error
+	if result.Channels, err = rewrapChannelKeys(); err != nil {
+		return result, err
+	}
+	if result.Tasks, err = rewrapTaskKeys(); err != nil {
+		return result, err
+	}
+	if result.Users, err = rewrapUserSettings(); err != nil {
+		return result, err
+	}
+	result.Options, err = rewrapOptions()
+	return result, err
+}
+
+// rewrapChannelKeys 重新加密渠道密钥，并补齐或更新密钥的 HMAC
+func rewrapChannelKeys() (int, error) {
+	count := 0
+	lastId := 0
+	for {
+		var rows []struct {
+			Id      int
+			Key     string
+			KeyHash string
+		}
+		if err := DB.Table("channels").Select("id, "+commonKeyCol+", key_hash").Where("id > ?", lastId).
+			Order("id").Limit(secretRewrapBatchSize).Scan(&rows).Error; err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			lastId = row.Id
+			plaintext, err := common.DecryptSecret(row.Key)
+			if err != nil {
+				return count, fmt.Errorf("channel %d: %w", row.Id, err)
+			}
+			keyHash := common.SecretLookupHash(plaintext)
+			if !common.SecretNeedsRewrap(row.Key) && row.KeyHash == keyHash {
+				continue
+			}
+			key, err := common.RewrapSecret(row.Key)
+			if err != nil {
+				return count, fmt.Errorf("channel %d: %w", row.Id, err)
+			}
+			tx := DB.Table("channels").Where("id = ? AND "+commonKeyCol+" = ?", row.Id, row.Key).
+				Updates(map[string]interface{}{"key": key, "key_hash": keyHash})
+			if tx.Error != nil {
+				return count, tx.Error
+			}
+			count += int(tx.RowsAffected)
+		}
+		if len(rows) < secretRewrapBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func rewrapTaskKeys() (int, error) {
+	count := 0
+	var lastId int64
+	// json 列（SQLite 中按二进制保存）不能直接与字符串比较，转换为文本后比较旧密文
+	privateDataCol := "CAST(private_data AS TEXT)"
+	if common.UsingMySQL {
+		privateDataCol = "CAST(private_data AS CHAR)"
+	}
+	for {
+		var rows []struct {
+			ID          int64
+			PrivateData string
+		}
+		if err := DB.Table("tasks").Select("id, private_data").Where("id > ? AND private_data IS NOT NULL", lastId).
+			Order("id").Limit(secretRewrapBatchSize).Scan(&rows).Error; err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			lastId = row.ID
+			var data TaskPrivateData
+			if row.PrivateData == "" || common.UnmarshalJsonStr(row.PrivateData, &data) != nil ||
+				!common.SecretNeedsRewrap(data.Key) {
+				continue
+			}
+			key, err := common.RewrapSecret(data.Key)
+			if err != nil {
+				return count, fmt.Errorf("task %d: %w", row.ID, err)
+			}
+			data.Key = key
+			dataJson, err := common.Marshal(data)
+			if err != nil {
+				return count, err
+			}
+			// 仅在密文未被并发修改时更新，否则跳过，下次重新加密时再处理
+			tx := DB.Table("tasks").Where("id = ? AND "+privateDataCol+" = ?", row.ID, row.PrivateData).
+				Update("private_data", dataJson)
+			if tx.Error != nil {
+				return count, tx.Error
+			}
+			count += int(tx.RowsAffected)
+		}
+		if len(rows) < secretRewrapBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func rewrapUserSettings() (int, error) {
+	count := 0
+	lastId := 0
+	for {
+		var rows []struct {
+			Id      int
+			Setting string
+		}
+		if err := DB.Table("users").Select("id, setting").
+			Where("id > ? AND (setting LIKE ? OR setting LIKE ?)", lastId, "%webhook_secret%", "%gotify_token%").
+			Order("id").Limit(secretRewrapBatchSize).Scan(&rows).Error; err != nil {
+			return count, err
+		}
+		for _, row := range rows {
+			lastId = row.Id
+			var setting dto.UserSetting
+			if common.UnmarshalJsonStr(row.Setting, &setting) != nil ||
+				!common.SecretNeedsRewrap(setting.WebhookSecret) && !common.SecretNeedsRewrap(setting.GotifyToken) {
+				continue
+			}
+			var err error
+			if setting.WebhookSecret, err = common.RewrapSecret(setting.WebhookSecret); err != nil {
+				return count, fmt.Errorf("user %d: %w", row.Id, err)
+			}
+			if setting.GotifyToken, err = common.RewrapSecret(setting.GotifyToken); err != nil {
+				return count, fmt.Errorf("user %d: %w", row.Id, err)
+			}
+			settingJson, err := common.Marshal(setting)
+			if err != nil {
+				return count, err
+			}
+			tx := DB.Table("users").Where("id = ? AND setting = ?", row.Id, row.Setting).Update("setting", string(settingJson))
+			if tx.Error != nil {
+				return count, tx.Error
+			}
+			if tx.RowsAffected > 0 {
+				count++
+				_ = invalidateUserCache(row.Id)
+			}
+		}
+		if len(rows) < secretRewrapBatchSize {
+			return count, nil
+		}
+	}
+}
+
+func rewrapOptions() (int, error) {
+	options, err := AllOption()
+	if err != nil {
+		return 0, err
+	}
+	count := 0
+	for _, option := range options {
+		if !IsSensitiveOptionKey(option.Key) || !common.SecretNeedsRewrap(option.Value) {
+			continue
+		}
+		value, err := common.RewrapSecret(option.Value)
+		if err != nil {
+			return count, fmt.Errorf("option %s: %w", option.Key, err)
+		}
+		tx := DB.Model(&Option{}).Where(commonKeyCol+" = ? AND value = ?", option.Key, option.Value).Update("value", value)
+		if tx.Error != nil {
+			return count, tx.Error
+		}
+		count += int(tx.RowsAffected)
+	}
+	return count, nil
+}
@@ -0,0 +1,61 @@
+package model
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+)
+
+func setTestSecretKeys(t *testing.T, current string, previous string) {
+	t.Helper()
+	t.Setenv("SECRET_ENCRYPTION_KEY_FILE", "")
+	t.Setenv("SECRET_ENCRYPTION_KEY", current)
+	t.Setenv("SECRET_ENCRYPTION_PREVIOUS_KEYS", previous)
+	if err := common.InitSecretEncryption(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRewrapTaskKeys(t *testing.T) {
+	setupTestDB(t, &Task{})
+	// 环境变量恢复后重新加载主密钥
+	t.Cleanup(func() { _ = common.InitSecretEncryption() })
+	setTestSecretKeys(t, "old-key", "")
+	task := &Task{TaskID: "task-rewrap", PrivateData: TaskPrivateData{Key: "sk-task"}}
+	if err := DB.Create(task).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	setTestSecretKeys(t, "new-key", "old-key")
+	count, err := rewrapTaskKeys()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 1 {
+		t.Fatalf("expected 1 task rewrapped, got %d", count)
+	}
+	var privateData string
+	if err := DB.Table("tasks").Select("private_data").Where("id = ?", task.ID).Scan(&privateData).Error; err != nil {
+		t.Fatal(err)
+	}
+	var data TaskPrivateData
+	if err := common.UnmarshalJsonStr(privateData, &data); err != nil {
+		t.Fatal(err)
+	}
+	if common.SecretNeedsRewrap(data.Key) {
+		t.Fatalf("expected task key to be encrypted by the current key, got %q", data.Key)
+	}
+	if key, err := common.DecryptSecret(data.Key); err != nil || key != "sk-task" {
+		t.Fatalf("expected rewrapped key to decrypt to sk-task, got %q, %v", key, err)
+	}
+	var loaded Task
+	if err := DB.First(&loaded, task.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if loaded.PrivateData.Key != "sk-task" {
+		t.Fatalf("expected task to load key sk-task, got %q", loaded.PrivateData.Key)
+	}
+	if count, err = rewrapTaskKeys(); err != nil || count != 0 {
+		t.Fatalf("expected nothing left to rewrap, got %d, %v", count, err)
+	}
+}
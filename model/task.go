@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"time"
 
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	commonRelay "github.com/QuantumNous/new-api/relay/common"
@@ -95,7 +96,8 @@ func (m Properties) Value() (driver.Value, error) {
 }
 
 type TaskPrivateData struct {
-	Key string `json:"key,omitempty"`
+	Key   string `json:"key,omitempty"`
+	OrgId int    `json:"org_id,omitempty"` // 组织令牌提交的任务，退款时退还到组织
 }
 
 func (p *TaskPrivateData) Scan(val interface{}) error {
@@ -103,13 +105,26 @@ func (p *TaskPrivateData) Scan(val interface{}) error {
 	if len(bytesValue) == 0 {
 		return nil
 	}
-	return json.Unmarshal(bytesValue, p)
+	if err := json.Unmarshal(bytesValue, p); err != nil {
+		return err
+	}
+	key, err := common.DecryptSecret(p.Key)
+	if err != nil {
+		return err
+	}
+	p.Key = key
+	return nil
 }
 
 func (p TaskPrivateData) Value() (driver.Value, error) {
 	if (p == TaskPrivateData{}) {
 		return nil, nil
 	}
+	key, err := common.EncryptSecret(p.Key)
+	if err != nil {
+		return nil, err
+	}
+	p.Key = key
 	return json.Marshal(p)
 }
 
@@ -129,6 +144,9 @@ type SyncTaskQueryParams struct {
 func InitTask(platform constant.TaskPlatform, relayInfo *commonRelay.RelayInfo) *Task {
 	properties := Properties{}
 	privateData := TaskPrivateData{}
+	if relayInfo != nil {
+		privateData.OrgId = relayInfo.OrgId
+	}
 	if relayInfo != nil && relayInfo.ChannelMeta != nil {
 		if relayInfo.ChannelMeta.ChannelType == constant.ChannelTypeGemini {
 			privateData.Key = relayInfo.ChannelMeta.ApiKey
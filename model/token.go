@@ -23,10 +23,21 @@ type Token struct {
 	UnlimitedQuota     bool           `json:"unlimited_quota"`
 	ModelLimitsEnabled bool           `json:"model_limits_enabled"`
 	ModelLimits        string         `json:"model_limits" gorm:"type:varchar(1024);default:''"`
-	AllowIps           *string        `json:"allow_ips" gorm:"default:''"`
+	AllowIps           *string        `json:"allow_ips" gorm:"default:''"` // 已弃用，保存时迁移到 Policy
+	Policy             *string        `json:"policy" gorm:"type:text"`     // 访问策略，见 dto.TokenPolicy
 	UsedQuota          int            `json:"used_quota" gorm:"default:0"` // used quota
 	Group              string         `json:"group" gorm:"default:''"`
-	CrossGroupRetry    bool           `json:"cross_group_retry"` // 跨分组重试，仅auto分组有效
+	CrossGroupRetry    bool           `json:"cross_group_retry"`                                 // 跨分组重试，仅auto分组有效
+	FallbackDisabled   bool           `json:"fallback_disabled"`                                 // 不使用模型降级链
+	HedgeDelay         int            `json:"hedge_delay" gorm:"default:0"`                      // 对冲请求等待首字节的毫秒数，0 表示使用分组配置
+	PriorityClass      string         `json:"priority_class" gorm:"type:varchar(32);default:''"` // 渠道并发已满时的排队优先级，空表示使用分组配置
+	TPMLimit           int            `json:"tpm_limit" gorm:"default:0"`                        // 每分钟 token 数限制，0 表示不限制
+	ConcurrencyLimit   int            `json:"concurrency_limit" gorm:"default:0"`                // 并发请求数限制，0 表示不限制
+	BudgetPeriod       string         `json:"budget_period" gorm:"type:varchar(16);default:''"`  // 预算周期：daily / monthly，空表示不限制
+	BudgetLimit        int            `json:"budget_limit" gorm:"default:0"`                     // 每个周期内的额度上限
+	BudgetUsed         int            `json:"budget_used" gorm:"default:0"`                      // 当前周期内已使用的额度
+	BudgetPeriodStart  int64          `json:"budget_period_start" gorm:"bigint;default:0"`       // 当前周期的开始时间
+	OrgId              int            `json:"org_id" gorm:"default:0;index"`                     // 所属组织，非 0 时从组织额度扣费
 	DeletedAt          gorm.DeletedAt `gorm:"index"`
 }
 
@@ -34,7 +45,7 @@ func (token *Token) Clean() {
 	token.Key = ""
 }
 
-func (token *Token) GetIpLimits() []string {
+func (token *Token) getLegacyIpLimits() []string {
 	// delete empty spaces
 	//split with \n
 	ipLimits := make([]string, 0)
@@ -190,7 +201,8 @@ func (token *Token) Update() (err error) {
 		}
 	}()
 	err = DB.Model(token).Select("name", "status", "expired_time", "remain_quota", "unlimited_quota",
-		"model_limits_enabled", "model_limits", "allow_ips", "group", "cross_group_retry").Updates(token).Error
+		"model_limits_enabled", "model_limits", "allow_ips", "policy", "group", "cross_group_retry", "fallback_disabled", "hedge_delay", "priority_class", "tpm_limit", "concurrency_limit",
+		"budget_period", "budget_limit").Updates(token).Error
 	return err
 }
 
@@ -280,6 +292,10 @@ func IncreaseTokenQuota(id int, key string, quota int) (err error) {
 			if err != nil {
 				common.SysLog("failed to increase token quota: " + err.Error())
 			}
+			err = cacheIncrTokenBudgetUsed(key, -int64(quota))
+			if err != nil {
+				common.SysLog("failed to decrease token budget used: " + err.Error())
+			}
 		})
 	}
 	if common.BatchUpdateEnabled {
@@ -294,6 +310,7 @@ func increaseTokenQuota(id int, quota int) (err error) {
 		map[string]interface{}{
 			"remain_quota":  gorm.Expr("remain_quota + ?", quota),
 			"used_quota":    gorm.Expr("used_quota - ?", quota),
+			"budget_used":   gorm.Expr("budget_used - ?", quota),
 			"accessed_time": common.GetTimestamp(),
 		},
 	).Error
@@ -310,6 +327,10 @@ func DecreaseTokenQuota(id int, key string, quota int) (err error) {
 			if err != nil {
 				common.SysLog("failed to decrease token quota: " + err.Error())
 			}
+			err = cacheIncrTokenBudgetUsed(key, int64(quota))
+			if err != nil {
+				common.SysLog("failed to increase token budget used: " + err.Error())
+			}
 		})
 	}
 	if common.BatchUpdateEnabled {
@@ -324,6 +345,7 @@ func decreaseTokenQuota(id int, quota int) (err error) {
 		map[string]interface{}{
 			"remain_quota":  gorm.Expr("remain_quota - ?", quota),
 			"used_quota":    gorm.Expr("used_quota + ?", quota),
+			"budget_used":   gorm.Expr("budget_used + ?", quota),
 			"accessed_time": common.GetTimestamp(),
 		},
 	).Error
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+)
+
+const (
+	TokenBudgetPeriodDaily   = "daily"
+	TokenBudgetPeriodMonthly = "monthly"
+)
+
+func IsValidTokenBudgetPeriod(period string) bool {
+	return period == "" || period == TokenBudgetPeriodDaily || period == TokenBudgetPeriodMonthly
+}
+
+// GetTokenBudgetPeriodStart 返回 now 所在预算周期的开始时间（服务器本地时区），未设置周期时返回 0
+func GetTokenBudgetPeriodStart(period string, now time.Time) int64 {
+	year, month, day := now.Date()
+	switch period {
+	case TokenBudgetPeriodDaily:
+		return time.Date(year, month, day, 0, 0, 0, 0, now.Location()).Unix()
+	case TokenBudgetPeriodMonthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, now.Location()).Unix()
+	}
+	return 0
+}
+
+func (token *Token) HasBudget() bool {
+	return token.BudgetPeriod != "" && token.BudgetLimit > 0
+}
+
+// ResetTokenBudgetIfNeeded 进入新的预算周期时清零已用预算，返回当前周期内已使用的额度
+func ResetTokenBudgetIfNeeded(token *Token) (int, error) {
+	periodStart := GetTokenBudgetPeriodStart(token.BudgetPeriod, time.Now())
+	if token.BudgetPeriodStart >= periodStart {
+		return max(token.BudgetUsed, 0), nil
+	}
+	// 仅在周期开始时间仍早于本周期时更新，避免多个请求重复清零
+	result := DB.Model(&Token{}).Where("id = ? AND budget_period_start < ?", token.Id, periodStart).Updates(
+		map[string]interface{}{
+			"budget_used":         0,
+			"budget_period_start": periodStart,
+		},
+	)
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	token.BudgetUsed = 0
+	token.BudgetPeriodStart = periodStart
+	// 其他请求已完成清零时不再重复清零缓存，避免覆盖之后累加的用量
+	if common.RedisEnabled && result.RowsAffected > 0 {
+		// 同步更新缓存，避免异步清零覆盖随后的用量累加
+		err := cacheSetTokenField(token.Key, constant.TokenFieldBudgetUsed, "0")
+		if err == nil {
+			err = cacheSetTokenField(token.Key, constant.TokenFieldBudgetPeriodStart, strconv.FormatInt(periodStart, 10))
+		}
+		if err != nil {
+			common.SysLog("failed to reset token budget cache: " + err.Error())
+		}
+	}
+	return 0, nil
+}
+
+// ResetTokenBudget 预算额度或周期变更后清零已用预算，只更新预算相关字段
+func ResetTokenBudget(token *Token) error {
+	err := DB.Model(&Token{}).Where("id = ?", token.Id).UpdateColumns(
+		map[string]interface{}{
+			"budget_used":         0,
+			"budget_period_start": 0,
+		},
+	).Error
+	if err != nil {
+		return err
+	}
+	token.BudgetUsed = 0
+	token.BudgetPeriodStart = 0
+	if common.RedisEnabled {
+		err = cacheSetTokenField(token.Key, constant.TokenFieldBudgetUsed, "0")
+		if err == nil {
+			err = cacheSetTokenField(token.Key, constant.TokenFieldBudgetPeriodStart, "0")
+		}
+		if err != nil {
+			common.SysLog("failed to reset token budget cache: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGetTokenBudgetPeriodStart(t *testing.T) {
+	loc := time.FixedZone("UTC+8", 8*3600)
+	tests := []struct {
+		name   string
+		period string
+		now    time.Time
+		want   time.Time
+	}{
+		{"daily", TokenBudgetPeriodDaily, time.Date(2026, 3, 15, 13, 30, 0, 0, loc), time.Date(2026, 3, 15, 0, 0, 0, 0, loc)},
+		{"daily at midnight", TokenBudgetPeriodDaily, time.Date(2026, 3, 15, 0, 0, 0, 0, loc), time.Date(2026, 3, 15, 0, 0, 0, 0, loc)},
+		{"daily before midnight", TokenBudgetPeriodDaily, time.Date(2026, 3, 14, 23, 59, 59, 0, loc), time.Date(2026, 3, 14, 0, 0, 0, 0, loc)},
+		{"monthly", TokenBudgetPeriodMonthly, time.Date(2026, 3, 15, 13, 30, 0, 0, loc), time.Date(2026, 3, 1, 0, 0, 0, 0, loc)},
+		{"monthly last second", TokenBudgetPeriodMonthly, time.Date(2026, 2, 28, 23, 59, 59, 0, loc), time.Date(2026, 2, 1, 0, 0, 0, 0, loc)},
+		{"monthly new year", TokenBudgetPeriodMonthly, time.Date(2027, 1, 1, 0, 0, 1, 0, loc), time.Date(2027, 1, 1, 0, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := GetTokenBudgetPeriodStart(tt.period, tt.now); got != tt.want.Unix() {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, time.Unix(got, 0).In(loc))
+		}
+	}
+	if got := GetTokenBudgetPeriodStart("", time.Now()); got != 0 {
+		t.Fatalf("expected 0 without budget period, got %d", got)
+	}
+}
+
+func TestResetTokenBudgetIfNeeded(t *testing.T) {
+	setupTestDB(t, &Token{})
+	now := time.Now()
+	dayStart := GetTokenBudgetPeriodStart(TokenBudgetPeriodDaily, now)
+	monthStart := GetTokenBudgetPeriodStart(TokenBudgetPeriodMonthly, now)
+	tests := []struct {
+		name        string
+		period      string
+		periodStart int64
+		used        int
+		want        int
+		wantStart   int64
+	}{
+		{"same day", TokenBudgetPeriodDaily, dayStart, 300, 300, dayStart},
+		{"previous day", TokenBudgetPeriodDaily, dayStart - 86400, 300, 0, dayStart},
+		{"never reset", TokenBudgetPeriodDaily, 0, 300, 0, dayStart},
+		{"same month", TokenBudgetPeriodMonthly, monthStart, 500, 500, monthStart},
+		{"previous month", TokenBudgetPeriodMonthly, monthStart - 86400, 500, 0, monthStart},
+		{"refund below zero", TokenBudgetPeriodDaily, dayStart, -20, 0, dayStart},
+	}
+	for i, tt := range tests {
+		token := &Token{
+			Key:               "budget-test-" + string(rune('a'+i)),
+			Name:              tt.name,
+			BudgetPeriod:      tt.period,
+			BudgetLimit:       1000,
+			BudgetUsed:        tt.used,
+			BudgetPeriodStart: tt.periodStart,
+		}
+		if err := DB.Create(token).Error; err != nil {
+			t.Fatal(err)
+		}
+		used, err := ResetTokenBudgetIfNeeded(token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if used != tt.want || token.BudgetPeriodStart != tt.wantStart {
+			t.Fatalf("%s: expected used %d from %d, got %d from %d", tt.name, tt.want, tt.wantStart, used, token.BudgetPeriodStart)
+		}
+		var stored Token
+		if err = DB.First(&stored, token.Id).Error; err != nil {
+			t.Fatal(err)
+		}
+		if stored.BudgetPeriodStart != tt.wantStart || (tt.want == 0 && tt.used > 0 && stored.BudgetUsed != 0) {
+			t.Fatalf("%s: stored budget not reset, used %d from %d", tt.name, stored.BudgetUsed, stored.BudgetPeriodStart)
+		}
+	}
+}
+
+func TestResetTokenBudgetOnce(t *testing.T) {
+	setupTestDB(t, &Token{})
+	dayStart := GetTokenBudgetPeriodStart(TokenBudgetPeriodDaily, time.Now())
+	token := &Token{Key: "budget-test-once", BudgetPeriod: TokenBudgetPeriodDaily, BudgetLimit: 1000, BudgetUsed: 300, BudgetPeriodStart: dayStart - 86400}
+	if err := DB.Create(token).Error; err != nil {
+		t.Fatal(err)
+	}
+	// 另一个请求持有的旧数据在清零后累加了用量，不能再次清零
+	stale := *token
+	if _, err := ResetTokenBudgetIfNeeded(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Model(&Token{}).Where("id = ?", token.Id).Update("budget_used", 200).Error; err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResetTokenBudgetIfNeeded(&stale); err != nil {
+		t.Fatal(err)
+	}
+	var stored Token
+	if err := DB.First(&stored, token.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stored.BudgetUsed != 200 {
+		t.Fatalf("expected usage after the reset to be kept, got %d", stored.BudgetUsed)
+	}
+}
+
+func TestTokenUpdateKeepsBudgetUsage(t *testing.T) {
+	setupTestDB(t, &Token{})
+	token := &Token{Key: "budget-update", Name: "budget", BudgetPeriod: TokenBudgetPeriodDaily, BudgetLimit: 1000, BudgetUsed: 100, BudgetPeriodStart: 1}
+	if err := DB.Create(token).Error; err != nil {
+		t.Fatal(err)
+	}
+	stale := *token
+	if err := DB.Model(&Token{}).Where("id = ?", token.Id).Update("budget_used", 400).Error; err != nil {
+		t.Fatal(err)
+	}
+	stale.Name = "renamed"
+	if err := stale.Update(); err != nil {
+		t.Fatal(err)
+	}
+	var got Token
+	if err := DB.First(&got, token.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "renamed" || got.BudgetUsed != 400 {
+		t.Fatalf("expected renamed token with budget used 400, got %q and %d", got.Name, got.BudgetUsed)
+	}
+	if err := ResetTokenBudget(&stale); err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.First(&got, token.Id).Error; err != nil {
+		t.Fatal(err)
+	}
+	if got.BudgetUsed != 0 || got.BudgetPeriodStart != 0 {
+		t.Fatalf("expected budget reset, got used %d and period start %d", got.BudgetUsed, got.BudgetPeriodStart)
+	}
+}
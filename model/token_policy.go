@@ -0,0 +1,251 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+var tokenPolicyLocations sync.Map
+
+// GetPolicy 读取令牌访问策略，兼容旧版本只填写了 AllowIps 的令牌
+func (token *Token) GetPolicy() dto.TokenPolicy {
+	policy := dto.TokenPolicy{}
+	if token.Policy != nil && *token.Policy != "" {
+		if err := common.UnmarshalJsonStr(*token.Policy, &policy); err != nil {
+			common.SysLog(fmt.Sprintf("failed to unmarshal token policy: token_id=%d, error=%v", token.Id, err))
+		}
+	}
+	if len(policy.AllowIps) == 0 {
+		policy.AllowIps = token.getLegacyIpLimits()
+	}
+	return policy
+}
+
+func (token *Token) SetPolicy(policy dto.TokenPolicy) {
+	policyBytes, err := common.Marshal(policy)
+	if err != nil {
+		common.SysLog(fmt.Sprintf("failed to marshal token policy: token_id=%d, error=%v", token.Id, err))
+		return
+	}
+	token.Policy = common.GetPointer[string](string(policyBytes))
+}
+
+// NormalizePolicy 校验并整理令牌访问策略，同时将旧版 AllowIps 迁移到策略中
+func (token *Token) NormalizePolicy() error {
+	policy := dto.TokenPolicy{}
+	if token.Policy != nil && *token.Policy != "" {
+		if err := common.UnmarshalJsonStr(*token.Policy, &policy); err != nil {
+			return errors.New("令牌访问策略格式错误")
+		}
+	}
+	if len(policy.AllowIps) == 0 {
+		policy.AllowIps = token.getLegacyIpLimits()
+	}
+	if err := validateTokenPolicy(&policy); err != nil {
+		return err
+	}
+	token.SetPolicy(policy)
+	token.AllowIps = common.GetPointer[string]("")
+	return nil
+}
+
+func trimTokenPolicyList(list []string) []string {
+	result := make([]string, 0, len(list))
+	for _, item := range list {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func parseTokenPolicyClock(value string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(value))
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间 %s，格式应为 HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func getTokenPolicyLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := tokenPolicyLocations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, err
+	}
+	tokenPolicyLocations.Store(name, loc)
+	return loc, nil
+}
+
+func validateTokenPolicy(policy *dto.TokenPolicy) error {
+	policy.AllowIps = trimTokenPolicyList(policy.AllowIps)
+	for _, ip := range policy.AllowIps {
+		if _, _, err := net.ParseCIDR(ip); err != nil && net.ParseIP(ip) == nil {
+			return fmt.Errorf("无效的 IP 或 CIDR：%s", ip)
+		}
+	}
+	policy.AllowReferers = trimTokenPolicyList(policy.AllowReferers)
+	policy.AllowEndpoints = trimTokenPolicyList(policy.AllowEndpoints)
+	for _, endpoint := range policy.AllowEndpoints {
+		if !strings.HasPrefix(endpoint, "/") {
+			return fmt.Errorf("无效的接口路径 %s，需以 / 开头", endpoint)
+		}
+	}
+	policy.TimeZone = strings.TrimSpace(policy.TimeZone)
+	if _, err := getTokenPolicyLocation(policy.TimeZone); err != nil {
+		return fmt.Errorf("无效的时区：%s", policy.TimeZone)
+	}
+	for i := range policy.TimeWindows {
+		window := &policy.TimeWindows[i]
+		start, err := parseTokenPolicyClock(window.Start)
+		if err != nil {
+			return err
+		}
+		end, err := parseTokenPolicyClock(window.End)
+		if err != nil {
+			return err
+		}
+		if start == end {
+			return errors.New("时间段的开始时间与结束时间不能相同")
+		}
+		for _, weekday := range window.Weekdays {
+			if weekday < 0 || weekday > 6 {
+				return errors.New("星期取值应为 0-6，0 表示周日")
+			}
+		}
+		window.Start = strings.TrimSpace(window.Start)
+		window.End = strings.TrimSpace(window.End)
+	}
+	return nil
+}
+
+// matchTokenPolicyPattern 大小写不敏感的通配匹配，* 匹配任意字符
+func matchTokenPolicyPattern(pattern string, value string) bool {
+	parts := strings.Split(strings.ToLower(pattern), "*")
+	value = strings.ToLower(value)
+	if len(parts) == 1 {
+		return parts[0] == value
+	}
+	if !strings.HasPrefix(value, parts[0]) {
+		return false
+	}
+	value = value[len(parts[0]):]
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		idx := strings.Index(value, part)
+		if idx < 0 {
+			return false
+		}
+		value = value[idx+len(part):]
+	}
+	return strings.HasSuffix(value, last)
+}
+
+// TokenPolicyAllowsIp 检查客户端 IP 是否在允许的 IP 或 CIDR 列表中
+func TokenPolicyAllowsIp(policy *dto.TokenPolicy, ip net.IP) bool {
+	return len(policy.AllowIps) == 0 || common.IsIpInCIDRList(ip, policy.AllowIps)
+}
+
+// TokenPolicyAllowsReferer 优先检查 Origin，没有时使用 Referer 的来源；
+// 带 :// 的规则匹配完整来源（如 https://app.example.com），否则只匹配主机名
+func TokenPolicyAllowsReferer(policy *dto.TokenPolicy, origin string, referer string) bool {
+	if len(policy.AllowReferers) == 0 {
+		return true
+	}
+	if origin == "" || origin == "null" {
+		u, err := url.Parse(referer)
+		if err != nil || u.Host == "" {
+			return false
+		}
+		origin = u.Scheme + "://" + u.Host
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	origin = u.Scheme + "://" + u.Host
+	for _, pattern := range policy.AllowReferers {
+		target := u.Hostname()
+		if strings.Contains(pattern, "://") {
+			target = origin
+		}
+		if matchTokenPolicyPattern(strings.TrimRight(pattern, "/"), target) {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenPolicyAllowsTime 检查当前时间是否在允许的时间段内
+func TokenPolicyAllowsTime(policy *dto.TokenPolicy, now time.Time) bool {
+	if len(policy.TimeWindows) == 0 {
+		return true
+	}
+	loc, err := getTokenPolicyLocation(policy.TimeZone)
+	if err != nil {
+		return false
+	}
+	now = now.In(loc)
+	minute := now.Hour()*60 + now.Minute()
+	weekday := int(now.Weekday())
+	yesterday := (weekday + 6) % 7
+	for _, window := range policy.TimeWindows {
+		start, err1 := parseTokenPolicyClock(window.Start)
+		end, err2 := parseTokenPolicyClock(window.End)
+		if err1 != nil || err2 != nil {
+			continue
+		}
+		if start < end {
+			if minute >= start && minute < end && tokenPolicyHasWeekday(window.Weekdays, weekday) {
+				return true
+			}
+			continue
+		}
+		// 跨越午夜的时间段，午夜后的部分属于前一天的时间段
+		if minute >= start && tokenPolicyHasWeekday(window.Weekdays, weekday) {
+			return true
+		}
+		if minute < end && tokenPolicyHasWeekday(window.Weekdays, yesterday) {
+			return true
+		}
+	}
+	return false
+}
+
+func tokenPolicyHasWeekday(weekdays []int, weekday int) bool {
+	if len(weekdays) == 0 {
+		return true
+	}
+	for _, day := range weekdays {
+		if day == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// TokenPolicyAllowsEndpoint 检查请求路径是否在允许的接口列表中
+func TokenPolicyAllowsEndpoint(policy *dto.TokenPolicy, path string) bool {
+	if len(policy.AllowEndpoints) == 0 {
+		return true
+	}
+	for _, pattern := range policy.AllowEndpoints {
+		if matchTokenPolicyPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
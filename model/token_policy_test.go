@@ -0,0 +1,121 @@
+package model
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/dto"
+)
+
+func TestTokenPolicyAllowsTime(t *testing.T) {
+	// 2026-10-16 为周五
+	at := func(day int, hour int, minute int) time.Time {
+		return time.Date(2026, 10, day, hour, minute, 0, 0, time.UTC)
+	}
+	friday := []dto.TokenTimeWindow{{Weekdays: []int{5}, Start: "22:00", End: "02:00"}}
+	sunday := []dto.TokenTimeWindow{{Weekdays: []int{0}, Start: "22:00", End: "02:00"}}
+	daily := []dto.TokenTimeWindow{{Start: "09:00", End: "18:00"}}
+	tests := []struct {
+		name     string
+		windows  []dto.TokenTimeWindow
+		timeZone string
+		now      time.Time
+		want     bool
+	}{
+		{"no windows", nil, "UTC", at(16, 3, 0), true},
+		{"daytime start", daily, "UTC", at(16, 9, 0), true},
+		{"daytime end is exclusive", daily, "UTC", at(16, 18, 0), false},
+		{"before midnight", friday, "UTC", at(16, 23, 0), true},
+		{"before start", friday, "UTC", at(16, 21, 59), false},
+		{"after midnight belongs to friday", friday, "UTC", at(17, 1, 59), true},
+		{"midnight end is exclusive", friday, "UTC", at(17, 2, 0), false},
+		{"after midnight belongs to thursday", friday, "UTC", at(16, 1, 0), false},
+		{"saturday night", friday, "UTC", at(17, 23, 0), false},
+		{"monday after midnight belongs to sunday", sunday, "UTC", at(19, 1, 0), true},
+		{"sunday after midnight belongs to saturday", sunday, "UTC", at(18, 1, 0), false},
+		{"time zone inside window", daily, "Asia/Shanghai", at(16, 2, 0), true},
+		{"time zone outside window", daily, "Asia/Shanghai", at(16, 11, 0), false},
+		{"invalid time zone", daily, "Invalid/Zone", at(16, 10, 0), false},
+	}
+	for _, tt := range tests {
+		policy := &dto.TokenPolicy{TimeWindows: tt.windows, TimeZone: tt.timeZone}
+		if got := TokenPolicyAllowsTime(policy, tt.now); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestTokenPolicyAllowsIp(t *testing.T) {
+	policy := &dto.TokenPolicy{AllowIps: []string{"2001:db8::/32", "10.0.0.0/8", "::1"}}
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"2001:db8:1::5", true},
+		{"2001:db9::1", false},
+		{"::1", true},
+		{"::2", false},
+		{"10.1.2.3", true},
+		{"::ffff:10.1.2.3", true},
+		{"192.168.1.1", false},
+	}
+	for _, tt := range tests {
+		if got := TokenPolicyAllowsIp(policy, net.ParseIP(tt.ip)); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.ip, tt.want, got)
+		}
+	}
+	if !TokenPolicyAllowsIp(&dto.TokenPolicy{}, net.ParseIP("192.168.1.1")) {
+		t.Fatal("expected empty ip list to allow all")
+	}
+}
+
+func TestTokenPolicyAllowsReferer(t *testing.T) {
+	policy := &dto.TokenPolicy{AllowReferers: []string{"https://*.example.com/", "app.test.com"}}
+	tests := []struct {
+		name    string
+		origin  string
+		referer string
+		want    bool
+	}{
+		{"wildcard subdomain", "https://api.example.com", "", true},
+		{"wildcard nested subdomain", "https://a.b.example.com", "", true},
+		{"wildcard requires subdomain", "https://example.com", "", false},
+		{"wildcard scheme mismatch", "http://api.example.com", "", false},
+		{"wildcard suffix spoof", "https://api.example.com.evil.com", "", false},
+		{"host rule ignores scheme and port", "http://APP.test.com:8443", "", true},
+		{"host rule mismatch", "https://app.test.com.cn", "", false},
+		{"referer fallback", "", "https://app.test.com/page?q=1", true},
+		{"null origin uses referer", "null", "https://api.example.com/path", true},
+		{"origin wins over referer", "https://evil.com", "https://app.test.com/", false},
+		{"no origin or referer", "", "", false},
+	}
+	for _, tt := range tests {
+		if got := TokenPolicyAllowsReferer(policy, tt.origin, tt.referer); got != tt.want {
+			t.Fatalf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestValidateTokenPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  dto.TokenPolicy
+		wantErr bool
+	}{
+		{"valid", dto.TokenPolicy{AllowIps: []string{" 2001:db8::/32 ", "1.2.3.4"}, AllowEndpoints: []string{"/v1/*"}, TimeZone: "UTC",
+			TimeWindows: []dto.TokenTimeWindow{{Weekdays: []int{0, 6}, Start: "22:00", End: "02:00"}}}, false},
+		{"invalid cidr", dto.TokenPolicy{AllowIps: []string{"2001:db8::/129"}}, true},
+		{"endpoint without slash", dto.TokenPolicy{AllowEndpoints: []string{"v1/chat"}}, true},
+		{"invalid time zone", dto.TokenPolicy{TimeZone: "Invalid/Zone"}, true},
+		{"invalid clock", dto.TokenPolicy{TimeWindows: []dto.TokenTimeWindow{{Start: "24:00", End: "02:00"}}}, true},
+		{"empty window", dto.TokenPolicy{TimeWindows: []dto.TokenTimeWindow{{Start: "08:00", End: "08:00"}}}, true},
+		{"invalid weekday", dto.TokenPolicy{TimeWindows: []dto.TokenTimeWindow{{Weekdays: []int{7}, Start: "08:00", End: "09:00"}}}, true},
+	}
+	for _, tt := range tests {
+		err := validateTokenPolicy(&tt.policy)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+}
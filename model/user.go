@@ -47,6 +47,9 @@ type User struct {
 	Setting          string         `json:"setting" gorm:"type:text;column:setting"`
 	Remark           string         `json:"remark,omitempty" gorm:"type:varchar(255)" validate:"max=255"`
 	StripeCustomer   string         `json:"stripe_customer" gorm:"type:varchar(64);column:stripe_customer;index"`
+	TPMLimit         int            `json:"tpm_limit" gorm:"type:int;default:0;column:tpm_limit"`                 // 每分钟 token 数限制，0 表示不限制
+	ConcurrencyLimit int            `json:"concurrency_limit" gorm:"type:int;default:0;column:concurrency_limit"` // 并发请求数限制，0 表示不限制
+	AdminRole        string         `json:"admin_role" gorm:"type:varchar(32);default:''"`                        // 管理角色，为空表示拥有全部管理权限
 }
 
 func (user *User) ToBaseUser() *UserBase {
@@ -58,6 +61,9 @@ func (user *User) ToBaseUser() *UserBase {
 		Username: user.Username,
 		Setting:  user.Setting,
 		Email:    user.Email,
+
+		TPMLimit:         user.TPMLimit,
+		ConcurrencyLimit: user.ConcurrencyLimit,
 	}
 	return cache
 }
@@ -81,10 +87,15 @@ func (user *User) GetSetting() dto.UserSetting {
 			common.SysLog("failed to unmarshal setting: " + err.Error())
 		}
 	}
+	decryptUserSettingSecrets(&setting)
 	return setting
 }
 
 func (user *User) SetSetting(setting dto.UserSetting) {
+	if err := encryptUserSettingSecrets(&setting); err != nil {
+		common.SysLog("failed to encrypt setting: " + err.Error())
+		return
+	}
 	settingBytes, err := json.Marshal(setting)
 	if err != nil {
 		common.SysLog("failed to marshal setting: " + err.Error())
@@ -116,9 +127,10 @@ func generateDefaultSidebarConfigForRole(userRole int) string {
 
 	// 个人中心区域 - 所有用户都可以访问
 	defaultConfig["personal"] = map[string]interface{}{
-		"enabled":  true,
-		"topup":    true,
-		"personal": true,
+		"enabled":      true,
+		"topup":        true,
+		"organization": true,
+		"personal":     true,
 	}
 
 	// 管理员区域 - 根据角色决定
@@ -463,6 +475,9 @@ func (user *User) Edit(updatePassword bool) error {
 		"group":        newUser.Group,
 		"quota":        newUser.Quota,
 		"remark":       newUser.Remark,
+
+		"tpm_limit":         newUser.TPMLimit,
+		"concurrency_limit": newUser.ConcurrencyLimit,
 	}
 	if updatePassword {
 		updates["password"] = newUser.Password
@@ -624,6 +639,18 @@ func IsAdmin(userId int) bool {
 	return user.Role >= common.RoleAdminUser
 }
 
+// GetUserRoles 返回用户的角色与管理角色，用于校验管理权限
+func GetUserRoles(userId int) (role int, adminRole string, err error) {
+	var user User
+	err = DB.Where("id = ?", userId).Select("role", "admin_role").First(&user).Error
+	return user.Role, user.AdminRole, err
+}
+
+// SetUserAdminRole 设置管理员的管理角色
+func SetUserAdminRole(userId int, adminRole string) error {
+	return DB.Model(&User{}).Where("id = ?", userId).Update("admin_role", adminRole).Error
+}
+
 //// IsUserEnabled checks user status from Redis first, falls back to DB if needed
 //func IsUserEnabled(id int, fromDB bool) (status bool, err error) {
 //	defer func() {
@@ -879,6 +906,14 @@ func updateUserRequestCount(id int, count int) {
 	}
 }
 
+func GetUserIdByUsername(username string) (id int, err error) {
+	if username == "" {
+		return 0, errors.New("username 为空！")
+	}
+	err = DB.Model(&User{}).Where("username = ?", username).Select("id").Find(&id).Error
+	return id, err
+}
+
 // GetUsernameById gets username from Redis first, falls back to DB if needed
 func GetUsernameById(id int, fromDB bool) (username string, err error) {
 	defer func() {
@@ -22,6 +22,9 @@ type UserBase struct {
 	Status   int    `json:"status"`
 	Username string `json:"username"`
 	Setting  string `json:"setting"`
+
+	TPMLimit         int `json:"tpm_limit"`
+	ConcurrencyLimit int `json:"concurrency_limit"`
 }
 
 func (user *UserBase) WriteContext(c *gin.Context) {
@@ -31,6 +34,8 @@ func (user *UserBase) WriteContext(c *gin.Context) {
 	common.SetContextKey(c, constant.ContextKeyUserEmail, user.Email)
 	common.SetContextKey(c, constant.ContextKeyUserName, user.Username)
 	common.SetContextKey(c, constant.ContextKeyUserSetting, user.GetSetting())
+	common.SetContextKey(c, constant.ContextKeyUserTPMLimit, user.TPMLimit)
+	common.SetContextKey(c, constant.ContextKeyUserConcurrencyLimit, user.ConcurrencyLimit)
 }
 
 func (user *UserBase) GetSetting() dto.UserSetting {
@@ -41,6 +46,7 @@ func (user *UserBase) GetSetting() dto.UserSetting {
 			common.SysLog("failed to unmarshal setting: " + err.Error())
 		}
 	}
+	decryptUserSettingSecrets(&setting)
 	return setting
 }
 
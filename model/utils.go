@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/metrics"
 
 	"github.com/bytedance/gopkg/util/gopool"
 	"gorm.io/gorm"
@@ -17,9 +18,15 @@ const (
 	BatchUpdateTypeUsedQuota
 	BatchUpdateTypeChannelUsedQuota
 	BatchUpdateTypeRequestCount
+	BatchUpdateTypeOrganizationQuota
+	BatchUpdateTypeOrganizationMemberUsedQuota
 	BatchUpdateTypeCount // if you add a new type, you need to add a new map and a new lock
 )
 
+// 批量更新类型名称，用于监控指标
+var batchUpdateTypeNames = []string{"user_quota", "token_quota", "used_quota", "channel_used_quota", "request_count",
+	"organization_quota", "organization_member_used_quota"}
+
 var batchUpdateStores []map[int]int
 var batchUpdateLocks []sync.Mutex
 
@@ -47,6 +54,7 @@ func addNewRecord(type_ int, id int, value int) {
 	} else {
 		batchUpdateStores[type_][id] += value
 	}
+	metrics.SetBatchUpdateQueueDepth(batchUpdateTypeNames[type_], len(batchUpdateStores[type_]))
 }
 
 func batchUpdate() {
@@ -71,6 +79,7 @@ func batchUpdate() {
 		batchUpdateLocks[i].Lock()
 		store := batchUpdateStores[i]
 		batchUpdateStores[i] = make(map[int]int)
+		metrics.SetBatchUpdateQueueDepth(batchUpdateTypeNames[i], 0)
 		batchUpdateLocks[i].Unlock()
 		// TODO: maybe we can combine updates with same key?
 		for key, value := range store {
@@ -91,6 +100,16 @@ func batchUpdate() {
 				updateUserRequestCount(key, value)
 			case BatchUpdateTypeChannelUsedQuota:
 				updateChannelUsedQuota(key, value)
+			case BatchUpdateTypeOrganizationQuota:
+				err := updateOrganizationUsedQuota(key, value)
+				if err != nil {
+					common.SysLog("failed to batch update organization quota: " + err.Error())
+				}
+			case BatchUpdateTypeOrganizationMemberUsedQuota:
+				err := updateOrganizationMemberUsedQuota(key, value)
+				if err != nil {
+					common.SysLog("failed to batch update organization member used quota: " + err.Error())
+				}
 			}
 		}
 	}
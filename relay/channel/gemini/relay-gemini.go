@@ -1256,6 +1256,11 @@ func GeminiChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.R
 			return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
 		}
 		responseBody = claudeRespStr
+	case types.RelayFormatOpenAIResponses:
+		responseBody, err = common.Marshal(service.ResponseOpenAI2Responses(fullTextResponse, info))
+		if err != nil {
+			return nil, types.NewError(err, types.ErrorCodeBadResponseBody)
+		}
 	case types.RelayFormatGemini:
 		break
 	}
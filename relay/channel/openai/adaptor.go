@@ -600,7 +600,14 @@ func (a *Adaptor) DoResponse(c *gin.Context, resp *http.Response, info *relaycom
 	case relayconstant.RelayModeRerank:
 		usage, err = common_handler.RerankHandler(c, info, resp)
 	case relayconstant.RelayModeResponses:
-		if info.IsStream {
+		if info.RelayFormat == types.RelayFormatOpenAI {
+			// Chat Completions 请求经 Responses API 转发，需要转换回 Chat Completions 格式
+			if info.IsStream {
+				usage, err = OaiResponsesToChatStreamHandler(c, info, resp)
+			} else {
+				usage, err = OaiResponsesToChatHandler(c, info, resp)
+			}
+		} else if info.IsStream {
 			usage, err = OaiResponsesStreamHandler(c, info, resp)
 		} else {
 			usage, err = OaiResponsesHandler(c, info, resp)
@@ -29,6 +29,8 @@ func HandleStreamFormat(c *gin.Context, info *relaycommon.RelayInfo, data string
 		return handleClaudeFormat(c, data, info)
 	case types.RelayFormatGemini:
 		return handleGeminiFormat(c, data, info)
+	case types.RelayFormatOpenAIResponses:
+		return handleResponsesFormat(c, data, info)
 	}
 	return nil
 }
@@ -75,6 +77,19 @@ func handleGeminiFormat(c *gin.Context, data string, info *relaycommon.RelayInfo
 	return nil
 }
 
+func handleResponsesFormat(c *gin.Context, data string, info *relaycommon.RelayInfo) error {
+	var streamResponse dto.ChatCompletionsStreamResponse
+	if err := common.Unmarshal(common.StringToByteSlice(data), &streamResponse); err != nil {
+		logger.LogError(c, "failed to unmarshal stream response: "+err.Error())
+		return err
+	}
+
+	for _, event := range service.StreamResponseOpenAI2Responses(&streamResponse, info) {
+		_ = helper.ResponsesData(c, *event)
+	}
+	return nil
+}
+
 func ProcessStreamResponse(streamResponse dto.ChatCompletionsStreamResponse, responseTextBuilder *strings.Builder, toolCount *int) error {
 	for _, choice := range streamResponse.Choices {
 		responseTextBuilder.WriteString(choice.Delta.GetContentString())
@@ -250,6 +265,17 @@ func HandleFinalResponse(c *gin.Context, info *relaycommon.RelayInfo, lastStream
 		// 发送最终的 Gemini 响应
 		c.Render(-1, common.CustomEvent{Data: "data: " + string(geminiResponseStr)})
 		_ = helper.FlushWriter(c)
+
+	case types.RelayFormatOpenAIResponses:
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if err := common.Unmarshal(common.StringToByteSlice(lastStreamData), &streamResponse); err == nil {
+			for _, event := range service.StreamResponseOpenAI2Responses(&streamResponse, info) {
+				_ = helper.ResponsesData(c, *event)
+			}
+		}
+		for _, event := range service.GenerateResponsesCompletedEvents(info, usage) {
+			_ = helper.ResponsesData(c, *event)
+		}
 	}
 }
 
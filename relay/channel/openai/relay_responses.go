@@ -148,3 +148,132 @@ func OaiResponsesStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp
 
 	return usage, nil
 }
+
+// OaiResponsesToChatHandler 将上游 Responses API 非流响应转换为 Chat Completions 响应
+func OaiResponsesToChatHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	defer service.CloseResponseBodyGracefully(resp)
+
+	var responsesResponse dto.OpenAIResponsesResponse
+	responseBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeReadResponseBodyFailed, http.StatusInternalServerError)
+	}
+	err = common.Unmarshal(responseBody, &responsesResponse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	if oaiError := responsesResponse.GetOpenAIError(); oaiError != nil && oaiError.Type != "" {
+		return nil, types.WithOpenAIError(*oaiError, resp.StatusCode)
+	}
+
+	openAIResponse := service.ResponsesResponse2OpenAI(&responsesResponse)
+	openAIResponse.Id = helper.GetResponseID(c)
+	if openAIResponse.Usage.PromptTokens == 0 && openAIResponse.Usage.CompletionTokens == 0 {
+		completionTokens := service.CountTextToken(openAIResponse.Choices[0].StringContent(), info.UpstreamModelName)
+		openAIResponse.Usage = dto.Usage{
+			PromptTokens:     info.GetEstimatePromptTokens(),
+			CompletionTokens: completionTokens,
+			TotalTokens:      info.GetEstimatePromptTokens() + completionTokens,
+		}
+	}
+
+	jsonResponse, err := common.Marshal(openAIResponse)
+	if err != nil {
+		return nil, types.NewOpenAIError(err, types.ErrorCodeBadResponseBody, http.StatusInternalServerError)
+	}
+	service.IOCopyBytesGracefully(c, resp, jsonResponse)
+
+	usage := openAIResponse.Usage
+	return &usage, nil
+}
+
+// OaiResponsesToChatStreamHandler 将上游 Responses API 流式事件转换为 Chat Completions chunk
+func OaiResponsesToChatStreamHandler(c *gin.Context, info *relaycommon.RelayInfo, resp *http.Response) (*dto.Usage, *types.NewAPIError) {
+	if resp == nil || resp.Body == nil {
+		logger.LogError(c, "invalid response or response body")
+		return nil, types.NewError(fmt.Errorf("invalid response"), types.ErrorCodeBadResponse)
+	}
+
+	defer service.CloseResponseBodyGracefully(resp)
+
+	state := service.NewResponsesChatStreamState(helper.GetResponseID(c), info.UpstreamModelName)
+	var responseTextBuilder strings.Builder
+	var streamErr *types.NewAPIError
+
+	helper.StreamScannerHandler(c, resp, info, func(data string) bool {
+		var streamResponse dto.ResponsesStreamResponse
+		if err := common.UnmarshalJsonStr(data, &streamResponse); err != nil {
+			logger.LogError(c, "failed to unmarshal stream response: "+err.Error())
+			return true
+		}
+		switch streamResponse.Type {
+		case dto.ResponsesStreamTypeOutputTextDelta, dto.ResponsesStreamTypeReasoningSummaryDelta, dto.ResponsesStreamTypeFunctionCallArgsDelta:
+			responseTextBuilder.WriteString(streamResponse.Delta)
+		case "response.failed", "error":
+			logger.LogError(c, "upstream responses stream error: "+data)
+			streamErr = responsesStreamError(c, info, &streamResponse, data)
+			return false
+		}
+		chunk := service.StreamResponseResponses2OpenAI(&streamResponse, state)
+		if chunk == nil {
+			return true
+		}
+		info.SendResponseCount++
+		if err := helper.ObjectData(c, chunk); err != nil {
+			logger.LogError(c, "failed to send chat chunk: "+err.Error())
+		}
+		return true
+	})
+	if streamErr != nil {
+		return nil, streamErr
+	}
+
+	usage := state.Usage
+	if usage == nil {
+		usage = &dto.Usage{}
+	}
+	if usage.CompletionTokens == 0 {
+		// 非正常结束，使用输出文本的 token 数量
+		if tempStr := responseTextBuilder.String(); len(tempStr) > 0 {
+			usage.CompletionTokens = service.CountTextToken(tempStr, info.UpstreamModelName)
+		}
+	}
+	if usage.PromptTokens == 0 && usage.CompletionTokens != 0 {
+		usage.PromptTokens = info.GetEstimatePromptTokens()
+	}
+	usage.TotalTokens = usage.PromptTokens + usage.CompletionTokens
+
+	if info.ShouldIncludeUsage {
+		response := helper.GenerateFinalUsageResponse(state.Id, state.Created, state.Model, *usage)
+		helper.ObjectData(c, response)
+	}
+	helper.Done(c)
+
+	return usage, nil
+}
+
+// responsesStreamError 将上游 Responses 流中的 response.failed 与 error 事件转换为错误；
+// 已向客户端输出内容时补发错误 chunk，且不再重试，避免重复输出
+func responsesStreamError(c *gin.Context, info *relaycommon.RelayInfo, event *dto.ResponsesStreamResponse, data string) *types.NewAPIError {
+	var oaiError types.OpenAIError
+	if event.Response != nil {
+		if err := event.Response.GetOpenAIError(); err != nil {
+			oaiError = *err
+		}
+	} else {
+		_ = common.UnmarshalJsonStr(data, &oaiError)
+	}
+	if oaiError.Message == "" {
+		oaiError.Message = "upstream responses stream failed"
+	}
+	if oaiError.Type == "" || oaiError.Type == "error" {
+		oaiError.Type = "upstream_error"
+	}
+	if info.SendResponseCount == 0 {
+		return types.WithOpenAIError(oaiError, http.StatusInternalServerError)
+	}
+	if err := helper.ObjectData(c, map[string]any{"error": oaiError}); err != nil {
+		logger.LogError(c, "failed to send error chunk: "+err.Error())
+	}
+	return types.WithOpenAIError(oaiError, http.StatusInternalServerError, types.ErrOptionWithSkipRetry())
+}
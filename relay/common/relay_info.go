@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
 	"strconv"
 	"strings"
 	"time"
@@ -40,6 +41,37 @@ type ClaudeConvertInfo struct {
 	Done             bool
 }
 
+// ResponsesToolCallState 记录转换为 Responses 流时单个函数调用的状态
+type ResponsesToolCallState struct {
+	ItemId      string
+	CallId      string
+	Name        string
+	Arguments   strings.Builder
+	OutputIndex int
+}
+
+// ResponsesConvertInfo 保存将 Chat Completions 流转换为 Responses 事件时的状态
+type ResponsesConvertInfo struct {
+	ResponseId     string
+	CreatedAt      int64
+	Model          string
+	SequenceNumber int
+	Started        bool
+	Completed      bool
+	OutputIndex    int
+	Output         []dto.ResponsesOutput
+
+	MessageItemId      string
+	MessageOutputIndex int
+	MessageText        strings.Builder
+
+	ReasoningItemId      string
+	ReasoningOutputIndex int
+	ReasoningText        strings.Builder
+
+	ToolCalls map[int]*ResponsesToolCallState
+}
+
 type RerankerInfo struct {
 	Documents       []any
 	ReturnDocuments bool
@@ -88,6 +120,7 @@ type RelayInfo struct {
 	UsingGroup        string // 使用的分组，当auto跨分组重试时，会变动
 	UserGroup         string // 用户所在分组
 	TokenUnlimited    bool
+	OrgId             int // 令牌所属组织，非 0 时从组织额度扣费
 	StartTime         time.Time
 	FirstResponseTime time.Time
 	isFirstResponse   bool
@@ -114,11 +147,22 @@ type RelayInfo struct {
 	UserQuota              int
 	RelayFormat            types.RelayFormat
 	SendResponseCount      int
-	FinalPreConsumedQuota  int  // 最终预消耗的配额
-	IsClaudeBetaQuery      bool // /v1/messages?beta=true
+	FinalPreConsumedQuota  int      // 最终预消耗的配额
+	PromptTokens           int      // 上游返回的输入 token 数，用于限流校正
+	CompletionTokens       int      // 上游返回的输出 token 数，用于渠道统计与限流校正
+	ResponseCacheHit       bool     // 是否命中响应缓存，命中时未请求上游
+	SensitiveWordsHit      []string // 模型输出中命中的敏感词
+	SensitiveStopped       bool     // 是否因输出命中敏感词而中断输出
+	IsClaudeBetaQuery      bool     // /v1/messages?beta=true
 
 	PriceData types.PriceData
 
+	PIIPlaceholders map[string]string // 脱敏占位符 -> 原始内容，非 nil 表示请求已脱敏
+	PIIRedactions   int               // 请求中被脱敏的内容数量
+
+	PayloadLogEnabled   bool   // 是否记录完整的请求与响应内容
+	UpstreamRequestBody []byte // 发送给上游的请求体，仅在记录完整内容时保存
+
 	Request dto.Request
 
 	ThinkingContentInfo
@@ -126,6 +170,7 @@ type RelayInfo struct {
 	*ClaudeConvertInfo
 	*RerankerInfo
 	*ResponsesUsageInfo
+	*ResponsesConvertInfo
 	*ChannelMeta
 	*TaskRelayInfo
 }
@@ -330,6 +375,9 @@ func GenRelayInfoResponses(c *gin.Context, request *dto.OpenAIResponsesRequest)
 	info.ResponsesUsageInfo = &ResponsesUsageInfo{
 		BuiltInTools: make(map[string]*BuildInToolInfo),
 	}
+	info.ResponsesConvertInfo = &ResponsesConvertInfo{
+		ToolCalls: make(map[int]*ResponsesToolCallState),
+	}
 	if len(request.Tools) > 0 {
 		for _, tool := range request.GetToolsMap() {
 			toolType := common.Interface2String(tool["type"])
@@ -410,6 +458,7 @@ func genBaseRelayInfo(c *gin.Context, request dto.Request) *RelayInfo {
 		TokenKey:       common.GetContextKeyString(c, constant.ContextKeyTokenKey),
 		TokenUnlimited: common.GetContextKeyBool(c, constant.ContextKeyTokenUnlimited),
 		TokenGroup:     tokenGroup,
+		OrgId:          common.GetContextKeyInt(c, constant.ContextKeyTokenOrgId),
 
 		isFirstResponse: true,
 		RelayMode:       relayconstant.Path2RelayMode(c.Request.URL.Path),
@@ -504,6 +553,54 @@ func (info *RelayInfo) HasSendResponse() bool {
 	return info.FirstResponseTime.After(info.StartTime)
 }
 
+// CloneForHedge 复制一份用于对冲请求的 RelayInfo，必须在首个请求开始前调用；
+// 请求体、流式转换状态与工具调用计数各自独立，渠道信息在选择渠道后重新初始化
+func (info *RelayInfo) CloneForHedge() (*RelayInfo, error) {
+	clone := *info
+	clone.ChannelMeta = nil
+	request, err := copyHedgeRequest(info.Request)
+	if err != nil {
+		return nil, err
+	}
+	clone.Request = request
+	if info.ClaudeConvertInfo != nil {
+		claudeConvertInfo := *info.ClaudeConvertInfo
+		clone.ClaudeConvertInfo = &claudeConvertInfo
+	}
+	if info.ResponsesConvertInfo != nil {
+		clone.ResponsesConvertInfo = &ResponsesConvertInfo{
+			ToolCalls: make(map[int]*ResponsesToolCallState),
+		}
+	}
+	if info.ResponsesUsageInfo != nil {
+		builtInTools := make(map[string]*BuildInToolInfo, len(info.ResponsesUsageInfo.BuiltInTools))
+		for name, tool := range info.ResponsesUsageInfo.BuiltInTools {
+			toolCopy := *tool
+			builtInTools[name] = &toolCopy
+		}
+		clone.ResponsesUsageInfo = &ResponsesUsageInfo{BuiltInTools: builtInTools}
+	}
+	if info.PIIPlaceholders != nil {
+		clone.PIIPlaceholders = maps.Clone(info.PIIPlaceholders)
+	}
+	return &clone, nil
+}
+
+// copyHedgeRequest 深拷贝支持对冲的请求，选择渠道时会改写请求中的模型名称
+func copyHedgeRequest(request dto.Request) (dto.Request, error) {
+	switch r := request.(type) {
+	case *dto.GeneralOpenAIRequest:
+		return common.DeepCopy(r)
+	case *dto.OpenAIResponsesRequest:
+		return common.DeepCopy(r)
+	case *dto.ClaudeRequest:
+		return common.DeepCopy(r)
+	case *dto.GeminiChatRequest:
+		return common.DeepCopy(r)
+	}
+	return nil, fmt.Errorf("request type %T does not support hedging", request)
+}
+
 type TaskRelayInfo struct {
 	Action       string
 	OriginTaskID string
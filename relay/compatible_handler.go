@@ -9,11 +9,14 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
 	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/relay/channel"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
 	"github.com/QuantumNous/new-api/relay/helper"
 	"github.com/QuantumNous/new-api/service"
 	"github.com/QuantumNous/new-api/setting/model_setting"
@@ -34,6 +37,15 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		return types.NewErrorWithStatusCode(fmt.Errorf("invalid request type, expected dto.GeneralOpenAIRequest, got %T", info.Request), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	var cacheKey string
+	if service.IsChatRequestCacheable(textReq) {
+		cacheKey = service.GetResponseCacheKey(c, info)
+		if usage := service.ReplayResponseCache(c, info, cacheKey); usage != nil {
+			postConsumeQuota(c, info, usage, "命中响应缓存")
+			return nil
+		}
+	}
+
 	request, err := common.DeepCopy(textReq)
 	if err != nil {
 		return types.NewError(fmt.Errorf("failed to copy request to GeneralOpenAIRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
@@ -132,6 +144,21 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 			}
 		}
 
+		// 上游仅开放 Responses API 时，改写为 Responses 请求
+		if info.ApiType == constant.APITypeOpenAI && info.RelayMode == relayconstant.RelayModeChatCompletions &&
+			info.ChannelOtherSettings.ShouldConvertChatToResponses(info.OriginModelName) {
+			relayMode, requestURLPath := info.RelayMode, info.RequestURLPath
+			// 重试时可能切换到其他渠道，需要恢复原始的 relay mode
+			defer func() {
+				info.RelayMode = relayMode
+				info.RequestURLPath = requestURLPath
+			}()
+			convertedRequest, err = chatToResponsesRequest(c, info, adaptor, convertedRequest)
+			if err != nil {
+				return types.NewError(err, types.ErrorCodeConvertRequestFailed, types.ErrOptionWithSkipRetry())
+			}
+		}
+
 		jsonData, err := common.Marshal(convertedRequest)
 		if err != nil {
 			return types.NewError(err, types.ErrorCodeJsonMarshalFailed, types.ErrOptionWithSkipRetry())
@@ -175,12 +202,15 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 		}
 	}
 
+	capture := service.StartResponseCapture(c, cacheKey)
 	usage, newApiErr := adaptor.DoResponse(c, httpResp, info)
 	if newApiErr != nil {
+		capture.Discard()
 		// reset status code 重置状态码
 		service.ResetStatusCode(newApiErr, statusCodeMappingStr)
 		return newApiErr
 	}
+	capture.Save(info, usage.(*dto.Usage))
 
 	var containAudioTokens = usage.(*dto.Usage).CompletionTokenDetails.AudioTokens > 0 || usage.(*dto.Usage).PromptTokensDetails.AudioTokens > 0
 	var containsAudioRatios = ratio_setting.ContainsAudioRatio(info.OriginModelName) || ratio_setting.ContainsAudioCompletionRatio(info.OriginModelName)
@@ -193,7 +223,24 @@ func TextHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *types
 	return nil
 }
 
+// chatToResponsesRequest 将 Chat Completions 请求转换为 Responses API 请求，并将请求路径切换到 /v1/responses
+func chatToResponsesRequest(c *gin.Context, info *relaycommon.RelayInfo, adaptor channel.Adaptor, convertedRequest any) (any, error) {
+	chatRequest, ok := convertedRequest.(*dto.GeneralOpenAIRequest)
+	if !ok {
+		return nil, fmt.Errorf("invalid request type, expected *dto.GeneralOpenAIRequest, got %T", convertedRequest)
+	}
+	responsesRequest, err := service.OpenAIRequestToResponsesRequest(chatRequest)
+	if err != nil {
+		return nil, err
+	}
+	info.RelayMode = relayconstant.RelayModeResponses
+	info.RequestURLPath = "/v1/responses"
+	return adaptor.ConvertOpenAIResponsesRequest(c, info, *responsesRequest)
+}
+
 func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage, extraContent ...string) {
+	_, span := tracing.Start(ctx.Request.Context(), "quota_settlement")
+	defer span.End()
 	if usage == nil {
 		usage = &dto.Usage{
 			PromptTokens:     relayInfo.GetEstimatePromptTokens(),
@@ -209,6 +256,8 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 	audioTokens := usage.PromptTokensDetails.AudioTokens
 	completionTokens := usage.CompletionTokens
 	cachedCreationTokens := usage.PromptTokensDetails.CachedCreationTokens
+	relayInfo.PromptTokens = promptTokens
+	relayInfo.CompletionTokens = completionTokens
 
 	modelName := relayInfo.OriginModelName
 
@@ -371,6 +420,12 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 	quota := int(quotaCalculateDecimal.Round(0).IntPart())
 	totalTokens := promptTokens + completionTokens
 
+	if !service.ClaimHedgeWin(ctx) {
+		// 对冲请求落选，不向用户计费
+		service.RecordHedgeLoserUsage(ctx, relayInfo, relayInfo.ChannelId, promptTokens, completionTokens, quota, "对冲请求落选，未计费")
+		return
+	}
+
 	//var logContent string
 
 	// record all the consume log even if quota is 0
@@ -382,11 +437,15 @@ func postConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage
 		logger.LogError(ctx, fmt.Sprintf("total tokens is 0, cannot consume quota, userId %d, channelId %d, "+
 			"tokenId %d, model %s， pre-consumed quota %d", relayInfo.UserId, relayInfo.ChannelId, relayInfo.TokenId, modelName, relayInfo.FinalPreConsumedQuota))
 	} else {
-		if !ratio.IsZero() && quota == 0 {
+		// 命中响应缓存时按命中倍率计费，倍率为 0 时不收费，且未请求上游，不计入渠道用量与消费指标
+		if !ratio.IsZero() && quota == 0 && !relayInfo.ResponseCacheHit {
 			quota = 1
 		}
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
-		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		if !relayInfo.ResponseCacheHit {
+			model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+			service.RecordConsumeMetrics(relayInfo, promptTokens, completionTokens, quota)
+		}
 	}
 
 	quotaDelta := quota - relayInfo.FinalPreConsumedQuota
@@ -25,6 +25,15 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		return types.NewErrorWithStatusCode(fmt.Errorf("invalid request type, expected *dto.EmbeddingRequest, got %T", info.Request), types.ErrorCodeInvalidRequest, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
 	}
 
+	var cacheKey string
+	if service.IsEmbeddingRequestCacheable() {
+		cacheKey = service.GetResponseCacheKey(c, info)
+		if usage := service.ReplayResponseCache(c, info, cacheKey); usage != nil {
+			postConsumeQuota(c, info, usage, "命中响应缓存")
+			return nil
+		}
+	}
+
 	request, err := common.DeepCopy(embeddingReq)
 	if err != nil {
 		return types.NewError(fmt.Errorf("failed to copy request to EmbeddingRequest: %w", err), types.ErrorCodeInvalidRequest, types.ErrOptionWithSkipRetry())
@@ -76,12 +85,15 @@ func EmbeddingHelper(c *gin.Context, info *relaycommon.RelayInfo) (newAPIError *
 		}
 	}
 
+	capture := service.StartResponseCapture(c, cacheKey)
 	usage, newAPIError := adaptor.DoResponse(c, httpResp, info)
 	if newAPIError != nil {
+		capture.Discard()
 		// reset status code 重置状态码
 		service.ResetStatusCode(newAPIError, statusCodeMappingStr)
 		return newAPIError
 	}
+	capture.Save(info, usage.(*dto.Usage))
 	postConsumeQuota(c, info, usage.(*dto.Usage))
 	return nil
 }
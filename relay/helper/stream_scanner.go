@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/logger"
 	relaycommon "github.com/QuantumNous/new-api/relay/common"
@@ -40,6 +41,9 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 		return
 	}
 
+	_, span := tracing.Start(c.Request.Context(), "stream")
+	defer span.End()
+
 	// 确保响应体总是被关闭
 	defer func() {
 		if resp.Body != nil {
@@ -230,7 +234,8 @@ func StreamScannerHandler(c *gin.Context, resp *http.Response, info *relaycommon
 
 				select {
 				case success := <-done:
-					if !success {
+					// 输出命中敏感词被中断时不再读取上游
+					if !success || info.SensitiveStopped {
 						return
 					}
 				case <-time.After(10 * time.Second):
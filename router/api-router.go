@@ -1,6 +1,7 @@
 package router
 
 import (
+	"github.com/QuantumNous/new-api/common"
 	"github.com/QuantumNous/new-api/controller"
 	"github.com/QuantumNous/new-api/middleware"
 
@@ -100,12 +101,17 @@ func SetApiRouter(router *gin.Engine) {
 				selfRoute.POST("/checkin", middleware.TurnstileCheck(), controller.DoCheckin)
 			}
 
+			billingRoute := userRoute.Group("/topup")
+			billingRoute.Use(middleware.PermissionAuth(common.PermissionBilling))
+			{
+				billingRoute.GET("", controller.GetAllTopUps)
+				billingRoute.POST("/complete", controller.AdminCompleteTopUp)
+			}
+
 			adminRoute := userRoute.Group("/")
-			adminRoute.Use(middleware.AdminAuth())
+			adminRoute.Use(middleware.PermissionAuth(common.PermissionUser))
 			{
 				adminRoute.GET("/", controller.GetAllUsers)
-				adminRoute.GET("/topup", controller.GetAllTopUps)
-				adminRoute.POST("/topup/complete", controller.AdminCompleteTopUp)
 				adminRoute.GET("/search", controller.SearchUsers)
 				adminRoute.GET("/:id", controller.GetUser)
 				adminRoute.POST("/", controller.CreateUser)
@@ -127,6 +133,19 @@ func SetApiRouter(router *gin.Engine) {
 			optionRoute.POST("/rest_model_ratio", controller.ResetModelRatio)
 			optionRoute.POST("/migrate_console_setting", controller.MigrateConsoleSetting) // 用于迁移检测的旧键，下个版本会删除
 		}
+		configRoute := apiRouter.Group("/config")
+		configRoute.Use(middleware.RootAuth())
+		{
+			configRoute.GET("/export", controller.ExportConfig)
+			configRoute.POST("/export/keys", middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.ExportConfigWithKeys)
+			configRoute.POST("/import", controller.ImportConfig)
+		}
+		secretRoute := apiRouter.Group("/secret")
+		secretRoute.Use(middleware.RootAuth())
+		{
+			secretRoute.GET("/status", controller.GetSecretEncryptionStatus)
+			secretRoute.POST("/rewrap", middleware.CriticalRateLimit(), controller.RewrapSecrets)
+		}
 		storageRoute := apiRouter.Group("/storage")
 		storageRoute.Use(middleware.RootAuth())
 		{
@@ -141,12 +160,13 @@ func SetApiRouter(router *gin.Engine) {
 			ratioSyncRoute.POST("/fetch", controller.FetchUpstreamRatios)
 		}
 		channelRoute := apiRouter.Group("/channel")
-		channelRoute.Use(middleware.AdminAuth())
+		channelRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			channelRoute.GET("/", controller.GetAllChannels)
 			channelRoute.GET("/search", controller.SearchChannels)
 			channelRoute.GET("/models", controller.ChannelListModels)
 			channelRoute.GET("/models_enabled", controller.EnabledListModels)
+			channelRoute.GET("/stats", controller.GetChannelStats)
 			channelRoute.GET("/:id", controller.GetChannel)
 			channelRoute.POST("/:id/key", middleware.RootAuth(), middleware.CriticalRateLimit(), middleware.DisableCache(), middleware.SecureVerificationRequired(), controller.GetChannelKey)
 			channelRoute.GET("/test", controller.TestAllChannels)
@@ -185,6 +205,29 @@ func SetApiRouter(router *gin.Engine) {
 			tokenRoute.POST("/batch", controller.DeleteTokenBatch)
 		}
 
+		orgRoute := apiRouter.Group("/org")
+		orgRoute.GET("/", middleware.PermissionAuth(common.PermissionBilling), controller.GetAllOrganizations)
+		orgRoute.PUT("/", middleware.PermissionAuth(common.PermissionBilling), controller.AdminUpdateOrganization)
+		orgSelfRoute := orgRoute.Group("/self")
+		orgSelfRoute.Use(middleware.UserAuth())
+		{
+			orgSelfRoute.GET("", controller.GetSelfOrganizations)
+			orgSelfRoute.POST("", controller.CreateOrganization)
+			orgSelfRoute.GET("/invitations", controller.GetSelfOrganizationInvitations)
+			orgSelfRoute.POST("/invitations/:id", controller.RespondOrganizationInvitation)
+			orgSelfRoute.PUT("/:id", controller.UpdateOrganization)
+			orgSelfRoute.DELETE("/:id", controller.DeleteOrganization)
+			orgSelfRoute.POST("/:id/transfer", controller.TransferOrganizationQuota)
+			orgSelfRoute.GET("/:id/members", controller.GetOrganizationMembers)
+			orgSelfRoute.PUT("/:id/members", controller.UpdateOrganizationMember)
+			orgSelfRoute.DELETE("/:id/members/:user_id", controller.RemoveOrganizationMember)
+			orgSelfRoute.GET("/:id/invitations", controller.GetOrganizationInvitations)
+			orgSelfRoute.POST("/:id/invitations", controller.CreateOrganizationInvitation)
+			orgSelfRoute.DELETE("/:id/invitations/:invitation_id", controller.CancelOrganizationInvitation)
+			orgSelfRoute.GET("/:id/logs", controller.GetOrganizationLogs)
+			orgSelfRoute.GET("/:id/data", controller.GetOrganizationQuotaDates)
+		}
+
 		usageRoute := apiRouter.Group("/usage")
 		usageRoute.Use(middleware.CriticalRateLimit())
 		{
@@ -196,7 +239,7 @@ func SetApiRouter(router *gin.Engine) {
 		}
 
 		redemptionRoute := apiRouter.Group("/redemption")
-		redemptionRoute.Use(middleware.AdminAuth())
+		redemptionRoute.Use(middleware.PermissionAuth(common.PermissionBilling))
 		{
 			redemptionRoute.GET("/", controller.GetAllRedemptions)
 			redemptionRoute.GET("/search", controller.SearchRedemptions)
@@ -207,30 +250,43 @@ func SetApiRouter(router *gin.Engine) {
 			redemptionRoute.DELETE("/:id", controller.DeleteRedemption)
 		}
 		logRoute := apiRouter.Group("/log")
-		logRoute.GET("/", middleware.AdminAuth(), controller.GetAllLogs)
-		logRoute.DELETE("/", middleware.AdminAuth(), controller.DeleteHistoryLogs)
-		logRoute.GET("/stat", middleware.AdminAuth(), controller.GetLogsStat)
+		logRoute.GET("/", middleware.PermissionAuth(common.PermissionLog), controller.GetAllLogs)
+		logRoute.DELETE("/", middleware.PermissionAuth(common.PermissionLog), controller.DeleteHistoryLogs)
+		logRoute.GET("/stat", middleware.PermissionAuth(common.PermissionLog), controller.GetLogsStat)
 		logRoute.GET("/self/stat", middleware.UserAuth(), controller.GetLogsSelfStat)
-		logRoute.GET("/search", middleware.AdminAuth(), controller.SearchAllLogs)
+		logRoute.GET("/search", middleware.PermissionAuth(common.PermissionLog), controller.SearchAllLogs)
 		logRoute.GET("/self", middleware.UserAuth(), controller.GetUserLogs)
 		logRoute.GET("/self/search", middleware.UserAuth(), controller.SearchUserLogs)
+		logRoute.GET("/payload/:request_id", middleware.PermissionAuth(common.PermissionLog), controller.GetPayloadLog)
+		logRoute.GET("/self/payload/:request_id", middleware.UserAuth(), controller.GetSelfPayloadLog)
 
 		dataRoute := apiRouter.Group("/data")
-		dataRoute.GET("/", middleware.AdminAuth(), controller.GetAllQuotaDates)
+		dataRoute.GET("/", middleware.PermissionAuth(common.PermissionLog), controller.GetAllQuotaDates)
 		dataRoute.GET("/self", middleware.UserAuth(), controller.GetUserQuotaDates)
 
 		logRoute.Use(middleware.CORS())
 		{
 			logRoute.GET("/token", controller.GetLogByKey)
 		}
+		guardrailRoute := apiRouter.Group("/guardrail")
+		guardrailRoute.Use(middleware.PermissionAuth(common.PermissionLog))
+		{
+			guardrailRoute.GET("/logs", controller.GetGuardrailLogs)
+			guardrailRoute.PUT("/logs/:id/review", controller.ReviewGuardrailLog)
+		}
+		auditRoute := apiRouter.Group("/audit")
+		{
+			auditRoute.GET("/", middleware.PermissionAuth(common.PermissionLog), controller.GetAuditVersions)
+			auditRoute.POST("/:id/rollback", middleware.AdminAuth(), controller.RollbackAuditVersion)
+		}
 		groupRoute := apiRouter.Group("/group")
-		groupRoute.Use(middleware.AdminAuth())
+		groupRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			groupRoute.GET("/", controller.GetGroups)
 		}
 
 		prefillGroupRoute := apiRouter.Group("/prefill_group")
-		prefillGroupRoute.Use(middleware.AdminAuth())
+		prefillGroupRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			prefillGroupRoute.GET("/", controller.GetPrefillGroups)
 			prefillGroupRoute.POST("/", controller.CreatePrefillGroup)
@@ -240,16 +296,16 @@ func SetApiRouter(router *gin.Engine) {
 
 		mjRoute := apiRouter.Group("/mj")
 		mjRoute.GET("/self", middleware.UserAuth(), controller.GetUserMidjourney)
-		mjRoute.GET("/", middleware.AdminAuth(), controller.GetAllMidjourney)
+		mjRoute.GET("/", middleware.PermissionAuth(common.PermissionLog), controller.GetAllMidjourney)
 
 		taskRoute := apiRouter.Group("/task")
 		{
 			taskRoute.GET("/self", middleware.UserAuth(), controller.GetUserTask)
-			taskRoute.GET("/", middleware.AdminAuth(), controller.GetAllTask)
+			taskRoute.GET("/", middleware.PermissionAuth(common.PermissionLog), controller.GetAllTask)
 		}
 
 		vendorRoute := apiRouter.Group("/vendors")
-		vendorRoute.Use(middleware.AdminAuth())
+		vendorRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			vendorRoute.GET("/", controller.GetAllVendors)
 			vendorRoute.GET("/search", controller.SearchVendors)
@@ -260,7 +316,7 @@ func SetApiRouter(router *gin.Engine) {
 		}
 
 		modelsRoute := apiRouter.Group("/models")
-		modelsRoute.Use(middleware.AdminAuth())
+		modelsRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			modelsRoute.GET("/sync_upstream/preview", controller.SyncUpstreamPreview)
 			modelsRoute.POST("/sync_upstream", controller.SyncUpstreamModels)
@@ -275,7 +331,7 @@ func SetApiRouter(router *gin.Engine) {
 
 		// Deployments (model deployment management)
 		deploymentsRoute := apiRouter.Group("/deployments")
-		deploymentsRoute.Use(middleware.AdminAuth())
+		deploymentsRoute.Use(middleware.PermissionAuth(common.PermissionChannel))
 		{
 			deploymentsRoute.GET("/settings", controller.GetModelDeploymentSettings)
 			deploymentsRoute.POST("/settings/test-connection", controller.TestIoNetConnection)
@@ -15,6 +15,8 @@ import (
 func SetRouter(router *gin.Engine, buildFS embed.FS, indexPage []byte) {
 	SetApiRouter(router)
 	SetDashboardRouter(router)
+	// 需在 relay 路由之前注册，避免 /metrics 抓取被链路追踪记录
+	SetMetricsRouter(router)
 	SetRelayRouter(router)
 	SetVideoRouter(router)
 	frontendBaseUrl := os.Getenv("FRONTEND_BASE_URL")
@@ -0,0 +1,16 @@
+package router
+
+import (
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func SetMetricsRouter(router *gin.Engine) {
+	if !constant.MetricsEnabled {
+		return
+	}
+	router.GET("/metrics", middleware.MetricsAuth(), gin.WrapH(promhttp.Handler()))
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/admission"
+	"github.com/QuantumNous/new-api/common/metrics"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelAdmission 请求占用的渠道并发名额，请求结束后需调用 Release
+type ChannelAdmission struct {
+	key string
+}
+
+// AcquireChannelAdmission 占用渠道的并发名额；渠道并发已满时按令牌或分组的优先级在模型的队列中排队，
+// 超过最长等待时间仍未获得名额时返回 429。渠道未设置并发上限时返回 nil
+func AcquireChannelAdmission(c *gin.Context, info *relaycommon.RelayInfo, channel *model.Channel) (*ChannelAdmission, *types.NewAPIError) {
+	limit := channel.GetConcurrencyLimit()
+	if limit <= 0 {
+		return nil, nil
+	}
+	key := model.ChannelAdmissionKey(channel.Id)
+	if admission.TryAcquire(key, limit) {
+		return &ChannelAdmission{key: key}, nil
+	}
+
+	modelName := info.OriginModelName
+	tokenClass := common.GetContextKeyString(c, constant.ContextKeyTokenPriorityClass)
+	if batchClass := operation_setting.GetAdmissionSetting().BatchClass; batchClass != "" && common.GetContextKeyBool(c, constant.ContextKeyBatchReplay) {
+		// 批处理回放使用低优先级排队，避免占用交互请求的名额
+		tokenClass = batchClass
+	}
+	className, class := operation_setting.GetAdmissionClass(info.UsingGroup, tokenClass)
+	if class.MaxWaitMs <= 0 {
+		metrics.AdmissionWait(modelName, className, "rejected", 0)
+		logger.LogWarn(c, fmt.Sprintf("渠道 #%d 并发已满（上限 %d），优先级 %s 的请求不排队", channel.Id, limit, className))
+		return nil, types.NewErrorWithStatusCode(fmt.Errorf("渠道并发已满，请稍后重试"), types.ErrorCodeAdmissionTimeout, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	}
+
+	metrics.AddAdmissionQueueDepth(modelName, className, 1)
+	start := time.Now()
+	err := admission.Wait(c.Request.Context(), key, limit, modelName, class.Priority, time.Duration(class.MaxWaitMs)*time.Millisecond)
+	waited := time.Since(start)
+	metrics.AddAdmissionQueueDepth(modelName, className, -1)
+	common.SetContextKey(c, constant.ContextKeyAdmissionWaitMs, int(waited.Milliseconds()))
+
+	result := "admitted"
+	switch {
+	case err == nil:
+	case errors.Is(err, admission.ErrTimeout):
+		result = "timeout"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		result = "canceled"
+	}
+	metrics.AdmissionWait(modelName, className, result, waited.Seconds())
+	if err != nil {
+		logger.LogWarn(c, fmt.Sprintf("渠道 #%d 并发已满（上限 %d），优先级 %s 的请求排队 %dms 后仍未获得名额（%s），队列剩余 %d 个请求",
+			channel.Id, limit, className, waited.Milliseconds(), result, admission.Depth(modelName)))
+		return nil, types.NewErrorWithStatusCode(fmt.Errorf("渠道并发已满，排队 %dms 后仍未获得名额", waited.Milliseconds()), types.ErrorCodeAdmissionTimeout, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	}
+	logger.LogInfo(c, fmt.Sprintf("渠道 #%d 并发已满（上限 %d），优先级 %s 的请求排队 %dms 后开始处理", channel.Id, limit, className, waited.Milliseconds()))
+	return &ChannelAdmission{key: key}, nil
+}
+
+// Release 释放并发名额，排队中的请求按优先级获得名额
+func (a *ChannelAdmission) Release() {
+	if a == nil {
+		return
+	}
+	admission.Release(a.key)
+}
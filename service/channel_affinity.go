@@ -0,0 +1,277 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ChannelAffinity 会话上次成功使用的渠道与密钥索引
+type ChannelAffinity struct {
+	ChannelId int    `json:"channel_id"`
+	KeyIndex  int    `json:"key_index"`
+	Group     string `json:"group"`
+	ExpiresAt int64  `json:"expires_at"`
+}
+
+// 未启用 Redis 时使用的本地缓存
+var (
+	localChannelAffinity      = make(map[string]*ChannelAffinity)
+	localChannelAffinityMutex sync.Mutex
+)
+
+// channelAffinityRequest 计算会话标识所需的请求字段，兼容 OpenAI、Responses、Claude 与 Gemini 格式
+type channelAffinityRequest struct {
+	User               string            `json:"user"`
+	Metadata           map[string]any    `json:"metadata"`
+	System             json.RawMessage   `json:"system"`
+	Instructions       json.RawMessage   `json:"instructions"`
+	SystemInstruction  json.RawMessage   `json:"systemInstruction"`
+	SystemInstruction2 json.RawMessage   `json:"system_instruction"`
+	Messages           []json.RawMessage `json:"messages"`
+	Contents           []json.RawMessage `json:"contents"`
+	Input              json.RawMessage   `json:"input"`
+}
+
+// getChannelAffinitySession 返回会话标识的哈希，无法识别会话时返回空字符串；
+// 优先使用请求头或 user 字段，否则使用系统提示词与前几条消息
+func getChannelAffinitySession(c *gin.Context) string {
+	if session, ok := common.GetContextKey(c, constant.ContextKeyChannelAffinitySession); ok {
+		return session.(string)
+	}
+	session := computeChannelAffinitySession(c)
+	common.SetContextKey(c, constant.ContextKeyChannelAffinitySession, session)
+	return session
+}
+
+func computeChannelAffinitySession(c *gin.Context) string {
+	setting := operation_setting.GetChannelAffinitySetting()
+	hash := sha256.New()
+	if setting.SessionHeader != "" {
+		if session := c.GetHeader(setting.SessionHeader); session != "" {
+			hash.Write([]byte("header\n" + session))
+			return hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return ""
+	}
+	var request channelAffinityRequest
+	if err := common.Unmarshal(body, &request); err != nil {
+		return ""
+	}
+	if setting.UseUserField {
+		user := request.User
+		if userId, ok := request.Metadata["user_id"].(string); ok && user == "" {
+			user = userId
+		}
+		if user != "" {
+			hash.Write([]byte("user\n" + user))
+			return hex.EncodeToString(hash.Sum(nil))
+		}
+	}
+
+	hashed := false
+	for _, system := range []json.RawMessage{request.System, request.Instructions, request.SystemInstruction, request.SystemInstruction2} {
+		if len(system) > 0 && string(system) != "null" {
+			hash.Write(system)
+			hash.Write([]byte("\n"))
+			hashed = true
+		}
+	}
+	messages := request.Messages
+	if len(messages) == 0 {
+		messages = request.Contents
+	}
+	if len(messages) == 0 && len(request.Input) > 0 {
+		if err := common.Unmarshal(request.Input, &messages); err != nil {
+			// input 为字符串时视为一条消息
+			messages = []json.RawMessage{request.Input}
+		}
+	}
+	prefix := 0
+	for _, message := range messages {
+		var item struct {
+			Role string `json:"role"`
+		}
+		_ = common.Unmarshal(message, &item)
+		// OpenAI 格式的系统提示词在消息列表中，不计入前缀消息数
+		isSystem := item.Role == "system" || item.Role == "developer"
+		if !isSystem {
+			if prefix >= setting.PrefixMessages {
+				break
+			}
+			prefix++
+		}
+		hash.Write(message)
+		hash.Write([]byte("\n"))
+		hashed = true
+	}
+	if !hashed {
+		return ""
+	}
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+func channelAffinityKey(c *gin.Context, modelName string) string {
+	if !operation_setting.GetChannelAffinitySetting().Enabled {
+		return ""
+	}
+	session := getChannelAffinitySession(c)
+	if session == "" {
+		return ""
+	}
+	userId := common.GetContextKeyInt(c, constant.ContextKeyUserId)
+	return fmt.Sprintf("channel_affinity:%d:%s:%s", userId, modelName, session)
+}
+
+func getChannelAffinity(key string) *ChannelAffinity {
+	if common.RedisEnabled {
+		data, err := common.RedisGet(key)
+		if err != nil {
+			return nil
+		}
+		var affinity ChannelAffinity
+		if err := common.UnmarshalJsonStr(data, &affinity); err != nil {
+			return nil
+		}
+		return &affinity
+	}
+
+	localChannelAffinityMutex.Lock()
+	defer localChannelAffinityMutex.Unlock()
+	affinity, ok := localChannelAffinity[key]
+	if !ok {
+		return nil
+	}
+	if affinity.ExpiresAt <= time.Now().Unix() {
+		delete(localChannelAffinity, key)
+		return nil
+	}
+	return affinity
+}
+
+func setChannelAffinity(key string, affinity *ChannelAffinity) error {
+	setting := operation_setting.GetChannelAffinitySetting()
+	ttl := time.Duration(max(setting.TTLSeconds, 1)) * time.Second
+	affinity.ExpiresAt = time.Now().Add(ttl).Unix()
+	if common.RedisEnabled {
+		data, err := common.Marshal(affinity)
+		if err != nil {
+			return err
+		}
+		return common.RedisSet(key, string(data), ttl)
+	}
+
+	localChannelAffinityMutex.Lock()
+	defer localChannelAffinityMutex.Unlock()
+	if _, ok := localChannelAffinity[key]; !ok && len(localChannelAffinity) >= max(setting.MaxEntries, 1) {
+		now := time.Now().Unix()
+		for k, v := range localChannelAffinity {
+			if v.ExpiresAt <= now {
+				delete(localChannelAffinity, k)
+			}
+		}
+		// 仍然已满时随机淘汰一条
+		for k := range localChannelAffinity {
+			if len(localChannelAffinity) < max(setting.MaxEntries, 1) {
+				break
+			}
+			delete(localChannelAffinity, k)
+		}
+	}
+	localChannelAffinity[key] = affinity
+	return nil
+}
+
+// SelectAffinityChannel 返回会话上次成功使用的渠道；未启用、无法识别会话、
+// 渠道不可用或本次请求已经尝试过该渠道时返回 nil，由调用方按原规则选择渠道
+func SelectAffinityChannel(param *RetryParam) (*model.Channel, string) {
+	common.SetContextKey(param.Ctx, constant.ContextKeyChannelAffinity, (*ChannelAffinity)(nil))
+	if param.fallbackPending {
+		return nil, ""
+	}
+	key := channelAffinityKey(param.Ctx, param.ModelName)
+	if key == "" {
+		return nil, ""
+	}
+	affinity := getChannelAffinity(key)
+	if affinity == nil {
+		return nil, ""
+	}
+	if slices.Contains(param.Ctx.GetStringSlice("use_channel"), strconv.Itoa(affinity.ChannelId)) {
+		return nil, ""
+	}
+	autoGroupIndex := -1
+	if param.TokenGroup == "auto" {
+		userGroup := common.GetContextKeyString(param.Ctx, constant.ContextKeyUserGroup)
+		autoGroupIndex = slices.Index(GetUserAutoGroup(userGroup), affinity.Group)
+		if autoGroupIndex < 0 {
+			return nil, ""
+		}
+	} else if affinity.Group != param.TokenGroup {
+		return nil, ""
+	}
+	channel := model.GetAffinityChannel(affinity.Group, param.ModelName, affinity.ChannelId)
+	if channel == nil {
+		return nil, ""
+	}
+	if autoGroupIndex >= 0 {
+		common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroup, affinity.Group)
+		common.SetContextKey(param.Ctx, constant.ContextKeyAutoGroupIndex, autoGroupIndex)
+	}
+	common.SetContextKey(param.Ctx, constant.ContextKeyChannelAffinity, affinity)
+	logger.LogDebug(param.Ctx, "channel affinity hit: channel #%d, key #%d", affinity.ChannelId, affinity.KeyIndex)
+	return channel, affinity.Group
+}
+
+// GetAffinityKeyIndex 返回会话亲和命中该渠道时上次使用的密钥索引
+func GetAffinityKeyIndex(c *gin.Context, channelId int) (int, bool) {
+	affinity, ok := common.GetContextKeyType[*ChannelAffinity](c, constant.ContextKeyChannelAffinity)
+	if !ok || affinity == nil || affinity.ChannelId != channelId {
+		return 0, false
+	}
+	return affinity.KeyIndex, true
+}
+
+// IsChannelAffinityHit 本次请求最终使用的渠道是否由会话亲和选出
+func IsChannelAffinityHit(c *gin.Context, channelId int) bool {
+	_, ok := GetAffinityKeyIndex(c, channelId)
+	return ok
+}
+
+// SaveChannelAffinity 请求成功后记录会话使用的渠道与密钥索引，并刷新有效期
+func SaveChannelAffinity(c *gin.Context, info *relaycommon.RelayInfo) {
+	if info.ChannelMeta == nil || info.ResponseCacheHit {
+		return
+	}
+	key := channelAffinityKey(c, info.OriginModelName)
+	if key == "" {
+		return
+	}
+	affinity := &ChannelAffinity{
+		ChannelId: info.ChannelId,
+		Group:     info.UsingGroup,
+	}
+	if info.ChannelIsMultiKey {
+		affinity.KeyIndex = info.ChannelMultiKeyIndex
+	}
+	if err := setChannelAffinity(key, affinity); err != nil {
+		logger.LogWarn(c, "failed to save channel affinity: "+err.Error())
+	}
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newAffinityTestContext(body string, header string) *gin.Context {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body))
+	if header != "" {
+		c.Request.Header.Set("X-Session-Id", header)
+	}
+	return c
+}
+
+func TestChannelAffinitySession(t *testing.T) {
+	const (
+		turn1      = `{"model":"m","messages":[{"role":"system","content":"s"},{"role":"user","content":"u1"}]}`
+		turn2      = `{"model":"m","messages":[{"role":"system","content":"s"},{"role":"user","content":"u1"},{"role":"assistant","content":"a1"},{"role":"user","content":"u2"}]}`
+		otherChat  = `{"model":"m","messages":[{"role":"system","content":"s"},{"role":"user","content":"x1"}]}`
+		claudeTurn = `{"model":"m","system":"s","messages":[{"role":"user","content":"u1"},{"role":"assistant","content":"a1"}]}`
+		withUser   = `{"model":"m","user":"alice","messages":[{"role":"user","content":"u1"}]}`
+		empty      = `{"model":"m"}`
+	)
+	session := func(body string, header string) string {
+		return computeChannelAffinitySession(newAffinityTestContext(body, header))
+	}
+
+	if session(turn1, "") == "" || session(turn1, "") != session(turn2, "") {
+		t.Fatal("turns of the same conversation should share a session")
+	}
+	if session(turn1, "") == session(otherChat, "") {
+		t.Fatal("different conversations should not share a session")
+	}
+	if session(claudeTurn, "") == "" || session(claudeTurn, "") == session(turn1, "") {
+		t.Fatal("unexpected session for claude request")
+	}
+	if session(withUser, "") != session(`{"user":"alice"}`, "") {
+		t.Fatal("user field should identify the session")
+	}
+	if session(turn1, "abc") != session(turn2, "abc") || session(turn1, "abc") == session(turn1, "") {
+		t.Fatal("session header should identify the session")
+	}
+	if session(empty, "") != "" {
+		t.Fatal("request without prompt should not have a session")
+	}
+}
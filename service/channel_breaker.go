@@ -0,0 +1,23 @@
+package service
+
+import (
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ReportChannelRelayBreaker 将一次转发的结果上报给渠道熔断器，请求本身的错误不计入
+func ReportChannelRelayBreaker(c *gin.Context, channelId int, err *types.NewAPIError) {
+	if channelId == 0 {
+		return
+	}
+	if err != nil && !isChannelFault(err) {
+		return
+	}
+	isMultiKey := common.GetContextKeyBool(c, constant.ContextKeyChannelIsMultiKey)
+	keyIndex := common.GetContextKeyInt(c, constant.ContextKeyChannelMultiKeyIndex)
+	model.ReportChannelBreaker(channelId, isMultiKey, keyIndex, err == nil)
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+// parseRateLimitReset 解析重置时间，支持 1s、6m0s 等时长、秒数以及 Unix 时间戳
+func parseRateLimitReset(value string, now time.Time) time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(d)
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+		if seconds > 1e9 {
+			return time.Unix(int64(seconds), 0)
+		}
+		return now.Add(time.Duration(seconds * float64(time.Second)))
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+func parseRateLimitCount(value string) int64 {
+	count, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+	if err != nil || count < 0 {
+		return -1
+	}
+	return count
+}
+
+// ParseRateLimitHeaders 解析上游的 x-ratelimit-* 与 retry-after 响应头
+func ParseRateLimitHeaders(header http.Header, now time.Time) model.ChannelKeyRateLimit {
+	limit := model.ChannelKeyRateLimit{
+		LimitRequests:     parseRateLimitCount(header.Get("x-ratelimit-limit-requests")),
+		RemainingRequests: parseRateLimitCount(header.Get("x-ratelimit-remaining-requests")),
+		ResetRequests:     parseRateLimitReset(header.Get("x-ratelimit-reset-requests"), now),
+		LimitTokens:       parseRateLimitCount(header.Get("x-ratelimit-limit-tokens")),
+		RemainingTokens:   parseRateLimitCount(header.Get("x-ratelimit-remaining-tokens")),
+		ResetTokens:       parseRateLimitReset(header.Get("x-ratelimit-reset-tokens"), now),
+	}
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if t, err := http.ParseTime(retryAfter); err == nil {
+			limit.RetryAfter = t
+		} else {
+			limit.RetryAfter = parseRateLimitReset(retryAfter, now)
+		}
+	}
+	return limit
+}
+
+// RecordChannelKeyResponse 多密钥渠道收到上游响应后，记录对应密钥的限流信息与 429 次数
+func RecordChannelKeyResponse(info *relaycommon.RelayInfo, resp *http.Response) {
+	if info.ChannelMeta == nil || !info.ChannelIsMultiKey {
+		return
+	}
+	limit := ParseRateLimitHeaders(resp.Header, time.Now())
+	rateLimited := resp.StatusCode == http.StatusTooManyRequests
+	if !rateLimited && limit.RemainingRequests < 0 && limit.RemainingTokens < 0 {
+		return
+	}
+	model.UpdateChannelKeyRateLimit(info.ChannelId, info.ChannelMultiKeyIndex, limit, rateLimited)
+}
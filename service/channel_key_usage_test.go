@@ -0,0 +1,56 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestParseRateLimitHeaders(t *testing.T) {
+	now := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name              string
+		headers           map[string]string
+		wantRemaining     int64
+		wantResetRequests time.Time
+		wantResetTokens   time.Time
+		wantRetryAfter    time.Time
+	}{
+		{"no headers", nil, -1, time.Time{}, time.Time{}, time.Time{}},
+		{"duration", map[string]string{
+			"x-ratelimit-remaining-requests": "12",
+			"x-ratelimit-reset-requests":     "6m0s",
+			"x-ratelimit-reset-tokens":       "1.5s",
+		}, 12, now.Add(6 * time.Minute), now.Add(1500 * time.Millisecond), time.Time{}},
+		{"plain seconds", map[string]string{
+			"x-ratelimit-remaining-requests": "0",
+			"x-ratelimit-reset-requests":     "20",
+			"x-ratelimit-reset-tokens":       "0.5",
+		}, 0, now.Add(20 * time.Second), now.Add(500 * time.Millisecond), time.Time{}},
+		{"epoch timestamp", map[string]string{
+			"x-ratelimit-reset-requests": "1792137600",
+		}, -1, time.Unix(1792137600, 0), time.Time{}, time.Time{}},
+		{"rfc3339", map[string]string{
+			"x-ratelimit-reset-tokens": "2026-10-16T08:01:00Z",
+		}, -1, time.Time{}, now.Add(time.Minute), time.Time{}},
+		{"invalid values", map[string]string{
+			"x-ratelimit-remaining-requests": "-3",
+			"x-ratelimit-reset-requests":     "soon",
+		}, -1, time.Time{}, time.Time{}, time.Time{}},
+		{"retry-after seconds", map[string]string{"Retry-After": "30"}, -1, time.Time{}, time.Time{}, now.Add(30 * time.Second)},
+		{"retry-after http date", map[string]string{"Retry-After": "Fri, 16 Oct 2026 08:02:00 GMT"}, -1, time.Time{}, time.Time{}, now.Add(2 * time.Minute)},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		for key, value := range tt.headers {
+			header.Set(key, value)
+		}
+		limit := ParseRateLimitHeaders(header, now)
+		if limit.RemainingRequests != tt.wantRemaining {
+			t.Fatalf("%s: expected remaining %d, got %d", tt.name, tt.wantRemaining, limit.RemainingRequests)
+		}
+		if !limit.ResetRequests.Equal(tt.wantResetRequests) || !limit.ResetTokens.Equal(tt.wantResetTokens) || !limit.RetryAfter.Equal(tt.wantRetryAfter) {
+			t.Fatalf("%s: unexpected reset times %v, %v, retry after %v", tt.name, limit.ResetRequests, limit.ResetTokens, limit.RetryAfter)
+		}
+	}
+}
@@ -17,6 +17,11 @@ type RetryParam struct {
 	ModelName    string
 	Retry        *int
 	resetNextTry bool
+
+	// 模型降级状态，fallbackModels 为尚未尝试的降级模型，首次需要降级时加载
+	fallbackModels  []string
+	fallbackLoaded  bool
+	fallbackPending bool
 }
 
 func (p *RetryParam) GetRetry() int {
@@ -83,6 +88,10 @@ func (p *RetryParam) ResetRetryNextTry() {
 func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string, error) {
 	var channel *model.Channel
 	var err error
+	if param.fallbackPending {
+		param.fallbackPending = false
+		param.switchFallbackModel()
+	}
 	selectGroup := param.TokenGroup
 	userGroup := common.GetContextKeyString(param.Ctx, constant.ContextKeyUserGroup)
 
@@ -154,9 +163,18 @@ func CacheGetRandomSatisfiedChannel(param *RetryParam) (*model.Channel, string,
 		}
 	} else {
 		channel, err = model.GetRandomSatisfiedChannel(param.TokenGroup, param.ModelName, param.GetRetry())
-		if err != nil {
-			return nil, param.TokenGroup, err
+	}
+	// 当前模型没有可用渠道、选择渠道出错，或重试时已尝试过全部渠道，按降级链切换到下一个模型；
+	// 降级链用完后才返回选择渠道的错误
+	if modelFallbackAllowed(param.Ctx) {
+		exhausted := channel == nil || err != nil ||
+			param.TokenGroup != "auto" && param.GetRetry() > 0 && modelChannelsExhausted(param.Ctx, param.TokenGroup, param.ModelName)
+		if exhausted && param.switchFallbackModel() {
+			return CacheGetRandomSatisfiedChannel(param)
 		}
 	}
+	if err != nil {
+		return nil, selectGroup, err
+	}
 	return channel, selectGroup, nil
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCacheGetRandomSatisfiedChannelFallbackOnError(t *testing.T) {
+	setupServiceTestDB(t)
+	memoryCacheEnabled := common.MemoryCacheEnabled
+	common.MemoryCacheEnabled = false
+	fallbackSetting := operation_setting.GetModelFallbackSetting()
+	oldFallback := *fallbackSetting
+	fallbackSetting.Enabled = true
+	fallbackSetting.Chains = map[string][]string{"default": {"broken-a -> broken-b -> healthy", "broken-c -> broken-d"}}
+	t.Cleanup(func() {
+		common.MemoryCacheEnabled = memoryCacheEnabled
+		*fallbackSetting = oldFallback
+	})
+
+	channel := &model.Channel{Name: "healthy", Key: "sk-test", Status: common.ChannelStatusEnabled, Group: "default", Models: "healthy"}
+	if err := model.DB.Create(channel).Error; err != nil {
+		t.Fatal(err)
+	}
+	abilities := []model.Ability{
+		// 能力指向不存在的渠道，选择渠道时返回错误
+		{Group: "default", Model: "broken-a", ChannelId: channel.Id + 100, Enabled: true},
+		{Group: "default", Model: "broken-b", ChannelId: channel.Id + 101, Enabled: true},
+		{Group: "default", Model: "broken-c", ChannelId: channel.Id + 102, Enabled: true},
+		{Group: "default", Model: "broken-d", ChannelId: channel.Id + 103, Enabled: true},
+		{Group: "default", Model: "healthy", ChannelId: channel.Id, Enabled: true},
+	}
+	if err := model.DB.Create(&abilities).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name      string
+		modelName string
+		wantModel string
+		wantErr   bool
+	}{
+		{"falls back past selection errors", "broken-a", "healthy", false},
+		{"returns error after chain is exhausted", "broken-c", "broken-d", true},
+	}
+	for _, tt := range tests {
+		gin.SetMode(gin.TestMode)
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+		param := &RetryParam{Ctx: c, TokenGroup: "default", ModelName: tt.modelName}
+		got, _, err := CacheGetRandomSatisfiedChannel(param)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+		if !tt.wantErr && (got == nil || got.Id != channel.Id) {
+			t.Fatalf("%s: expected channel %d, got %v", tt.name, channel.Id, got)
+		}
+		if param.ModelName != tt.wantModel {
+			t.Fatalf("%s: expected model %s, got %s", tt.name, tt.wantModel, param.ModelName)
+		}
+	}
+}
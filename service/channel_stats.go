@@ -0,0 +1,59 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+)
+
+// isChannelFault 判断错误是否由渠道引起，请求本身的错误不计入渠道失败率
+func isChannelFault(err *types.NewAPIError) bool {
+	if types.IsChannelError(err) {
+		return true
+	}
+	switch err.GetErrorCode() {
+	case types.ErrorCodeDoRequestFailed, types.ErrorCodeBadResponse, types.ErrorCodeBadResponseBody,
+		types.ErrorCodeReadResponseBodyFailed, types.ErrorCodeEmptyResponse:
+		return true
+	}
+	return err.StatusCode == http.StatusTooManyRequests ||
+		err.StatusCode == http.StatusUnauthorized ||
+		err.StatusCode == http.StatusForbidden ||
+		err.StatusCode >= http.StatusInternalServerError
+}
+
+// RecordChannelRelayResult 将一次转发的结果计入渠道统计，用于自适应渠道选择
+func RecordChannelRelayResult(info *relaycommon.RelayInfo, channelId int, attemptStart time.Time, err *types.NewAPIError) {
+	if channelId == 0 {
+		return
+	}
+	if err != nil {
+		if isChannelFault(err) {
+			model.RecordChannelStats(channelId, info.OriginModelName, false, 0, 0)
+		}
+		return
+	}
+
+	now := time.Now()
+	ttft := now.Sub(attemptStart)
+	if info.HasSendResponse() && info.FirstResponseTime.After(attemptStart) {
+		ttft = info.FirstResponseTime.Sub(attemptStart)
+	}
+	var tps float64
+	if info.CompletionTokens > 0 {
+		generateStart := attemptStart
+		if info.IsStream && info.FirstResponseTime.After(attemptStart) {
+			generateStart = info.FirstResponseTime
+		}
+		if seconds := now.Sub(generateStart).Seconds(); seconds > 0 {
+			tps = float64(info.CompletionTokens) / seconds
+		}
+	}
+	model.RecordChannelStats(channelId, info.OriginModelName, true, ttft, tps)
+	if info.ChannelMeta != nil && info.ChannelIsMultiKey {
+		model.RecordChannelKeyTokens(channelId, info.ChannelMultiKeyIndex, info.PromptTokens, info.CompletionTokens)
+	}
+}
@@ -0,0 +1,433 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/dto"
+)
+
+// OpenAIRequestToResponsesRequest 将 Chat Completions 请求转换为 Responses API 请求，
+// 供只开放 /v1/responses 的上游模型服务 Chat Completions 客户端
+func OpenAIRequestToResponsesRequest(request *dto.GeneralOpenAIRequest) (*dto.OpenAIResponsesRequest, error) {
+	responsesRequest := &dto.OpenAIResponsesRequest{
+		Model:    request.Model,
+		Stream:   request.Stream,
+		TopP:     request.TopP,
+		User:     request.User,
+		Metadata: request.Metadata,
+		Store:    request.Store,
+	}
+	if request.Temperature != nil {
+		responsesRequest.Temperature = *request.Temperature
+	}
+	if request.MaxCompletionTokens != 0 {
+		responsesRequest.MaxOutputTokens = request.MaxCompletionTokens
+	} else {
+		responsesRequest.MaxOutputTokens = request.MaxTokens
+	}
+	if request.ReasoningEffort != "" {
+		// 请求推理摘要，以便转换为 reasoning_content 返回
+		responsesRequest.Reasoning = &dto.Reasoning{
+			Effort:  request.ReasoningEffort,
+			Summary: "auto",
+		}
+	}
+	if request.ParallelTooCalls != nil {
+		responsesRequest.ParallelToolCalls, _ = common.Marshal(*request.ParallelTooCalls)
+	}
+	if request.PromptCacheKey != "" {
+		responsesRequest.PromptCacheKey, _ = common.Marshal(request.PromptCacheKey)
+	}
+	responsesRequest.PromptCacheRetention = request.PromptCacheRetention
+
+	input, err := common.Marshal(messagesToResponsesInput(request.Messages))
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal input: %w", err)
+	}
+	responsesRequest.Input = input
+
+	// 转换工具，Responses API 的函数字段平铺在顶层
+	tools := make([]map[string]any, 0, len(request.Tools))
+	for _, tool := range request.Tools {
+		if tool.Type != "function" {
+			continue
+		}
+		responsesTool := map[string]any{
+			"type": "function",
+			"name": tool.Function.Name,
+		}
+		if tool.Function.Description != "" {
+			responsesTool["description"] = tool.Function.Description
+		}
+		if tool.Function.Parameters != nil {
+			responsesTool["parameters"] = tool.Function.Parameters
+		}
+		tools = append(tools, responsesTool)
+	}
+	if request.WebSearchOptions != nil {
+		webSearchTool := map[string]any{
+			"type": dto.BuildInToolWebSearchPreview,
+		}
+		if request.WebSearchOptions.SearchContextSize != "" {
+			webSearchTool["search_context_size"] = request.WebSearchOptions.SearchContextSize
+		}
+		if len(request.WebSearchOptions.UserLocation) > 0 {
+			webSearchTool["user_location"] = request.WebSearchOptions.UserLocation
+		}
+		tools = append(tools, webSearchTool)
+	}
+	if len(tools) > 0 {
+		responsesRequest.Tools, _ = common.Marshal(tools)
+	}
+
+	if request.ToolChoice != nil {
+		responsesRequest.ToolChoice, _ = common.Marshal(openAIToolChoiceToResponses(request.ToolChoice))
+	}
+
+	// response_format 对应 text.format
+	if request.ResponseFormat != nil {
+		format := map[string]any{
+			"type": request.ResponseFormat.Type,
+		}
+		if request.ResponseFormat.Type == "json_schema" && len(request.ResponseFormat.JsonSchema) > 0 {
+			var schema dto.FormatJsonSchema
+			if err := common.Unmarshal(request.ResponseFormat.JsonSchema, &schema); err != nil {
+				return nil, fmt.Errorf("invalid response_format.json_schema: %w", err)
+			}
+			format["name"] = schema.Name
+			if schema.Description != "" {
+				format["description"] = schema.Description
+			}
+			if schema.Schema != nil {
+				format["schema"] = schema.Schema
+			}
+			if len(schema.Strict) > 0 {
+				format["strict"] = schema.Strict
+			}
+		}
+		if request.ResponseFormat.Type != "" && request.ResponseFormat.Type != "text" {
+			responsesRequest.Text, _ = common.Marshal(map[string]any{"format": format})
+		}
+	}
+
+	return responsesRequest, nil
+}
+
+func openAIToolChoiceToResponses(toolChoice any) any {
+	choice, ok := toolChoice.(map[string]any)
+	if !ok {
+		return toolChoice
+	}
+	if common.Interface2String(choice["type"]) != "function" {
+		return toolChoice
+	}
+	if function, ok := choice["function"].(map[string]any); ok {
+		return map[string]any{
+			"type": "function",
+			"name": common.Interface2String(function["name"]),
+		}
+	}
+	return toolChoice
+}
+
+func messagesToResponsesInput(messages []dto.Message) []map[string]any {
+	input := make([]map[string]any, 0, len(messages))
+	for _, message := range messages {
+		switch message.Role {
+		case "tool":
+			input = append(input, map[string]any{
+				"type":    "function_call_output",
+				"call_id": message.ToolCallId,
+				"output":  message.StringContent(),
+			})
+		case "assistant":
+			if content := messageToResponsesContent(message, "output_text"); content != nil {
+				input = append(input, map[string]any{
+					"type":    "message",
+					"role":    "assistant",
+					"content": content,
+				})
+			}
+			for _, toolCall := range message.ParseToolCalls() {
+				input = append(input, map[string]any{
+					"type":      "function_call",
+					"call_id":   toolCall.ID,
+					"name":      toolCall.Function.Name,
+					"arguments": toolCall.Function.Arguments,
+				})
+			}
+		default:
+			content := messageToResponsesContent(message, "input_text")
+			if content == nil {
+				content = ""
+			}
+			input = append(input, map[string]any{
+				"type":    "message",
+				"role":    message.Role,
+				"content": content,
+			})
+		}
+	}
+	return input
+}
+
+// messageToResponsesContent 转换消息内容，内容为空时返回 nil
+func messageToResponsesContent(message dto.Message, textType string) any {
+	if message.Content == nil {
+		return nil
+	}
+	if message.IsStringContent() {
+		text := message.StringContent()
+		if text == "" {
+			return nil
+		}
+		return text
+	}
+	parts := make([]map[string]any, 0)
+	for _, content := range message.ParseContent() {
+		switch content.Type {
+		case dto.ContentTypeText:
+			parts = append(parts, map[string]any{
+				"type": textType,
+				"text": content.Text,
+			})
+		case dto.ContentTypeImageURL:
+			image := content.GetImageMedia()
+			if image == nil || image.Url == "" {
+				continue
+			}
+			part := map[string]any{
+				"type":      "input_image",
+				"image_url": image.Url,
+			}
+			if image.Detail != "" {
+				part["detail"] = image.Detail
+			}
+			parts = append(parts, part)
+		case dto.ContentTypeFile:
+			file := content.GetFile()
+			if file == nil {
+				continue
+			}
+			part := map[string]any{
+				"type": "input_file",
+			}
+			if file.FileId != "" {
+				part["file_id"] = file.FileId
+			}
+			if file.FileData != "" {
+				part["file_data"] = file.FileData
+			}
+			if file.FileName != "" {
+				part["filename"] = file.FileName
+			}
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return parts
+}
+
+// ResponsesResponse2OpenAI 将 Responses API 非流响应转换为 Chat Completions 响应
+func ResponsesResponse2OpenAI(responsesResponse *dto.OpenAIResponsesResponse) *dto.OpenAITextResponse {
+	var textBuilder strings.Builder
+	var reasoningBuilder strings.Builder
+	toolCalls := make([]dto.ToolCallResponse, 0)
+	for _, output := range responsesResponse.Output {
+		switch output.Type {
+		case dto.ResponsesItemTypeMessage:
+			for _, content := range output.Content {
+				if content.Type == "output_text" {
+					textBuilder.WriteString(content.Text)
+				}
+			}
+		case dto.ResponsesItemTypeReasoning:
+			for _, summary := range output.Summary {
+				reasoningBuilder.WriteString(summary.Text)
+			}
+		case dto.ResponsesItemTypeFunctionCall:
+			toolCalls = append(toolCalls, dto.ToolCallResponse{
+				ID:   output.CallId,
+				Type: "function",
+				Function: dto.FunctionResponse{
+					Name:      output.Name,
+					Arguments: output.Arguments,
+				},
+			})
+		}
+	}
+
+	message := dto.Message{
+		Role:             "assistant",
+		ReasoningContent: reasoningBuilder.String(),
+	}
+	message.SetStringContent(textBuilder.String())
+	if len(toolCalls) > 0 {
+		message.SetToolCalls(toolCalls)
+	}
+
+	openAIResponse := &dto.OpenAITextResponse{
+		Id:      responsesResponse.ID,
+		Model:   responsesResponse.Model,
+		Object:  "chat.completion",
+		Created: responsesResponse.CreatedAt,
+		Choices: []dto.OpenAITextResponseChoice{
+			{
+				Index:        0,
+				Message:      message,
+				FinishReason: responsesFinishReason(responsesResponse, len(toolCalls) > 0),
+			},
+		},
+	}
+	if usage := usageResponses2OpenAI(responsesResponse.Usage); usage != nil {
+		openAIResponse.Usage = *usage
+	}
+	return openAIResponse
+}
+
+func responsesFinishReason(responsesResponse *dto.OpenAIResponsesResponse, hasToolCalls bool) string {
+	if responsesResponse != nil && responsesResponse.IncompleteDetails != nil &&
+		responsesResponse.IncompleteDetails.Reasoning == "max_output_tokens" {
+		return constant.FinishReasonLength
+	}
+	if hasToolCalls {
+		return constant.FinishReasonToolCalls
+	}
+	return constant.FinishReasonStop
+}
+
+// usageResponses2OpenAI 将 Responses API 的 usage 转换为 Chat Completions 的 usage
+func usageResponses2OpenAI(usage *dto.Usage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	openAIUsage := &dto.Usage{
+		PromptTokens:           usage.InputTokens,
+		CompletionTokens:       usage.OutputTokens,
+		TotalTokens:            usage.TotalTokens,
+		CompletionTokenDetails: usage.CompletionTokenDetails,
+	}
+	if usage.InputTokensDetails != nil {
+		openAIUsage.PromptTokensDetails.CachedTokens = usage.InputTokensDetails.CachedTokens
+	}
+	if openAIUsage.TotalTokens == 0 {
+		openAIUsage.TotalTokens = openAIUsage.PromptTokens + openAIUsage.CompletionTokens
+	}
+	return openAIUsage
+}
+
+// ResponsesChatStreamState 记录 Responses 流式事件转换为 Chat Completions chunk 时的状态
+type ResponsesChatStreamState struct {
+	Id      string
+	Created int64
+	Model   string
+	// item_id -> tool_calls 下标
+	ToolCallIndex map[string]int
+	Usage         *dto.Usage
+	Finished      bool
+}
+
+func NewResponsesChatStreamState(id string, model string) *ResponsesChatStreamState {
+	return &ResponsesChatStreamState{
+		Id:            id,
+		Created:       common.GetTimestamp(),
+		Model:         model,
+		ToolCallIndex: make(map[string]int),
+	}
+}
+
+func (s *ResponsesChatStreamState) newChunk() *dto.ChatCompletionsStreamResponse {
+	return &dto.ChatCompletionsStreamResponse{
+		Id:      s.Id,
+		Object:  "chat.completion.chunk",
+		Created: s.Created,
+		Model:   s.Model,
+		Choices: []dto.ChatCompletionsStreamResponseChoice{
+			{Index: 0},
+		},
+	}
+}
+
+// StreamResponseResponses2OpenAI 将单个 Responses 流式事件转换为 Chat Completions chunk，
+// 无需向下游输出时返回 nil
+func StreamResponseResponses2OpenAI(event *dto.ResponsesStreamResponse, state *ResponsesChatStreamState) *dto.ChatCompletionsStreamResponse {
+	switch event.Type {
+	case dto.ResponsesStreamTypeCreated:
+		if event.Response != nil {
+			if event.Response.Model != "" {
+				state.Model = event.Response.Model
+			}
+			if event.Response.CreatedAt != 0 {
+				state.Created = int64(event.Response.CreatedAt)
+			}
+		}
+		chunk := state.newChunk()
+		chunk.Choices[0].Delta.Role = "assistant"
+		chunk.Choices[0].Delta.SetContentString("")
+		return chunk
+	case dto.ResponsesStreamTypeOutputTextDelta:
+		if event.Delta == "" {
+			return nil
+		}
+		chunk := state.newChunk()
+		chunk.Choices[0].Delta.SetContentString(event.Delta)
+		return chunk
+	case dto.ResponsesStreamTypeReasoningSummaryDelta, "response.reasoning_text.delta":
+		if event.Delta == "" {
+			return nil
+		}
+		chunk := state.newChunk()
+		chunk.Choices[0].Delta.SetReasoningContent(event.Delta)
+		return chunk
+	case dto.ResponsesOutputTypeItemAdded:
+		if event.Item == nil || event.Item.Type != dto.ResponsesItemTypeFunctionCall {
+			return nil
+		}
+		index := len(state.ToolCallIndex)
+		state.ToolCallIndex[event.Item.ID] = index
+		toolCall := dto.ToolCallResponse{
+			ID:   event.Item.CallId,
+			Type: "function",
+			Function: dto.FunctionResponse{
+				Name:      event.Item.Name,
+				Arguments: event.Item.Arguments,
+			},
+		}
+		toolCall.SetIndex(index)
+		chunk := state.newChunk()
+		chunk.Choices[0].Delta.ToolCalls = []dto.ToolCallResponse{toolCall}
+		return chunk
+	case dto.ResponsesStreamTypeFunctionCallArgsDelta:
+		index, ok := state.ToolCallIndex[event.ItemId]
+		if !ok || event.Delta == "" {
+			return nil
+		}
+		toolCall := dto.ToolCallResponse{
+			Function: dto.FunctionResponse{
+				Arguments: event.Delta,
+			},
+		}
+		toolCall.SetIndex(index)
+		chunk := state.newChunk()
+		chunk.Choices[0].Delta.ToolCalls = []dto.ToolCallResponse{toolCall}
+		return chunk
+	case dto.ResponsesStreamTypeCompleted, "response.incomplete":
+		if state.Finished {
+			return nil
+		}
+		state.Finished = true
+		if event.Response != nil {
+			state.Usage = usageResponses2OpenAI(event.Response.Usage)
+		}
+		finishReason := responsesFinishReason(event.Response, len(state.ToolCallIndex) > 0)
+		chunk := state.newChunk()
+		chunk.Choices[0].FinishReason = &finishReason
+		return chunk
+	}
+	return nil
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/bytedance/gopkg/util/gopool"
+	"github.com/gin-gonic/gin"
+)
+
+// 审核记录中保存的内容长度上限（字符）
+const guardrailLogContentLimit = 2000
+
+// 请求完成后审核输出时最多记录的响应大小
+const guardrailCaptureLimit = 1 << 20
+
+type GuardrailVerdict struct {
+	Flagged    bool     `json:"flagged"`
+	Categories []string `json:"categories"`
+}
+
+type guardrailWebhookRequest struct {
+	Stage  string `json:"stage"`
+	Group  string `json:"group"`
+	Model  string `json:"model"`
+	UserId int    `json:"user_id"`
+	Input  string `json:"input"`
+}
+
+type moderationResponse struct {
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// Moderate 调用配置的审核后端审核文本
+func Moderate(ctx context.Context, stage string, group string, modelName string, userId int, input string) (*GuardrailVerdict, error) {
+	setting := operation_setting.GetGuardrailSetting()
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(max(setting.TimeoutSeconds, 1))*time.Second)
+	defer cancel()
+	switch setting.Backend {
+	case operation_setting.GuardrailBackendWebhook:
+		return moderateByWebhook(ctx, setting, &guardrailWebhookRequest{
+			Stage:  stage,
+			Group:  group,
+			Model:  modelName,
+			UserId: userId,
+			Input:  input,
+		})
+	case operation_setting.GuardrailBackendModeration:
+		return moderateByChannel(ctx, setting, input)
+	}
+	return nil, fmt.Errorf("unknown guardrail backend: %s", setting.Backend)
+}
+
+func moderateByWebhook(ctx context.Context, setting *operation_setting.GuardrailSetting, payload *guardrailWebhookRequest) (*GuardrailVerdict, error) {
+	if setting.WebhookURL == "" {
+		return nil, errors.New("guardrail webhook url is empty")
+	}
+	body, err := common.Marshal(payload)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, setting.WebhookURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if setting.WebhookSecret != "" {
+		req.Header.Set("Authorization", "Bearer "+setting.WebhookSecret)
+	}
+	respBody, err := doGuardrailRequest(GetHttpClient(), req)
+	if err != nil {
+		return nil, err
+	}
+	var verdict GuardrailVerdict
+	if err := common.Unmarshal(respBody, &verdict); err != nil {
+		return nil, fmt.Errorf("invalid guardrail webhook response: %w", err)
+	}
+	return &verdict, nil
+}
+
+// moderateByChannel 通过本站的 OpenAI 渠道调用 /v1/moderations，不计费；请求结果计入渠道熔断与密钥用量
+func moderateByChannel(ctx context.Context, setting *operation_setting.GuardrailSetting, input string) (*GuardrailVerdict, error) {
+	channel, err := model.GetRandomSatisfiedChannel(setting.ModerationGroup, setting.ModerationModel, 0)
+	if err != nil {
+		return nil, err
+	}
+	if channel == nil {
+		return nil, fmt.Errorf("no available channel for moderation model %s in group %s", setting.ModerationModel, setting.ModerationGroup)
+	}
+	// 审核请求不经过渠道适配器，只支持原生 /v1/moderations 的 OpenAI 渠道
+	if channel.Type != constant.ChannelTypeOpenAI {
+		return nil, fmt.Errorf("moderation channel #%d is not an OpenAI channel", channel.Id)
+	}
+	key, keyIndex, apiErr := channel.GetNextEnabledKey()
+	if apiErr != nil {
+		return nil, apiErr
+	}
+	body, err := common.Marshal(map[string]any{
+		"model": setting.ModerationModel,
+		"input": input,
+	})
+	if err != nil {
+		return nil, err
+	}
+	baseURL := strings.TrimSuffix(channel.GetBaseURL(), "/")
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, relaycommon.GetFullRequestURL(baseURL, "/v1/moderations", channel.Type), bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+key)
+	if channel.OpenAIOrganization != nil && *channel.OpenAIOrganization != "" {
+		req.Header.Set("OpenAI-Organization", *channel.OpenAIOrganization)
+	}
+	client, err := GetHttpClientWithProxy(channel.GetSetting().Proxy)
+	if err != nil {
+		return nil, err
+	}
+	isMultiKey := channel.ChannelInfo.IsMultiKey
+	resp, err := client.Do(req)
+	if err != nil {
+		// 超时或取消时不计入渠道故障
+		if ctx.Err() == nil {
+			model.ReportChannelBreaker(channel.Id, isMultiKey, keyIndex, false)
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if isMultiKey {
+		limit := ParseRateLimitHeaders(resp.Header, time.Now())
+		rateLimited := resp.StatusCode == http.StatusTooManyRequests
+		if rateLimited || limit.RemainingRequests >= 0 || limit.RemainingTokens >= 0 {
+			model.UpdateChannelKeyRateLimit(channel.Id, keyIndex, limit, rateLimited)
+		}
+	}
+	respBody, err := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		channelFault := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusUnauthorized ||
+			resp.StatusCode == http.StatusForbidden || resp.StatusCode >= http.StatusInternalServerError
+		if channelFault {
+			model.ReportChannelBreaker(channel.Id, isMultiKey, keyIndex, false)
+		}
+		return nil, fmt.Errorf("moderation channel #%d returned status %d", channel.Id, resp.StatusCode)
+	}
+	if err != nil {
+		model.ReportChannelBreaker(channel.Id, isMultiKey, keyIndex, false)
+		return nil, err
+	}
+	var moderation moderationResponse
+	if err := common.Unmarshal(respBody, &moderation); err != nil {
+		model.ReportChannelBreaker(channel.Id, isMultiKey, keyIndex, false)
+		return nil, fmt.Errorf("invalid moderation response: %w", err)
+	}
+	model.ReportChannelBreaker(channel.Id, isMultiKey, keyIndex, true)
+	verdict := &GuardrailVerdict{}
+	for _, result := range moderation.Results {
+		verdict.Flagged = verdict.Flagged || result.Flagged
+		for category, hit := range result.Categories {
+			if hit {
+				verdict.Categories = append(verdict.Categories, category)
+			}
+		}
+	}
+	verdict.Categories = RemoveDuplicate(verdict.Categories)
+	sort.Strings(verdict.Categories)
+	return verdict, nil
+}
+
+func doGuardrailRequest(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("guardrail backend returned status %d", resp.StatusCode)
+	}
+	return respBody, nil
+}
+
+func newGuardrailLog(c *gin.Context, info *relaycommon.RelayInfo, stage string, action string, verdict *GuardrailVerdict, content string) *model.GuardrailLog {
+	if utf8.RuneCountInString(content) > guardrailLogContentLimit {
+		content = string([]rune(content)[:guardrailLogContentLimit])
+	}
+	return &model.GuardrailLog{
+		RequestId:  c.GetString(common.RequestIdKey),
+		UserId:     info.UserId,
+		Username:   c.GetString("username"),
+		TokenId:    info.TokenId,
+		TokenName:  c.GetString("token_name"),
+		Group:      info.UsingGroup,
+		ModelName:  info.OriginModelName,
+		Stage:      stage,
+		Action:     action,
+		Categories: strings.Join(verdict.Categories, ","),
+		Content:    content,
+	}
+}
+
+// CheckPromptGuardrail 在预扣费前审核用户输入，分组策略为 block 时拒绝命中的请求
+func CheckPromptGuardrail(c *gin.Context, info *relaycommon.RelayInfo, text string) *types.NewAPIError {
+	action := operation_setting.GetGuardrailAction(info.UsingGroup)
+	if action == operation_setting.GuardrailActionOff || text == "" {
+		return nil
+	}
+	verdict, err := Moderate(c.Request.Context(), model.GuardrailStagePrompt, info.UsingGroup, info.OriginModelName, info.UserId, text)
+	if err != nil {
+		logger.LogError(c, "guardrail check failed: "+err.Error())
+		if operation_setting.GetGuardrailSetting().FailOpenEnabled {
+			return nil
+		}
+		return types.NewErrorWithStatusCode(errors.New("content moderation is unavailable"), types.ErrorCodeGuardrailCheckFailed, http.StatusServiceUnavailable, types.ErrOptionWithSkipRetry())
+	}
+	if !verdict.Flagged {
+		return nil
+	}
+
+	blocked := action == operation_setting.GuardrailActionBlock
+	logger.LogWarn(c, fmt.Sprintf("guardrail flagged prompt, action: %s, categories: %s", action, strings.Join(verdict.Categories, ",")))
+	guardrailLog := newGuardrailLog(c, info, model.GuardrailStagePrompt, action, verdict, text)
+	guardrailLog.Blocked = blocked
+	if err := model.CreateGuardrailLog(guardrailLog); err != nil {
+		logger.LogError(c, "failed to record guardrail log: "+err.Error())
+	}
+	if blocked {
+		return types.NewErrorWithStatusCode(fmt.Errorf("request blocked by content moderation: %s", strings.Join(verdict.Categories, ", ")),
+			types.ErrorCodeGuardrailBlocked, http.StatusBadRequest, types.ErrOptionWithSkipRetry())
+	}
+	return nil
+}
+
+type guardrailCaptureWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	overflow bool
+}
+
+func (w *guardrailCaptureWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > guardrailCaptureLimit {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
+
+func (w *guardrailCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *guardrailCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// GuardrailCapture 记录返回给用户的输出，请求完成后异步审核
+type GuardrailCapture struct {
+	c      *gin.Context
+	info   *relaycommon.RelayInfo
+	action string
+	writer *guardrailCaptureWriter
+	origin gin.ResponseWriter
+}
+
+// StartGuardrailCapture 开始记录输出，未开启输出审核时返回 nil，nil 的 GuardrailCapture 可以安全调用
+func StartGuardrailCapture(c *gin.Context, info *relaycommon.RelayInfo) *GuardrailCapture {
+	if !operation_setting.GetGuardrailSetting().CheckCompletionEnabled {
+		return nil
+	}
+	action := operation_setting.GetGuardrailAction(info.UsingGroup)
+	if action == operation_setting.GuardrailActionOff {
+		return nil
+	}
+	switch info.RelayFormat {
+	case types.RelayFormatOpenAI, types.RelayFormatClaude, types.RelayFormatGemini:
+	default:
+		return nil
+	}
+	writer := &guardrailCaptureWriter{ResponseWriter: c.Writer}
+	capture := &GuardrailCapture{c: c, info: info, action: action, writer: writer, origin: c.Writer}
+	c.Writer = writer
+	return capture
+}
+
+// Finish 停止记录，请求成功时异步审核输出。输出已返回给用户，命中时只记录不拦截
+func (gc *GuardrailCapture) Finish(success bool) {
+	if gc == nil {
+		return
+	}
+	gc.c.Writer = gc.origin
+	if !success || gc.writer.overflow || gc.writer.Status() != http.StatusOK {
+		return
+	}
+	text := extractCompletionText(gc.info.RelayFormat, gc.writer.buf.Bytes())
+	if text == "" {
+		return
+	}
+	// 请求结束后 gin.Context 会被复用，提前生成记录模板
+	guardrailLog := newGuardrailLog(gc.c, gc.info, model.GuardrailStageCompletion, gc.action, &GuardrailVerdict{}, text)
+	gopool.Go(func() {
+		verdict, err := Moderate(context.Background(), model.GuardrailStageCompletion, guardrailLog.Group, guardrailLog.ModelName, guardrailLog.UserId, text)
+		if err != nil {
+			common.SysError("guardrail completion check failed: " + err.Error())
+			return
+		}
+		if !verdict.Flagged {
+			return
+		}
+		guardrailLog.Categories = strings.Join(verdict.Categories, ",")
+		if err := model.CreateGuardrailLog(guardrailLog); err != nil {
+			common.SysError("failed to record guardrail log: " + err.Error())
+		}
+	})
+}
+
+// extractCompletionText 从返回给用户的响应中提取模型输出的文本，支持流式与非流式
+func extractCompletionText(format types.RelayFormat, data []byte) string {
+	var builder strings.Builder
+	collect := func(body map[string]any) {
+		switch format {
+		case types.RelayFormatOpenAI:
+			for _, item := range jsonArray(body["choices"]) {
+				choice := jsonObject(item)
+				for _, key := range []string{"delta", "message"} {
+					if content, ok := jsonObject(choice[key])["content"].(string); ok {
+						builder.WriteString(content)
+					}
+				}
+			}
+		case types.RelayFormatClaude:
+			if text, ok := jsonObject(body["delta"])["text"].(string); ok {
+				builder.WriteString(text)
+			}
+			for _, item := range jsonArray(body["content"]) {
+				if text, ok := jsonObject(item)["text"].(string); ok {
+					builder.WriteString(text)
+				}
+			}
+		case types.RelayFormatGemini:
+			for _, item := range jsonArray(body["candidates"]) {
+				for _, part := range jsonArray(jsonObject(jsonObject(item)["content"])["parts"]) {
+					partObject := jsonObject(part)
+					if text, ok := partObject["text"].(string); ok && partObject["thought"] != true {
+						builder.WriteString(text)
+					}
+				}
+			}
+		}
+	}
+
+	var body map[string]any
+	if decodeJsonNumber(data, &body) == nil {
+		collect(body)
+		return builder.String()
+	}
+	for len(data) > 0 {
+		end, sepLen := nextEventEnd(data)
+		event := data
+		if end >= 0 {
+			event = data[:end]
+			data = data[end+sepLen:]
+		} else {
+			data = nil
+		}
+		for _, line := range strings.Split(string(event), "\n") {
+			value, ok := strings.CutPrefix(strings.TrimSpace(line), "data:")
+			if !ok {
+				continue
+			}
+			var chunk map[string]any
+			if decodeJsonNumber([]byte(strings.TrimSpace(value)), &chunk) == nil {
+				collect(chunk)
+			}
+		}
+	}
+	return builder.String()
+}
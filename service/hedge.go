@@ -0,0 +1,278 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// hedgeSelectTimes 选择对冲渠道时的最大尝试次数，随机选择可能多次选中首个请求的渠道
+const hedgeSelectTimes = 3
+
+var errHedgeLost = errors.New("hedged request lost to another attempt")
+
+// GetHedgeDelay 返回请求等待首字节的时间，返回 0 表示不发出对冲请求；
+// 仅文本对话类请求支持对冲，指定渠道的请求不发出对冲请求
+func GetHedgeDelay(c *gin.Context, info *relaycommon.RelayInfo) time.Duration {
+	if _, ok := c.Get("specific_channel_id"); ok {
+		return 0
+	}
+	switch info.RelayFormat {
+	case types.RelayFormatOpenAI:
+		if info.RelayMode != relayconstant.RelayModeChatCompletions && info.RelayMode != relayconstant.RelayModeCompletions {
+			return 0
+		}
+	case types.RelayFormatOpenAIResponses, types.RelayFormatClaude, types.RelayFormatGemini:
+	default:
+		return 0
+	}
+	delayMs := operation_setting.GetHedgeDelayMs(info.UsingGroup, common.GetContextKeyInt(c, constant.ContextKeyTokenHedgeDelay))
+	return time.Duration(delayMs) * time.Millisecond
+}
+
+// SelectHedgeChannel 为对冲请求选择一个与首个请求不同的渠道，没有其他可用渠道时返回 nil
+func SelectHedgeChannel(param *RetryParam, excludeChannelId int) *model.Channel {
+	modelName := param.ModelName
+	for i := 0; i < hedgeSelectTimes; i++ {
+		channel, _, err := CacheGetRandomSatisfiedChannel(param)
+		// 对冲请求不切换降级模型，模型不同时计费与首个请求不一致
+		if err != nil || channel == nil || param.ModelName != modelName {
+			return nil
+		}
+		if channel.Id != excludeChannelId {
+			return channel
+		}
+	}
+	return nil
+}
+
+// HedgeGroup 同一个用户请求的首个请求与对冲请求，先向客户端写出内容的请求胜出，其余请求被取消
+type HedgeGroup struct {
+	origin gin.ResponseWriter
+
+	mu       sync.Mutex
+	winner   *HedgeAttempt
+	attempts []*HedgeAttempt
+	won      chan struct{}
+}
+
+// HedgeAttempt 对冲中的单个请求，作为该请求的 writer，胜出前写出的内容只在本地保存
+type HedgeAttempt struct {
+	gin.ResponseWriter
+	group  *HedgeGroup
+	cancel context.CancelFunc
+	header http.Header
+	status int
+
+	Hedged  bool // 是否为超时后发出的对冲请求
+	Settled bool // 落选后是否已记录用量
+}
+
+func NewHedgeGroup(origin gin.ResponseWriter) *HedgeGroup {
+	return &HedgeGroup{
+		origin: origin,
+		won:    make(chan struct{}),
+	}
+}
+
+// Attach 将请求加入对冲，替换请求的 writer；已有请求胜出时立即取消该请求
+func (g *HedgeGroup) Attach(c *gin.Context, cancel context.CancelFunc, hedged bool) *HedgeAttempt {
+	attempt := &HedgeAttempt{
+		ResponseWriter: g.origin,
+		group:          g,
+		cancel:         cancel,
+		header:         make(http.Header),
+		Hedged:         hedged,
+	}
+	g.mu.Lock()
+	g.attempts = append(g.attempts, attempt)
+	lost := g.winner != nil
+	g.mu.Unlock()
+	if lost {
+		cancel()
+	}
+	c.Writer = attempt
+	common.SetContextKey(c, constant.ContextKeyHedgeAttempt, attempt)
+	return attempt
+}
+
+// Detach 恢复请求原始的 writer
+func (g *HedgeGroup) Detach(c *gin.Context) {
+	c.Writer = g.origin
+	common.SetContextKey(c, constant.ContextKeyHedgeAttempt, (*HedgeAttempt)(nil))
+}
+
+// Won 有请求胜出时关闭
+func (g *HedgeGroup) Won() <-chan struct{} {
+	return g.won
+}
+
+func (g *HedgeGroup) Winner() *HedgeAttempt {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.winner
+}
+
+// claim 尝试让当前请求胜出，输出保存的响应头并取消其余请求，返回当前请求是否胜出
+func (a *HedgeAttempt) claim() bool {
+	g := a.group
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.winner == nil {
+		g.winner = a
+		header := g.origin.Header()
+		for key, values := range a.header {
+			header[key] = values
+		}
+		if a.status != 0 {
+			g.origin.WriteHeader(a.status)
+		}
+		for _, attempt := range g.attempts {
+			if attempt != a {
+				attempt.cancel()
+			}
+		}
+		close(g.won)
+	}
+	return g.winner == a
+}
+
+func (a *HedgeAttempt) isWinner() bool {
+	return a.group.Winner() == a
+}
+
+// Lost 其他请求已经胜出
+func (a *HedgeAttempt) Lost() bool {
+	winner := a.group.Winner()
+	return winner != nil && winner != a
+}
+
+func (a *HedgeAttempt) Header() http.Header {
+	if a.isWinner() {
+		return a.group.origin.Header()
+	}
+	return a.header
+}
+
+func (a *HedgeAttempt) WriteHeader(code int) {
+	if a.isWinner() {
+		a.group.origin.WriteHeader(code)
+		return
+	}
+	if code > 0 {
+		a.status = code
+	}
+}
+
+func (a *HedgeAttempt) WriteHeaderNow() {
+	if a.claim() {
+		a.group.origin.WriteHeaderNow()
+	}
+}
+
+func (a *HedgeAttempt) Write(data []byte) (int, error) {
+	// SSE 注释（如 ping）不是模型输出，不能作为首字节
+	if bytes.HasPrefix(data, []byte(":")) && !a.isWinner() {
+		if a.Lost() {
+			return 0, errHedgeLost
+		}
+		return len(data), nil
+	}
+	if !a.claim() {
+		return 0, errHedgeLost
+	}
+	return a.group.origin.Write(data)
+}
+
+func (a *HedgeAttempt) WriteString(s string) (int, error) {
+	return a.Write([]byte(s))
+}
+
+func (a *HedgeAttempt) Flush() {
+	if a.isWinner() {
+		a.group.origin.Flush()
+	}
+}
+
+func (a *HedgeAttempt) Status() int {
+	if a.isWinner() {
+		return a.group.origin.Status()
+	}
+	if a.status != 0 {
+		return a.status
+	}
+	return http.StatusOK
+}
+
+func (a *HedgeAttempt) Size() int {
+	if a.isWinner() {
+		return a.group.origin.Size()
+	}
+	return -1
+}
+
+func (a *HedgeAttempt) Written() bool {
+	if a.isWinner() {
+		return a.group.origin.Written()
+	}
+	return false
+}
+
+func getHedgeAttempt(c *gin.Context) *HedgeAttempt {
+	attempt, _ := common.GetContextKeyType[*HedgeAttempt](c, constant.ContextKeyHedgeAttempt)
+	return attempt
+}
+
+// HedgeLost 对冲中其他请求已经胜出，未使用对冲时返回 false
+func HedgeLost(c *gin.Context) bool {
+	attempt := getHedgeAttempt(c)
+	return attempt != nil && attempt.Lost()
+}
+
+// ClaimHedgeWin 结算前调用，未使用对冲或当前请求胜出时返回 true；
+// 请求未写出任何内容就已完成且尚无请求胜出时，当前请求胜出
+func ClaimHedgeWin(c *gin.Context) bool {
+	attempt := getHedgeAttempt(c)
+	return attempt == nil || attempt.claim()
+}
+
+// RecordHedgeLoserUsage 记录落选请求在上游产生的用量，不向用户计费，消耗计入渠道已用额度用于成本统计
+func RecordHedgeLoserUsage(c *gin.Context, info *relaycommon.RelayInfo, channelId int, promptTokens int, completionTokens int, quota int, content string) {
+	if attempt := getHedgeAttempt(c); attempt != nil {
+		attempt.Settled = true
+	}
+	if quota > 0 {
+		model.UpdateChannelUsedQuota(channelId, quota)
+	}
+	other := map[string]interface{}{
+		"hedge_loser": true,
+		"hedge_cost":  quota,
+	}
+	model.RecordConsumeLog(c, info.UserId, model.RecordConsumeLogParams{
+		ChannelId:        channelId,
+		PromptTokens:     promptTokens,
+		CompletionTokens: completionTokens,
+		ModelName:        info.OriginModelName,
+		TokenName:        c.GetString("token_name"),
+		Quota:            0,
+		Content:          content,
+		TokenId:          info.TokenId,
+		UseTimeSeconds:   int(time.Since(info.StartTime).Seconds()),
+		IsStream:         info.IsStream,
+		Group:            info.UsingGroup,
+		Other:            other,
+	})
+}
@@ -0,0 +1,104 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newHedgeTestContexts(t *testing.T) (*httptest.ResponseRecorder, *HedgeGroup, *gin.Context, *gin.Context, context.Context, context.Context) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	recorder := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(recorder)
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	hc := c.Copy()
+	group := NewHedgeGroup(c.Writer)
+	primaryCtx, cancelPrimary := context.WithCancel(context.Background())
+	hedgeCtx, cancelHedge := context.WithCancel(context.Background())
+	t.Cleanup(cancelPrimary)
+	t.Cleanup(cancelHedge)
+	group.Attach(c, cancelPrimary, false)
+	group.Attach(hc, cancelHedge, true)
+	return recorder, group, c, hc, primaryCtx, hedgeCtx
+}
+
+func TestHedgeFirstWriteWins(t *testing.T) {
+	recorder, group, c, hc, primaryCtx, hedgeCtx := newHedgeTestContexts(t)
+
+	c.Writer.Header().Set("X-Attempt", "primary")
+	hc.Writer.Header().Set("Content-Type", "text/event-stream")
+	hc.Writer.Header().Set("X-Attempt", "hedge")
+	if _, err := hc.Writer.WriteString("data: hedge\n\n"); err != nil {
+		t.Fatalf("hedge write failed: %v", err)
+	}
+	if _, err := c.Writer.WriteString("data: primary\n\n"); err == nil {
+		t.Fatal("expected losing attempt write to fail")
+	}
+
+	if group.Winner() == nil || !group.Winner().Hedged {
+		t.Fatal("expected hedged attempt to win")
+	}
+	if primaryCtx.Err() == nil {
+		t.Fatal("expected losing attempt to be cancelled")
+	}
+	if hedgeCtx.Err() != nil {
+		t.Fatal("winning attempt must not be cancelled")
+	}
+	if !HedgeLost(c) || HedgeLost(hc) {
+		t.Fatal("unexpected HedgeLost result")
+	}
+	if ClaimHedgeWin(c) || !ClaimHedgeWin(hc) {
+		t.Fatal("unexpected ClaimHedgeWin result")
+	}
+	if got := recorder.Body.String(); got != "data: hedge\n\n" {
+		t.Fatalf("unexpected body %q", got)
+	}
+	if got := recorder.Header().Get("X-Attempt"); got != "hedge" {
+		t.Fatalf("expected winner headers, got %q", got)
+	}
+}
+
+func TestHedgePingDoesNotClaim(t *testing.T) {
+	recorder, group, c, hc, _, _ := newHedgeTestContexts(t)
+
+	if _, err := c.Writer.Write([]byte(": PING\n\n")); err != nil {
+		t.Fatalf("ping write failed: %v", err)
+	}
+	if group.Winner() != nil {
+		t.Fatal("ping must not decide the winner")
+	}
+	c.Writer.WriteHeader(http.StatusBadRequest)
+	if _, err := hc.Writer.Write([]byte("data: hedge\n\n")); err != nil {
+		t.Fatalf("hedge write failed: %v", err)
+	}
+	if _, err := c.Writer.Write([]byte(": PING\n\n")); err == nil {
+		t.Fatal("expected ping of losing attempt to fail")
+	}
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("losing attempt status leaked: %d", recorder.Code)
+	}
+	if got := recorder.Body.String(); got != "data: hedge\n\n" {
+		t.Fatalf("unexpected body %q", got)
+	}
+}
+
+func TestHedgeSettleClaimsWithoutOutput(t *testing.T) {
+	_, group, c, hc, _, hedgeCtx := newHedgeTestContexts(t)
+
+	if !ClaimHedgeWin(c) {
+		t.Fatal("first settled attempt should win")
+	}
+	if group.Winner().Hedged {
+		t.Fatal("expected primary attempt to win")
+	}
+	if hedgeCtx.Err() == nil {
+		t.Fatal("expected hedged attempt to be cancelled")
+	}
+	if ClaimHedgeWin(hc) {
+		t.Fatal("second settled attempt must lose")
+	}
+}
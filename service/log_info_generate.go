@@ -49,6 +49,29 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		other["is_model_mapped"] = true
 		other["upstream_model_name"] = relayInfo.UpstreamModelName
 	}
+	if fallbackFrom := common.GetContextKeyString(ctx, constant.ContextKeyModelFallbackFrom); fallbackFrom != "" {
+		other["fallback_from"] = fallbackFrom
+	}
+	if attempt := getHedgeAttempt(ctx); attempt != nil && attempt.Hedged {
+		other["hedge_won"] = true
+	}
+	if waitMs := common.GetContextKeyInt(ctx, constant.ContextKeyAdmissionWaitMs); waitMs > 0 {
+		other["admission_wait_ms"] = waitMs
+	}
+	if relayInfo.ResponseCacheHit {
+		other["response_cache_hit"] = true
+		other["response_cache_ratio"] = relayInfo.PriceData.OtherRatios[responseCacheRatioKey]
+	}
+	if len(relayInfo.SensitiveWordsHit) > 0 {
+		other["sensitive_words"] = relayInfo.SensitiveWordsHit
+		other["sensitive_stopped"] = relayInfo.SensitiveStopped
+	}
+	if relayInfo.PIIRedactions > 0 {
+		other["pii_redactions"] = relayInfo.PIIRedactions
+	}
+	if relayInfo.PayloadLogEnabled {
+		other["payload_request_id"] = ctx.GetString(common.RequestIdKey)
+	}
 
 	isSystemPromptOverwritten := common.GetContextKeyBool(ctx, constant.ContextKeySystemPromptOverride)
 	if isSystemPromptOverwritten {
@@ -62,6 +85,9 @@ func GenerateTextOtherInfo(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, m
 		adminInfo["is_multi_key"] = true
 		adminInfo["multi_key_index"] = common.GetContextKeyInt(ctx, constant.ContextKeyChannelMultiKeyIndex)
 	}
+	if IsChannelAffinityHit(ctx, common.GetContextKeyInt(ctx, constant.ContextKeyChannelId)) {
+		adminInfo["channel_affinity"] = true
+	}
 
 	isLocalCountTokens := common.GetContextKeyBool(ctx, constant.ContextKeyLocalCountTokens)
 	if isLocalCountTokens {
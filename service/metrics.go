@@ -0,0 +1,36 @@
+package service
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/QuantumNous/new-api/common/metrics"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RecordRelayMetrics 记录一次转发尝试的状态码、耗时与首字时间
+func RecordRelayMetrics(c *gin.Context, info *relaycommon.RelayInfo, channelId int, attemptStart time.Time, err *types.NewAPIError) {
+	if channelId == 0 {
+		return
+	}
+	statusCode := c.Writer.Status()
+	if err != nil {
+		statusCode = err.StatusCode
+		if statusCode == 0 {
+			statusCode = http.StatusInternalServerError
+		}
+	}
+	var ttft float64
+	if err == nil && info.IsStream && info.FirstResponseTime.After(attemptStart) {
+		ttft = info.FirstResponseTime.Sub(attemptStart).Seconds()
+	}
+	metrics.RelayAttempt(info.OriginModelName, channelId, info.UsingGroup, statusCode, time.Since(attemptStart).Seconds(), ttft)
+}
+
+// RecordConsumeMetrics 记录一次请求最终计费的 token 数与额度
+func RecordConsumeMetrics(info *relaycommon.RelayInfo, promptTokens int, completionTokens int, quota int) {
+	metrics.Consume(info.OriginModelName, info.ChannelId, info.UsingGroup, promptTokens, completionTokens, quota)
+}
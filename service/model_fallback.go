@@ -0,0 +1,124 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/setting/ratio_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ModelFallbackHeader 发生模型降级时，通过该响应头返回实际使用的模型
+const ModelFallbackHeader = "X-Fallback-Model"
+
+func modelFallbackAllowed(c *gin.Context) bool {
+	return operation_setting.GetModelFallbackSetting().Enabled &&
+		!common.GetContextKeyBool(c, constant.ContextKeyTokenFallbackDisabled)
+}
+
+// tokenAllowsModel 令牌开启模型限制时，检查模型是否在允许范围内
+func tokenAllowsModel(c *gin.Context, modelName string) bool {
+	if !common.GetContextKeyBool(c, constant.ContextKeyTokenModelLimitEnabled) {
+		return true
+	}
+	modelLimit, ok := common.GetContextKeyType[map[string]bool](c, constant.ContextKeyTokenModelLimit)
+	return ok && modelLimit[ratio_setting.FormatMatchingModelName(modelName)]
+}
+
+func (p *RetryParam) selectGroups() []string {
+	if p.TokenGroup == "auto" {
+		return GetUserAutoGroup(common.GetContextKeyString(p.Ctx, constant.ContextKeyUserGroup))
+	}
+	return []string{p.TokenGroup}
+}
+
+func hasSatisfiedChannel(groups []string, modelName string) bool {
+	for _, group := range groups {
+		if channelIds, err := model.GetSatisfiedChannelIds(group, modelName); err == nil && len(channelIds) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// modelChannelsExhausted 本次请求是否已经尝试过分组下该模型的全部渠道
+func modelChannelsExhausted(c *gin.Context, group string, modelName string) bool {
+	channelIds, err := model.GetSatisfiedChannelIds(group, modelName)
+	if err != nil {
+		return false
+	}
+	usedChannels := c.GetStringSlice("use_channel")
+	for _, channelId := range channelIds {
+		if !slices.Contains(usedChannels, strconv.Itoa(channelId)) {
+			return false
+		}
+	}
+	return true
+}
+
+// nextFallbackModel 返回降级链中下一个令牌可访问且有可用渠道的模型，没有时返回空字符串
+func (p *RetryParam) nextFallbackModel() string {
+	if !modelFallbackAllowed(p.Ctx) {
+		return ""
+	}
+	if !p.fallbackLoaded {
+		p.fallbackLoaded = true
+		for _, modelName := range operation_setting.GetModelFallbacks(p.TokenGroup, p.ModelName) {
+			// 降级链中出现环时，已经尝试过的模型不再重复尝试
+			if modelName != p.ModelName && !slices.Contains(p.fallbackModels, modelName) {
+				p.fallbackModels = append(p.fallbackModels, modelName)
+			}
+		}
+	}
+	groups := p.selectGroups()
+	for len(p.fallbackModels) > 0 {
+		modelName := p.fallbackModels[0]
+		if tokenAllowsModel(p.Ctx, modelName) && hasSatisfiedChannel(groups, modelName) {
+			return modelName
+		}
+		p.fallbackModels = p.fallbackModels[1:]
+	}
+	return ""
+}
+
+// PrepareModelFallback 当前模型的重试次数用完后调用，存在可用的降级模型时返回 true，
+// 下一次选择渠道时将切换到该模型并重新开始计算重试次数
+func (p *RetryParam) PrepareModelFallback() bool {
+	if p.nextFallbackModel() == "" {
+		return false
+	}
+	p.fallbackPending = true
+	p.SetRetry(0)
+	p.ResetRetryNextTry()
+	return true
+}
+
+// switchFallbackModel 切换到下一个降级模型，并重置重试与自动分组的状态
+func (p *RetryParam) switchFallbackModel() bool {
+	modelName := p.nextFallbackModel()
+	if modelName == "" {
+		return false
+	}
+	p.fallbackModels = p.fallbackModels[1:]
+	logger.LogInfo(p.Ctx, fmt.Sprintf("模型 %s 的渠道已用尽，降级到模型 %s", p.ModelName, modelName))
+	p.ModelName = modelName
+	p.SetRetry(0)
+	common.SetContextKey(p.Ctx, constant.ContextKeyAutoGroupIndex, 0)
+	common.SetContextKey(p.Ctx, constant.ContextKeyAutoGroupRetryIndex, 0)
+	return true
+}
+
+// MarkModelFallback 记录用户请求的模型，并在响应头中返回实际使用的模型
+func MarkModelFallback(c *gin.Context, fromModel string, toModel string) {
+	if common.GetContextKeyString(c, constant.ContextKeyModelFallbackFrom) == "" {
+		common.SetContextKey(c, constant.ContextKeyModelFallbackFrom, fromModel)
+	}
+	c.Header(ModelFallbackHeader, toModel)
+}
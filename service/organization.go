@@ -0,0 +1,36 @@
+package service
+
+import (
+	"fmt"
+
+	"github.com/QuantumNous/new-api/logger"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+// GetPayerQuota 返回本次请求付费方的剩余额度，组织令牌使用组织额度
+func GetPayerQuota(relayInfo *relaycommon.RelayInfo) (int, error) {
+	if relayInfo.OrgId != 0 {
+		org, err := model.GetOrganizationCache(relayInfo.OrgId)
+		if err != nil {
+			return 0, err
+		}
+		return org.Quota, nil
+	}
+	return model.GetUserQuota(relayInfo.UserId, false)
+}
+
+// CheckOrganizationMemberLimit 检查成员在组织内的消费上限
+func CheckOrganizationMemberLimit(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.OrgId == 0 {
+		return nil
+	}
+	member, err := model.GetOrganizationMemberCache(relayInfo.OrgId, relayInfo.UserId)
+	if err != nil {
+		return err
+	}
+	if member.QuotaLimit > 0 && (member.UsedQuota >= member.QuotaLimit || member.UsedQuota+quota > member.QuotaLimit) {
+		return fmt.Errorf("organization member quota limit exceeded, used: %s, limit: %s, need quota: %s", logger.FormatQuota(member.UsedQuota), logger.FormatQuota(member.QuotaLimit), logger.FormatQuota(quota))
+	}
+	return nil
+}
@@ -0,0 +1,81 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func TestGetPayerQuota(t *testing.T) {
+	setupServiceTestDB(t)
+	user := &model.User{Username: "payer", Quota: 1000}
+	if err := model.DB.Create(user).Error; err != nil {
+		t.Fatal(err)
+	}
+	org, err := model.CreateOrganization("payer-org", user.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = model.SetOrganizationQuota(org.Id, 300); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name  string
+		orgId int
+		want  int
+	}{
+		{"personal token", 0, 1000},
+		{"organization token", org.Id, 300},
+	}
+	for _, tt := range tests {
+		quota, err := GetPayerQuota(&relaycommon.RelayInfo{UserId: user.Id, OrgId: tt.orgId})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if quota != tt.want {
+			t.Fatalf("%s: expected %d, got %d", tt.name, tt.want, quota)
+		}
+	}
+}
+
+func TestCheckOrganizationMemberLimit(t *testing.T) {
+	setupServiceTestDB(t)
+	owner := &model.User{Username: "limit-owner"}
+	if err := model.DB.Create(owner).Error; err != nil {
+		t.Fatal(err)
+	}
+	org, err := model.CreateOrganization("limit-org", owner.Id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		limit   int
+		used    int
+		quota   int
+		wantErr bool
+	}{
+		{"no limit", 0, 5000, 100, false},
+		{"within limit", 1000, 800, 200, false},
+		{"exceeds limit", 1000, 800, 201, true},
+		{"limit used up", 1000, 1000, 0, true},
+		{"over limit after refund lag", 1000, 1200, 0, true},
+	}
+	for i, tt := range tests {
+		member := &model.OrganizationMember{OrgId: org.Id, UserId: owner.Id + i + 1, Role: model.OrganizationRoleMember, QuotaLimit: tt.limit, UsedQuota: tt.used}
+		if err := model.DB.Create(member).Error; err != nil {
+			t.Fatal(err)
+		}
+		err := CheckOrganizationMemberLimit(&relaycommon.RelayInfo{UserId: member.UserId, OrgId: org.Id}, tt.quota)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if err := CheckOrganizationMemberLimit(&relaycommon.RelayInfo{UserId: owner.Id}, 1<<30); err != nil {
+		t.Fatalf("personal tokens have no member limit, got %v", err)
+	}
+	if err := CheckOrganizationMemberLimit(&relaycommon.RelayInfo{UserId: owner.Id + 100, OrgId: org.Id}, 1); err == nil {
+		t.Fatal("expected error for a user outside the organization")
+	}
+}
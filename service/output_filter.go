@@ -0,0 +1,723 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	relayconstant "github.com/QuantumNous/new-api/relay/constant"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	outputFilterModeUnknown = iota
+	outputFilterModeStream
+	outputFilterModeJson
+	outputFilterModeRaw
+)
+
+// outputTextFilter 处理模型输出的文本，流式输出按 index 分别维护缓存
+type outputTextFilter interface {
+	// delta 处理流式增量文本，返回可以输出的文本以及是否需要中断输出
+	delta(index int, text string) (string, bool)
+	// flush 取出缓存的全部文本
+	flush(index int) string
+	// full 处理非流式响应中的完整文本
+	full(text string) (string, bool)
+}
+
+// OutputFilterWriter 改写写给客户端的模型输出，按客户端请求的格式解析 OpenAI、Claude、Gemini 的响应
+type OutputFilterWriter struct {
+	gin.ResponseWriter
+	c       *gin.Context
+	origin  gin.ResponseWriter
+	format  types.RelayFormat
+	filters []outputTextFilter
+	// 文本补全接口的输出在 choices[].text 中
+	completions bool
+
+	mu        sync.Mutex
+	mode      int
+	pending   bytes.Buffer
+	indexes   map[int]struct{}
+	lastChunk map[string]any
+	itemIds   map[int]any // Responses 接口各段文本所属的 item_id
+	stopped   bool
+}
+
+// StartOutputFilter 按配置开启输出改写（PII 还原、敏感词检查），无需改写或不支持的请求返回 nil，nil 的 OutputFilterWriter 可以安全调用
+func StartOutputFilter(c *gin.Context, info *relaycommon.RelayInfo) *OutputFilterWriter {
+	// 文本补全与 Responses 接口只还原占位符，敏感词检查仅支持对话格式
+	restoreOnly := false
+	switch info.RelayFormat {
+	case types.RelayFormatOpenAI:
+		switch info.RelayMode {
+		case relayconstant.RelayModeChatCompletions:
+		case relayconstant.RelayModeCompletions:
+			restoreOnly = true
+		default:
+			return nil
+		}
+	case types.RelayFormatOpenAIResponses:
+		restoreOnly = true
+	case types.RelayFormatClaude, types.RelayFormatGemini:
+	default:
+		return nil
+	}
+	var filters []outputTextFilter
+	// 先还原占位符，再检查还原后的文本
+	if filter := newPIIRestoreFilter(info); filter != nil {
+		filters = append(filters, filter)
+	}
+	if !restoreOnly {
+		if filter := newSensitiveOutputFilter(c, info); filter != nil {
+			filters = append(filters, filter)
+		}
+	}
+	if len(filters) == 0 {
+		return nil
+	}
+	writer := &OutputFilterWriter{
+		ResponseWriter: c.Writer,
+		c:              c,
+		origin:         c.Writer,
+		format:         info.RelayFormat,
+		filters:        filters,
+		completions:    info.RelayFormat == types.RelayFormatOpenAI && info.RelayMode == relayconstant.RelayModeCompletions,
+		indexes:        make(map[int]struct{}),
+		itemIds:        make(map[int]any),
+	}
+	c.Writer = writer
+	return writer
+}
+
+// Finish 输出剩余的缓存内容并恢复原始的 writer
+func (w *OutputFilterWriter) Finish() {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.c.Writer = w.origin
+	if w.stopped {
+		return
+	}
+	if w.mode == outputFilterModeStream {
+		// 上游未发送结束标记时补发窗口中剩余的文本
+		w.flushWindows()
+	}
+	if w.pending.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.pending.Bytes())
+		w.pending.Reset()
+	}
+	// 未写出任何内容时不能 Flush，否则会提前发送状态码，导致之后无法返回错误
+	if w.ResponseWriter.Written() {
+		w.ResponseWriter.Flush()
+	}
+}
+
+func (w *OutputFilterWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *OutputFilterWriter) Write(data []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.stopped {
+		// 已中断输出，丢弃后续内容
+		return len(data), nil
+	}
+	if w.mode == outputFilterModeUnknown {
+		w.mode = w.detectMode()
+	}
+	switch w.mode {
+	case outputFilterModeStream:
+		w.pending.Write(data)
+		w.handleStream()
+		return len(data), nil
+	case outputFilterModeJson:
+		w.pending.Write(data)
+		w.handleJson()
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *OutputFilterWriter) detectMode() int {
+	status := w.ResponseWriter.Status()
+	if status != 0 && status != http.StatusOK {
+		return outputFilterModeRaw
+	}
+	contentType := w.ResponseWriter.Header().Get("Content-Type")
+	switch {
+	case strings.HasPrefix(contentType, "text/event-stream"):
+		return outputFilterModeStream
+	case strings.HasPrefix(contentType, "application/json"):
+		return outputFilterModeJson
+	}
+	return outputFilterModeRaw
+}
+
+func (w *OutputFilterWriter) markStopped() {
+	w.stopped = true
+}
+
+func (w *OutputFilterWriter) filterDelta(index int, text string) (string, bool) {
+	w.indexes[index] = struct{}{}
+	for _, filter := range w.filters {
+		var stop bool
+		if text, stop = filter.delta(index, text); stop {
+			return text, true
+		}
+	}
+	return text, false
+}
+
+// filterFlush 依次取出各过滤器缓存的文本，前面过滤器取出的文本需经过后续过滤器处理
+func (w *OutputFilterWriter) filterFlush(index int) string {
+	text := ""
+	for _, filter := range w.filters {
+		if text != "" {
+			var stop bool
+			if text, stop = filter.delta(index, text); stop {
+				w.markStopped()
+				return text
+			}
+		}
+		text += filter.flush(index)
+	}
+	return text
+}
+
+func (w *OutputFilterWriter) filterText(text string) (string, bool) {
+	for _, filter := range w.filters {
+		var stop bool
+		if text, stop = filter.full(text); stop {
+			return text, true
+		}
+	}
+	return text, false
+}
+
+func (w *OutputFilterWriter) handleJson() {
+	var body map[string]any
+	if err := decodeJsonNumber(w.pending.Bytes(), &body); err != nil {
+		// 响应尚未写完，等待后续内容
+		return
+	}
+	w.mode = outputFilterModeRaw
+	if !w.checkResponse(body) {
+		_, _ = w.ResponseWriter.Write(w.pending.Bytes())
+		w.pending.Reset()
+		return
+	}
+	data, err := json.Marshal(body)
+	if err != nil {
+		_, _ = w.ResponseWriter.Write(w.pending.Bytes())
+		w.pending.Reset()
+		return
+	}
+	w.pending.Reset()
+	if w.ResponseWriter.Header().Get("Content-Length") != "" {
+		w.ResponseWriter.Header().Set("Content-Length", strconv.Itoa(len(data)))
+	}
+	_, _ = w.ResponseWriter.Write(data)
+}
+
+// checkResponse 检查非流式响应，返回响应是否被修改
+func (w *OutputFilterWriter) checkResponse(body map[string]any) bool {
+	changed := false
+	switch w.format {
+	case types.RelayFormatOpenAI:
+		for _, item := range jsonArray(body["choices"]) {
+			choice := jsonObject(item)
+			message, textKey := jsonObject(choice["message"]), "content"
+			if w.completions {
+				message, textKey = choice, "text"
+			}
+			content, ok := message[textKey].(string)
+			if !ok {
+				continue
+			}
+			text, stop := w.filterText(content)
+			if text != content {
+				message[textKey] = text
+				changed = true
+			}
+			if stop {
+				choice["finish_reason"] = "content_filter"
+				changed = true
+			}
+		}
+	case types.RelayFormatClaude:
+		blocks := jsonArray(body["content"])
+		for i, item := range blocks {
+			block := jsonObject(item)
+			content, ok := block["text"].(string)
+			if block["type"] != "text" || !ok {
+				continue
+			}
+			text, stop := w.filterText(content)
+			if text != content {
+				block["text"] = text
+				changed = true
+			}
+			if stop {
+				body["content"] = blocks[:i+1]
+				body["stop_reason"] = "refusal"
+				return true
+			}
+		}
+	case types.RelayFormatGemini:
+		for _, item := range jsonArray(body["candidates"]) {
+			candidate := jsonObject(item)
+			content := jsonObject(candidate["content"])
+			parts := jsonArray(content["parts"])
+			for i, partItem := range parts {
+				part := jsonObject(partItem)
+				partText, ok := part["text"].(string)
+				if !ok || part["thought"] == true {
+					continue
+				}
+				text, stop := w.filterText(partText)
+				if text != partText {
+					part["text"] = text
+					changed = true
+				}
+				if stop {
+					content["parts"] = parts[:i+1]
+					candidate["finishReason"] = "SAFETY"
+					changed = true
+					break
+				}
+			}
+		}
+	case types.RelayFormatOpenAIResponses:
+		changed = w.filterResponsesOutput(body["output"])
+	}
+	return changed
+}
+
+// filterResponsesOutput 处理 Responses 接口 output 中的文本，返回是否被修改
+func (w *OutputFilterWriter) filterResponsesOutput(output any) bool {
+	changed := false
+	for _, item := range jsonArray(output) {
+		for _, partItem := range jsonArray(jsonObject(item)["content"]) {
+			if w.filterResponsesPart(jsonObject(partItem)) {
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (w *OutputFilterWriter) filterResponsesPart(part map[string]any) bool {
+	content, ok := part["text"].(string)
+	if part["type"] != "output_text" || !ok {
+		return false
+	}
+	text, _ := w.filterText(content)
+	if text == content {
+		return false
+	}
+	part["text"] = text
+	return true
+}
+
+func (w *OutputFilterWriter) handleStream() {
+	for !w.stopped {
+		data := w.pending.Bytes()
+		end, sepLen := nextEventEnd(data)
+		if end < 0 {
+			return
+		}
+		event := append([]byte(nil), data[:end]...)
+		w.pending.Next(end + sepLen)
+		w.handleEvent(event)
+	}
+	if w.stopped {
+		w.pending.Reset()
+	}
+}
+
+// nextEventEnd 返回第一个完整事件的结束位置与分隔符长度，没有完整事件时返回 -1
+func nextEventEnd(data []byte) (int, int) {
+	lf := bytes.Index(data, []byte("\n\n"))
+	crlf := bytes.Index(data, []byte("\r\n\r\n"))
+	if crlf >= 0 && (lf < 0 || crlf < lf) {
+		return crlf, 4
+	}
+	if lf >= 0 {
+		return lf, 2
+	}
+	return -1, 0
+}
+
+func (w *OutputFilterWriter) handleEvent(raw []byte) {
+	var eventName string
+	var dataLines []string
+	for _, line := range strings.Split(strings.TrimLeft(string(raw), "\r\n"), "\n") {
+		line = strings.TrimSuffix(line, "\r")
+		if value, ok := strings.CutPrefix(line, "event:"); ok {
+			eventName = strings.TrimSpace(value)
+		} else if value, ok := strings.CutPrefix(line, "data:"); ok {
+			dataLines = append(dataLines, strings.TrimPrefix(value, " "))
+		}
+	}
+	data := strings.Join(dataLines, "\n")
+	if len(dataLines) == 0 {
+		w.writeRaw(raw)
+		return
+	}
+	if data == "[DONE]" {
+		w.flushWindows()
+		w.writeRaw(raw)
+		return
+	}
+	var body map[string]any
+	if err := decodeJsonNumber([]byte(data), &body); err != nil {
+		w.writeRaw(raw)
+		return
+	}
+
+	var changed bool
+	switch w.format {
+	case types.RelayFormatOpenAI:
+		changed = w.handleOpenAIChunk(body)
+	case types.RelayFormatClaude:
+		changed = w.handleClaudeEvent(body)
+	case types.RelayFormatGemini:
+		changed = w.handleGeminiChunk(body)
+	case types.RelayFormatOpenAIResponses:
+		changed = w.handleResponsesEvent(body)
+	}
+	if !changed {
+		w.writeRaw(raw)
+	} else {
+		w.writeEvent(eventName, body)
+	}
+
+	if w.stopped {
+		w.writeStopEvents(body)
+	}
+}
+
+func (w *OutputFilterWriter) handleOpenAIChunk(body map[string]any) bool {
+	changed := false
+	for i, item := range jsonArray(body["choices"]) {
+		choice := jsonObject(item)
+		index := jsonInt(choice["index"], i)
+		delta, textKey := jsonObject(choice["delta"]), "content"
+		if w.completions {
+			delta, textKey = choice, "text"
+		}
+		if content, ok := delta[textKey].(string); ok && content != "" {
+			w.lastChunk = body
+			text, stop := w.filterDelta(index, content)
+			delta[textKey] = text
+			changed = true
+			if stop {
+				choice["finish_reason"] = "content_filter"
+				w.markStopped()
+				return true
+			}
+		}
+		if reason, ok := choice["finish_reason"].(string); ok && reason != "" {
+			if held := w.filterFlush(index); held != "" {
+				content, _ := delta[textKey].(string)
+				delta[textKey] = content + held
+				if !w.completions {
+					choice["delta"] = delta
+				}
+				changed = true
+			}
+		}
+	}
+	return changed
+}
+
+func (w *OutputFilterWriter) handleClaudeEvent(body map[string]any) bool {
+	index := jsonInt(body["index"], 0)
+	switch body["type"] {
+	case "content_block_delta":
+		delta := jsonObject(body["delta"])
+		content, ok := delta["text"].(string)
+		if delta["type"] != "text_delta" || !ok {
+			return false
+		}
+		text, stop := w.filterDelta(index, content)
+		delta["text"] = text
+		if stop {
+			w.markStopped()
+		}
+		return true
+	case "content_block_stop":
+		// 内容块结束前补发窗口中剩余的文本
+		if held := w.filterFlush(index); held != "" {
+			w.writeEvent("content_block_delta", claudeTextDelta(index, held))
+		}
+	case "message_delta", "message_stop":
+		w.flushWindows()
+	}
+	return false
+}
+
+func (w *OutputFilterWriter) handleGeminiChunk(body map[string]any) bool {
+	changed := false
+	for i, item := range jsonArray(body["candidates"]) {
+		candidate := jsonObject(item)
+		index := jsonInt(candidate["index"], i)
+		content := jsonObject(candidate["content"])
+		parts := jsonArray(content["parts"])
+		lastText := -1
+		for j, partItem := range parts {
+			part := jsonObject(partItem)
+			partText, ok := part["text"].(string)
+			if !ok || part["thought"] == true {
+				continue
+			}
+			lastText = j
+			w.lastChunk = body
+			text, stop := w.filterDelta(index, partText)
+			part["text"] = text
+			changed = true
+			if stop {
+				content["parts"] = parts[:j+1]
+				candidate["finishReason"] = "SAFETY"
+				w.markStopped()
+				return true
+			}
+		}
+		if reason, ok := candidate["finishReason"].(string); ok && reason != "" {
+			held := w.filterFlush(index)
+			if held == "" {
+				continue
+			}
+			if lastText >= 0 {
+				part := jsonObject(parts[lastText])
+				part["text"] = part["text"].(string) + held
+			} else {
+				content["parts"] = append(parts, map[string]any{"text": held})
+				if _, ok := content["role"]; !ok {
+					content["role"] = "model"
+				}
+				candidate["content"] = content
+			}
+			changed = true
+		}
+	}
+	return changed
+}
+
+// handleResponsesEvent 处理 Responses 接口的流式事件，同一段文本以 output_index 与 content_index 区分
+func (w *OutputFilterWriter) handleResponsesEvent(body map[string]any) bool {
+	index := jsonInt(body["output_index"], 0)<<16 | jsonInt(body["content_index"], 0)
+	switch body["type"] {
+	case "response.output_text.delta":
+		content, ok := body["delta"].(string)
+		if !ok {
+			return false
+		}
+		w.itemIds[index] = body["item_id"]
+		body["delta"], _ = w.filterDelta(index, content)
+		return true
+	case "response.output_text.done":
+		// 文本结束前补发窗口中剩余的文本
+		if held := w.filterFlush(index); held != "" {
+			w.writeEvent("response.output_text.delta", responsesTextDelta(body, held))
+		}
+		content, ok := body["text"].(string)
+		if !ok {
+			return false
+		}
+		text, _ := w.filterText(content)
+		body["text"] = text
+		return text != content
+	case "response.content_part.done":
+		return w.filterResponsesPart(jsonObject(body["part"]))
+	case "response.output_item.done":
+		return w.filterResponsesOutput([]any{body["item"]})
+	case "response.completed", "response.incomplete":
+		w.flushWindows()
+		return w.filterResponsesOutput(jsonObject(body["response"])["output"])
+	}
+	return false
+}
+
+// writeStopEvents 中断输出后补发对应格式的结束事件
+func (w *OutputFilterWriter) writeStopEvents(body map[string]any) {
+	switch w.format {
+	case types.RelayFormatOpenAI:
+		w.writeRaw([]byte("data: [DONE]"))
+	case types.RelayFormatClaude:
+		// 在补发剩余文本时中断的，当前事件本身已经写出
+		eventType := body["type"]
+		if eventType == "content_block_delta" {
+			w.writeEvent("content_block_stop", map[string]any{
+				"type":  "content_block_stop",
+				"index": jsonInt(body["index"], 0),
+			})
+		}
+		if eventType != "message_delta" && eventType != "message_stop" {
+			w.writeEvent("message_delta", map[string]any{
+				"type": "message_delta",
+				"delta": map[string]any{
+					"stop_reason":   "refusal",
+					"stop_sequence": nil,
+				},
+				"usage": map[string]any{
+					"output_tokens": 0,
+				},
+			})
+		}
+		if eventType != "message_stop" {
+			w.writeEvent("message_stop", map[string]any{"type": "message_stop"})
+		}
+	}
+	w.ResponseWriter.Flush()
+}
+
+// flushWindows 以单独的事件补发所有窗口中剩余的文本
+func (w *OutputFilterWriter) flushWindows() {
+	for index := range w.indexes {
+		held := w.filterFlush(index)
+		if held == "" {
+			continue
+		}
+		switch w.format {
+		case types.RelayFormatOpenAI:
+			chunk := map[string]any{
+				"object": "chat.completion.chunk",
+				"choices": []any{map[string]any{
+					"index":         index,
+					"delta":         map[string]any{"content": held},
+					"finish_reason": nil,
+				}},
+			}
+			if w.completions {
+				chunk["object"] = "text_completion"
+				chunk["choices"] = []any{map[string]any{
+					"index":         index,
+					"text":          held,
+					"finish_reason": nil,
+				}}
+			}
+			for _, key := range []string{"id", "created", "model", "system_fingerprint"} {
+				if value, ok := w.lastChunk[key]; ok {
+					chunk[key] = value
+				}
+			}
+			w.writeEvent("", chunk)
+		case types.RelayFormatClaude:
+			w.writeEvent("content_block_delta", claudeTextDelta(index, held))
+		case types.RelayFormatGemini:
+			chunk := map[string]any{
+				"candidates": []any{map[string]any{
+					"index": index,
+					"content": map[string]any{
+						"role":  "model",
+						"parts": []any{map[string]any{"text": held}},
+					},
+				}},
+			}
+			if value, ok := w.lastChunk["modelVersion"]; ok {
+				chunk["modelVersion"] = value
+			}
+			w.writeEvent("", chunk)
+		case types.RelayFormatOpenAIResponses:
+			w.writeEvent("response.output_text.delta", responsesTextDelta(map[string]any{
+				"item_id":       w.itemIds[index],
+				"output_index":  index >> 16,
+				"content_index": index & 0xffff,
+			}, held))
+		}
+		if w.stopped {
+			return
+		}
+	}
+}
+
+func claudeTextDelta(index int, text string) map[string]any {
+	return map[string]any{
+		"type":  "content_block_delta",
+		"index": index,
+		"delta": map[string]any{
+			"type": "text_delta",
+			"text": text,
+		},
+	}
+}
+
+// responsesTextDelta 构造 Responses 接口的文本增量事件，位置信息取自 ref
+func responsesTextDelta(ref map[string]any, text string) map[string]any {
+	return map[string]any{
+		"type":          "response.output_text.delta",
+		"item_id":       ref["item_id"],
+		"output_index":  ref["output_index"],
+		"content_index": ref["content_index"],
+		"delta":         text,
+	}
+}
+
+func (w *OutputFilterWriter) writeRaw(event []byte) {
+	_, _ = w.ResponseWriter.Write(event)
+	_, _ = w.ResponseWriter.Write([]byte("\n\n"))
+}
+
+func (w *OutputFilterWriter) writeEvent(eventName string, body map[string]any) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return
+	}
+	var buf bytes.Buffer
+	if eventName != "" {
+		buf.WriteString("event: " + eventName + "\n")
+	}
+	buf.WriteString("data: ")
+	buf.Write(data)
+	buf.WriteString("\n\n")
+	_, _ = w.ResponseWriter.Write(buf.Bytes())
+}
+
+// decodeJsonNumber 解析 JSON 对象，数字保留原始精度
+func decodeJsonNumber(data []byte, v any) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if decoder.More() {
+		return fmt.Errorf("unexpected data after json object")
+	}
+	return nil
+}
+
+func jsonObject(v any) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	return map[string]any{}
+}
+
+func jsonArray(v any) []any {
+	if a, ok := v.([]any); ok {
+		return a
+	}
+	return nil
+}
+
+func jsonInt(v any, def int) int {
+	if n, ok := v.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return int(i)
+		}
+	}
+	return def
+}
@@ -0,0 +1,176 @@
+package service
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PayloadTranscript 一次请求的完整内容
+type PayloadTranscript struct {
+	RequestId           string `json:"request_id"`
+	Method              string `json:"method"`
+	Path                string `json:"path"`
+	RequestBody         string `json:"request_body"`
+	UpstreamRequestBody string `json:"upstream_request_body"`
+	ResponseStatus      int    `json:"response_status"`
+	ResponseContentType string `json:"response_content_type"`
+	ResponseBody        string `json:"response_body"`
+	Error               string `json:"error,omitempty"`
+	Truncated           bool   `json:"truncated"`
+	// 发送给上游前脱敏的个人信息数量，RequestBody 为脱敏前的原始内容
+	PIIRedactions int `json:"pii_redactions,omitempty"`
+}
+
+func payloadBodyLimit() int {
+	return max(operation_setting.GetPayloadLogSetting().MaxBodySizeKB, 1) << 10
+}
+
+// truncatePayload 截断超出大小限制的内容，返回是否被截断
+func truncatePayload(data []byte) ([]byte, bool) {
+	limit := payloadBodyLimit()
+	if len(data) > limit {
+		return data[:limit], true
+	}
+	return data, false
+}
+
+// CaptureUpstreamRequestBody 记录发送给上游的请求体（参数覆盖之后），返回可以继续读取的请求体
+func CaptureUpstreamRequestBody(info *relaycommon.RelayInfo, requestBody io.Reader) (io.Reader, error) {
+	if !info.PayloadLogEnabled || requestBody == nil {
+		return requestBody, nil
+	}
+	data, err := io.ReadAll(requestBody)
+	if err != nil {
+		return nil, err
+	}
+	captured, _ := truncatePayload(data)
+	info.UpstreamRequestBody = bytes.Clone(captured)
+	return bytes.NewReader(data), nil
+}
+
+type payloadCaptureWriter struct {
+	gin.ResponseWriter
+	buf       bytes.Buffer
+	limit     int
+	truncated bool
+}
+
+func (w *payloadCaptureWriter) capture(data []byte) {
+	if w.truncated {
+		return
+	}
+	if remain := w.limit - w.buf.Len(); len(data) > remain {
+		w.buf.Write(data[:remain])
+		w.truncated = true
+		return
+	}
+	w.buf.Write(data)
+}
+
+func (w *payloadCaptureWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *payloadCaptureWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// PayloadCapture 记录请求体、上游请求体与返回给用户的响应
+type PayloadCapture struct {
+	c          *gin.Context
+	info       *relaycommon.RelayInfo
+	transcript *PayloadTranscript
+	writer     *payloadCaptureWriter
+	origin     gin.ResponseWriter
+}
+
+// StartPayloadCapture 用户、令牌或分组开启了完整内容记录时开始记录，否则返回 nil，nil 的 PayloadCapture 可以安全调用
+func StartPayloadCapture(c *gin.Context, info *relaycommon.RelayInfo) *PayloadCapture {
+	if !operation_setting.ShouldLogPayload(info.UserId, info.TokenId, info.UsingGroup) {
+		return nil
+	}
+	info.PayloadLogEnabled = true
+	transcript := &PayloadTranscript{
+		RequestId: c.GetString(common.RequestIdKey),
+		Method:    c.Request.Method,
+		Path:      c.Request.URL.Path,
+	}
+	if body, err := common.GetRequestBody(c); err == nil {
+		captured, truncated := truncatePayload(body)
+		transcript.RequestBody = string(captured)
+		transcript.Truncated = truncated
+	}
+	writer := &payloadCaptureWriter{ResponseWriter: c.Writer, limit: payloadBodyLimit()}
+	capture := &PayloadCapture{c: c, info: info, transcript: transcript, writer: writer, origin: c.Writer}
+	c.Writer = writer
+	return capture
+}
+
+// Finish 停止记录，返回待保存的记录与完整内容；请求失败时记录错误信息
+func (pc *PayloadCapture) Finish(apiErr *types.NewAPIError) (*model.PayloadLog, *PayloadTranscript) {
+	if pc == nil {
+		return nil, nil
+	}
+	pc.c.Writer = pc.origin
+	transcript := pc.transcript
+	transcript.UpstreamRequestBody = string(pc.info.UpstreamRequestBody)
+	transcript.ResponseStatus = pc.writer.Status()
+	transcript.ResponseContentType = pc.writer.Header().Get("Content-Type")
+	transcript.ResponseBody = pc.writer.buf.String()
+	transcript.Truncated = transcript.Truncated || pc.writer.truncated
+	transcript.PIIRedactions = pc.info.PIIRedactions
+	if apiErr != nil {
+		transcript.ResponseStatus = apiErr.StatusCode
+		transcript.Error = apiErr.Error()
+	}
+	log := &model.PayloadLog{
+		RequestId: transcript.RequestId,
+		CreatedAt: common.GetTimestamp(),
+		UserId:    pc.info.UserId,
+		TokenId:   pc.info.TokenId,
+		Group:     pc.info.UsingGroup,
+		ModelName: pc.info.OriginModelName,
+	}
+	return log, transcript
+}
+
+// EncodePayloadTranscript 以 gzip 压缩的 JSON 保存
+func EncodePayloadTranscript(transcript *PayloadTranscript) ([]byte, int, error) {
+	data, err := common.Marshal(transcript)
+	if err != nil {
+		return nil, 0, err
+	}
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write(data); err != nil {
+		return nil, 0, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, 0, err
+	}
+	return buf.Bytes(), len(data), nil
+}
+
+func DecodePayloadTranscript(reader io.Reader) (*PayloadTranscript, error) {
+	gz, err := gzip.NewReader(reader)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+	var transcript PayloadTranscript
+	if err := common.DecodeJson(gz, &transcript); err != nil {
+		return nil, err
+	}
+	return &transcript, nil
+}
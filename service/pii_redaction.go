@@ -0,0 +1,335 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+type piiDetector struct {
+	name     string
+	pattern  *regexp.Regexp
+	validate func(match string) bool
+}
+
+// 内置检测器，按顺序匹配，先匹配格式更确定的内容
+var builtinPIIDetectors = []piiDetector{
+	{
+		name:    "api_key",
+		pattern: regexp.MustCompile(`\b(?:sk-[A-Za-z0-9_-]{20,}|AKIA[0-9A-Z]{16}|AIza[0-9A-Za-z_-]{35}|gh[pousr]_[A-Za-z0-9]{36,}|xox[abpr]-[A-Za-z0-9-]{10,})`),
+	},
+	{
+		name:     "iban",
+		pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?: ?[A-Z0-9]{4}){2,7}(?: ?[A-Z0-9]{1,3})?\b`),
+		validate: isValidIBAN,
+	},
+	{
+		name:     "credit_card",
+		pattern:  regexp.MustCompile(`\b(?:\d[ -]?){12,18}\d\b`),
+		validate: isValidLuhn,
+	},
+	{
+		name:    "email",
+		pattern: regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`),
+	},
+	{
+		name:    "phone",
+		pattern: regexp.MustCompile(`\+\d{8,15}\b|(?:\+\d{1,3}[ .-]?)?\(?\b\d{2,4}\)?[ .-]\d{3,4}[ .-]\d{3,4}\b|\b1[3-9]\d{9}\b`),
+	},
+}
+
+// 只脱敏这些字段下的文本，避免改动模型名、工具定义等参数
+var piiTextKeys = map[string]bool{
+	"content":      true,
+	"text":         true,
+	"input":        true,
+	"prompt":       true,
+	"system":       true,
+	"instructions": true,
+	"query":        true,
+	"documents":    true,
+	"arguments":    true,
+}
+
+// 图片、音频、文件等二进制内容不做脱敏
+var piiSkipKeys = map[string]bool{
+	"image_url":   true,
+	"url":         true,
+	"data":        true,
+	"source":      true,
+	"inline_data": true,
+	"inlineData":  true,
+	"file_data":   true,
+	"fileData":    true,
+	"input_audio": true,
+	"file":        true,
+}
+
+var (
+	piiCustomPatternCache      = make(map[string]*regexp.Regexp)
+	piiCustomPatternCacheMutex sync.Mutex
+)
+
+func getPIICustomPattern(expr string) *regexp.Regexp {
+	piiCustomPatternCacheMutex.Lock()
+	defer piiCustomPatternCacheMutex.Unlock()
+	if re, ok := piiCustomPatternCache[expr]; ok {
+		return re
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		common.SysError(fmt.Sprintf("invalid pii redaction pattern %q: %s", expr, err.Error()))
+		re = nil
+	}
+	piiCustomPatternCache[expr] = re
+	return re
+}
+
+func getPIIDetectors() []piiDetector {
+	setting := operation_setting.GetPIIRedactionSetting()
+	var detectors []piiDetector
+	for _, detector := range builtinPIIDetectors {
+		for _, name := range setting.Detectors {
+			if name == detector.name {
+				detectors = append(detectors, detector)
+				break
+			}
+		}
+	}
+	names := make([]string, 0, len(setting.CustomPatterns))
+	for name := range setting.CustomPatterns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if re := getPIICustomPattern(setting.CustomPatterns[name]); re != nil {
+			detectors = append(detectors, piiDetector{name: name, pattern: re})
+		}
+	}
+	return detectors
+}
+
+// piiRedactor 为相同的原始内容生成相同的占位符
+type piiRedactor struct {
+	detectors    []piiDetector
+	placeholders map[string]string
+	originals    map[string]string
+	counters     map[string]int
+	count        int
+}
+
+func newPIIRedactor(placeholders map[string]string) *piiRedactor {
+	return &piiRedactor{
+		detectors:    getPIIDetectors(),
+		placeholders: placeholders,
+		originals:    make(map[string]string),
+		counters:     make(map[string]int),
+	}
+}
+
+func (r *piiRedactor) placeholder(name string, original string) string {
+	if placeholder, ok := r.originals[original]; ok {
+		return placeholder
+	}
+	label := strings.ToUpper(strings.Map(func(ch rune) rune {
+		if unicode.IsLetter(ch) || unicode.IsDigit(ch) {
+			return ch
+		}
+		return '_'
+	}, name))
+	r.counters[label]++
+	placeholder := fmt.Sprintf("[%s_%d]", label, r.counters[label])
+	r.placeholders[placeholder] = original
+	r.originals[original] = placeholder
+	return placeholder
+}
+
+func (r *piiRedactor) redactText(text string) string {
+	for _, detector := range r.detectors {
+		text = detector.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if detector.validate != nil && !detector.validate(match) {
+				return match
+			}
+			r.count++
+			return r.placeholder(detector.name, match)
+		})
+	}
+	return text
+}
+
+func (r *piiRedactor) redactValue(value any, redact bool) any {
+	switch v := value.(type) {
+	case string:
+		if redact {
+			return r.redactText(v)
+		}
+	case map[string]any:
+		for key, item := range v {
+			itemRedact := redact || piiTextKeys[key]
+			if piiSkipKeys[key] {
+				itemRedact = false
+			}
+			v[key] = r.redactValue(item, itemRedact)
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = r.redactValue(item, redact)
+		}
+	}
+	return value
+}
+
+// RedactRequestPII 将请求中的个人信息替换为占位符，同时更新缓存的请求体与解析后的请求，请求只会脱敏一次
+func RedactRequestPII(c *gin.Context, info *relaycommon.RelayInfo) error {
+	if info.PIIPlaceholders != nil || info.Request == nil {
+		return nil
+	}
+	if !strings.HasPrefix(c.Request.Header.Get("Content-Type"), "application/json") {
+		return nil
+	}
+	info.PIIPlaceholders = make(map[string]string)
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return err
+	}
+	var payload map[string]any
+	if err := decodeJsonNumber(body, &payload); err != nil {
+		return nil
+	}
+	redactor := newPIIRedactor(info.PIIPlaceholders)
+	if len(redactor.detectors) == 0 {
+		return nil
+	}
+	redactor.redactValue(payload, false)
+	if redactor.count == 0 {
+		return nil
+	}
+	redacted, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	request := reflect.New(reflect.TypeOf(info.Request).Elem()).Interface().(dto.Request)
+	if err := common.Unmarshal(redacted, request); err != nil {
+		return err
+	}
+	c.Set(common.KeyRequestBody, redacted)
+	info.Request = request
+	info.PIIRedactions = redactor.count
+	logger.LogInfo(c, fmt.Sprintf("redacted %d pii items from request", redactor.count))
+	return nil
+}
+
+// isValidLuhn 校验银行卡号
+func isValidLuhn(number string) bool {
+	sum := 0
+	digits := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		ch := number[i]
+		if ch < '0' || ch > '9' {
+			continue
+		}
+		d := int(ch - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		digits++
+		double = !double
+	}
+	return digits >= 13 && digits <= 19 && sum%10 == 0
+}
+
+// isValidIBAN 按 ISO 13616 校验 IBAN
+func isValidIBAN(iban string) bool {
+	iban = strings.ReplaceAll(iban, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+	var numeric strings.Builder
+	for _, ch := range iban[4:] + iban[:4] {
+		switch {
+		case ch >= '0' && ch <= '9':
+			numeric.WriteRune(ch)
+		case ch >= 'A' && ch <= 'Z':
+			numeric.WriteString(fmt.Sprintf("%d", ch-'A'+10))
+		default:
+			return false
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	if !ok {
+		return false
+	}
+	return new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// piiRestoreFilter 将模型输出中的占位符还原为原始内容
+type piiRestoreFilter struct {
+	replacer     *strings.Replacer
+	placeholders map[string]string
+	maxLen       int
+	pending      map[int]string
+}
+
+func newPIIRestoreFilter(info *relaycommon.RelayInfo) *piiRestoreFilter {
+	if len(info.PIIPlaceholders) == 0 || !operation_setting.GetPIIRedactionSetting().RestoreEnabled {
+		return nil
+	}
+	pairs := make([]string, 0, len(info.PIIPlaceholders)*2)
+	maxLen := 0
+	for placeholder, original := range info.PIIPlaceholders {
+		pairs = append(pairs, placeholder, original)
+		maxLen = max(maxLen, len(placeholder))
+	}
+	return &piiRestoreFilter{
+		replacer:     strings.NewReplacer(pairs...),
+		placeholders: info.PIIPlaceholders,
+		maxLen:       maxLen,
+		pending:      make(map[int]string),
+	}
+}
+
+func (f *piiRestoreFilter) full(text string) (string, bool) {
+	return f.replacer.Replace(text), false
+}
+
+func (f *piiRestoreFilter) delta(index int, text string) (string, bool) {
+	text = f.replacer.Replace(f.pending[index] + text)
+	// 占位符可能被拆到多个片段中，保留结尾可能是占位符开头的部分
+	hold := ""
+	if start := strings.LastIndexByte(text, '['); start >= 0 && len(text)-start < f.maxLen {
+		tail := text[start:]
+		for placeholder := range f.placeholders {
+			if strings.HasPrefix(placeholder, tail) {
+				hold = tail
+				break
+			}
+		}
+	}
+	f.pending[index] = hold
+	return text[:len(text)-len(hold)], false
+}
+
+func (f *piiRestoreFilter) flush(index int) string {
+	text := f.pending[index]
+	delete(f.pending, index)
+	return text
+}
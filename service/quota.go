@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/tracing"
 	"github.com/QuantumNous/new-api/constant"
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/logger"
@@ -90,7 +91,7 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 	if relayInfo.UsePrice {
 		return nil
 	}
-	userQuota, err := model.GetUserQuota(relayInfo.UserId, false)
+	userQuota, err := GetPayerQuota(relayInfo)
 	if err != nil {
 		return err
 	}
@@ -156,6 +157,8 @@ func PreWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usag
 
 func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, modelName string,
 	usage *dto.RealtimeUsage, extraContent string) {
+	_, span := tracing.Start(ctx.Request.Context(), "quota_settlement")
+	defer span.End()
 
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.InputTokenDetails.TextTokens
@@ -211,6 +214,7 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		RecordConsumeMetrics(relayInfo, usage.InputTokens, usage.OutputTokens, quota)
 	}
 
 	logModel := modelName
@@ -236,10 +240,14 @@ func PostWssConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, mod
 }
 
 func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage) {
+	_, span := tracing.Start(ctx.Request.Context(), "quota_settlement")
+	defer span.End()
 
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	promptTokens := usage.PromptTokens
 	completionTokens := usage.CompletionTokens
+	relayInfo.PromptTokens = promptTokens
+	relayInfo.CompletionTokens = completionTokens
 	modelName := relayInfo.OriginModelName
 
 	tokenName := ctx.GetString("token_name")
@@ -293,6 +301,12 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 
 	totalTokens := promptTokens + completionTokens
 
+	if !ClaimHedgeWin(ctx) {
+		// 对冲请求落选，不向用户计费
+		RecordHedgeLoserUsage(ctx, relayInfo, relayInfo.ChannelId, promptTokens, completionTokens, quota, "对冲请求落选，未计费")
+		return
+	}
+
 	var logContent string
 	// record all the consume log even if quota is 0
 	if totalTokens == 0 {
@@ -305,6 +319,7 @@ func PostClaudeConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo,
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		RecordConsumeMetrics(relayInfo, promptTokens, completionTokens, quota)
 	}
 
 	quotaDelta := quota - relayInfo.FinalPreConsumedQuota
@@ -375,10 +390,14 @@ func CalcOpenRouterCacheCreateTokens(usage dto.Usage, priceData types.PriceData)
 }
 
 func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, usage *dto.Usage, extraContent string) {
+	_, span := tracing.Start(ctx.Request.Context(), "quota_settlement")
+	defer span.End()
 
 	useTimeSeconds := time.Now().Unix() - relayInfo.StartTime.Unix()
 	textInputTokens := usage.PromptTokensDetails.TextTokens
 	textOutTokens := usage.CompletionTokenDetails.TextTokens
+	relayInfo.PromptTokens = usage.PromptTokens
+	relayInfo.CompletionTokens = usage.CompletionTokens
 
 	audioInputTokens := usage.PromptTokensDetails.AudioTokens
 	audioOutTokens := usage.CompletionTokenDetails.AudioTokens
@@ -411,6 +430,12 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 	quota := calculateAudioQuota(quotaInfo)
 
 	totalTokens := usage.TotalTokens
+
+	if !ClaimHedgeWin(ctx) {
+		// 对冲请求落选，不向用户计费
+		RecordHedgeLoserUsage(ctx, relayInfo, relayInfo.ChannelId, usage.PromptTokens, usage.CompletionTokens, quota, "对冲请求落选，未计费")
+		return
+	}
 	var logContent string
 	if !usePrice {
 		logContent = fmt.Sprintf("模型倍率 %.2f，补全倍率 %.2f，音频倍率 %.2f，音频补全倍率 %.2f，分组倍率 %.2f",
@@ -430,6 +455,7 @@ func PostAudioConsumeQuota(ctx *gin.Context, relayInfo *relaycommon.RelayInfo, u
 	} else {
 		model.UpdateUserUsedQuotaAndRequestCount(relayInfo.UserId, quota)
 		model.UpdateChannelUsedQuota(relayInfo.ChannelId, quota)
+		RecordConsumeMetrics(relayInfo, usage.PromptTokens, usage.CompletionTokens, quota)
 	}
 
 	quotaDelta := quota - relayInfo.FinalPreConsumedQuota
@@ -501,12 +527,34 @@ func PreConsumeTokenQuota(relayInfo *relaycommon.RelayInfo, quota int) error {
 	return nil
 }
 
+// CheckTokenBudget 检查令牌在当前预算周期内的剩余预算，进入新周期时自动清零已用预算
+func CheckTokenBudget(relayInfo *relaycommon.RelayInfo, quota int) error {
+	if relayInfo.IsPlayground {
+		return nil
+	}
+	token, err := model.GetTokenByKey(relayInfo.TokenKey, false)
+	if err != nil {
+		return err
+	}
+	if !token.HasBudget() {
+		return nil
+	}
+	budgetUsed, err := model.ResetTokenBudgetIfNeeded(token)
+	if err != nil {
+		return err
+	}
+	if budgetUsed >= token.BudgetLimit || budgetUsed+quota > token.BudgetLimit {
+		return fmt.Errorf("token budget is not enough, budget used: %s, budget limit: %s, need quota: %s", logger.FormatQuota(budgetUsed), logger.FormatQuota(token.BudgetLimit), logger.FormatQuota(quota))
+	}
+	return nil
+}
+
 func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQuota int, sendEmail bool) (err error) {
 
 	if quota > 0 {
-		err = model.DecreaseUserQuota(relayInfo.UserId, quota)
+		err = model.DecreasePayerQuota(relayInfo.UserId, relayInfo.OrgId, quota)
 	} else {
-		err = model.IncreaseUserQuota(relayInfo.UserId, -quota, false)
+		err = model.IncreasePayerQuota(relayInfo.UserId, relayInfo.OrgId, -quota)
 	}
 	if err != nil {
 		return err
@@ -523,7 +571,8 @@ func PostConsumeQuota(relayInfo *relaycommon.RelayInfo, quota int, preConsumedQu
 		}
 	}
 
-	if sendEmail {
+	// 组织额度不属于个人，不发送个人额度提醒
+	if sendEmail && relayInfo.OrgId == 0 {
 		if (quota + preConsumedQuota) != 0 {
 			checkAndSendQuotaNotify(relayInfo, quota, preConsumedQuota)
 		}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/model"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+// setupServiceTestDB 使用临时的 SQLite 数据库并关闭 Redis，测试结束后恢复
+func setupServiceTestDB(t *testing.T) {
+	t.Helper()
+	t.Setenv("SQL_DSN", "")
+	oldDB, sqlitePath, isMasterNode, redisEnabled := model.DB, common.SQLitePath, common.IsMasterNode, common.RedisEnabled
+	common.SQLitePath = filepath.Join(t.TempDir(), "test.db")
+	common.IsMasterNode, common.RedisEnabled = true, false
+	if err := model.InitDB(); err != nil {
+		t.Fatal(err)
+	}
+	db := model.DB
+	t.Cleanup(func() {
+		if sqlDB, err := db.DB(); err == nil {
+			_ = sqlDB.Close()
+		}
+		model.DB, common.SQLitePath, common.IsMasterNode, common.RedisEnabled = oldDB, sqlitePath, isMasterNode, redisEnabled
+	})
+}
+
+func TestCheckTokenBudget(t *testing.T) {
+	setupServiceTestDB(t)
+	dayStart := model.GetTokenBudgetPeriodStart(model.TokenBudgetPeriodDaily, time.Now())
+	monthStart := model.GetTokenBudgetPeriodStart(model.TokenBudgetPeriodMonthly, time.Now())
+	tests := []struct {
+		name        string
+		period      string
+		periodStart int64
+		used        int
+		quota       int
+		wantErr     bool
+	}{
+		{"no budget", "", 0, 5000, 100, false},
+		{"within budget", model.TokenBudgetPeriodDaily, dayStart, 800, 200, false},
+		{"exceeds budget", model.TokenBudgetPeriodDaily, dayStart, 800, 201, true},
+		{"budget used up", model.TokenBudgetPeriodDaily, dayStart, 1000, 0, true},
+		{"previous day rolls over", model.TokenBudgetPeriodDaily, dayStart - 1, 1000, 1000, false},
+		{"same month", model.TokenBudgetPeriodMonthly, monthStart, 999, 2, true},
+		{"previous month rolls over", model.TokenBudgetPeriodMonthly, monthStart - 1, 1000, 500, false},
+	}
+	for i, tt := range tests {
+		token := &model.Token{
+			Key:               "budget-test-" + strings.Repeat("x", i+1),
+			Name:              tt.name,
+			BudgetPeriod:      tt.period,
+			BudgetLimit:       1000,
+			BudgetUsed:        tt.used,
+			BudgetPeriodStart: tt.periodStart,
+		}
+		if err := token.Insert(); err != nil {
+			t.Fatal(err)
+		}
+		err := CheckTokenBudget(&relaycommon.RelayInfo{TokenKey: token.Key}, tt.quota)
+		if (err != nil) != tt.wantErr {
+			t.Fatalf("%s: expected error=%v, got %v", tt.name, tt.wantErr, err)
+		}
+	}
+	if err := CheckTokenBudget(&relaycommon.RelayInfo{TokenKey: "missing", IsPlayground: true}, 1); err != nil {
+		t.Fatalf("playground requests skip the budget, got %v", err)
+	}
+}
@@ -0,0 +1,186 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/common/limiter"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting"
+	"github.com/QuantumNous/new-api/types"
+
+	"github.com/gin-gonic/gin"
+)
+
+// TPM 令牌桶的时间窗口（秒），容量为 TPM * 60，每秒补充 TPM 个单位，每个 token 消耗 60 个单位
+const tpmWindowSeconds = 60
+
+type relayLimitScope struct {
+	name        string
+	key         string
+	tpm         int
+	concurrency int
+}
+
+// RelayLimit 记录一次请求占用的 TPM 与并发名额，请求结束后需调用 Release
+type RelayLimit struct {
+	scopes          []relayLimitScope
+	estimatedTokens int
+	concurrencyKeys []string
+}
+
+func getRelayLimitScopes(c *gin.Context, info *relaycommon.RelayInfo) []relayLimitScope {
+	groupLimit := setting.GetGroupRelayLimit(info.UsingGroup)
+	scopes := []relayLimitScope{
+		{
+			name:        "令牌",
+			key:         fmt.Sprintf("token:%d", info.TokenId),
+			tpm:         common.GetContextKeyInt(c, constant.ContextKeyTokenTPMLimit),
+			concurrency: common.GetContextKeyInt(c, constant.ContextKeyTokenConcurrencyLimit),
+		},
+		{
+			name:        "用户",
+			key:         fmt.Sprintf("user:%d", info.UserId),
+			tpm:         common.GetContextKeyInt(c, constant.ContextKeyUserTPMLimit),
+			concurrency: common.GetContextKeyInt(c, constant.ContextKeyUserConcurrencyLimit),
+		},
+		{
+			name:        "分组",
+			key:         fmt.Sprintf("group:%s", info.UsingGroup),
+			tpm:         groupLimit.TPM,
+			concurrency: groupLimit.Concurrency,
+		},
+	}
+	enabled := make([]relayLimitScope, 0, len(scopes))
+	for _, scope := range scopes {
+		if scope.tpm > 0 || scope.concurrency > 0 {
+			enabled = append(enabled, scope)
+		}
+	}
+	return enabled
+}
+
+func tpmAllow(ctx context.Context, key string, tpm int, tokens int) (bool, error) {
+	opts := []limiter.Option{
+		limiter.WithCapacity(int64(tpm) * tpmWindowSeconds),
+		limiter.WithRate(int64(tpm)),
+		limiter.WithRequested(int64(tokens) * tpmWindowSeconds),
+	}
+	if common.RedisEnabled {
+		return limiter.New(ctx, common.RDB).Allow(ctx, key, opts...)
+	}
+	return limiter.NewMemory().Allow(key, opts...), nil
+}
+
+func tpmConsume(ctx context.Context, key string, tpm int, tokens int) error {
+	opts := []limiter.Option{
+		limiter.WithCapacity(int64(tpm) * tpmWindowSeconds),
+		limiter.WithRate(int64(tpm)),
+		limiter.WithRequested(int64(tokens) * tpmWindowSeconds),
+	}
+	if common.RedisEnabled {
+		return limiter.New(ctx, common.RDB).Consume(ctx, key, opts...)
+	}
+	limiter.NewMemory().Consume(key, opts...)
+	return nil
+}
+
+// AcquireRelayLimit 按令牌、用户、分组检查并发数与 TPM 限制，TPM 按预估的输入 token 数预扣
+func AcquireRelayLimit(c *gin.Context, info *relaycommon.RelayInfo, estimatedTokens int) (*RelayLimit, *types.NewAPIError) {
+	relayLimit := &RelayLimit{
+		scopes:          getRelayLimitScopes(c, info),
+		estimatedTokens: max(estimatedTokens, 1),
+	}
+	if len(relayLimit.scopes) == 0 {
+		return relayLimit, nil
+	}
+	ctx := c.Request.Context()
+
+	for _, scope := range relayLimit.scopes {
+		if scope.concurrency <= 0 {
+			continue
+		}
+		key := "relayConcurrency:" + scope.key
+		allowed, err := limiter.AcquireConcurrency(ctx, key, scope.concurrency)
+		if err != nil {
+			relayLimit.releaseConcurrency()
+			return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeRateLimitExceeded, http.StatusInternalServerError, types.ErrOptionWithSkipRetry())
+		}
+		if !allowed {
+			relayLimit.releaseConcurrency()
+			return nil, types.NewErrorWithStatusCode(fmt.Errorf("%s并发请求数已达上限：%d", scope.name, scope.concurrency),
+				types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+		}
+		relayLimit.concurrencyKeys = append(relayLimit.concurrencyKeys, key)
+	}
+
+	for i, scope := range relayLimit.scopes {
+		if scope.tpm <= 0 {
+			continue
+		}
+		allowed, err := tpmAllow(ctx, "relayTPM:"+scope.key, scope.tpm, relayLimit.estimatedTokens)
+		if err == nil && allowed {
+			continue
+		}
+		// 退还之前已预扣的范围
+		relayLimit.refundTPM(relayLimit.scopes[:i])
+		relayLimit.releaseConcurrency()
+		if err != nil {
+			return nil, types.NewErrorWithStatusCode(err, types.ErrorCodeRateLimitExceeded, http.StatusInternalServerError, types.ErrOptionWithSkipRetry())
+		}
+		return nil, types.NewErrorWithStatusCode(fmt.Errorf("%s已达到每分钟 token 数限制：%d，本次请求预估 %d tokens", scope.name, scope.tpm, relayLimit.estimatedTokens),
+			types.ErrorCodeRateLimitExceeded, http.StatusTooManyRequests, types.ErrOptionWithSkipRetry())
+	}
+	return relayLimit, nil
+}
+
+func (l *RelayLimit) refundTPM(scopes []relayLimitScope) {
+	for _, scope := range scopes {
+		if scope.tpm <= 0 {
+			continue
+		}
+		if err := tpmConsume(context.Background(), "relayTPM:"+scope.key, scope.tpm, -l.estimatedTokens); err != nil {
+			common.SysError("failed to refund tpm: " + err.Error())
+		}
+	}
+}
+
+func (l *RelayLimit) releaseConcurrency() {
+	for _, key := range l.concurrencyKeys {
+		if err := limiter.ReleaseConcurrency(context.Background(), key); err != nil {
+			common.SysError("failed to release concurrency: " + err.Error())
+		}
+	}
+	l.concurrencyKeys = nil
+}
+
+// Release 释放并发名额，并按实际用量校正 TPM 预扣：失败的请求全部退还，成功但无用量信息时保留预扣
+func (l *RelayLimit) Release(info *relaycommon.RelayInfo, success bool) {
+	if l == nil {
+		return
+	}
+	l.releaseConcurrency()
+
+	actualTokens := 0
+	if success {
+		actualTokens = info.PromptTokens + info.CompletionTokens
+		if actualTokens == 0 {
+			actualTokens = l.estimatedTokens
+		}
+	}
+	diff := actualTokens - l.estimatedTokens
+	if diff == 0 {
+		return
+	}
+	for _, scope := range l.scopes {
+		if scope.tpm <= 0 {
+			continue
+		}
+		if err := tpmConsume(context.Background(), "relayTPM:"+scope.key, scope.tpm, diff); err != nil {
+			common.SysError("failed to reconcile tpm: " + err.Error())
+		}
+	}
+}
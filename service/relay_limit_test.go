@@ -0,0 +1,98 @@
+package service
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/constant"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 内存限流器在进程内共享，每次使用新的令牌 id 避免用例之间互相影响
+var relayLimitTestTokenId = 900000
+
+func newRelayLimitTestContext(t *testing.T, tpm int, concurrency int) (*gin.Context, *relaycommon.RelayInfo) {
+	t.Helper()
+	redisEnabled := common.RedisEnabled
+	common.RedisEnabled = false
+	t.Cleanup(func() { common.RedisEnabled = redisEnabled })
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodPost, "/v1/chat/completions", nil)
+	common.SetContextKey(c, constant.ContextKeyTokenTPMLimit, tpm)
+	common.SetContextKey(c, constant.ContextKeyTokenConcurrencyLimit, concurrency)
+	relayLimitTestTokenId++
+	return c, &relaycommon.RelayInfo{TokenId: relayLimitTestTokenId, UsingGroup: "relay-limit-test"}
+}
+
+func TestRelayLimitReleasesConcurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		tpm    int
+		tokens int
+		status int
+	}{
+		// TPM 检查失败时，已占用的并发名额需要释放
+		{"tpm rejected", 10, 11, http.StatusTooManyRequests},
+		{"admitted", 0, 10, 0},
+	}
+	for _, tt := range tests {
+		c, info := newRelayLimitTestContext(t, tt.tpm, 1)
+		relayLimit, apiErr := AcquireRelayLimit(c, info, tt.tokens)
+		if tt.status != 0 {
+			if apiErr == nil || apiErr.StatusCode != tt.status {
+				t.Fatalf("%s: expected status %d, got %v", tt.name, tt.status, apiErr)
+			}
+		} else {
+			if apiErr != nil {
+				t.Fatalf("%s: unexpected error %v", tt.name, apiErr)
+			}
+			if _, apiErr = AcquireRelayLimit(c, info, tt.tokens); apiErr == nil {
+				t.Fatalf("%s: expected concurrency limit while the first request is running", tt.name)
+			}
+			relayLimit.Release(info, false)
+		}
+		again, apiErr := AcquireRelayLimit(c, info, 1)
+		if apiErr != nil {
+			t.Fatalf("%s: expected concurrency slot to be released, got %v", tt.name, apiErr)
+		}
+		again.Release(info, false)
+	}
+}
+
+func TestRelayLimitReleaseReconcilesTPM(t *testing.T) {
+	tests := []struct {
+		name       string
+		success    bool
+		usedTokens int
+		next       int
+		allowed    bool
+	}{
+		{"failed request refunds estimate", false, 0, 100, true},
+		{"usage below estimate refunds difference", true, 40, 60, true},
+		{"usage above estimate debits difference", true, 100, 60, false},
+		{"missing usage keeps estimate", true, 0, 60, false},
+	}
+	for _, tt := range tests {
+		c, info := newRelayLimitTestContext(t, 100, 0)
+		relayLimit, apiErr := AcquireRelayLimit(c, info, 50)
+		if apiErr != nil {
+			t.Fatalf("%s: unexpected error %v", tt.name, apiErr)
+		}
+		info.PromptTokens = tt.usedTokens
+		relayLimit.Release(info, tt.success)
+
+		next, apiErr := AcquireRelayLimit(c, info, tt.next)
+		if (apiErr == nil) != tt.allowed {
+			t.Fatalf("%s: expected allowed=%v, got %v", tt.name, tt.allowed, apiErr)
+		}
+		if next != nil {
+			next.Release(info, false)
+		}
+	}
+}
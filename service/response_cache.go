@@ -0,0 +1,245 @@
+package service
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting/operation_setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+const responseCacheRatioKey = "response_cache"
+
+type responseCacheEntry struct {
+	ContentType string    `json:"content_type"`
+	Body        []byte    `json:"body"`
+	IsStream    bool      `json:"is_stream"`
+	Usage       dto.Usage `json:"usage"`
+	ExpiresAt   int64     `json:"expires_at"`
+}
+
+type responseCacheItem struct {
+	key   string
+	entry *responseCacheEntry
+}
+
+// 未启用 Redis 时使用的本地 LRU 缓存
+var (
+	localResponseCache      = make(map[string]*list.Element)
+	localResponseCacheList  = list.New()
+	localResponseCacheMutex sync.Mutex
+)
+
+// IsChatRequestCacheable 仅缓存 temperature=0 且只生成一个结果的请求，流式请求需额外开启
+func IsChatRequestCacheable(request *dto.GeneralOpenAIRequest) bool {
+	setting := operation_setting.GetResponseCacheSetting()
+	if !setting.Enabled {
+		return false
+	}
+	if request.Temperature == nil || *request.Temperature != 0 || request.N > 1 {
+		return false
+	}
+	return !request.Stream || setting.StreamEnabled
+}
+
+func IsEmbeddingRequestCacheable() bool {
+	return operation_setting.GetResponseCacheSetting().Enabled
+}
+
+// GetResponseCacheKey 以规范化后的请求体、模型与分组生成缓存键，请求体无法解析时返回空字符串
+func GetResponseCacheKey(c *gin.Context, info *relaycommon.RelayInfo) string {
+	body, err := common.GetRequestBody(c)
+	if err != nil {
+		return ""
+	}
+	var payload map[string]any
+	if err := common.Unmarshal(body, &payload); err != nil {
+		return ""
+	}
+	// encoding/json 按键名排序输出 map，使字段顺序不同的相同请求得到相同的键
+	normalized, err := json.Marshal(payload)
+	if err != nil {
+		return ""
+	}
+	hash := sha256.New()
+	hash.Write([]byte(fmt.Sprintf("%d\n%s\n%s\n", info.RelayMode, info.OriginModelName, info.UsingGroup)))
+	hash.Write(normalized)
+	return "response_cache:" + hex.EncodeToString(hash.Sum(nil))
+}
+
+func getResponseCache(key string) *responseCacheEntry {
+	if common.RedisEnabled {
+		data, err := common.RedisGet(key)
+		if err != nil {
+			return nil
+		}
+		var entry responseCacheEntry
+		if err := common.UnmarshalJsonStr(data, &entry); err != nil {
+			return nil
+		}
+		return &entry
+	}
+
+	localResponseCacheMutex.Lock()
+	defer localResponseCacheMutex.Unlock()
+	element, ok := localResponseCache[key]
+	if !ok {
+		return nil
+	}
+	item := element.Value.(*responseCacheItem)
+	if item.entry.ExpiresAt <= time.Now().Unix() {
+		localResponseCacheList.Remove(element)
+		delete(localResponseCache, key)
+		return nil
+	}
+	localResponseCacheList.MoveToFront(element)
+	return item.entry
+}
+
+func setResponseCache(key string, entry *responseCacheEntry) error {
+	setting := operation_setting.GetResponseCacheSetting()
+	ttl := time.Duration(max(setting.TTLSeconds, 1)) * time.Second
+	entry.ExpiresAt = time.Now().Add(ttl).Unix()
+	if common.RedisEnabled {
+		data, err := common.Marshal(entry)
+		if err != nil {
+			return err
+		}
+		return common.RedisSet(key, string(data), ttl)
+	}
+
+	localResponseCacheMutex.Lock()
+	defer localResponseCacheMutex.Unlock()
+	if element, ok := localResponseCache[key]; ok {
+		element.Value.(*responseCacheItem).entry = entry
+		localResponseCacheList.MoveToFront(element)
+		return nil
+	}
+	localResponseCache[key] = localResponseCacheList.PushFront(&responseCacheItem{key: key, entry: entry})
+	for localResponseCacheList.Len() > max(setting.MaxEntries, 1) {
+		oldest := localResponseCacheList.Back()
+		localResponseCacheList.Remove(oldest)
+		delete(localResponseCache, oldest.Value.(*responseCacheItem).key)
+	}
+	return nil
+}
+
+// ReplayResponseCache 命中缓存时直接返回缓存的响应，并按命中倍率设置计费，返回缓存时的用量；未命中返回 nil
+func ReplayResponseCache(c *gin.Context, info *relaycommon.RelayInfo, key string) *dto.Usage {
+	if key == "" {
+		return nil
+	}
+	entry := getResponseCache(key)
+	if entry == nil {
+		return nil
+	}
+	info.ResponseCacheHit = true
+	info.IsStream = entry.IsStream
+	info.SetFirstResponseTime()
+	if info.PriceData.OtherRatios == nil {
+		info.PriceData.OtherRatios = make(map[string]float64)
+	}
+	// 不使用 AddOtherRatio，倍率为 0 时同样需要生效
+	info.PriceData.OtherRatios[responseCacheRatioKey] = operation_setting.GetResponseCacheSetting().HitRatio
+
+	c.Header("Content-Type", entry.ContentType)
+	c.Header("X-Response-Cache", "hit")
+	c.Status(http.StatusOK)
+	_, _ = c.Writer.Write(entry.Body)
+	c.Writer.Flush()
+	logger.LogInfo(c, fmt.Sprintf("response cache hit, model %s", info.OriginModelName))
+	usage := entry.Usage
+	return &usage
+}
+
+type responseCacheWriter struct {
+	gin.ResponseWriter
+	buf      bytes.Buffer
+	limit    int
+	overflow bool
+}
+
+func (w *responseCacheWriter) capture(data []byte) {
+	if w.overflow {
+		return
+	}
+	if w.buf.Len()+len(data) > w.limit {
+		w.overflow = true
+		w.buf.Reset()
+		return
+	}
+	w.buf.Write(data)
+}
+
+func (w *responseCacheWriter) Write(data []byte) (int, error) {
+	w.capture(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseCacheWriter) WriteString(s string) (int, error) {
+	w.capture([]byte(s))
+	return w.ResponseWriter.WriteString(s)
+}
+
+// ResponseCapture 记录写给客户端的响应，请求成功后写入缓存
+type ResponseCapture struct {
+	c      *gin.Context
+	key    string
+	writer *responseCacheWriter
+	origin gin.ResponseWriter
+}
+
+// StartResponseCapture 开始记录响应，key 为空时返回 nil，nil 的 ResponseCapture 可以安全调用
+func StartResponseCapture(c *gin.Context, key string) *ResponseCapture {
+	if key == "" {
+		return nil
+	}
+	writer := &responseCacheWriter{
+		ResponseWriter: c.Writer,
+		limit:          max(operation_setting.GetResponseCacheSetting().MaxEntrySizeKB, 1) << 10,
+	}
+	capture := &ResponseCapture{c: c, key: key, writer: writer, origin: c.Writer}
+	c.Writer = writer
+	return capture
+}
+
+// Discard 请求失败时停止记录，不写入缓存
+func (rc *ResponseCapture) Discard() {
+	if rc == nil {
+		return
+	}
+	rc.c.Writer = rc.origin
+}
+
+// Save 停止记录，响应完整且成功时写入缓存
+func (rc *ResponseCapture) Save(info *relaycommon.RelayInfo, usage *dto.Usage) {
+	if rc == nil {
+		return
+	}
+	rc.c.Writer = rc.origin
+	if rc.writer.overflow || rc.writer.buf.Len() == 0 || rc.writer.Status() != http.StatusOK ||
+		usage == nil || usage.PromptTokens+usage.CompletionTokens == 0 {
+		return
+	}
+	entry := &responseCacheEntry{
+		ContentType: rc.writer.Header().Get("Content-Type"),
+		Body:        rc.writer.buf.Bytes(),
+		IsStream:    info.IsStream,
+		Usage:       *usage,
+	}
+	if err := setResponseCache(rc.key, entry); err != nil {
+		logger.LogError(rc.c, "failed to save response cache: "+err.Error())
+	}
+}
@@ -0,0 +1,745 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+// responsesInputItem 为 Responses API input 数组中单个元素的宽松结构
+type responsesInputItem struct {
+	Type      string          `json:"type,omitempty"`
+	Role      string          `json:"role,omitempty"`
+	Content   json.RawMessage `json:"content,omitempty"`
+	CallId    string          `json:"call_id,omitempty"`
+	Name      string          `json:"name,omitempty"`
+	Arguments string          `json:"arguments,omitempty"`
+	Output    json.RawMessage `json:"output,omitempty"`
+}
+
+// responsesTool 为 Responses API 的工具定义，函数字段平铺在顶层
+type responsesTool struct {
+	Type              string          `json:"type"`
+	Name              string          `json:"name,omitempty"`
+	Description       string          `json:"description,omitempty"`
+	Parameters        any             `json:"parameters,omitempty"`
+	SearchContextSize string          `json:"search_context_size,omitempty"`
+	UserLocation      json.RawMessage `json:"user_location,omitempty"`
+}
+
+// ResponsesToOpenAIRequest 将 Responses API 请求转换为 Chat Completions 请求，
+// 供只支持 Chat Completions 语义的渠道（Claude、Gemini 等）复用现有转换逻辑
+func ResponsesToOpenAIRequest(responsesRequest *dto.OpenAIResponsesRequest) (*dto.GeneralOpenAIRequest, error) {
+	openAIRequest := &dto.GeneralOpenAIRequest{
+		Model:     responsesRequest.Model,
+		Stream:    responsesRequest.Stream,
+		MaxTokens: responsesRequest.MaxOutputTokens,
+		TopP:      responsesRequest.TopP,
+		User:      responsesRequest.User,
+	}
+	if responsesRequest.Stream {
+		openAIRequest.StreamOptions = &dto.StreamOptions{IncludeUsage: true}
+	}
+	if responsesRequest.Temperature != 0 {
+		openAIRequest.Temperature = common.GetPointer(responsesRequest.Temperature)
+	}
+	if responsesRequest.Reasoning != nil && responsesRequest.Reasoning.Effort != "" {
+		openAIRequest.ReasoningEffort = responsesRequest.Reasoning.Effort
+	}
+	if len(responsesRequest.ParallelToolCalls) > 0 {
+		var parallel bool
+		if err := common.Unmarshal(responsesRequest.ParallelToolCalls, &parallel); err == nil {
+			openAIRequest.ParallelTooCalls = &parallel
+		}
+	}
+
+	messages := make([]dto.Message, 0)
+
+	// instructions 作为 system 消息
+	if len(responsesRequest.Instructions) > 0 && common.GetJsonType(responsesRequest.Instructions) == "string" {
+		var instructions string
+		if err := common.Unmarshal(responsesRequest.Instructions, &instructions); err != nil {
+			return nil, fmt.Errorf("invalid instructions: %w", err)
+		}
+		if instructions != "" {
+			systemMessage := dto.Message{Role: "system"}
+			systemMessage.SetStringContent(instructions)
+			messages = append(messages, systemMessage)
+		}
+	}
+
+	inputMessages, err := responsesInputToMessages(responsesRequest.Input)
+	if err != nil {
+		return nil, err
+	}
+	messages = append(messages, inputMessages...)
+	openAIRequest.Messages = messages
+
+	// 转换工具
+	if len(responsesRequest.Tools) > 0 {
+		var tools []responsesTool
+		if err := common.Unmarshal(responsesRequest.Tools, &tools); err != nil {
+			return nil, fmt.Errorf("invalid tools: %w", err)
+		}
+		openAITools := make([]dto.ToolCallRequest, 0, len(tools))
+		for _, tool := range tools {
+			switch tool.Type {
+			case "function":
+				openAITools = append(openAITools, dto.ToolCallRequest{
+					Type: "function",
+					Function: dto.FunctionRequest{
+						Name:        tool.Name,
+						Description: tool.Description,
+						Parameters:  tool.Parameters,
+					},
+				})
+			case dto.BuildInToolWebSearchPreview, "web_search":
+				openAIRequest.WebSearchOptions = &dto.WebSearchOptions{
+					SearchContextSize: tool.SearchContextSize,
+					UserLocation:      tool.UserLocation,
+				}
+			}
+		}
+		if len(openAITools) > 0 {
+			openAIRequest.Tools = openAITools
+		}
+	}
+
+	if len(responsesRequest.ToolChoice) > 0 {
+		openAIRequest.ToolChoice = responsesToolChoiceToOpenAI(responsesRequest.ToolChoice)
+	}
+
+	// text.format 对应 response_format
+	if len(responsesRequest.Text) > 0 {
+		var text struct {
+			Format map[string]any `json:"format"`
+		}
+		if err := common.Unmarshal(responsesRequest.Text, &text); err == nil && text.Format != nil {
+			formatType := common.Interface2String(text.Format["type"])
+			switch formatType {
+			case "json_schema":
+				schema := dto.FormatJsonSchema{
+					Name:        common.Interface2String(text.Format["name"]),
+					Description: common.Interface2String(text.Format["description"]),
+					Schema:      text.Format["schema"],
+				}
+				if strict, ok := text.Format["strict"].(bool); ok {
+					schema.Strict, _ = common.Marshal(strict)
+				}
+				schemaJson, _ := common.Marshal(schema)
+				openAIRequest.ResponseFormat = &dto.ResponseFormat{
+					Type:       formatType,
+					JsonSchema: schemaJson,
+				}
+			case "json_object":
+				openAIRequest.ResponseFormat = &dto.ResponseFormat{Type: formatType}
+			}
+		}
+	}
+
+	return openAIRequest, nil
+}
+
+func responsesToolChoiceToOpenAI(toolChoice json.RawMessage) any {
+	if common.GetJsonType(toolChoice) == "string" {
+		var choice string
+		_ = common.Unmarshal(toolChoice, &choice)
+		return choice
+	}
+	var choice map[string]any
+	if err := common.Unmarshal(toolChoice, &choice); err != nil {
+		return nil
+	}
+	if common.Interface2String(choice["type"]) == "function" {
+		return map[string]any{
+			"type": "function",
+			"function": map[string]any{
+				"name": common.Interface2String(choice["name"]),
+			},
+		}
+	}
+	return nil
+}
+
+func responsesInputToMessages(input json.RawMessage) ([]dto.Message, error) {
+	messages := make([]dto.Message, 0)
+	if len(input) == 0 {
+		return messages, nil
+	}
+
+	if common.GetJsonType(input) == "string" {
+		var str string
+		if err := common.Unmarshal(input, &str); err != nil {
+			return nil, fmt.Errorf("invalid input: %w", err)
+		}
+		message := dto.Message{Role: "user"}
+		message.SetStringContent(str)
+		return append(messages, message), nil
+	}
+
+	var items []responsesInputItem
+	if err := common.Unmarshal(input, &items); err != nil {
+		return nil, fmt.Errorf("invalid input: %w", err)
+	}
+
+	var pendingToolCalls []dto.ToolCallRequest
+	flushToolCalls := func() {
+		if len(pendingToolCalls) == 0 {
+			return
+		}
+		// 将函数调用合并到紧邻的 assistant 消息中
+		if len(messages) > 0 && messages[len(messages)-1].Role == "assistant" && messages[len(messages)-1].ToolCalls == nil {
+			messages[len(messages)-1].SetToolCalls(pendingToolCalls)
+		} else {
+			message := dto.Message{Role: "assistant"}
+			message.SetMediaContent(make([]dto.MediaContent, 0))
+			message.SetToolCalls(pendingToolCalls)
+			messages = append(messages, message)
+		}
+		pendingToolCalls = nil
+	}
+
+	for _, item := range items {
+		switch item.Type {
+		case "function_call":
+			pendingToolCalls = append(pendingToolCalls, dto.ToolCallRequest{
+				ID:   item.CallId,
+				Type: "function",
+				Function: dto.FunctionRequest{
+					Name:      item.Name,
+					Arguments: item.Arguments,
+				},
+			})
+		case "function_call_output":
+			flushToolCalls()
+			toolMessage := dto.Message{
+				Role:       "tool",
+				ToolCallId: item.CallId,
+			}
+			if common.GetJsonType(item.Output) == "string" {
+				var output string
+				_ = common.Unmarshal(item.Output, &output)
+				toolMessage.SetStringContent(output)
+			} else {
+				toolMessage.SetStringContent(string(item.Output))
+			}
+			messages = append(messages, toolMessage)
+		case "reasoning":
+			// 推理内容无法跨厂商回放，直接忽略
+			continue
+		case "", "message":
+			flushToolCalls()
+			message, err := responsesMessageToOpenAI(item)
+			if err != nil {
+				return nil, err
+			}
+			messages = append(messages, message)
+		}
+	}
+	flushToolCalls()
+	return messages, nil
+}
+
+func responsesMessageToOpenAI(item responsesInputItem) (dto.Message, error) {
+	role := item.Role
+	if role == "" {
+		role = "user"
+	}
+	if role == "developer" {
+		role = "system"
+	}
+	message := dto.Message{Role: role}
+
+	if common.GetJsonType(item.Content) == "string" {
+		var str string
+		if err := common.Unmarshal(item.Content, &str); err != nil {
+			return message, fmt.Errorf("invalid message content: %w", err)
+		}
+		message.SetStringContent(str)
+		return message, nil
+	}
+
+	var parts []map[string]any
+	if err := common.Unmarshal(item.Content, &parts); err != nil {
+		return message, fmt.Errorf("invalid message content: %w", err)
+	}
+	mediaContents := make([]dto.MediaContent, 0, len(parts))
+	for _, part := range parts {
+		switch common.Interface2String(part["type"]) {
+		case "input_text", "output_text", "text":
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeText,
+				Text: common.Interface2String(part["text"]),
+			})
+		case "input_image":
+			var imageUrl string
+			switch v := part["image_url"].(type) {
+			case string:
+				imageUrl = v
+			case map[string]any:
+				imageUrl = common.Interface2String(v["url"])
+			}
+			if imageUrl == "" {
+				continue
+			}
+			detail := common.Interface2String(part["detail"])
+			if detail == "" {
+				detail = "auto"
+			}
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeImageURL,
+				ImageUrl: &dto.MessageImageUrl{
+					Url:    imageUrl,
+					Detail: detail,
+				},
+			})
+		case "input_file":
+			file := &dto.MessageFile{
+				FileName: common.Interface2String(part["filename"]),
+				FileData: common.Interface2String(part["file_data"]),
+				FileId:   common.Interface2String(part["file_id"]),
+			}
+			mediaContents = append(mediaContents, dto.MediaContent{
+				Type: dto.ContentTypeFile,
+				File: file,
+			})
+		}
+	}
+	if len(mediaContents) == 1 && mediaContents[0].Type == dto.ContentTypeText {
+		message.SetStringContent(mediaContents[0].Text)
+	} else {
+		message.SetMediaContent(mediaContents)
+	}
+	return message, nil
+}
+
+// ResponseOpenAI2Responses 将 Chat Completions 非流响应转换为 Responses API 响应
+func ResponseOpenAI2Responses(openAIResponse *dto.OpenAITextResponse, info *relaycommon.RelayInfo) *dto.OpenAIResponsesResponse {
+	response := &dto.OpenAIResponsesResponse{
+		ID:        responsesId(openAIResponse.Id),
+		Object:    "response",
+		CreatedAt: int(common.GetTimestamp()),
+		Status:    "completed",
+		Model:     openAIResponse.Model,
+		Output:    make([]dto.ResponsesOutput, 0),
+		Tools:     make([]map[string]any, 0),
+	}
+	if info != nil && info.Request != nil {
+		if request, ok := info.Request.(*dto.OpenAIResponsesRequest); ok {
+			response.MaxOutputTokens = int(request.MaxOutputTokens)
+			response.PreviousResponseID = request.PreviousResponseID
+			response.Reasoning = request.Reasoning
+			response.Temperature = request.Temperature
+			response.TopP = request.TopP
+			response.Tools = request.GetToolsMap()
+		}
+	}
+
+	for _, choice := range openAIResponse.Choices {
+		if choice.FinishReason == "length" {
+			response.Status = "incomplete"
+		}
+		reasoning := choice.Message.ReasoningContent
+		if reasoning == "" {
+			reasoning = choice.Message.Reasoning
+		}
+		if reasoning != "" {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:   dto.ResponsesItemTypeReasoning,
+				ID:     "rs_" + common.GetUUID(),
+				Status: "completed",
+				Summary: []dto.ResponsesOutputContent{
+					{Type: "summary_text", Text: reasoning},
+				},
+			})
+		}
+		if text := choice.Message.StringContent(); text != "" {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:   dto.ResponsesItemTypeMessage,
+				ID:     "msg_" + common.GetUUID(),
+				Status: "completed",
+				Role:   "assistant",
+				Content: []dto.ResponsesOutputContent{
+					{Type: "output_text", Text: text, Annotations: []interface{}{}},
+				},
+			})
+		}
+		for _, toolCall := range choice.Message.ParseToolCalls() {
+			response.Output = append(response.Output, dto.ResponsesOutput{
+				Type:      dto.ResponsesItemTypeFunctionCall,
+				ID:        "fc_" + common.GetUUID(),
+				Status:    "completed",
+				CallId:    toolCall.ID,
+				Name:      toolCall.Function.Name,
+				Arguments: toolCall.Function.Arguments,
+			})
+		}
+	}
+
+	response.Usage = usageOpenAI2Responses(&openAIResponse.Usage)
+	return response
+}
+
+func responsesId(id string) string {
+	if strings.HasPrefix(id, "resp_") {
+		return id
+	}
+	if id == "" {
+		id = common.GetUUID()
+	}
+	return "resp_" + strings.TrimPrefix(id, "chatcmpl-")
+}
+
+func usageOpenAI2Responses(usage *dto.Usage) *dto.Usage {
+	if usage == nil {
+		return nil
+	}
+	return &dto.Usage{
+		InputTokens:  usage.PromptTokens,
+		OutputTokens: usage.CompletionTokens,
+		TotalTokens:  usage.PromptTokens + usage.CompletionTokens,
+		InputTokensDetails: &dto.InputTokenDetails{
+			CachedTokens: usage.PromptTokensDetails.CachedTokens,
+		},
+	}
+}
+
+func newResponsesStreamEvent(info *relaycommon.RelayInfo, eventType string) *dto.ResponsesStreamResponse {
+	info.ResponsesConvertInfo.SequenceNumber++
+	return &dto.ResponsesStreamResponse{
+		Type:           eventType,
+		SequenceNumber: info.ResponsesConvertInfo.SequenceNumber,
+	}
+}
+
+func buildResponsesSnapshot(info *relaycommon.RelayInfo, status string) *dto.OpenAIResponsesResponse {
+	convertInfo := info.ResponsesConvertInfo
+	response := &dto.OpenAIResponsesResponse{
+		ID:        convertInfo.ResponseId,
+		Object:    "response",
+		CreatedAt: int(convertInfo.CreatedAt),
+		Status:    status,
+		Model:     convertInfo.Model,
+		Output:    make([]dto.ResponsesOutput, 0, len(convertInfo.Output)),
+		Tools:     make([]map[string]any, 0),
+	}
+	response.Output = append(response.Output, convertInfo.Output...)
+	if request, ok := info.Request.(*dto.OpenAIResponsesRequest); ok {
+		response.MaxOutputTokens = int(request.MaxOutputTokens)
+		response.PreviousResponseID = request.PreviousResponseID
+		response.Reasoning = request.Reasoning
+		response.Temperature = request.Temperature
+		response.TopP = request.TopP
+		response.Tools = request.GetToolsMap()
+	}
+	return response
+}
+
+func closeResponsesReasoning(info *relaycommon.RelayInfo) []*dto.ResponsesStreamResponse {
+	convertInfo := info.ResponsesConvertInfo
+	if convertInfo.ReasoningItemId == "" {
+		return nil
+	}
+	text := convertInfo.ReasoningText.String()
+	outputIndex := convertInfo.ReasoningOutputIndex
+	part := dto.ResponsesOutputContent{Type: "summary_text", Text: text}
+	item := dto.ResponsesOutput{
+		Type:    dto.ResponsesItemTypeReasoning,
+		ID:      convertInfo.ReasoningItemId,
+		Status:  "completed",
+		Summary: []dto.ResponsesOutputContent{part},
+	}
+
+	textDone := newResponsesStreamEvent(info, dto.ResponsesStreamTypeReasoningSummaryTextDone)
+	textDone.ItemId = item.ID
+	textDone.OutputIndex = common.GetPointer(outputIndex)
+	textDone.SummaryIndex = common.GetPointer(0)
+	textDone.Text = text
+
+	partDone := newResponsesStreamEvent(info, dto.ResponsesStreamTypeReasoningSummaryDone)
+	partDone.ItemId = item.ID
+	partDone.OutputIndex = common.GetPointer(outputIndex)
+	partDone.SummaryIndex = common.GetPointer(0)
+	partDone.Part = &part
+
+	itemDone := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemDone)
+	itemDone.OutputIndex = common.GetPointer(outputIndex)
+	itemDone.Item = &item
+
+	convertInfo.Output = append(convertInfo.Output, item)
+	convertInfo.ReasoningItemId = ""
+	convertInfo.ReasoningText.Reset()
+	return []*dto.ResponsesStreamResponse{textDone, partDone, itemDone}
+}
+
+func closeResponsesMessage(info *relaycommon.RelayInfo) []*dto.ResponsesStreamResponse {
+	convertInfo := info.ResponsesConvertInfo
+	if convertInfo.MessageItemId == "" {
+		return nil
+	}
+	text := convertInfo.MessageText.String()
+	outputIndex := convertInfo.MessageOutputIndex
+	part := dto.ResponsesOutputContent{Type: "output_text", Text: text, Annotations: []interface{}{}}
+	item := dto.ResponsesOutput{
+		Type:    dto.ResponsesItemTypeMessage,
+		ID:      convertInfo.MessageItemId,
+		Status:  "completed",
+		Role:    "assistant",
+		Content: []dto.ResponsesOutputContent{part},
+	}
+
+	textDone := newResponsesStreamEvent(info, dto.ResponsesStreamTypeOutputTextDone)
+	textDone.ItemId = item.ID
+	textDone.OutputIndex = common.GetPointer(outputIndex)
+	textDone.ContentIndex = common.GetPointer(0)
+	textDone.Text = text
+
+	partDone := newResponsesStreamEvent(info, dto.ResponsesStreamTypeContentPartDone)
+	partDone.ItemId = item.ID
+	partDone.OutputIndex = common.GetPointer(outputIndex)
+	partDone.ContentIndex = common.GetPointer(0)
+	partDone.Part = &part
+
+	itemDone := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemDone)
+	itemDone.OutputIndex = common.GetPointer(outputIndex)
+	itemDone.Item = &item
+
+	convertInfo.Output = append(convertInfo.Output, item)
+	convertInfo.MessageItemId = ""
+	convertInfo.MessageText.Reset()
+	return []*dto.ResponsesStreamResponse{textDone, partDone, itemDone}
+}
+
+func closeResponsesToolCall(info *relaycommon.RelayInfo, toolCall *relaycommon.ResponsesToolCallState) []*dto.ResponsesStreamResponse {
+	arguments := toolCall.Arguments.String()
+	item := dto.ResponsesOutput{
+		Type:      dto.ResponsesItemTypeFunctionCall,
+		ID:        toolCall.ItemId,
+		Status:    "completed",
+		CallId:    toolCall.CallId,
+		Name:      toolCall.Name,
+		Arguments: arguments,
+	}
+
+	argsDone := newResponsesStreamEvent(info, dto.ResponsesStreamTypeFunctionCallArgsDone)
+	argsDone.ItemId = item.ID
+	argsDone.OutputIndex = common.GetPointer(toolCall.OutputIndex)
+	argsDone.Arguments = arguments
+
+	itemDone := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemDone)
+	itemDone.OutputIndex = common.GetPointer(toolCall.OutputIndex)
+	itemDone.Item = &item
+
+	info.ResponsesConvertInfo.Output = append(info.ResponsesConvertInfo.Output, item)
+	return []*dto.ResponsesStreamResponse{argsDone, itemDone}
+}
+
+func closeResponsesToolCalls(info *relaycommon.RelayInfo) []*dto.ResponsesStreamResponse {
+	convertInfo := info.ResponsesConvertInfo
+	if len(convertInfo.ToolCalls) == 0 {
+		return nil
+	}
+	// 按输出顺序关闭，保证 output_index 递增
+	toolCalls := make([]*relaycommon.ResponsesToolCallState, 0, len(convertInfo.ToolCalls))
+	for _, toolCall := range convertInfo.ToolCalls {
+		toolCalls = append(toolCalls, toolCall)
+	}
+	sort.Slice(toolCalls, func(i, j int) bool {
+		return toolCalls[i].OutputIndex < toolCalls[j].OutputIndex
+	})
+	var events []*dto.ResponsesStreamResponse
+	for _, toolCall := range toolCalls {
+		events = append(events, closeResponsesToolCall(info, toolCall)...)
+	}
+	convertInfo.ToolCalls = make(map[int]*relaycommon.ResponsesToolCallState)
+	return events
+}
+
+func closeResponsesItems(info *relaycommon.RelayInfo) []*dto.ResponsesStreamResponse {
+	var events []*dto.ResponsesStreamResponse
+	events = append(events, closeResponsesReasoning(info)...)
+	events = append(events, closeResponsesMessage(info)...)
+	events = append(events, closeResponsesToolCalls(info)...)
+	return events
+}
+
+// StreamResponseOpenAI2Responses 将单个 Chat Completions 流式块转换为 Responses API 流事件，
+// 转换状态保存在 info.ResponsesConvertInfo 中，response.completed 由 GenerateResponsesCompletedEvents 发送
+func StreamResponseOpenAI2Responses(openAIResponse *dto.ChatCompletionsStreamResponse, info *relaycommon.RelayInfo) []*dto.ResponsesStreamResponse {
+	convertInfo := info.ResponsesConvertInfo
+	if convertInfo == nil || convertInfo.Completed {
+		return nil
+	}
+
+	var events []*dto.ResponsesStreamResponse
+	if !convertInfo.Started {
+		convertInfo.Started = true
+		convertInfo.ResponseId = responsesId(openAIResponse.Id)
+		convertInfo.CreatedAt = openAIResponse.Created
+		if convertInfo.CreatedAt == 0 {
+			convertInfo.CreatedAt = common.GetTimestamp()
+		}
+		convertInfo.Model = openAIResponse.Model
+		if convertInfo.Model == "" {
+			convertInfo.Model = info.UpstreamModelName
+		}
+
+		created := newResponsesStreamEvent(info, dto.ResponsesStreamTypeCreated)
+		created.Response = buildResponsesSnapshot(info, "in_progress")
+		inProgress := newResponsesStreamEvent(info, dto.ResponsesStreamTypeInProgress)
+		inProgress.Response = buildResponsesSnapshot(info, "in_progress")
+		events = append(events, created, inProgress)
+	}
+
+	if len(openAIResponse.Choices) == 0 {
+		return events
+	}
+	choice := openAIResponse.Choices[0]
+
+	if reasoning := choice.Delta.GetReasoningContent(); reasoning != "" {
+		if convertInfo.ReasoningItemId == "" {
+			events = append(events, closeResponsesMessage(info)...)
+			events = append(events, closeResponsesToolCalls(info)...)
+			convertInfo.ReasoningItemId = "rs_" + common.GetUUID()
+			convertInfo.ReasoningOutputIndex = convertInfo.OutputIndex
+			convertInfo.OutputIndex++
+
+			itemAdded := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemAdded)
+			itemAdded.OutputIndex = common.GetPointer(convertInfo.ReasoningOutputIndex)
+			itemAdded.Item = &dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeReasoning,
+				ID:      convertInfo.ReasoningItemId,
+				Status:  "in_progress",
+				Summary: []dto.ResponsesOutputContent{},
+			}
+			partAdded := newResponsesStreamEvent(info, dto.ResponsesStreamTypeReasoningSummaryAdded)
+			partAdded.ItemId = convertInfo.ReasoningItemId
+			partAdded.OutputIndex = common.GetPointer(convertInfo.ReasoningOutputIndex)
+			partAdded.SummaryIndex = common.GetPointer(0)
+			partAdded.Part = &dto.ResponsesOutputContent{Type: "summary_text"}
+			events = append(events, itemAdded, partAdded)
+		}
+		convertInfo.ReasoningText.WriteString(reasoning)
+		delta := newResponsesStreamEvent(info, dto.ResponsesStreamTypeReasoningSummaryDelta)
+		delta.ItemId = convertInfo.ReasoningItemId
+		delta.OutputIndex = common.GetPointer(convertInfo.ReasoningOutputIndex)
+		delta.SummaryIndex = common.GetPointer(0)
+		delta.Delta = reasoning
+		events = append(events, delta)
+	}
+
+	if content := choice.Delta.GetContentString(); content != "" {
+		if convertInfo.MessageItemId == "" {
+			events = append(events, closeResponsesReasoning(info)...)
+			events = append(events, closeResponsesToolCalls(info)...)
+			convertInfo.MessageItemId = "msg_" + common.GetUUID()
+			convertInfo.MessageOutputIndex = convertInfo.OutputIndex
+			convertInfo.OutputIndex++
+
+			itemAdded := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemAdded)
+			itemAdded.OutputIndex = common.GetPointer(convertInfo.MessageOutputIndex)
+			itemAdded.Item = &dto.ResponsesOutput{
+				Type:    dto.ResponsesItemTypeMessage,
+				ID:      convertInfo.MessageItemId,
+				Status:  "in_progress",
+				Role:    "assistant",
+				Content: []dto.ResponsesOutputContent{},
+			}
+			partAdded := newResponsesStreamEvent(info, dto.ResponsesStreamTypeContentPartAdded)
+			partAdded.ItemId = convertInfo.MessageItemId
+			partAdded.OutputIndex = common.GetPointer(convertInfo.MessageOutputIndex)
+			partAdded.ContentIndex = common.GetPointer(0)
+			partAdded.Part = &dto.ResponsesOutputContent{Type: "output_text", Annotations: []interface{}{}}
+			events = append(events, itemAdded, partAdded)
+		}
+		convertInfo.MessageText.WriteString(content)
+		delta := newResponsesStreamEvent(info, dto.ResponsesStreamTypeOutputTextDelta)
+		delta.ItemId = convertInfo.MessageItemId
+		delta.OutputIndex = common.GetPointer(convertInfo.MessageOutputIndex)
+		delta.ContentIndex = common.GetPointer(0)
+		delta.Delta = content
+		events = append(events, delta)
+	}
+
+	for i, toolCall := range choice.Delta.ToolCalls {
+		index := i
+		if toolCall.Index != nil {
+			index = *toolCall.Index
+		}
+		state, ok := convertInfo.ToolCalls[index]
+		if ok && toolCall.ID != "" && toolCall.ID != state.CallId {
+			// 同一索引出现新的调用 ID，视为新的函数调用
+			events = append(events, closeResponsesToolCall(info, state)...)
+			delete(convertInfo.ToolCalls, index)
+			ok = false
+		}
+		if !ok {
+			events = append(events, closeResponsesReasoning(info)...)
+			events = append(events, closeResponsesMessage(info)...)
+			callId := toolCall.ID
+			if callId == "" {
+				callId = "call_" + common.GetUUID()
+			}
+			state = &relaycommon.ResponsesToolCallState{
+				ItemId:      "fc_" + common.GetUUID(),
+				CallId:      callId,
+				Name:        toolCall.Function.Name,
+				OutputIndex: convertInfo.OutputIndex,
+			}
+			convertInfo.OutputIndex++
+			convertInfo.ToolCalls[index] = state
+
+			itemAdded := newResponsesStreamEvent(info, dto.ResponsesOutputTypeItemAdded)
+			itemAdded.OutputIndex = common.GetPointer(state.OutputIndex)
+			itemAdded.Item = &dto.ResponsesOutput{
+				Type:   dto.ResponsesItemTypeFunctionCall,
+				ID:     state.ItemId,
+				Status: "in_progress",
+				CallId: state.CallId,
+				Name:   state.Name,
+			}
+			events = append(events, itemAdded)
+		} else if state.Name == "" && toolCall.Function.Name != "" {
+			state.Name = toolCall.Function.Name
+		}
+		if toolCall.Function.Arguments != "" {
+			state.Arguments.WriteString(toolCall.Function.Arguments)
+			delta := newResponsesStreamEvent(info, dto.ResponsesStreamTypeFunctionCallArgsDelta)
+			delta.ItemId = state.ItemId
+			delta.OutputIndex = common.GetPointer(state.OutputIndex)
+			delta.Delta = toolCall.Function.Arguments
+			events = append(events, delta)
+		}
+	}
+
+	if choice.FinishReason != nil && *choice.FinishReason != "" {
+		events = append(events, closeResponsesItems(info)...)
+	}
+	return events
+}
+
+// GenerateResponsesCompletedEvents 关闭仍未结束的输出项并生成携带用量的 response.completed 事件
+func GenerateResponsesCompletedEvents(info *relaycommon.RelayInfo, usage *dto.Usage) []*dto.ResponsesStreamResponse {
+	convertInfo := info.ResponsesConvertInfo
+	if convertInfo == nil || convertInfo.Completed {
+		return nil
+	}
+	var events []*dto.ResponsesStreamResponse
+	if !convertInfo.Started {
+		// 上游没有返回任何内容，仍需补齐 response.created
+		events = append(events, StreamResponseOpenAI2Responses(&dto.ChatCompletionsStreamResponse{}, info)...)
+	}
+	events = append(events, closeResponsesItems(info)...)
+
+	completed := newResponsesStreamEvent(info, dto.ResponsesStreamTypeCompleted)
+	completed.Response = buildResponsesSnapshot(info, "completed")
+	completed.Response.Usage = usageOpenAI2Responses(usage)
+	events = append(events, completed)
+	convertInfo.Completed = true
+	return events
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/QuantumNous/new-api/common"
+	"github.com/QuantumNous/new-api/dto"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+)
+
+func TestResponsesToOpenAIRequestFunctionCalls(t *testing.T) {
+	request := &dto.OpenAIResponsesRequest{
+		Model:        "claude-sonnet-4",
+		Instructions: []byte(`"be brief"`),
+		Input: []byte(`[
+			{"role":"user","content":[{"type":"input_text","text":"weather?"}]},
+			{"type":"function_call","call_id":"call_1","name":"get_weather","arguments":"{\"city\":\"Paris\"}"},
+			{"type":"function_call_output","call_id":"call_1","output":"sunny"}
+		]`),
+		Tools:      []byte(`[{"type":"function","name":"get_weather","parameters":{"type":"object"}}]`),
+		ToolChoice: []byte(`{"type":"function","name":"get_weather"}`),
+	}
+
+	out, err := ResponsesToOpenAIRequest(request)
+	if err != nil {
+		t.Fatalf("ResponsesToOpenAIRequest returned error: %v", err)
+	}
+	if len(out.Messages) != 4 {
+		t.Fatalf("expected 4 messages, got %d", len(out.Messages))
+	}
+	if out.Messages[0].Role != "system" || out.Messages[0].StringContent() != "be brief" {
+		t.Fatalf("unexpected system message: %+v", out.Messages[0])
+	}
+	if out.Messages[1].StringContent() != "weather?" {
+		t.Fatalf("unexpected user message: %+v", out.Messages[1])
+	}
+	toolCalls := out.Messages[2].ParseToolCalls()
+	if out.Messages[2].Role != "assistant" || len(toolCalls) != 1 || toolCalls[0].ID != "call_1" {
+		t.Fatalf("unexpected assistant tool call message: %+v", out.Messages[2])
+	}
+	if out.Messages[3].Role != "tool" || out.Messages[3].ToolCallId != "call_1" || out.Messages[3].StringContent() != "sunny" {
+		t.Fatalf("unexpected tool message: %+v", out.Messages[3])
+	}
+	if len(out.Tools) != 1 || out.Tools[0].Function.Name != "get_weather" {
+		t.Fatalf("unexpected tools: %+v", out.Tools)
+	}
+}
+
+func TestStreamResponseOpenAI2ResponsesEvents(t *testing.T) {
+	info := &relaycommon.RelayInfo{
+		Request: &dto.OpenAIResponsesRequest{Model: "gemini-2.5-pro", Stream: true},
+		ResponsesConvertInfo: &relaycommon.ResponsesConvertInfo{
+			ToolCalls: make(map[int]*relaycommon.ResponsesToolCallState),
+		},
+	}
+	chunks := []string{
+		`{"id":"chatcmpl-1","model":"gemini-2.5-pro","choices":[{"delta":{"content":"Hel"}}]}`,
+		`{"id":"chatcmpl-1","model":"gemini-2.5-pro","choices":[{"delta":{"content":"lo"}}]}`,
+		`{"id":"chatcmpl-1","model":"gemini-2.5-pro","choices":[{"delta":{"tool_calls":[{"index":0,"id":"call_1","function":{"name":"f","arguments":"{}"}}]},"finish_reason":"tool_calls"}]}`,
+	}
+
+	var types []string
+	for _, chunk := range chunks {
+		var streamResponse dto.ChatCompletionsStreamResponse
+		if err := common.UnmarshalJsonStr(chunk, &streamResponse); err != nil {
+			t.Fatalf("failed to unmarshal chunk: %v", err)
+		}
+		for _, event := range StreamResponseOpenAI2Responses(&streamResponse, info) {
+			types = append(types, event.Type)
+		}
+	}
+	events := GenerateResponsesCompletedEvents(info, &dto.Usage{PromptTokens: 5, CompletionTokens: 7})
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+
+	expected := []string{
+		dto.ResponsesStreamTypeCreated,
+		dto.ResponsesStreamTypeInProgress,
+		dto.ResponsesOutputTypeItemAdded,
+		dto.ResponsesStreamTypeContentPartAdded,
+		dto.ResponsesStreamTypeOutputTextDelta,
+		dto.ResponsesStreamTypeOutputTextDelta,
+		dto.ResponsesStreamTypeOutputTextDone,
+		dto.ResponsesStreamTypeContentPartDone,
+		dto.ResponsesOutputTypeItemDone,
+		dto.ResponsesOutputTypeItemAdded,
+		dto.ResponsesStreamTypeFunctionCallArgsDelta,
+		dto.ResponsesStreamTypeFunctionCallArgsDone,
+		dto.ResponsesOutputTypeItemDone,
+		dto.ResponsesStreamTypeCompleted,
+	}
+	if len(types) != len(expected) {
+		t.Fatalf("expected %d events, got %d: %v", len(expected), len(types), types)
+	}
+	for i := range expected {
+		if types[i] != expected[i] {
+			t.Fatalf("event %d: expected %s, got %s", i, expected[i], types[i])
+		}
+	}
+
+	completed := events[len(events)-1].Response
+	if completed.Usage == nil || completed.Usage.InputTokens != 5 || completed.Usage.OutputTokens != 7 {
+		t.Fatalf("unexpected usage: %+v", completed.Usage)
+	}
+	if len(completed.Output) != 2 || completed.Output[0].Content[0].Text != "Hello" || completed.Output[1].CallId != "call_1" {
+		t.Fatalf("unexpected output: %+v", completed.Output)
+	}
+}
+
+func TestStreamResponseResponses2OpenAIToolCalls(t *testing.T) {
+	state := NewResponsesChatStreamState("chatcmpl-1", "gpt-5-codex")
+	events := []string{
+		`{"type":"response.created","response":{"id":"resp_1","model":"gpt-5-codex","created_at":1}}`,
+		`{"type":"response.reasoning_summary_text.delta","item_id":"rs_1","delta":"thinking"}`,
+		`{"type":"response.output_item.added","item":{"type":"function_call","id":"fc_1","call_id":"call_1","name":"get_weather","arguments":""}}`,
+		`{"type":"response.function_call_arguments.delta","item_id":"fc_1","delta":"{\"city\":"}`,
+		`{"type":"response.function_call_arguments.delta","item_id":"fc_1","delta":"\"Paris\"}"}`,
+		`{"type":"response.completed","response":{"id":"resp_1","usage":{"input_tokens":3,"output_tokens":4,"total_tokens":7}}}`,
+	}
+
+	var chunks []*dto.ChatCompletionsStreamResponse
+	for _, data := range events {
+		var event dto.ResponsesStreamResponse
+		if err := common.UnmarshalJsonStr(data, &event); err != nil {
+			t.Fatalf("failed to unmarshal event: %v", err)
+		}
+		if chunk := StreamResponseResponses2OpenAI(&event, state); chunk != nil {
+			chunks = append(chunks, chunk)
+		}
+	}
+
+	if len(chunks) != 6 {
+		t.Fatalf("expected 6 chunks, got %d", len(chunks))
+	}
+	if chunks[0].Choices[0].Delta.Role != "assistant" {
+		t.Fatalf("expected first chunk to carry assistant role")
+	}
+	if chunks[1].Choices[0].Delta.GetReasoningContent() != "thinking" {
+		t.Fatalf("unexpected reasoning chunk: %+v", chunks[1].Choices[0].Delta)
+	}
+	toolCall := chunks[2].Choices[0].Delta.ToolCalls[0]
+	if toolCall.ID != "call_1" || toolCall.Function.Name != "get_weather" || *toolCall.Index != 0 {
+		t.Fatalf("unexpected tool call chunk: %+v", toolCall)
+	}
+	arguments := chunks[3].Choices[0].Delta.ToolCalls[0].Function.Arguments + chunks[4].Choices[0].Delta.ToolCalls[0].Function.Arguments
+	if arguments != `{"city":"Paris"}` {
+		t.Fatalf("unexpected arguments: %s", arguments)
+	}
+	finish := chunks[5].Choices[0].FinishReason
+	if finish == nil || *finish != "tool_calls" {
+		t.Fatalf("unexpected finish reason: %v", finish)
+	}
+	if state.Usage == nil || state.Usage.PromptTokens != 3 || state.Usage.CompletionTokens != 4 {
+		t.Fatalf("unexpected usage: %+v", state.Usage)
+	}
+}
@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"strings"
+	"unicode"
 
 	"github.com/QuantumNous/new-api/dto"
 	"github.com/QuantumNous/new-api/setting"
@@ -53,25 +54,52 @@ func SensitiveWordReplace(text string, returnImmediately bool) (bool, []string,
 	if len(setting.SensitiveWords) == 0 {
 		return false, nil, text
 	}
-	checkText := strings.ToLower(text)
+	runes := []rune(text)
+	masked, words := sensitiveWordMask(runes, returnImmediately)
+	if len(words) == 0 {
+		return false, nil, text
+	}
+	return true, words, maskRunes(runes, masked)
+}
+
+// sensitiveWordMask 标记命中敏感词的字符，返回每个字符是否命中以及命中的敏感词（已去重）
+// 匹配结果中的位置是字符下标而不是字节下标，因此需要在 rune 上处理
+func sensitiveWordMask(runes []rune, returnImmediately bool) ([]bool, []string) {
 	m := getOrBuildAC(setting.SensitiveWords)
-	hits := m.MultiPatternSearch([]rune(checkText), returnImmediately)
-	if len(hits) > 0 {
-		words := make([]string, 0, len(hits))
-		var builder strings.Builder
-		builder.Grow(len(text))
-		lastPos := 0
+	if m == nil || len(runes) == 0 {
+		return nil, nil
+	}
+	checkRunes := make([]rune, len(runes))
+	for i, r := range runes {
+		checkRunes[i] = unicode.ToLower(r)
+	}
+	hits := m.MultiPatternSearch(checkRunes, returnImmediately)
+	if len(hits) == 0 {
+		return nil, nil
+	}
+	masked := make([]bool, len(runes))
+	words := make([]string, 0, len(hits))
+	for _, hit := range hits {
+		for i := hit.Pos; i < hit.Pos+len(hit.Word) && i < len(masked); i++ {
+			masked[i] = true
+		}
+		words = append(words, string(hit.Word))
+	}
+	return masked, RemoveDuplicate(words)
+}
 
-		for _, hit := range hits {
-			pos := hit.Pos
-			word := string(hit.Word)
-			builder.WriteString(text[lastPos:pos])
-			builder.WriteString("**###**")
-			lastPos = pos + len(word)
-			words = append(words, word)
+// maskRunes 将连续命中的字符替换为一个 **###**
+func maskRunes(runes []rune, masked []bool) string {
+	var builder strings.Builder
+	builder.Grow(len(runes))
+	for i, r := range runes {
+		if i < len(masked) && masked[i] {
+			if i == 0 || !masked[i-1] {
+				builder.WriteString("**###**")
+			}
+			continue
 		}
-		builder.WriteString(text[lastPos:])
-		return true, words, builder.String()
+		builder.WriteRune(r)
 	}
-	return false, nil, text
+	return builder.String()
 }
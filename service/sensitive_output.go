@@ -0,0 +1,137 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/QuantumNous/new-api/logger"
+	relaycommon "github.com/QuantumNous/new-api/relay/common"
+	"github.com/QuantumNous/new-api/setting"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sensitiveTextWindow 流式输出的滑动窗口，保留尾部文本用于匹配跨片段的敏感词
+type sensitiveTextWindow struct {
+	held   []rune
+	masked []bool
+	recent []int
+}
+
+// sensitiveOutputFilter 检查模型输出中的敏感词，命中时替换或中断输出
+type sensitiveOutputFilter struct {
+	c       *gin.Context
+	info    *relaycommon.RelayInfo
+	stop    bool
+	minHold int
+	windows map[int]*sensitiveTextWindow
+}
+
+func newSensitiveOutputFilter(c *gin.Context, info *relaycommon.RelayInfo) *sensitiveOutputFilter {
+	if !setting.ShouldCheckCompletionSensitive() {
+		return nil
+	}
+	maxWordLen := 0
+	for _, word := range setting.SensitiveWords {
+		maxWordLen = max(maxWordLen, utf8.RuneCountInString(strings.TrimSpace(word)))
+	}
+	return &sensitiveOutputFilter{
+		c:       c,
+		info:    info,
+		stop:    setting.StopOnSensitiveEnabled,
+		minHold: max(maxWordLen-1, 0),
+		windows: make(map[int]*sensitiveTextWindow),
+	}
+}
+
+func (f *sensitiveOutputFilter) recordHits(words []string) {
+	for _, word := range words {
+		found := false
+		for _, hit := range f.info.SensitiveWordsHit {
+			if hit == word {
+				found = true
+				break
+			}
+		}
+		if !found {
+			f.info.SensitiveWordsHit = append(f.info.SensitiveWordsHit, word)
+			logger.LogWarn(f.c, fmt.Sprintf("completion sensitive words detected: %s", word))
+		}
+	}
+}
+
+func (f *sensitiveOutputFilter) full(text string) (string, bool) {
+	runes := []rune(text)
+	masked, words := sensitiveWordMask(runes, false)
+	if len(words) == 0 {
+		return text, false
+	}
+	f.recordHits(words)
+	if f.stop {
+		f.info.SensitiveStopped = true
+		return string(runes[:firstMasked(masked)]), true
+	}
+	return maskRunes(runes, masked), false
+}
+
+func (f *sensitiveOutputFilter) delta(index int, text string) (string, bool) {
+	window, ok := f.windows[index]
+	if !ok {
+		window = &sensitiveTextWindow{}
+		f.windows[index] = window
+	}
+	deltaRunes := []rune(text)
+	runes := append(window.held, deltaRunes...)
+	masked := append(window.masked, make([]bool, len(deltaRunes))...)
+	hitMask, words := sensitiveWordMask(runes, false)
+	if len(words) > 0 {
+		f.recordHits(words)
+		if f.stop {
+			f.info.SensitiveStopped = true
+			window.held, window.masked = nil, nil
+			return string(runes[:firstMasked(hitMask)]), true
+		}
+		for i, hit := range hitMask {
+			masked[i] = masked[i] || hit
+		}
+	}
+
+	queueLength := max(setting.StreamCacheQueueLength, 0)
+	window.recent = append(window.recent, len(deltaRunes))
+	if len(window.recent) > queueLength {
+		window.recent = window.recent[len(window.recent)-queueLength:]
+	}
+	hold := 0
+	for _, n := range window.recent {
+		hold += n
+	}
+	hold = max(hold, f.minHold)
+	cut := max(len(runes)-hold, 0)
+	// 命中的敏感词已经完整出现在窗口中，整段输出，避免被拆成两段替换
+	for cut > 0 && cut < len(runes) && masked[cut-1] && masked[cut] {
+		cut++
+	}
+	window.held = append([]rune(nil), runes[cut:]...)
+	window.masked = append([]bool(nil), masked[cut:]...)
+	return maskRunes(runes[:cut], masked[:cut]), false
+}
+
+func (f *sensitiveOutputFilter) flush(index int) string {
+	window, ok := f.windows[index]
+	if !ok || len(window.held) == 0 {
+		return ""
+	}
+	text := maskRunes(window.held, window.masked)
+	window.held, window.masked = nil, nil
+	return text
+}
+
+func firstMasked(masked []bool) int {
+	for i, m := range masked {
+		if m {
+			return i
+		}
+	}
+	return len(masked)
+}
@@ -0,0 +1,30 @@
+package setting
+
+import (
+	"github.com/QuantumNous/new-api/common"
+)
+
+// 启用自适应渠道选择的分组，未列出的分组仍按优先级与权重随机选择
+var adaptiveSelectGroups = []string{}
+
+func IsAdaptiveSelectGroup(group string) bool {
+	for _, g := range adaptiveSelectGroups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+func UpdateAdaptiveSelectGroupsByJsonString(jsonString string) error {
+	adaptiveSelectGroups = make([]string, 0)
+	return common.Unmarshal([]byte(jsonString), &adaptiveSelectGroups)
+}
+
+func AdaptiveSelectGroups2JsonString() string {
+	jsonBytes, err := common.Marshal(adaptiveSelectGroups)
+	if err != nil {
+		return "[]"
+	}
+	return string(jsonBytes)
+}
@@ -0,0 +1,75 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// AdmissionClass 请求优先级
+type AdmissionClass struct {
+	// 数值越大越先获得渠道的并发名额
+	Priority int `json:"priority"`
+	// 渠道并发已满时的最长排队时间（毫秒），0 表示不排队直接返回错误
+	MaxWaitMs int `json:"max_wait_ms"`
+}
+
+// AdmissionSetting 渠道并发上限与请求排队配置，注意bool要以enabled结尾才可以生效编辑
+type AdmissionSetting struct {
+	Enabled bool `json:"enabled"`
+	// 渠道默认的并发上限，0 表示不限制；渠道设置中单独配置时以渠道为准
+	ChannelConcurrency int `json:"channel_concurrency"`
+	// 优先级名称 -> 优先级
+	Classes map[string]AdmissionClass `json:"classes"`
+	// 分组 -> 优先级名称，令牌单独配置时以令牌为准
+	GroupClasses map[string]string `json:"group_classes"`
+	// 未配置优先级的请求使用的优先级名称
+	DefaultClass string `json:"default_class"`
+	// 批处理回放使用的优先级名称，为空时与普通请求相同
+	BatchClass string `json:"batch_class"`
+}
+
+// 默认配置
+var admissionSetting = AdmissionSetting{
+	Enabled:            false,
+	ChannelConcurrency: 0,
+	Classes: map[string]AdmissionClass{
+		"interactive": {Priority: 10, MaxWaitMs: 5000},
+		"batch":       {Priority: 0, MaxWaitMs: 60000},
+	},
+	GroupClasses: map[string]string{},
+	DefaultClass: "interactive",
+	BatchClass:   "batch",
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("admission_setting", &admissionSetting)
+}
+
+func GetAdmissionSetting() *AdmissionSetting {
+	return &admissionSetting
+}
+
+// GetAdmissionClass 返回请求使用的优先级名称与配置，tokenClass 为令牌配置的优先级名称；
+// 配置的优先级不存在时使用默认优先级，默认优先级也不存在时按优先级 0、不排队处理
+func GetAdmissionClass(group string, tokenClass string) (string, AdmissionClass) {
+	for _, name := range []string{tokenClass, admissionSetting.GroupClasses[group], admissionSetting.DefaultClass} {
+		if name == "" {
+			continue
+		}
+		if class, ok := admissionSetting.Classes[name]; ok {
+			return name, class
+		}
+	}
+	return "default", AdmissionClass{}
+}
+
+// GetChannelConcurrency 返回渠道的并发上限，channelLimit 为渠道设置中的并发上限，返回 0 表示不限制
+func GetChannelConcurrency(channelLimit int) int {
+	if !admissionSetting.Enabled {
+		return 0
+	}
+	if channelLimit > 0 {
+		return channelLimit
+	}
+	return max(admissionSetting.ChannelConcurrency, 0)
+}
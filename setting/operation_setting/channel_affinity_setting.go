@@ -0,0 +1,38 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type ChannelAffinitySetting struct {
+	Enabled bool `json:"enabled"`
+	// 会话与渠道对应关系的有效期（秒），每次命中后重新计时
+	TTLSeconds int `json:"ttl_seconds"`
+	// 显式指定会话的请求头，为空时不读取请求头
+	SessionHeader string `json:"session_header"`
+	// 是否使用请求体中的 user 字段作为会话标识
+	UseUserField bool `json:"use_user_field"`
+	// 没有显式会话标识时，除系统提示词外参与计算哈希的前几条消息数
+	PrefixMessages int `json:"prefix_messages"`
+	// 未启用 Redis 时本地内存保存的最大会话数
+	MaxEntries int `json:"max_entries"`
+}
+
+// 默认配置
+var channelAffinitySetting = ChannelAffinitySetting{
+	Enabled:        false,
+	TTLSeconds:     3600,
+	SessionHeader:  "X-Session-Id",
+	UseUserField:   true,
+	PrefixMessages: 1,
+	MaxEntries:     10000,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("channel_affinity_setting", &channelAffinitySetting)
+}
+
+func GetChannelAffinitySetting() *ChannelAffinitySetting {
+	return &channelAffinitySetting
+}
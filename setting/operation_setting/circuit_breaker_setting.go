@@ -0,0 +1,29 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type CircuitBreakerSetting struct {
+	Enabled          bool `json:"enabled"`
+	FailureThreshold int  `json:"failure_threshold"`
+	CooldownSeconds  int  `json:"cooldown_seconds"`
+	HalfOpenProbes   int  `json:"half_open_probes"`
+}
+
+// 默认配置
+var circuitBreakerSetting = CircuitBreakerSetting{
+	Enabled:          false,
+	FailureThreshold: 5,
+	CooldownSeconds:  60,
+	HalfOpenProbes:   3,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("circuit_breaker_setting", &circuitBreakerSetting)
+}
+
+func GetCircuitBreakerSetting() *CircuitBreakerSetting {
+	return &circuitBreakerSetting
+}
@@ -0,0 +1,77 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	GuardrailBackendModeration = "moderation"
+	GuardrailBackendWebhook    = "webhook"
+)
+
+const (
+	GuardrailActionOff   = "off"
+	GuardrailActionLog   = "log"
+	GuardrailActionFlag  = "flag"
+	GuardrailActionBlock = "block"
+)
+
+// GuardrailSetting 外部内容审核配置，注意bool要以enabled结尾才可以生效编辑
+type GuardrailSetting struct {
+	Enabled bool `json:"enabled"`
+	// 审核后端：moderation 通过本站渠道调用 OpenAI 兼容的 /v1/moderations，webhook 调用自定义地址
+	Backend string `json:"backend"`
+	// moderation 后端使用的模型与渠道分组，分组中支持该模型的渠道需为 OpenAI 类型
+	ModerationModel string `json:"moderation_model"`
+	ModerationGroup string `json:"moderation_group"`
+	WebhookURL      string `json:"webhook_url"`
+	// 以 Bearer 方式发送给 webhook
+	WebhookSecret  string `json:"webhook_secret"`
+	TimeoutSeconds int    `json:"timeout_seconds"`
+	// 审核服务不可用时是否放行请求
+	FailOpenEnabled bool `json:"fail_open_enabled"`
+	// 是否在请求完成后审核模型输出，输出已返回给用户，只记录不拦截
+	CheckCompletionEnabled bool `json:"check_completion_enabled"`
+	// 未单独配置的分组使用的处理方式：block、flag、log、off
+	DefaultAction string `json:"default_action"`
+	// 分组 -> 处理方式
+	GroupPolicies map[string]string `json:"group_policies"`
+}
+
+// 默认配置
+var guardrailSetting = GuardrailSetting{
+	Enabled:                false,
+	Backend:                GuardrailBackendModeration,
+	ModerationModel:        "omni-moderation-latest",
+	ModerationGroup:        "default",
+	TimeoutSeconds:         10,
+	FailOpenEnabled:        true,
+	CheckCompletionEnabled: false,
+	DefaultAction:          GuardrailActionFlag,
+	GroupPolicies:          map[string]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("guardrail_setting", &guardrailSetting)
+}
+
+func GetGuardrailSetting() *GuardrailSetting {
+	return &guardrailSetting
+}
+
+// GetGuardrailAction 返回分组使用的处理方式，未启用时返回 off
+func GetGuardrailAction(group string) string {
+	if !guardrailSetting.Enabled {
+		return GuardrailActionOff
+	}
+	action, ok := guardrailSetting.GroupPolicies[group]
+	if !ok {
+		action = guardrailSetting.DefaultAction
+	}
+	switch action {
+	case GuardrailActionLog, GuardrailActionFlag, GuardrailActionBlock:
+		return action
+	}
+	return GuardrailActionOff
+}
@@ -0,0 +1,48 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// HedgeSetting 对冲请求配置，首个渠道超过等待时间仍未开始输出时向另一个渠道发出相同的请求，注意bool要以enabled结尾才可以生效编辑
+type HedgeSetting struct {
+	Enabled bool `json:"enabled"`
+	// 分组 -> 等待首字节的毫秒数，未配置的分组不发出对冲请求；令牌单独配置时以令牌为准
+	GroupDelays map[string]int `json:"group_delays"`
+	// 对冲请求等待时间的下限，避免配置过小导致几乎每个请求都向两个渠道发出
+	MinDelayMs int `json:"min_delay_ms"`
+}
+
+// 默认配置
+var hedgeSetting = HedgeSetting{
+	Enabled:     false,
+	GroupDelays: map[string]int{},
+	MinDelayMs:  200,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("hedge_setting", &hedgeSetting)
+}
+
+func GetHedgeSetting() *HedgeSetting {
+	return &hedgeSetting
+}
+
+// GetHedgeDelayMs 返回请求等待首字节的毫秒数，tokenDelayMs 为令牌配置的等待时间，返回 0 表示不发出对冲请求
+func GetHedgeDelayMs(group string, tokenDelayMs int) int {
+	if !hedgeSetting.Enabled {
+		return 0
+	}
+	delay := tokenDelayMs
+	if delay <= 0 {
+		delay = hedgeSetting.GroupDelays[group]
+	}
+	if delay <= 0 {
+		return 0
+	}
+	if delay < hedgeSetting.MinDelayMs {
+		delay = hedgeSetting.MinDelayMs
+	}
+	return delay
+}
@@ -0,0 +1,62 @@
+package operation_setting
+
+import (
+	"strings"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+// ModelFallbackAllGroups 对所有分组生效的降级链使用的分组名
+const ModelFallbackAllGroups = "*"
+
+// ModelFallbackSetting 模型降级链配置，某个模型的渠道全部不可用时改用链中的下一个模型，注意bool要以enabled结尾才可以生效编辑
+type ModelFallbackSetting struct {
+	Enabled bool `json:"enabled"`
+	// 分组 -> 降级链列表，降级链形如 "gpt-4o -> gpt-4o-mini -> deepseek-chat"；分组 "*" 对所有分组生效
+	Chains map[string][]string `json:"chains"`
+}
+
+// 默认配置
+var modelFallbackSetting = ModelFallbackSetting{
+	Enabled: false,
+	Chains:  map[string][]string{},
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("model_fallback_setting", &modelFallbackSetting)
+}
+
+func GetModelFallbackSetting() *ModelFallbackSetting {
+	return &modelFallbackSetting
+}
+
+// ParseModelFallbackChain 将 "a -> b -> c" 形式的降级链拆分为模型列表
+func ParseModelFallbackChain(chain string) []string {
+	models := make([]string, 0)
+	for _, name := range strings.Split(chain, "->") {
+		if name = strings.TrimSpace(name); name != "" {
+			models = append(models, name)
+		}
+	}
+	return models
+}
+
+// GetModelFallbacks 返回模型在分组中的降级模型，按降级顺序排列；
+// 优先使用分组自己的降级链，其次使用 "*" 的降级链，取第一条包含该模型的降级链
+func GetModelFallbacks(group string, modelName string) []string {
+	if !modelFallbackSetting.Enabled {
+		return nil
+	}
+	for _, g := range []string{group, ModelFallbackAllGroups} {
+		for _, chain := range modelFallbackSetting.Chains[g] {
+			models := ParseModelFallbackChain(chain)
+			for i, name := range models {
+				if name == modelName {
+					return models[i+1:]
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	PayloadLogStorageDatabase = "database"
+	PayloadLogStorageObject   = "storage"
+)
+
+// PayloadLogSetting 完整请求/响应内容记录，注意bool要以enabled结尾才可以生效编辑
+type PayloadLogSetting struct {
+	Enabled bool `json:"enabled"`
+	// 记录范围，满足任一条件即记录；全部为空时不记录
+	UserIds  []int    `json:"user_ids"`
+	TokenIds []int    `json:"token_ids"`
+	Groups   []string `json:"groups"`
+	// 保存位置：database 保存在日志数据库，storage 保存到对象存储（未启用时保存在本地磁盘）
+	Storage string `json:"storage"`
+	// 保留天数，0 表示永久保留
+	RetentionDays int `json:"retention_days"`
+	// 请求体、上游请求体与响应各自的最大记录大小（KB），超出部分截断
+	MaxBodySizeKB int `json:"max_body_size_kb"`
+}
+
+// 默认配置
+var payloadLogSetting = PayloadLogSetting{
+	Enabled:       false,
+	UserIds:       []int{},
+	TokenIds:      []int{},
+	Groups:        []string{},
+	Storage:       PayloadLogStorageDatabase,
+	RetentionDays: 7,
+	MaxBodySizeKB: 1024,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("payload_log_setting", &payloadLogSetting)
+}
+
+func GetPayloadLogSetting() *PayloadLogSetting {
+	return &payloadLogSetting
+}
+
+// ShouldLogPayload 用户、令牌或分组是否需要记录完整内容
+func ShouldLogPayload(userId int, tokenId int, group string) bool {
+	if !payloadLogSetting.Enabled {
+		return false
+	}
+	return slices.Contains(payloadLogSetting.UserIds, userId) ||
+		slices.Contains(payloadLogSetting.TokenIds, tokenId) ||
+		slices.Contains(payloadLogSetting.Groups, group)
+}
@@ -0,0 +1,60 @@
+package operation_setting
+
+import (
+	"slices"
+
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+const (
+	PIIDetectorEmail      = "email"
+	PIIDetectorPhone      = "phone"
+	PIIDetectorCreditCard = "credit_card"
+	PIIDetectorIBAN       = "iban"
+	PIIDetectorAPIKey     = "api_key"
+)
+
+// PIIRedactionSetting 发送给上游前脱敏请求中的个人信息，注意bool要以enabled结尾才可以生效编辑
+type PIIRedactionSetting struct {
+	Enabled bool `json:"enabled"`
+	// 需要脱敏的分组，为空时对所有分组生效；渠道也可以在渠道设置中单独开启
+	Groups []string `json:"groups"`
+	// 启用的内置检测器：email、phone、credit_card、iban、api_key
+	Detectors []string `json:"detectors"`
+	// 自定义正则，名称 -> 正则表达式，名称用于生成占位符
+	CustomPatterns map[string]string `json:"custom_patterns"`
+	// 是否在模型输出中将占位符还原为原始内容
+	RestoreEnabled bool `json:"restore_enabled"`
+}
+
+// 默认配置
+var piiRedactionSetting = PIIRedactionSetting{
+	Enabled: false,
+	Groups:  []string{},
+	Detectors: []string{
+		PIIDetectorEmail,
+		PIIDetectorPhone,
+		PIIDetectorCreditCard,
+		PIIDetectorIBAN,
+		PIIDetectorAPIKey,
+	},
+	CustomPatterns: map[string]string{},
+	RestoreEnabled: true,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("pii_redaction_setting", &piiRedactionSetting)
+}
+
+func GetPIIRedactionSetting() *PIIRedactionSetting {
+	return &piiRedactionSetting
+}
+
+// ShouldRedactPIIForGroup 分组是否需要脱敏
+func ShouldRedactPIIForGroup(group string) bool {
+	if !piiRedactionSetting.Enabled {
+		return false
+	}
+	return len(piiRedactionSetting.Groups) == 0 || slices.Contains(piiRedactionSetting.Groups, group)
+}
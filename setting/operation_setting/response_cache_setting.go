@@ -0,0 +1,38 @@
+package operation_setting
+
+import (
+	"github.com/QuantumNous/new-api/setting/config"
+)
+
+type ResponseCacheSetting struct {
+	Enabled bool `json:"enabled"`
+	// 缓存有效期（秒）
+	TTLSeconds int `json:"ttl_seconds"`
+	// 未启用 Redis 时本地内存缓存的最大条目数
+	MaxEntries int `json:"max_entries"`
+	// 单条响应的最大缓存大小（KB），超出时不缓存
+	MaxEntrySizeKB int `json:"max_entry_size_kb"`
+	// 命中缓存时的计费倍率，0 表示不计费
+	HitRatio float64 `json:"hit_ratio"`
+	// 是否缓存并回放 temperature=0 的流式请求
+	StreamEnabled bool `json:"stream_enabled"`
+}
+
+// 默认配置
+var responseCacheSetting = ResponseCacheSetting{
+	Enabled:        false,
+	TTLSeconds:     3600,
+	MaxEntries:     1000,
+	MaxEntrySizeKB: 512,
+	HitRatio:       0.1,
+	StreamEnabled:  false,
+}
+
+func init() {
+	// 注册到全局配置管理器
+	config.GlobalConfig.Register("response_cache_setting", &responseCacheSetting)
+}
+
+func GetResponseCacheSetting() *ResponseCacheSetting {
+	return &responseCacheSetting
+}
@@ -67,3 +67,53 @@ func CheckModelRequestRateLimitGroup(jsonStr string) error {
 
 	return nil
 }
+
+// GroupRelayLimit 分组内所有请求共享的 TPM 与并发限制，0 表示不限制
+type GroupRelayLimit struct {
+	TPM         int `json:"tpm"`
+	Concurrency int `json:"concurrency"`
+}
+
+var groupRelayLimit = map[string]GroupRelayLimit{}
+var groupRelayLimitMutex sync.RWMutex
+
+func GroupRelayLimit2JSONString() string {
+	groupRelayLimitMutex.RLock()
+	defer groupRelayLimitMutex.RUnlock()
+
+	jsonBytes, err := json.Marshal(groupRelayLimit)
+	if err != nil {
+		common.SysLog("error marshalling group relay limit: " + err.Error())
+	}
+	return string(jsonBytes)
+}
+
+func UpdateGroupRelayLimitByJSONString(jsonStr string) error {
+	limits := make(map[string]GroupRelayLimit)
+	if err := json.Unmarshal([]byte(jsonStr), &limits); err != nil {
+		return err
+	}
+	groupRelayLimitMutex.Lock()
+	defer groupRelayLimitMutex.Unlock()
+	groupRelayLimit = limits
+	return nil
+}
+
+func GetGroupRelayLimit(group string) GroupRelayLimit {
+	groupRelayLimitMutex.RLock()
+	defer groupRelayLimitMutex.RUnlock()
+	return groupRelayLimit[group]
+}
+
+func CheckGroupRelayLimit(jsonStr string) error {
+	limits := make(map[string]GroupRelayLimit)
+	if err := json.Unmarshal([]byte(jsonStr), &limits); err != nil {
+		return err
+	}
+	for group, limit := range limits {
+		if limit.TPM < 0 || limit.Concurrency < 0 {
+			return fmt.Errorf("group %s has negative limit values: tpm %d, concurrency %d", group, limit.TPM, limit.Concurrency)
+		}
+	}
+	return nil
+}
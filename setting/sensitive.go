@@ -5,12 +5,13 @@ import "strings"
 var CheckSensitiveEnabled = true
 var CheckSensitiveOnPromptEnabled = true
 
-//var CheckSensitiveOnCompletionEnabled = true
+// CheckSensitiveOnCompletionEnabled 是否检查模型输出，开启后流式输出会缓存一小段文本用于跨片段匹配
+var CheckSensitiveOnCompletionEnabled = false
 
 // StopOnSensitiveEnabled 如果检测到敏感词，是否立刻停止生成，否则替换敏感词
 var StopOnSensitiveEnabled = true
 
-// StreamCacheQueueLength 流模式缓存队列长度，0表示无缓存
+// StreamCacheQueueLength 流模式缓存队列长度，即额外缓存的增量片段数量，0表示仅缓存匹配敏感词所需的最少文本
 var StreamCacheQueueLength = 0
 
 // SensitiveWords 敏感词
@@ -38,6 +39,6 @@ func ShouldCheckPromptSensitive() bool {
 	return CheckSensitiveEnabled && CheckSensitiveOnPromptEnabled
 }
 
-//func ShouldCheckCompletionSensitive() bool {
-//	return CheckSensitiveEnabled && CheckSensitiveOnCompletionEnabled
-//}
+func ShouldCheckCompletionSensitive() bool {
+	return CheckSensitiveEnabled && CheckSensitiveOnCompletionEnabled && len(SensitiveWords) > 0
+}
@@ -40,6 +40,8 @@ type ErrorCode string
 const (
 	ErrorCodeInvalidRequest         ErrorCode = "invalid_request"
 	ErrorCodeSensitiveWordsDetected ErrorCode = "sensitive_words_detected"
+	ErrorCodeGuardrailBlocked       ErrorCode = "guardrail_blocked"
+	ErrorCodeGuardrailCheckFailed   ErrorCode = "guardrail_check_failed"
 
 	// new api error
 	ErrorCodeCountTokenFailed   ErrorCode = "count_token_failed"
@@ -63,6 +65,8 @@ const (
 	ErrorCodeReadRequestBodyFailed ErrorCode = "read_request_body_failed"
 	ErrorCodeConvertRequestFailed  ErrorCode = "convert_request_failed"
 	ErrorCodeAccessDenied          ErrorCode = "access_denied"
+	ErrorCodeRateLimitExceeded     ErrorCode = "rate_limit_exceeded"
+	ErrorCodeAdmissionTimeout      ErrorCode = "admission_timeout"
 
 	// request error
 	ErrorCodeBadRequestBody ErrorCode = "bad_request_body"
@@ -35,6 +35,7 @@ import Channel from './pages/Channel';
 import Token from './pages/Token';
 import Redemption from './pages/Redemption';
 import TopUp from './pages/TopUp';
+import Organization from './pages/Organization';
 import Log from './pages/Log';
 import Chat from './pages/Chat';
 import Chat2Link from './pages/Chat2Link';
@@ -256,6 +257,16 @@ function App() {
             </PrivateRoute>
           }
         />
+        <Route
+          path='/console/organization'
+          element={
+            <PrivateRoute>
+              <Suspense fallback={<Loading></Loading>} key={location.pathname}>
+                <Organization />
+              </Suspense>
+            </PrivateRoute>
+          }
+        />
         <Route
           path='/console/log'
           element={
@@ -36,6 +36,7 @@ const routerMap = {
   token: '/console/token',
   redemption: '/console/redemption',
   topup: '/console/topup',
+  organization: '/console/organization',
   user: '/console/user',
   log: '/console/log',
   midjourney: '/console/midjourney',
@@ -128,6 +129,11 @@ const SiderBar = ({ onNavigate = () => {} }) => {
         itemKey: 'topup',
         to: '/topup',
       },
+      {
+        text: t('组织管理'),
+        itemKey: 'organization',
+        to: '/organization',
+      },
       {
         text: t('个人设置'),
         itemKey: 'personal',
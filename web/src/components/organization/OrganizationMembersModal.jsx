@@ -0,0 +1,349 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import { useTranslation } from 'react-i18next';
+import {
+  Button,
+  Checkbox,
+  Divider,
+  Input,
+  InputNumber,
+  Modal,
+  Popconfirm,
+  Select,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  API,
+  renderQuota,
+  renderQuotaWithPrompt,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../helpers';
+
+const { Text } = Typography;
+
+export const organizationRoleColors = {
+  owner: 'orange',
+  admin: 'blue',
+  member: 'green',
+};
+
+export const organizationRoleNames = {
+  owner: '所有者',
+  admin: '管理员',
+  member: '成员',
+};
+
+const OrganizationMembersModal = ({ organization, onClose, onChange }) => {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [members, setMembers] = useState([]);
+  const [invitations, setInvitations] = useState([]);
+  const [inviteUsername, setInviteUsername] = useState('');
+  const [inviteRole, setInviteRole] = useState('member');
+  const [editingMember, setEditingMember] = useState(null);
+
+  const isOwner = organization.role === 'owner';
+  const canManage = isOwner || organization.role === 'admin';
+  const baseUrl = `/api/org/self/${organization.id}`;
+
+  const roleOptions = [
+    { label: t('成员'), value: 'member' },
+    ...(isOwner ? [{ label: t('管理员'), value: 'admin' }] : []),
+  ];
+
+  const loadMembers = async () => {
+    setLoading(true);
+    try {
+      const res = await API.get(`${baseUrl}/members`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setMembers(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (e) {
+      showError(e.message);
+    }
+    setLoading(false);
+  };
+
+  const loadInvitations = async () => {
+    if (!canManage) {
+      return;
+    }
+    try {
+      const res = await API.get(`${baseUrl}/invitations`);
+      const { success, message, data } = res.data;
+      if (success) {
+        setInvitations(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (e) {
+      showError(e.message);
+    }
+  };
+
+  const refresh = async () => {
+    await Promise.all([loadMembers(), loadInvitations()]);
+  };
+
+  useEffect(() => {
+    refresh();
+  }, [organization.id]);
+
+  const handleResult = async (res, successMessage) => {
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(successMessage);
+      await refresh();
+      onChange && onChange();
+      return true;
+    }
+    showError(message);
+    return false;
+  };
+
+  const invite = async () => {
+    if (!inviteUsername.trim()) {
+      showError(t('请输入用户名'));
+      return;
+    }
+    const res = await API.post(`${baseUrl}/invitations`, {
+      username: inviteUsername.trim(),
+      role: inviteRole,
+    });
+    if (await handleResult(res, t('邀请已发送'))) {
+      setInviteUsername('');
+    }
+  };
+
+  const cancelInvitation = async (invitation) => {
+    const res = await API.delete(`${baseUrl}/invitations/${invitation.id}`);
+    await handleResult(res, t('邀请已取消'));
+  };
+
+  const removeMember = async (member) => {
+    const res = await API.delete(`${baseUrl}/members/${member.user_id}`);
+    await handleResult(res, t('成员已移除'));
+  };
+
+  const saveMember = async () => {
+    const res = await API.put(`${baseUrl}/members`, {
+      user_id: editingMember.user_id,
+      role: editingMember.role,
+      quota_limit: parseInt(editingMember.quota_limit) || 0,
+      reset_used_quota: editingMember.reset_used_quota || false,
+    });
+    if (await handleResult(res, t('成员已更新'))) {
+      setEditingMember(null);
+    }
+  };
+
+  // 所有者不可修改，管理员只能由所有者管理
+  const canEditMember = (member) =>
+    canManage &&
+    member.role !== 'owner' &&
+    (isOwner || member.role !== 'admin');
+
+  const columns = [
+    {
+      title: t('用户名'),
+      dataIndex: 'username',
+    },
+    {
+      title: t('角色'),
+      dataIndex: 'role',
+      render: (role) => (
+        <Tag color={organizationRoleColors[role]} shape='circle'>
+          {t(organizationRoleNames[role])}
+        </Tag>
+      ),
+    },
+    {
+      title: t('已用额度'),
+      dataIndex: 'used_quota',
+      render: (quota, record) =>
+        record.quota_limit > 0
+          ? `${renderQuota(quota)} / ${renderQuota(record.quota_limit)}`
+          : renderQuota(quota),
+    },
+    {
+      title: t('加入时间'),
+      dataIndex: 'created_time',
+      render: (time) => timestamp2string(time),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (text, record) =>
+        canEditMember(record) && (
+          <Space>
+            <Button
+              size='small'
+              onClick={() =>
+                setEditingMember({ ...record, reset_used_quota: false })
+              }
+            >
+              {t('编辑')}
+            </Button>
+            <Popconfirm
+              title={t('确定移除该成员？')}
+              onConfirm={() => removeMember(record)}
+            >
+              <Button size='small' type='danger'>
+                {t('移除')}
+              </Button>
+            </Popconfirm>
+          </Space>
+        ),
+    },
+  ];
+
+  return (
+    <Modal
+      title={`${organization.name} - ${t('成员')}`}
+      visible
+      onCancel={onClose}
+      footer={null}
+      width={800}
+    >
+      <Table
+        columns={columns}
+        dataSource={members}
+        rowKey='id'
+        loading={loading}
+        pagination={false}
+        size='small'
+        scroll={{ x: 'max-content' }}
+      />
+
+      {canManage && (
+        <>
+          <Divider margin='12px' align='left'>
+            {t('邀请成员')}
+          </Divider>
+          <Space>
+            <Input
+              value={inviteUsername}
+              onChange={setInviteUsername}
+              placeholder={t('请输入用户名')}
+              style={{ width: 240 }}
+            />
+            <Select
+              value={inviteRole}
+              onChange={setInviteRole}
+              optionList={roleOptions}
+              style={{ width: 120 }}
+            />
+            <Button theme='solid' onClick={invite}>
+              {t('邀请')}
+            </Button>
+          </Space>
+          {invitations.length > 0 && (
+            <div className='mt-2'>
+              <Text type='tertiary' size='small'>
+                {t('待接受的邀请')}
+              </Text>
+              {invitations.map((invitation) => (
+                <div
+                  key={invitation.id}
+                  className='flex items-center justify-between py-1'
+                >
+                  <Space>
+                    <Text>{invitation.username}</Text>
+                    <Tag color={organizationRoleColors[invitation.role]}>
+                      {t(organizationRoleNames[invitation.role])}
+                    </Tag>
+                  </Space>
+                  <Button
+                    size='small'
+                    type='tertiary'
+                    onClick={() => cancelInvitation(invitation)}
+                  >
+                    {t('取消邀请')}
+                  </Button>
+                </div>
+              ))}
+            </div>
+          )}
+        </>
+      )}
+
+      <Modal
+        title={t('编辑成员')}
+        visible={editingMember !== null}
+        onOk={saveMember}
+        onCancel={() => setEditingMember(null)}
+      >
+        {editingMember && (
+          <div className='flex flex-col gap-3'>
+            <div>
+              <Text strong>{editingMember.username}</Text>
+            </div>
+            <Select
+              value={editingMember.role}
+              onChange={(role) => setEditingMember({ ...editingMember, role })}
+              optionList={roleOptions}
+              disabled={!isOwner}
+            />
+            <div>
+              <Text>{t('消费上限')}</Text>
+              <InputNumber
+                value={editingMember.quota_limit}
+                onChange={(quota_limit) =>
+                  setEditingMember({ ...editingMember, quota_limit })
+                }
+                min={0}
+                step={500000}
+                style={{ width: '100%' }}
+              />
+              <Text type='tertiary' size='small'>
+                {renderQuotaWithPrompt(
+                  parseInt(editingMember.quota_limit) || 0,
+                )}{' '}
+                {t('0 表示不限制')}
+              </Text>
+            </div>
+            <Checkbox
+              checked={editingMember.reset_used_quota}
+              onChange={(e) =>
+                setEditingMember({
+                  ...editingMember,
+                  reset_used_quota: e.target.checked,
+                })
+              }
+            >
+              {t('清零已用额度')}
+            </Checkbox>
+          </div>
+        )}
+      </Modal>
+    </Modal>
+  );
+};
+
+export default OrganizationMembersModal;
@@ -0,0 +1,350 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import { useTranslation } from 'react-i18next';
+import {
+  Button,
+  Card,
+  Input,
+  InputNumber,
+  Modal,
+  Popconfirm,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  API,
+  getUserIdFromLocalStorage,
+  renderQuota,
+  renderQuotaWithPrompt,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../helpers';
+import OrganizationMembersModal, {
+  organizationRoleColors,
+  organizationRoleNames,
+} from './OrganizationMembersModal';
+
+const { Text, Title } = Typography;
+
+const OrganizationPage = () => {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [organizations, setOrganizations] = useState([]);
+  const [invitations, setInvitations] = useState([]);
+  const [showCreate, setShowCreate] = useState(false);
+  const [newName, setNewName] = useState('');
+  const [transferOrg, setTransferOrg] = useState(null);
+  const [transferQuota, setTransferQuota] = useState(0);
+  const [membersOrg, setMembersOrg] = useState(null);
+
+  const loadOrganizations = async () => {
+    setLoading(true);
+    try {
+      const res = await API.get('/api/org/self');
+      const { success, message, data } = res.data;
+      if (success) {
+        setOrganizations(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (e) {
+      showError(e.message);
+    }
+    setLoading(false);
+  };
+
+  const loadInvitations = async () => {
+    try {
+      const res = await API.get('/api/org/self/invitations');
+      const { success, message, data } = res.data;
+      if (success) {
+        setInvitations(data || []);
+      } else {
+        showError(message);
+      }
+    } catch (e) {
+      showError(e.message);
+    }
+  };
+
+  const refresh = async () => {
+    await Promise.all([loadOrganizations(), loadInvitations()]);
+  };
+
+  useEffect(() => {
+    refresh();
+  }, []);
+
+  const handleResult = async (res, successMessage) => {
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(successMessage);
+      await refresh();
+      return true;
+    }
+    showError(message);
+    return false;
+  };
+
+  const createOrganization = async () => {
+    const res = await API.post('/api/org/self', { name: newName.trim() });
+    if (await handleResult(res, t('组织创建成功'))) {
+      setShowCreate(false);
+      setNewName('');
+    }
+  };
+
+  const respondInvitation = async (invitation, accept) => {
+    const res = await API.post(`/api/org/self/invitations/${invitation.id}`, {
+      accept,
+    });
+    await handleResult(res, accept ? t('已加入组织') : t('已拒绝邀请'));
+  };
+
+  const transfer = async (direction) => {
+    const quota = parseInt(transferQuota) || 0;
+    if (quota <= 0) {
+      showError(t('请输入额度'));
+      return;
+    }
+    const res = await API.post(`/api/org/self/${transferOrg.id}/transfer`, {
+      quota: direction * quota,
+    });
+    if (await handleResult(res, t('额度转移成功'))) {
+      setTransferOrg(null);
+      setTransferQuota(0);
+    }
+  };
+
+  const deleteOrganization = async (org) => {
+    const res = await API.delete(`/api/org/self/${org.id}`);
+    await handleResult(res, t('组织已删除'));
+  };
+
+  const leaveOrganization = async (org) => {
+    const userId = getUserIdFromLocalStorage();
+    const res = await API.delete(`/api/org/self/${org.id}/members/${userId}`);
+    await handleResult(res, t('已退出组织'));
+  };
+
+  const columns = [
+    {
+      title: t('组织名称'),
+      dataIndex: 'name',
+      render: (text, record) => (
+        <Space>
+          <Text strong>{text}</Text>
+          {record.status !== 1 && <Tag color='red'>{t('已禁用')}</Tag>}
+        </Space>
+      ),
+    },
+    {
+      title: t('我的角色'),
+      dataIndex: 'role',
+      render: (role) => (
+        <Tag color={organizationRoleColors[role]} shape='circle'>
+          {t(organizationRoleNames[role])}
+        </Tag>
+      ),
+    },
+    {
+      title: t('组织剩余额度'),
+      dataIndex: 'quota',
+      render: (quota) => renderQuota(quota),
+    },
+    {
+      title: t('组织已用额度'),
+      dataIndex: 'used_quota',
+      render: (quota) => renderQuota(quota),
+    },
+    {
+      title: t('我的用量'),
+      dataIndex: 'member_used_quota',
+      render: (quota, record) =>
+        record.quota_limit > 0
+          ? `${renderQuota(quota)} / ${renderQuota(record.quota_limit)}`
+          : renderQuota(quota),
+    },
+    {
+      title: t('创建时间'),
+      dataIndex: 'created_time',
+      render: (time) => timestamp2string(time),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      fixed: 'right',
+      render: (text, record) => (
+        <Space>
+          <Button size='small' onClick={() => setMembersOrg(record)}>
+            {t('成员')}
+          </Button>
+          {record.role === 'owner' && (
+            <Button size='small' onClick={() => setTransferOrg(record)}>
+              {t('转移额度')}
+            </Button>
+          )}
+          {record.role === 'owner' ? (
+            <Popconfirm
+              title={t('确定删除该组织？')}
+              content={t('组织剩余额度将退还到你的账户')}
+              onConfirm={() => deleteOrganization(record)}
+            >
+              <Button size='small' type='danger'>
+                {t('删除')}
+              </Button>
+            </Popconfirm>
+          ) : (
+            <Popconfirm
+              title={t('确定退出该组织？')}
+              onConfirm={() => leaveOrganization(record)}
+            >
+              <Button size='small' type='warning'>
+                {t('退出')}
+              </Button>
+            </Popconfirm>
+          )}
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <>
+      {invitations.length > 0 && (
+        <Card className='!rounded-2xl mb-2' title={t('待处理的组织邀请')}>
+          {invitations.map((invitation) => (
+            <div
+              key={invitation.id}
+              className='flex items-center justify-between py-1'
+            >
+              <Space>
+                <Text strong>{invitation.org_name}</Text>
+                <Tag color={organizationRoleColors[invitation.role]}>
+                  {t(organizationRoleNames[invitation.role])}
+                </Tag>
+              </Space>
+              <Space>
+                <Button
+                  size='small'
+                  theme='solid'
+                  onClick={() => respondInvitation(invitation, true)}
+                >
+                  {t('接受')}
+                </Button>
+                <Button
+                  size='small'
+                  onClick={() => respondInvitation(invitation, false)}
+                >
+                  {t('拒绝')}
+                </Button>
+              </Space>
+            </div>
+          ))}
+        </Card>
+      )}
+      <Card
+        className='!rounded-2xl'
+        title={
+          <div className='flex items-center justify-between w-full'>
+            <div>
+              <Title heading={5} className='m-0'>
+                {t('我的组织')}
+              </Title>
+              <Text type='tertiary' size='small'>
+                {t('组织成员在组织下创建的令牌共用组织额度')}
+              </Text>
+            </div>
+            <Button theme='solid' onClick={() => setShowCreate(true)}>
+              {t('创建组织')}
+            </Button>
+          </div>
+        }
+      >
+        <Table
+          columns={columns}
+          dataSource={organizations}
+          rowKey='id'
+          loading={loading}
+          pagination={false}
+          scroll={{ x: 'max-content' }}
+        />
+      </Card>
+
+      <Modal
+        title={t('创建组织')}
+        visible={showCreate}
+        onOk={createOrganization}
+        onCancel={() => setShowCreate(false)}
+      >
+        <Input
+          value={newName}
+          onChange={setNewName}
+          placeholder={t('请输入组织名称')}
+          maxLength={64}
+        />
+      </Modal>
+
+      <Modal
+        title={t('转移额度')}
+        visible={transferOrg !== null}
+        onCancel={() => setTransferOrg(null)}
+        footer={
+          <Space>
+            <Button onClick={() => transfer(-1)}>{t('转回个人')}</Button>
+            <Button theme='solid' onClick={() => transfer(1)}>
+              {t('转入组织')}
+            </Button>
+          </Space>
+        }
+      >
+        <div className='mb-2'>
+          <Text>
+            {t('组织剩余额度')}: {renderQuota(transferOrg?.quota || 0)}
+          </Text>
+        </div>
+        <InputNumber
+          value={transferQuota}
+          onChange={setTransferQuota}
+          min={0}
+          step={500000}
+          style={{ width: '100%' }}
+        />
+        <Text type='tertiary' size='small'>
+          {renderQuotaWithPrompt(parseInt(transferQuota) || 0)}
+        </Text>
+      </Modal>
+
+      {membersOrg && (
+        <OrganizationMembersModal
+          organization={membersOrg}
+          onClose={() => setMembersOrg(null)}
+          onChange={refresh}
+        />
+      )}
+    </>
+  );
+};
+
+export default OrganizationPage;
@@ -27,6 +27,16 @@ import SettingsLog from '../../pages/Setting/Operation/SettingsLog';
 import SettingsMonitoring from '../../pages/Setting/Operation/SettingsMonitoring';
 import SettingsCreditLimit from '../../pages/Setting/Operation/SettingsCreditLimit';
 import SettingsCheckin from '../../pages/Setting/Operation/SettingsCheckin';
+import SettingsResponseCache from '../../pages/Setting/Operation/SettingsResponseCache';
+import SettingsGuardrail from '../../pages/Setting/Operation/SettingsGuardrail';
+import GuardrailReviewLog from '../../pages/Setting/Operation/GuardrailReviewLog';
+import SettingsPIIRedaction from '../../pages/Setting/Operation/SettingsPIIRedaction';
+import SettingsPayloadLog from '../../pages/Setting/Operation/SettingsPayloadLog';
+import SettingsModelFallback from '../../pages/Setting/Operation/SettingsModelFallback';
+import SettingsHedge from '../../pages/Setting/Operation/SettingsHedge';
+import SettingsChannelAffinity from '../../pages/Setting/Operation/SettingsChannelAffinity';
+import SettingsAdmission from '../../pages/Setting/Operation/SettingsAdmission';
+import AuditVersionLog from '../../pages/Setting/Operation/AuditVersionLog';
 import { API, showError, toBoolean } from '../../helpers';
 
 const OperationSetting = () => {
@@ -59,6 +69,9 @@ const OperationSetting = () => {
     /* 敏感词设置 */
     CheckSensitiveEnabled: false,
     CheckSensitiveOnPromptEnabled: false,
+    CheckSensitiveOnCompletionEnabled: false,
+    StopOnSensitiveEnabled: true,
+    StreamCacheQueueLength: 0,
     SensitiveWords: '',
 
     /* 日志设置 */
@@ -71,10 +84,64 @@ const OperationSetting = () => {
     AutomaticEnableChannelEnabled: false,
     AutomaticDisableKeywords: '',
     'monitor_setting.auto_test_channel_enabled': false,
-    'monitor_setting.auto_test_channel_minutes': 10 /* 签到设置 */,
+    'monitor_setting.auto_test_channel_minutes': 10,
+    'circuit_breaker_setting.enabled': false,
+    'circuit_breaker_setting.failure_threshold': 5,
+    'circuit_breaker_setting.cooldown_seconds': 60,
+    'circuit_breaker_setting.half_open_probes': 3 /* 签到设置 */,
     'checkin_setting.enabled': false,
     'checkin_setting.min_quota': 1000,
     'checkin_setting.max_quota': 10000,
+    /* 响应缓存设置 */
+    'response_cache_setting.enabled': false,
+    'response_cache_setting.ttl_seconds': 3600,
+    'response_cache_setting.max_entries': 1000,
+    'response_cache_setting.max_entry_size_kb': 512,
+    'response_cache_setting.hit_ratio': 0.1,
+    'response_cache_setting.stream_enabled': false,
+
+    /* 内容审核设置 */
+    'guardrail_setting.enabled': false,
+    'guardrail_setting.backend': 'moderation',
+    'guardrail_setting.moderation_model': 'omni-moderation-latest',
+    'guardrail_setting.moderation_group': 'default',
+    'guardrail_setting.webhook_url': '',
+    'guardrail_setting.webhook_secret': '',
+    'guardrail_setting.timeout_seconds': 10,
+    'guardrail_setting.fail_open_enabled': true,
+    'guardrail_setting.check_completion_enabled': false,
+    'guardrail_setting.default_action': 'flag',
+    'guardrail_setting.group_policies': '',
+    'pii_redaction_setting.enabled': false,
+    'pii_redaction_setting.groups': '[]',
+    'pii_redaction_setting.detectors':
+      '["email","phone","credit_card","iban","api_key"]',
+    'pii_redaction_setting.custom_patterns': '',
+    'pii_redaction_setting.restore_enabled': true,
+    'payload_log_setting.enabled': false,
+    'payload_log_setting.user_ids': '[]',
+    'payload_log_setting.token_ids': '[]',
+    'payload_log_setting.groups': '[]',
+    'payload_log_setting.storage': 'database',
+    'payload_log_setting.retention_days': 7,
+    'payload_log_setting.max_body_size_kb': 1024,
+    'model_fallback_setting.enabled': false,
+    'model_fallback_setting.chains': '',
+    'hedge_setting.enabled': false,
+    'hedge_setting.group_delays': '',
+    'hedge_setting.min_delay_ms': 200,
+    'channel_affinity_setting.enabled': false,
+    'channel_affinity_setting.ttl_seconds': 3600,
+    'channel_affinity_setting.session_header': 'X-Session-Id',
+    'channel_affinity_setting.use_user_field': true,
+    'channel_affinity_setting.prefix_messages': 1,
+    'channel_affinity_setting.max_entries': 10000,
+    'admission_setting.enabled': false,
+    'admission_setting.channel_concurrency': 0,
+    'admission_setting.classes': '',
+    'admission_setting.group_classes': '',
+    'admission_setting.default_class': 'interactive',
+    'admission_setting.batch_class': 'batch',
   });
 
   let [loading, setLoading] = useState(false);
@@ -148,6 +215,43 @@ const OperationSetting = () => {
         <Card style={{ marginTop: '10px' }}>
           <SettingsCheckin options={inputs} refresh={onRefresh} />
         </Card>
+        {/* 响应缓存设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsResponseCache options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 内容审核设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsGuardrail options={inputs} refresh={onRefresh} />
+          <GuardrailReviewLog />
+        </Card>
+        {/* 隐私信息脱敏设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsPIIRedaction options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 完整内容记录设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsPayloadLog options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 模型降级设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsModelFallback options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 对冲请求设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsHedge options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 会话亲和设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsChannelAffinity options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 请求排队设置 */}
+        <Card style={{ marginTop: '10px' }}>
+          <SettingsAdmission options={inputs} refresh={onRefresh} />
+        </Card>
+        {/* 配置变更历史 */}
+        <Card style={{ marginTop: '10px' }}>
+          <AuditVersionLog />
+        </Card>
       </Spin>
     </>
   );
@@ -46,6 +46,7 @@ const RatioSetting = () => {
     ParamRatioConfig: '',
     AutoGroups: '',
     DefaultUseAutoGroup: false,
+    AdaptiveSelectGroups: '',
     ExposeRatioEnabled: false,
     UserUsableGroups: '',
     'group_ratio_setting.group_special_usable_group': '',
@@ -79,6 +79,7 @@ const NotificationSettings = ({
     personal: {
       enabled: true,
       topup: true,
+      organization: true,
       personal: true,
     },
     admin: {
@@ -163,7 +164,12 @@ const NotificationSettings = ({
         midjourney: true,
         task: true,
       },
-      personal: { enabled: true, topup: true, personal: true },
+      personal: {
+        enabled: true,
+        topup: true,
+        organization: true,
+        personal: true,
+      },
       admin: {
         enabled: true,
         channel: true,
@@ -276,6 +282,11 @@ const NotificationSettings = ({
       description: t('用户个人功能'),
       modules: [
         { key: 'topup', title: t('钱包管理'), description: t('余额充值管理') },
+        {
+          key: 'organization',
+          title: t('组织管理'),
+          description: t('组织成员与共享额度'),
+        },
         {
           key: 'personal',
           title: t('个人设置'),
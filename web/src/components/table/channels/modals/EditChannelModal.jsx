@@ -151,6 +151,8 @@ const EditChannelModal = (props) => {
     thinking_to_content: false,
     proxy: '',
     pass_through_body_enabled: false,
+    pii_redaction_enabled: false,
+    max_concurrency: 0,
     system_prompt: '',
     system_prompt_override: false,
     settings: '',
@@ -164,6 +166,9 @@ const EditChannelModal = (props) => {
     allow_service_tier: false,
     disable_store: false, // false = 允许透传（默认开启）
     allow_safety_identifier: false,
+    // Chat Completions 转 Responses API
+    chat_completions_to_responses: false,
+    chat_completions_to_responses_models: '',
   };
   const [batch, setBatch] = useState(false);
   const [multiToSingle, setMultiToSingle] = useState(false);
@@ -373,6 +378,8 @@ const EditChannelModal = (props) => {
     thinking_to_content: false,
     proxy: '',
     pass_through_body_enabled: false,
+    pii_redaction_enabled: false,
+    max_concurrency: 0,
     system_prompt: '',
   });
   const showApiConfigCard = true; // 控制是否显示 API 配置卡片
@@ -557,6 +564,9 @@ const EditChannelModal = (props) => {
           data.proxy = parsedSettings.proxy || '';
           data.pass_through_body_enabled =
             parsedSettings.pass_through_body_enabled || false;
+          data.pii_redaction_enabled =
+            parsedSettings.pii_redaction_enabled || false;
+          data.max_concurrency = parsedSettings.max_concurrency || 0;
           data.system_prompt = parsedSettings.system_prompt || '';
           data.system_prompt_override =
             parsedSettings.system_prompt_override || false;
@@ -566,6 +576,7 @@ const EditChannelModal = (props) => {
           data.thinking_to_content = false;
           data.proxy = '';
           data.pass_through_body_enabled = false;
+          data.pii_redaction_enabled = false;
           data.system_prompt = '';
           data.system_prompt_override = false;
         }
@@ -574,6 +585,8 @@ const EditChannelModal = (props) => {
         data.thinking_to_content = false;
         data.proxy = '';
         data.pass_through_body_enabled = false;
+        data.pii_redaction_enabled = false;
+        data.max_concurrency = 0;
         data.system_prompt = '';
         data.system_prompt_override = false;
       }
@@ -595,6 +608,11 @@ const EditChannelModal = (props) => {
           data.disable_store = parsedSettings.disable_store || false;
           data.allow_safety_identifier =
             parsedSettings.allow_safety_identifier || false;
+          data.chat_completions_to_responses =
+            parsedSettings.chat_completions_to_responses || false;
+          data.chat_completions_to_responses_models = (
+            parsedSettings.chat_completions_to_responses_models || []
+          ).join(',');
         } catch (error) {
           console.error('解析其他设置失败:', error);
           data.azure_responses_version = '';
@@ -605,6 +623,8 @@ const EditChannelModal = (props) => {
           data.allow_service_tier = false;
           data.disable_store = false;
           data.allow_safety_identifier = false;
+          data.chat_completions_to_responses = false;
+          data.chat_completions_to_responses_models = '';
         }
       } else {
         // 兼容历史数据：老渠道没有 settings 时，默认按 json 展示
@@ -614,6 +634,8 @@ const EditChannelModal = (props) => {
         data.allow_service_tier = false;
         data.disable_store = false;
         data.allow_safety_identifier = false;
+        data.chat_completions_to_responses = false;
+        data.chat_completions_to_responses_models = '';
       }
 
       if (
@@ -642,6 +664,8 @@ const EditChannelModal = (props) => {
         thinking_to_content: data.thinking_to_content,
         proxy: data.proxy,
         pass_through_body_enabled: data.pass_through_body_enabled,
+        pii_redaction_enabled: data.pii_redaction_enabled || false,
+        max_concurrency: data.max_concurrency || 0,
         system_prompt: data.system_prompt,
         system_prompt_override: data.system_prompt_override || false,
       });
@@ -922,6 +946,8 @@ const EditChannelModal = (props) => {
       thinking_to_content: false,
       proxy: '',
       pass_through_body_enabled: false,
+      pii_redaction_enabled: false,
+      max_concurrency: 0,
       system_prompt: '',
       system_prompt_override: false,
     });
@@ -1208,6 +1234,8 @@ const EditChannelModal = (props) => {
       thinking_to_content: localInputs.thinking_to_content || false,
       proxy: localInputs.proxy || '',
       pass_through_body_enabled: localInputs.pass_through_body_enabled || false,
+      pii_redaction_enabled: localInputs.pii_redaction_enabled || false,
+      max_concurrency: localInputs.max_concurrency || 0,
       system_prompt: localInputs.system_prompt || '',
       system_prompt_override: localInputs.system_prompt_override || false,
     };
@@ -1249,6 +1277,14 @@ const EditChannelModal = (props) => {
         settings.disable_store = localInputs.disable_store === true;
         settings.allow_safety_identifier =
           localInputs.allow_safety_identifier === true;
+        settings.chat_completions_to_responses =
+          localInputs.chat_completions_to_responses === true;
+        settings.chat_completions_to_responses_models = (
+          localInputs.chat_completions_to_responses_models || ''
+        )
+          .split(',')
+          .map((model) => model.trim())
+          .filter((model) => model !== '');
       }
     }
 
@@ -1259,6 +1295,8 @@ const EditChannelModal = (props) => {
     delete localInputs.thinking_to_content;
     delete localInputs.proxy;
     delete localInputs.pass_through_body_enabled;
+    delete localInputs.pii_redaction_enabled;
+    delete localInputs.max_concurrency;
     delete localInputs.system_prompt;
     delete localInputs.system_prompt_override;
     delete localInputs.is_enterprise_account;
@@ -1270,6 +1308,8 @@ const EditChannelModal = (props) => {
     delete localInputs.allow_service_tier;
     delete localInputs.disable_store;
     delete localInputs.allow_safety_identifier;
+    delete localInputs.chat_completions_to_responses;
+    delete localInputs.chat_completions_to_responses_models;
 
     let res;
     localInputs.auto_ban = localInputs.auto_ban ? 1 : 0;
@@ -2108,6 +2148,7 @@ const EditChannelModal = (props) => {
                           optionList={[
                             { label: t('随机'), value: 'random' },
                             { label: t('轮询'), value: 'polling' },
+                            { label: t('最低负载'), value: 'least_loaded' },
                           ]}
                           style={{ width: '100%' }}
                           value={inputs.multi_key_mode || 'random'}
@@ -2125,6 +2166,15 @@ const EditChannelModal = (props) => {
                             className='!rounded-lg mt-2'
                           />
                         )}
+                        {inputs.multi_key_mode === 'least_loaded' && (
+                          <Banner
+                            type='info'
+                            description={t(
+                              '根据各密钥的用量与上游限流响应头选择负载最低的密钥，被限流的密钥会冷却至上游给出的重置时间，用量仅统计在当前节点',
+                            )}
+                            className='!rounded-lg mt-2'
+                          />
+                        )}
                       </>
                     )}
 
@@ -2946,6 +2996,44 @@ const EditChannelModal = (props) => {
                             'safety_identifier 字段用于帮助 OpenAI 识别可能违反使用政策的应用程序用户。默认关闭以保护用户隐私',
                           )}
                         />
+
+                        <div className='mt-4 mb-2 text-sm font-medium text-gray-700'>
+                          {t('Responses API 转换')}
+                        </div>
+
+                        <Form.Switch
+                          field='chat_completions_to_responses'
+                          label={t('Chat Completions 转 Responses')}
+                          checkedText={t('开')}
+                          uncheckedText={t('关')}
+                          onChange={(value) =>
+                            handleInputChange(
+                              'chat_completions_to_responses',
+                              value,
+                            )
+                          }
+                          extraText={t(
+                            '开启后该渠道所有模型的 Chat Completions 请求都将改为调用上游 /v1/responses，并将结果转换回 Chat Completions 格式',
+                          )}
+                        />
+
+                        <Form.Input
+                          field='chat_completions_to_responses_models'
+                          label={t('转换为 Responses 的模型')}
+                          placeholder={t(
+                            '多个模型用英文逗号分隔，例如：gpt-5-codex,o3-pro',
+                          )}
+                          onChange={(value) =>
+                            handleInputChange(
+                              'chat_completions_to_responses_models',
+                              value,
+                            )
+                          }
+                          showClear
+                          extraText={t(
+                            '仅对列出的模型进行转换，开关开启时对所有模型生效',
+                          )}
+                        />
                       </>
                     )}
 
@@ -3044,6 +3132,34 @@ const EditChannelModal = (props) => {
                       extraText={t('启用请求体透传功能')}
                     />
 
+                    <Form.Switch
+                      field='pii_redaction_enabled'
+                      label={t('隐私信息脱敏')}
+                      checkedText={t('开')}
+                      uncheckedText={t('关')}
+                      onChange={(value) =>
+                        handleChannelSettingsChange(
+                          'pii_redaction_enabled',
+                          value,
+                        )
+                      }
+                      extraText={t(
+                        '发送给该渠道前将请求中的个人信息替换为占位符，规则见运营设置',
+                      )}
+                    />
+
+                    <Form.InputNumber
+                      field='max_concurrency'
+                      label={t('并发上限')}
+                      min={0}
+                      onChange={(value) =>
+                        handleChannelSettingsChange('max_concurrency', value)
+                      }
+                      extraText={t(
+                        '该渠道同时处理的请求数上限，0 表示使用运营设置中的默认值；超出时请求按优先级排队',
+                      )}
+                    />
+
                     <Form.Input
                       field='proxy'
                       label={t('代理地址')}
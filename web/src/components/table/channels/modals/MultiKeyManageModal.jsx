@@ -43,6 +43,7 @@ import {
 } from '@douyinfe/semi-illustrations';
 import {
   API,
+  renderNumber,
   showError,
   showSuccess,
   timestamp2string,
@@ -355,6 +356,59 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
     }
   };
 
+  const multiKeyModeLabels = {
+    random: t('随机模式'),
+    polling: t('轮询模式'),
+    least_loaded: t('最低负载模式'),
+  };
+
+  const renderUsage = (usage) => {
+    if (!usage) {
+      return <Text type='quaternary'>-</Text>;
+    }
+    const tokens = usage.prompt_tokens + usage.completion_tokens;
+    const remaining = [];
+    if (usage.remaining_requests >= 0) {
+      remaining.push(`${t('剩余请求')}: ${usage.remaining_requests}`);
+    }
+    if (usage.remaining_tokens >= 0) {
+      remaining.push(`${t('剩余 Token')}: ${usage.remaining_tokens}`);
+    }
+    const detail = [
+      `${t('最近一分钟')}: ${usage.recent_requests} / ${usage.recent_tokens}`,
+      ...remaining,
+    ];
+    const cooldownText = `${t('冷却至')} ${timestamp2string(
+      usage.cooldown_until,
+    )}`;
+    if (usage.last_used_time) {
+      detail.push(
+        `${t('最后使用')}: ${timestamp2string(usage.last_used_time)}`,
+      );
+    }
+    return (
+      <Tooltip content={detail.join('\n')} style={{ whiteSpace: 'pre-line' }}>
+        <Space spacing={4} wrap>
+          <Text style={{ fontSize: '12px' }}>
+            {usage.requests} / {renderNumber(tokens)}
+          </Text>
+          {usage.rate_limited > 0 && (
+            <Tag color='orange' shape='circle' size='small'>
+              429 × {usage.rate_limited}
+            </Tag>
+          )}
+          {usage.cooldown_until > 0 && (
+            <Tooltip content={cooldownText}>
+              <Tag color='red' shape='circle' size='small'>
+                {t('冷却中')}
+              </Tag>
+            </Tooltip>
+          )}
+        </Space>
+      </Tooltip>
+    );
+  };
+
   // Table columns definition
   const columns = [
     {
@@ -376,6 +430,11 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
       dataIndex: 'status',
       render: (status) => renderStatusTag(status),
     },
+    {
+      title: t('请求 / Token'),
+      dataIndex: 'usage',
+      render: (usage) => renderUsage(usage),
+    },
     {
       title: t('禁用原因'),
       dataIndex: 'reason',
@@ -467,9 +526,8 @@ const MultiKeyManageModal = ({ visible, onCancel, channel, onRefresh }) => {
           </Tag>
           {channel?.channel_info?.multi_key_mode && (
             <Tag size='small' shape='circle' color='white'>
-              {channel.channel_info.multi_key_mode === 'random'
-                ? t('随机模式')
-                : t('轮询模式')}
+              {multiKeyModeLabels[channel.channel_info.multi_key_mode] ||
+                t('轮询模式')}
             </Tag>
           )}
         </Space>
@@ -39,6 +39,7 @@ import {
   renderQuota,
   getModelCategories,
   showError,
+  getTokenPolicy,
 } from '../../../helpers';
 import {
   IconTreeTriangleDown,
@@ -213,8 +214,15 @@ const renderModelLimits = (text, record, t) => {
 };
 
 // Render IP restrictions column
-const renderAllowIps = (text, t) => {
-  if (!text || text.trim() === '') {
+const renderAccessPolicy = (record, t) => {
+  const policy = getTokenPolicy(record);
+  const ips = policy.allow_ips;
+  const limits = [
+    policy.allow_referers?.length > 0 && t('来源限制'),
+    policy.time_windows?.length > 0 && t('时段限制'),
+    policy.allow_endpoints?.length > 0 && t('接口限制'),
+  ].filter(Boolean);
+  if (ips.length === 0 && limits.length === 0) {
     return (
       <Tag color='white' shape='circle'>
         {t('无限制')}
@@ -222,11 +230,6 @@ const renderAllowIps = (text, t) => {
     );
   }
 
-  const ips = text
-    .split('\n')
-    .map((ip) => ip.trim())
-    .filter(Boolean);
-
   const displayIps = ips.slice(0, 1);
   const extraCount = ips.length - displayIps.length;
 
@@ -249,6 +252,14 @@ const renderAllowIps = (text, t) => {
     );
   }
 
+  limits.forEach((limit) => {
+    ipTags.push(
+      <Tag key={limit} color='purple' shape='circle'>
+        {limit}
+      </Tag>,
+    );
+  });
+
   return <Space wrap>{ipTags}</Space>;
 };
 
@@ -469,9 +480,9 @@ export const getTokensColumns = ({
       render: (text, record) => renderModelLimits(text, record, t),
     },
     {
-      title: t('IP限制'),
-      dataIndex: 'allow_ips',
-      render: (text) => renderAllowIps(text, t),
+      title: t('访问策略'),
+      dataIndex: 'policy',
+      render: (text, record) => renderAccessPolicy(record, t),
     },
     {
       title: t('创建时间'),
@@ -24,9 +24,13 @@ import {
   showSuccess,
   timestamp2string,
   renderGroupOption,
+  renderQuota,
   renderQuotaWithPrompt,
   getModelCategories,
   selectFilter,
+  getTokenPolicy,
+  formatTokenTimeWindows,
+  buildTokenPolicy,
 } from '../../../../helpers';
 import { useIsMobile } from '../../../../hooks/common/useIsMobile';
 import {
@@ -54,6 +58,20 @@ import { StatusContext } from '../../../../context/Status';
 
 const { Text, Title } = Typography;
 
+const endpointOptions = [
+  '/v1/chat/completions',
+  '/v1/completions',
+  '/v1/responses',
+  '/v1/messages',
+  '/v1/embeddings',
+  '/v1/rerank',
+  '/v1/moderations',
+  '/v1/images/*',
+  '/v1/audio/*',
+  '/v1/models',
+  '/v1beta/models/*',
+].map((endpoint) => ({ label: endpoint, value: endpoint }));
+
 const EditTokenModal = (props) => {
   const { t } = useTranslation();
   const [statusState, statusDispatch] = useContext(StatusContext);
@@ -62,6 +80,7 @@ const EditTokenModal = (props) => {
   const formApiRef = useRef(null);
   const [models, setModels] = useState([]);
   const [groups, setGroups] = useState([]);
+  const [organizations, setOrganizations] = useState([]);
   const isEdit = props.editingToken.id !== undefined;
 
   const getInitValues = () => ({
@@ -72,8 +91,20 @@ const EditTokenModal = (props) => {
     model_limits_enabled: false,
     model_limits: [],
     allow_ips: '',
+    policy_allow_referers: '',
+    policy_allow_endpoints: [],
+    policy_time_windows: '',
+    policy_time_zone: '',
     group: '',
     cross_group_retry: false,
+    fallback_disabled: false,
+    hedge_delay: 0,
+    priority_class: '',
+    tpm_limit: 0,
+    concurrency_limit: 0,
+    budget_period: '',
+    budget_limit: 0,
+    org_id: 0,
     tokenCount: 1,
   });
 
@@ -149,6 +180,18 @@ const EditTokenModal = (props) => {
     }
   };
 
+  const loadOrganizations = async () => {
+    let res = await API.get(`/api/org/self`);
+    const { success, message, data } = res.data;
+    if (success) {
+      setOrganizations(
+        (data || []).map((org) => ({ label: org.name, value: org.id })),
+      );
+    } else {
+      showError(t(message));
+    }
+  };
+
   const loadToken = async () => {
     setLoading(true);
     let res = await API.get(`/api/token/${props.editingToken.id}`);
@@ -162,6 +205,12 @@ const EditTokenModal = (props) => {
       } else {
         data.model_limits = [];
       }
+      const policy = getTokenPolicy(data);
+      data.allow_ips = policy.allow_ips.join('\n');
+      data.policy_allow_referers = (policy.allow_referers || []).join('\n');
+      data.policy_allow_endpoints = policy.allow_endpoints || [];
+      data.policy_time_windows = formatTokenTimeWindows(policy.time_windows);
+      data.policy_time_zone = policy.time_zone || '';
       if (formApiRef.current) {
         formApiRef.current.setValues({ ...getInitValues(), ...data });
       }
@@ -179,6 +228,7 @@ const EditTokenModal = (props) => {
     }
     loadModels();
     loadGroups();
+    loadOrganizations();
   }, [props.editingToken.id]);
 
   useEffect(() => {
@@ -205,11 +255,30 @@ const EditTokenModal = (props) => {
     return result;
   };
 
+  // 将表单中的访问策略字段合并为 policy，时间段格式错误时返回 false
+  const applyPolicyInputs = (localInputs) => {
+    let policy;
+    try {
+      policy = buildTokenPolicy(localInputs);
+    } catch (e) {
+      showError(`${t('时间段格式错误')}: ${e.message}`);
+      return false;
+    }
+    localInputs.policy = JSON.stringify(policy);
+    localInputs.allow_ips = '';
+    delete localInputs.policy_allow_referers;
+    delete localInputs.policy_allow_endpoints;
+    delete localInputs.policy_time_windows;
+    delete localInputs.policy_time_zone;
+    return true;
+  };
+
   const submit = async (values) => {
     setLoading(true);
     if (isEdit) {
       let { tokenCount: _tc, ...localInputs } = values;
       localInputs.remain_quota = parseInt(localInputs.remain_quota);
+      localInputs.budget_limit = parseInt(localInputs.budget_limit) || 0;
       if (localInputs.expired_time !== -1) {
         let time = Date.parse(localInputs.expired_time);
         if (isNaN(time)) {
@@ -221,6 +290,10 @@ const EditTokenModal = (props) => {
       }
       localInputs.model_limits = localInputs.model_limits.join(',');
       localInputs.model_limits_enabled = localInputs.model_limits.length > 0;
+      if (!applyPolicyInputs(localInputs)) {
+        setLoading(false);
+        return;
+      }
       let res = await API.put(`/api/token/`, {
         ...localInputs,
         id: parseInt(props.editingToken.id),
@@ -246,6 +319,7 @@ const EditTokenModal = (props) => {
           localInputs.name = baseName;
         }
         localInputs.remain_quota = parseInt(localInputs.remain_quota);
+        localInputs.budget_limit = parseInt(localInputs.budget_limit) || 0;
 
         if (localInputs.expired_time !== -1) {
           let time = Date.parse(localInputs.expired_time);
@@ -258,6 +332,9 @@ const EditTokenModal = (props) => {
         }
         localInputs.model_limits = localInputs.model_limits.join(',');
         localInputs.model_limits_enabled = localInputs.model_limits.length > 0;
+        if (!applyPolicyInputs(localInputs)) {
+          break;
+        }
         let res = await API.post(`/api/token/`, localInputs);
         const { success, message } = res.data;
         if (success) {
@@ -388,6 +465,16 @@ const EditTokenModal = (props) => {
                       )}
                     />
                   </Col>
+                  <Col span={24}>
+                    <Form.Switch
+                      field='fallback_disabled'
+                      label={t('禁用模型降级')}
+                      size='default'
+                      extraText={t(
+                        '开启后，模型的渠道全部不可用时直接返回错误，不会改用降级链中的其他模型',
+                      )}
+                    />
+                  </Col>
                   <Col xs={24} sm={24} md={24} lg={10} xl={10}>
                     <Form.DatePicker
                       field='expired_time'
@@ -516,6 +603,53 @@ const EditTokenModal = (props) => {
                       )}
                     />
                   </Col>
+                  {organizations.length > 0 && (
+                    <Col span={24}>
+                      <Form.Select
+                        field='org_id'
+                        label={t('扣费来源')}
+                        optionList={[
+                          { value: 0, label: t('个人额度') },
+                          ...organizations,
+                        ]}
+                        extraText={t('选择组织后，该令牌的消费从组织额度中扣除')}
+                        style={{ width: '100%' }}
+                      />
+                    </Col>
+                  )}
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.Select
+                      field='budget_period'
+                      label={t('预算周期')}
+                      optionList={[
+                        { value: '', label: t('不限制') },
+                        { value: 'daily', label: t('每日') },
+                        { value: 'monthly', label: t('每月') },
+                      ]}
+                      extraText={t('每个周期开始时自动清零已用预算')}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.AutoComplete
+                      field='budget_limit'
+                      label={t('周期预算')}
+                      placeholder={t('请输入额度')}
+                      type='number'
+                      disabled={!values.budget_period}
+                      extraText={
+                        isEdit && values.budget_period
+                          ? `${t('本周期已使用')}: ${renderQuota(values.budget_used || 0)}`
+                          : renderQuotaWithPrompt(values.budget_limit)
+                      }
+                      data={[
+                        { value: 500000, label: '1$' },
+                        { value: 5000000, label: '10$' },
+                        { value: 25000000, label: '50$' },
+                        { value: 50000000, label: '100$' },
+                      ]}
+                    />
+                  </Col>
                 </Row>
               </Card>
 
@@ -566,6 +700,102 @@ const EditTokenModal = (props) => {
                       style={{ width: '100%' }}
                     />
                   </Col>
+                  <Col span={24}>
+                    <Form.TextArea
+                      field='policy_allow_referers'
+                      label={t('来源白名单')}
+                      placeholder={t(
+                        '允许的 Origin 或 Referer，一行一个，支持 * 通配',
+                      )}
+                      autosize
+                      rows={1}
+                      extraText={t(
+                        '用于浏览器端使用的令牌，如 https://*.example.com',
+                      )}
+                      showClear
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col span={24}>
+                    <Form.Select
+                      field='policy_allow_endpoints'
+                      label={t('允许访问的接口')}
+                      placeholder={t('留空允许访问所有接口')}
+                      multiple
+                      allowCreate
+                      filter
+                      optionList={endpointOptions}
+                      extraText={t('支持 * 通配，可输入自定义路径')}
+                      showClear
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={16} lg={16} xl={16}>
+                    <Form.TextArea
+                      field='policy_time_windows'
+                      label={t('允许访问的时间段')}
+                      placeholder={t('如 09:00-18:00 1-5，一行一个')}
+                      autosize
+                      rows={1}
+                      extraText={t(
+                        '星期可选，0 表示周日；结束早于开始表示跨越午夜',
+                      )}
+                      showClear
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={8} lg={8} xl={8}>
+                    <Form.Input
+                      field='policy_time_zone'
+                      label={t('时区')}
+                      placeholder='Asia/Shanghai'
+                      extraText={t('留空使用服务器时区')}
+                      showClear
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.InputNumber
+                      field='tpm_limit'
+                      label={t('每分钟 token 数限制')}
+                      min={0}
+                      step={1000}
+                      extraText={t('0 表示不限制')}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.InputNumber
+                      field='concurrency_limit'
+                      label={t('并发请求数限制')}
+                      min={0}
+                      step={1}
+                      extraText={t('0 表示不限制')}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.InputNumber
+                      field='hedge_delay'
+                      label={t('对冲等待时间（毫秒）')}
+                      min={0}
+                      step={100}
+                      extraText={t(
+                        '首个渠道超过该时间未开始输出时向另一个渠道发出对冲请求，0 表示使用分组配置',
+                      )}
+                      style={{ width: '100%' }}
+                    />
+                  </Col>
+                  <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                    <Form.Input
+                      field='priority_class'
+                      label={t('排队优先级')}
+                      placeholder={t('例如：interactive、batch')}
+                      extraText={t(
+                        '渠道并发已满时按该优先级排队，留空表示使用分组配置',
+                      )}
+                      showClear
+                    />
+                  </Col>
                 </Row>
               </Card>
             </div>
@@ -18,7 +18,7 @@ For commercial licensing, please contact support@quantumnous.com
 */
 
 import React, { useMemo } from 'react';
-import { Empty, Descriptions } from '@douyinfe/semi-ui';
+import { Button, Empty, Descriptions } from '@douyinfe/semi-ui';
 import CardTable from '../../common/ui/CardTable';
 import {
   IllustrationNoResult,
@@ -40,6 +40,7 @@ const LogsTable = (logsData) => {
     handlePageSizeChange,
     copyText,
     showUserInfoFunc,
+    showPayloadFunc,
     hasExpandableRows,
     isAdminUser,
     t,
@@ -73,7 +74,23 @@ const LogsTable = (logsData) => {
   }, [compactMode, visibleColumnsList]);
 
   const expandRowRender = (record, index) => {
-    return <Descriptions data={expandData[record.key]} />;
+    const data = expandData[record.key].map((item) =>
+      item.payloadRequestId
+        ? {
+            ...item,
+            value: (
+              <Button
+                size='small'
+                theme='borderless'
+                onClick={() => showPayloadFunc(item.payloadRequestId)}
+              >
+                {t('查看')}
+              </Button>
+            ),
+          }
+        : item,
+    );
+    return <Descriptions data={data} />;
   };
 
   return (
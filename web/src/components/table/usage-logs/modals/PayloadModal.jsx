@@ -0,0 +1,102 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React from 'react';
+import { Modal, Typography, Tag } from '@douyinfe/semi-ui';
+
+// JSON 内容格式化显示，流式响应等其他内容原样显示
+const formatBody = (body) => {
+  if (!body) return '';
+  try {
+    return JSON.stringify(JSON.parse(body), null, 2);
+  } catch (e) {
+    return body;
+  }
+};
+
+const PayloadModal = ({ showPayload, setShowPayloadModal, payloadData, t }) => {
+  const preStyle = {
+    maxHeight: 300,
+    overflow: 'auto',
+    padding: 12,
+    borderRadius: 6,
+    fontSize: 12,
+    whiteSpace: 'pre-wrap',
+    wordBreak: 'break-all',
+    background: 'var(--semi-color-fill-0)',
+  };
+
+  const renderSection = (title, body) => (
+    <div style={{ marginBottom: 16 }}>
+      <Typography.Title heading={6} style={{ marginBottom: 8 }}>
+        {title}
+      </Typography.Title>
+      <pre style={preStyle}>{formatBody(body) || t('无')}</pre>
+    </div>
+  );
+
+  return (
+    <Modal
+      title={t('完整内容')}
+      visible={showPayload}
+      onCancel={() => setShowPayloadModal(false)}
+      footer={null}
+      centered
+      closable
+      maskClosable
+      width={800}
+    >
+      {payloadData && (
+        <div style={{ paddingBottom: 12 }}>
+          <div style={{ marginBottom: 16 }}>
+            <Tag style={{ marginRight: 8 }}>
+              {payloadData.method} {payloadData.path}
+            </Tag>
+            <Tag
+              color={payloadData.response_status === 200 ? 'green' : 'red'}
+              style={{ marginRight: 8 }}
+            >
+              {payloadData.response_status}
+            </Tag>
+            {payloadData.pii_redactions > 0 && (
+              <Tag color='blue' style={{ marginRight: 8 }}>
+                {t('隐私信息脱敏')}：
+                {t('已脱敏 {{count}} 处', {
+                  count: payloadData.pii_redactions,
+                })}
+              </Tag>
+            )}
+            {payloadData.truncated && (
+              <Tag color='orange'>{t('内容过长已截断')}</Tag>
+            )}
+          </div>
+          {payloadData.error && renderSection(t('错误'), payloadData.error)}
+          {renderSection(t('请求体'), payloadData.request_body)}
+          {renderSection(
+            t('发送给上游的请求体'),
+            payloadData.upstream_request_body,
+          )}
+          {renderSection(t('响应内容'), payloadData.response_body)}
+        </div>
+      )}
+    </Modal>
+  );
+};
+
+export default PayloadModal;
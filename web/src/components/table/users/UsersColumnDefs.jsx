@@ -34,7 +34,14 @@ import { renderGroup, renderNumber, renderQuota } from '../../../helpers';
 /**
  * Render user role
  */
-const renderRole = (role, t) => {
+const adminRoleNames = {
+  billing_operator: '计费运营',
+  channel_operator: '渠道运维',
+  support: '客服支持',
+  auditor: '只读审计',
+};
+
+const renderRole = (role, t, adminRole) => {
   switch (role) {
     case 1:
       return (
@@ -44,9 +51,16 @@ const renderRole = (role, t) => {
       );
     case 10:
       return (
-        <Tag color='yellow' shape='circle'>
-          {t('管理员')}
-        </Tag>
+        <Space spacing={2}>
+          <Tag color='yellow' shape='circle'>
+            {t('管理员')}
+          </Tag>
+          {adminRoleNames[adminRole] && (
+            <Tag color='white' shape='circle'>
+              {t(adminRoleNames[adminRole])}
+            </Tag>
+          )}
+        </Space>
       );
     case 100:
       return (
@@ -332,7 +346,7 @@ export const getUsersColumns = ({
       title: t('角色'),
       dataIndex: 'role',
       render: (text, record, index) => {
-        return <div>{renderRole(text, t)}</div>;
+        return <div>{renderRole(text, t, record.admin_role)}</div>;
       },
     },
     {
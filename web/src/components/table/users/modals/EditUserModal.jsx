@@ -21,6 +21,7 @@ import React, { useEffect, useState, useRef } from 'react';
 import { useTranslation } from 'react-i18next';
 import {
   API,
+  isRoot,
   showError,
   showSuccess,
   renderQuota,
@@ -63,6 +64,7 @@ const EditUserModal = (props) => {
   const isMobile = useIsMobile();
   const [groupOptions, setGroupOptions] = useState([]);
   const formApiRef = useRef(null);
+  const originAdminRoleRef = useRef('');
 
   const isEdit = Boolean(userId);
 
@@ -79,8 +81,19 @@ const EditUserModal = (props) => {
     quota: 0,
     group: 'default',
     remark: '',
+    tpm_limit: 0,
+    concurrency_limit: 0,
+    admin_role: '',
   });
 
+  const adminRoleOptions = [
+    { label: t('全部权限'), value: '' },
+    { label: t('计费运营'), value: 'billing_operator' },
+    { label: t('渠道运维'), value: 'channel_operator' },
+    { label: t('客服支持'), value: 'support' },
+    { label: t('只读审计'), value: 'auditor' },
+  ];
+
   const fetchGroups = async () => {
     try {
       let res = await API.get(`/api/group/`);
@@ -99,6 +112,7 @@ const EditUserModal = (props) => {
     const { success, message, data } = res.data;
     if (success) {
       data.password = '';
+      originAdminRoleRef.current = data.admin_role || '';
       formApiRef.current?.setValues({ ...getInitValues(), ...data });
     } else {
       showError(message);
@@ -123,6 +137,21 @@ const EditUserModal = (props) => {
     const url = userId ? `/api/user/` : `/api/user/self`;
     const res = await API.put(url, payload);
     const { success, message } = res.data;
+    if (success && userId && isRoot() && values.role === 10) {
+      const adminRole = values.admin_role || '';
+      if (adminRole !== originAdminRoleRef.current) {
+        const roleRes = await API.post('/api/user/manage', {
+          id: parseInt(userId),
+          action: 'set_admin_role',
+          admin_role: adminRole,
+        });
+        if (!roleRes.data.success) {
+          showError(roleRes.data.message);
+          setLoading(false);
+          return;
+        }
+      }
+    }
     if (success) {
       showSuccess(t('用户信息更新成功！'));
       props.refresh();
@@ -286,6 +315,18 @@ const EditUserModal = (props) => {
                         />
                       </Col>
 
+                      {isRoot() && values.role === 10 && (
+                        <Col span={24}>
+                          <Form.Select
+                            field='admin_role'
+                            label={t('管理角色')}
+                            optionList={adminRoleOptions}
+                            extraText={t('限制该管理员可访问的管理功能')}
+                            style={{ width: '100%' }}
+                          />
+                        </Col>
+                      )}
+
                       <Col span={10}>
                         <Form.InputNumber
                           field='quota'
@@ -306,6 +347,28 @@ const EditUserModal = (props) => {
                           />
                         </Form.Slot>
                       </Col>
+
+                      <Col span={12}>
+                        <Form.InputNumber
+                          field='tpm_limit'
+                          label={t('每分钟 token 数限制')}
+                          min={0}
+                          step={1000}
+                          extraText={t('0 表示不限制')}
+                          style={{ width: '100%' }}
+                        />
+                      </Col>
+
+                      <Col span={12}>
+                        <Form.InputNumber
+                          field='concurrency_limit'
+                          label={t('并发请求数限制')}
+                          min={0}
+                          step={1}
+                          extraText={t('0 表示不限制')}
+                          style={{ width: '100%' }}
+                        />
+                      </Col>
                     </Row>
                   </Card>
                 )}
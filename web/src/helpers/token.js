@@ -61,3 +61,89 @@ export function getServerAddress() {
 
   return serverAddress;
 }
+
+const splitPolicyLines = (text) =>
+  (text || '')
+    .split('\n')
+    .map((line) => line.trim())
+    .filter(Boolean);
+
+/**
+ * 解析令牌访问策略，兼容旧版本只填写了 allow_ips 的令牌
+ * @param {object} token 令牌
+ * @returns {object} 访问策略
+ */
+export function getTokenPolicy(token) {
+  let policy = {};
+  if (token.policy) {
+    try {
+      policy = JSON.parse(token.policy) || {};
+    } catch (error) {
+      console.error('Failed to parse token policy:', error);
+    }
+  }
+  if (!policy.allow_ips || policy.allow_ips.length === 0) {
+    policy.allow_ips = splitPolicyLines(token.allow_ips);
+  }
+  return policy;
+}
+
+/**
+ * 将时间段格式化为文本，每行一个，如 09:00-18:00 1-5
+ * @param {Array} windows 时间段
+ * @returns {string}
+ */
+export function formatTokenTimeWindows(windows) {
+  return (windows || [])
+    .map((window) => {
+      const range = `${window.start}-${window.end}`;
+      return window.weekdays && window.weekdays.length > 0
+        ? `${range} ${window.weekdays.join(',')}`
+        : range;
+    })
+    .join('\n');
+}
+
+/**
+ * 解析时间段文本，星期支持 1,3,5 或 1-5 的写法，0 表示周日
+ * @param {string} text 时间段文本
+ * @returns {Array} 时间段，格式错误时抛出异常
+ */
+export function parseTokenTimeWindows(text) {
+  return splitPolicyLines(text).map((line) => {
+    const [range, days] = line.split(/\s+/);
+    const match = /^(\d{1,2}:\d{2})-(\d{1,2}:\d{2})$/.exec(range);
+    if (!match) {
+      throw new Error(line);
+    }
+    const weekdays = [];
+    (days || '')
+      .split(',')
+      .filter(Boolean)
+      .forEach((day) => {
+        const [from, to] = day.split('-').map((v) => parseInt(v, 10));
+        if (isNaN(from) || (to !== undefined && isNaN(to))) {
+          throw new Error(line);
+        }
+        for (let d = from; d <= (to === undefined ? from : to); d++) {
+          weekdays.push(d);
+        }
+      });
+    return { start: match[1], end: match[2], weekdays };
+  });
+}
+
+/**
+ * 根据表单中的文本构建访问策略
+ * @param {object} values 表单值
+ * @returns {object} 访问策略
+ */
+export function buildTokenPolicy(values) {
+  return {
+    allow_ips: splitPolicyLines(values.allow_ips),
+    allow_referers: splitPolicyLines(values.policy_allow_referers),
+    allow_endpoints: values.policy_allow_endpoints || [],
+    time_windows: parseTokenTimeWindows(values.policy_time_windows),
+    time_zone: (values.policy_time_zone || '').trim(),
+  };
+}
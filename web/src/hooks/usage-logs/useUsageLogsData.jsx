@@ -112,6 +112,10 @@ export const useLogsData = () => {
   const [showUserInfo, setShowUserInfoModal] = useState(false);
   const [userInfoData, setUserInfoData] = useState(null);
 
+  // Payload modal state
+  const [showPayload, setShowPayloadModal] = useState(false);
+  const [payloadData, setPayloadData] = useState(null);
+
   // Load saved column preferences from localStorage
   useEffect(() => {
     const savedColumns = localStorage.getItem(STORAGE_KEY);
@@ -304,6 +308,21 @@ export const useLogsData = () => {
     }
   };
 
+  // Payload function
+  const showPayloadFunc = async (requestId) => {
+    const url = isAdminUser
+      ? `/api/log/payload/${requestId}`
+      : `/api/log/self/payload/${requestId}`;
+    const res = await API.get(url);
+    const { success, message, data } = res.data;
+    if (success) {
+      setPayloadData(data);
+      setShowPayloadModal(true);
+    } else {
+      showError(message);
+    }
+  };
+
   // Format logs data
   const setLogsFormat = (logs) => {
     let expandDatesLocal = {};
@@ -403,6 +422,32 @@ export const useLogsData = () => {
             value: other.upstream_model_name,
           });
         }
+        if (other?.fallback_from) {
+          expandDataLocal.push({
+            key: t('降级前模型'),
+            value: other.fallback_from,
+          });
+        }
+        if (other?.admission_wait_ms) {
+          expandDataLocal.push({
+            key: t('排队等待'),
+            value: t('{{ms}} 毫秒', { ms: other.admission_wait_ms }),
+          });
+        }
+        if (other?.hedge_won) {
+          expandDataLocal.push({
+            key: t('对冲请求'),
+            value: t('对冲请求胜出'),
+          });
+        }
+        if (other?.hedge_loser) {
+          expandDataLocal.push({
+            key: t('对冲请求'),
+            value: t('落选未计费，渠道成本 {{cost}}', {
+              cost: renderQuota(other.hedge_cost || 0),
+            }),
+          });
+        }
         let content = '';
         if (other?.ws || other?.audio) {
           content = renderAudioModelPrice(
@@ -476,6 +521,27 @@ export const useLogsData = () => {
           });
         }
       }
+      if (other?.sensitive_words?.length > 0) {
+        expandDataLocal.push({
+          key: t('输出屏蔽词'),
+          value:
+            other.sensitive_words.join(', ') +
+            (other.sensitive_stopped ? ` (${t('已中断输出')})` : ''),
+        });
+      }
+      if (other?.pii_redactions > 0) {
+        expandDataLocal.push({
+          key: t('隐私信息脱敏'),
+          value: t('已脱敏 {{count}} 处', { count: other.pii_redactions }),
+        });
+      }
+      if (other?.payload_request_id) {
+        expandDataLocal.push({
+          key: t('完整内容'),
+          value: other.payload_request_id,
+          payloadRequestId: other.payload_request_id,
+        });
+      }
       if (other?.request_path) {
         expandDataLocal.push({
           key: t('请求路径'),
@@ -646,6 +712,12 @@ export const useLogsData = () => {
     userInfoData,
     showUserInfoFunc,
 
+    // Payload modal
+    showPayload,
+    setShowPayloadModal,
+    payloadData,
+    showPayloadFunc,
+
     // Functions
     loadLogs,
     handlePageChange,
@@ -0,0 +1,227 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import {
+  Button,
+  Form,
+  Input,
+  Popconfirm,
+  Select,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  API,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const formatAuditValue = (value) => {
+  if (value === undefined || value === null) {
+    return '-';
+  }
+  return typeof value === 'string' ? value : JSON.stringify(value);
+};
+
+export default function AuditVersionLog() {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [versions, setVersions] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const [pageSize, setPageSize] = useState(10);
+  const [entityType, setEntityType] = useState('');
+  const [entityId, setEntityId] = useState('');
+
+  const entityTypeNames = {
+    channel: t('渠道'),
+    option: t('系统设置'),
+    token: t('令牌'),
+    user: t('用户分组'),
+  };
+
+  const actionTags = {
+    baseline: <Tag color='grey'>{t('初始版本')}</Tag>,
+    update: <Tag color='blue'>{t('修改')}</Tag>,
+    rollback: <Tag color='orange'>{t('回滚')}</Tag>,
+  };
+
+  const loadVersions = async (
+    currentPage = page,
+    currentPageSize = pageSize,
+  ) => {
+    setLoading(true);
+    try {
+      const res = await API.get(
+        `/api/audit/?p=${currentPage}&page_size=${currentPageSize}&entity_type=${entityType}&entity_id=${encodeURIComponent(entityId.trim())}`,
+      );
+      const { success, message, data } = res.data;
+      if (success) {
+        setVersions(data.items || []);
+        setTotal(data.total);
+      } else {
+        showError(message);
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const rollback = async (id) => {
+    const res = await API.post(`/api/audit/${id}/rollback`);
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(t('回滚成功'));
+      await loadVersions();
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    setPage(1);
+    loadVersions(1, pageSize);
+  }, [entityType]);
+
+  const renderDiff = (value) => {
+    let diff = {};
+    try {
+      diff = JSON.parse(value || '{}');
+    } catch (e) {
+      return value;
+    }
+    const fields = Object.keys(diff);
+    if (fields.length === 0) {
+      return '-';
+    }
+    return (
+      <div style={{ maxWidth: 480 }}>
+        {fields.map((field) => (
+          <div key={field}>
+            <Typography.Text strong>{field}</Typography.Text>
+            {': '}
+            <Typography.Text type='danger' delete>
+              {formatAuditValue(diff[field].old)}
+            </Typography.Text>
+            {' → '}
+            <Typography.Text type='success'>
+              {formatAuditValue(diff[field].new)}
+            </Typography.Text>
+          </div>
+        ))}
+      </div>
+    );
+  };
+
+  const columns = [
+    {
+      title: t('时间'),
+      dataIndex: 'created_time',
+      render: (value) => timestamp2string(value),
+    },
+    {
+      title: t('对象'),
+      dataIndex: 'entity_type',
+      render: (value, record) =>
+        `${entityTypeNames[value] || value} ${record.entity_id}`,
+    },
+    { title: t('版本'), dataIndex: 'version' },
+    {
+      title: t('操作'),
+      dataIndex: 'action',
+      render: (value) => actionTags[value] || value,
+    },
+    {
+      title: t('操作人'),
+      dataIndex: 'actor_name',
+      render: (value) => value || '-',
+    },
+    { title: 'IP', dataIndex: 'ip', render: (value) => value || '-' },
+    {
+      title: t('变更内容'),
+      dataIndex: 'diff',
+      render: (value) => renderDiff(value),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (value, record) => (
+        <Popconfirm
+          title={t('确定回滚到该版本？')}
+          onConfirm={() => rollback(record.id)}
+        >
+          <Button size='small'>{t('回滚到此版本')}</Button>
+        </Popconfirm>
+      ),
+    },
+  ];
+
+  return (
+    <Form.Section text={t('配置变更历史')}>
+      <Space style={{ marginBottom: 12 }}>
+        <Select
+          value={entityType}
+          onChange={setEntityType}
+          style={{ width: 160 }}
+          optionList={[
+            { label: t('全部'), value: '' },
+            ...Object.entries(entityTypeNames).map(([value, label]) => ({
+              label,
+              value,
+            })),
+          ]}
+        />
+        <Input
+          value={entityId}
+          onChange={setEntityId}
+          placeholder={t('对象 ID 或设置项名称')}
+          style={{ width: 220 }}
+        />
+        <Button onClick={() => loadVersions(1, pageSize)}>{t('查询')}</Button>
+      </Space>
+      <Table
+        columns={columns}
+        dataSource={versions}
+        rowKey='id'
+        loading={loading}
+        size='small'
+        pagination={{
+          currentPage: page,
+          pageSize: pageSize,
+          total: total,
+          showSizeChanger: true,
+          onPageChange: (newPage) => {
+            setPage(newPage);
+            loadVersions(newPage, pageSize);
+          },
+          onPageSizeChange: (newPageSize) => {
+            setPage(1);
+            setPageSize(newPageSize);
+            loadVersions(1, newPageSize);
+          },
+        }}
+      />
+    </Form.Section>
+  );
+}
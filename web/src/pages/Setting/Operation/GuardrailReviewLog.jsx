@@ -0,0 +1,203 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState } from 'react';
+import {
+  Button,
+  Form,
+  Select,
+  Space,
+  Table,
+  Tag,
+  Typography,
+} from '@douyinfe/semi-ui';
+import {
+  API,
+  showError,
+  showSuccess,
+  timestamp2string,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+export default function GuardrailReviewLog() {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [logs, setLogs] = useState([]);
+  const [total, setTotal] = useState(0);
+  const [page, setPage] = useState(1);
+  const [pageSize, setPageSize] = useState(10);
+  const [reviewStatus, setReviewStatus] = useState('pending');
+
+  const loadLogs = async (currentPage = page, currentPageSize = pageSize) => {
+    setLoading(true);
+    try {
+      const res = await API.get(
+        `/api/guardrail/logs?p=${currentPage}&page_size=${currentPageSize}&review_status=${reviewStatus}`,
+      );
+      const { success, message, data } = res.data;
+      if (success) {
+        setLogs(data.items || []);
+        setTotal(data.total);
+      } else {
+        showError(message);
+      }
+    } finally {
+      setLoading(false);
+    }
+  };
+
+  const review = async (id, status) => {
+    const res = await API.put(`/api/guardrail/logs/${id}/review`, {
+      review_status: status,
+    });
+    const { success, message } = res.data;
+    if (success) {
+      showSuccess(t('操作成功完成！'));
+      await loadLogs();
+    } else {
+      showError(message);
+    }
+  };
+
+  useEffect(() => {
+    setPage(1);
+    loadLogs(1, pageSize);
+  }, [reviewStatus]);
+
+  const reviewStatusTag = (status) => {
+    switch (status) {
+      case 'approved':
+        return <Tag color='green'>{t('已放行')}</Tag>;
+      case 'rejected':
+        return <Tag color='red'>{t('已确认违规')}</Tag>;
+      default:
+        return <Tag color='orange'>{t('待复核')}</Tag>;
+    }
+  };
+
+  const columns = [
+    {
+      title: t('时间'),
+      dataIndex: 'created_at',
+      render: (value) => timestamp2string(value),
+    },
+    { title: t('用户'), dataIndex: 'username' },
+    { title: t('分组'), dataIndex: 'group' },
+    { title: t('模型'), dataIndex: 'model_name' },
+    {
+      title: t('阶段'),
+      dataIndex: 'stage',
+      render: (value) => (value === 'completion' ? t('输出') : t('输入')),
+    },
+    {
+      title: t('处理方式'),
+      dataIndex: 'action',
+      render: (value, record) => (
+        <Tag color={record.blocked ? 'red' : 'grey'}>{value}</Tag>
+      ),
+    },
+    {
+      title: t('审核类别'),
+      dataIndex: 'categories',
+      render: (value) => (
+        <Space wrap>
+          {(value ? value.split(',') : []).map((category) => (
+            <Tag key={category}>{category}</Tag>
+          ))}
+        </Space>
+      ),
+    },
+    {
+      title: t('内容'),
+      dataIndex: 'content',
+      render: (value) => (
+        <Typography.Paragraph
+          ellipsis={{ rows: 2, showTooltip: { type: 'popover' } }}
+          style={{ maxWidth: 300 }}
+        >
+          {value}
+        </Typography.Paragraph>
+      ),
+    },
+    {
+      title: t('复核状态'),
+      dataIndex: 'review_status',
+      render: (value) => reviewStatusTag(value),
+    },
+    {
+      title: '',
+      dataIndex: 'operate',
+      render: (value, record) => (
+        <Space>
+          <Button size='small' onClick={() => review(record.id, 'approved')}>
+            {t('放行')}
+          </Button>
+          <Button
+            size='small'
+            type='danger'
+            onClick={() => review(record.id, 'rejected')}
+          >
+            {t('确认违规')}
+          </Button>
+        </Space>
+      ),
+    },
+  ];
+
+  return (
+    <Form.Section text={t('内容审核日志')}>
+      <Space style={{ marginBottom: 12 }}>
+        <Select
+          value={reviewStatus}
+          onChange={setReviewStatus}
+          style={{ width: 160 }}
+          optionList={[
+            { label: t('待复核'), value: 'pending' },
+            { label: t('已放行'), value: 'approved' },
+            { label: t('已确认违规'), value: 'rejected' },
+            { label: t('全部'), value: '' },
+          ]}
+        />
+        <Button onClick={() => loadLogs()}>{t('刷新')}</Button>
+      </Space>
+      <Table
+        columns={columns}
+        dataSource={logs}
+        rowKey='id'
+        loading={loading}
+        size='small'
+        pagination={{
+          currentPage: page,
+          pageSize: pageSize,
+          total: total,
+          showSizeChanger: true,
+          onPageChange: (newPage) => {
+            setPage(newPage);
+            loadLogs(newPage, pageSize);
+          },
+          onPageSizeChange: (newPageSize) => {
+            setPage(1);
+            setPageSize(newPageSize);
+            loadLogs(1, newPageSize);
+          },
+        }}
+      />
+    </Form.Section>
+  );
+}
@@ -0,0 +1,228 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const CLASSES_EXAMPLE = {
+  interactive: { priority: 10, max_wait_ms: 5000 },
+  batch: { priority: 0, max_wait_ms: 60000 },
+};
+
+const GROUP_CLASSES_EXAMPLE = {
+  default: 'interactive',
+  batch: 'batch',
+};
+
+export default function SettingsAdmission(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'admission_setting.enabled': false,
+    'admission_setting.channel_concurrency': 0,
+    'admission_setting.classes': '',
+    'admission_setting.group_classes': '',
+    'admission_setting.default_class': 'interactive',
+    'admission_setting.batch_class': 'batch',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  async function onSubmit() {
+    await refForm.current
+      .validate()
+      .then(() => {
+        const updateArray = compareObjects(inputs, inputsRow);
+        if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+        const requestQueue = updateArray.map((item) => {
+          return API.put('/api/option/', {
+            key: item.key,
+            value: String(inputs[item.key]),
+          });
+        });
+        setLoading(true);
+        Promise.all(requestQueue)
+          .then((res) => {
+            if (requestQueue.length === 1) {
+              if (res.includes(undefined)) return;
+            } else if (requestQueue.length > 1) {
+              if (res.includes(undefined))
+                return showError(t('部分保存失败，请重试'));
+            }
+            showSuccess(t('保存成功'));
+            props.refresh();
+          })
+          .catch(() => {
+            showError(t('保存失败，请重试'));
+          })
+          .finally(() => {
+            setLoading(false);
+          });
+      })
+      .catch(() => {
+        showError(t('请检查输入'));
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('请求排队设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '限制每个渠道同时处理的请求数，优先选择未满的渠道；全部渠道已满时请求按优先级在模型的队列中排队，超过最长等待时间后返回 429。并发计数仅在当前节点内生效',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Switch
+                  field={'admission_setting.enabled'}
+                  label={t('启用请求排队')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('admission_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.InputNumber
+                  field={'admission_setting.channel_concurrency'}
+                  label={t('渠道默认并发上限')}
+                  min={0}
+                  step={1}
+                  extraText={t('0 表示不限制，渠道设置中单独配置的并发上限优先')}
+                  onChange={handleFieldChange(
+                    'admission_setting.channel_concurrency',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Input
+                  field={'admission_setting.default_class'}
+                  label={t('默认优先级')}
+                  extraText={t('令牌与分组都未配置优先级时使用')}
+                  onChange={handleFieldChange(
+                    'admission_setting.default_class',
+                  )}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={6} lg={6} xl={6}>
+                <Form.Input
+                  field={'admission_setting.batch_class'}
+                  label={t('批处理优先级')}
+                  extraText={t('批处理后台回放的请求使用，为空时与普通请求相同')}
+                  onChange={handleFieldChange('admission_setting.batch_class')}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                <Form.TextArea
+                  field={'admission_setting.classes'}
+                  label={t('优先级配置')}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(CLASSES_EXAMPLE, null, 2)
+                  }
+                  extraText={t(
+                    'priority 越大越先获得名额，max_wait_ms 为最长排队时间，0 表示不排队直接返回 429',
+                  )}
+                  autosize={{ minRows: 4, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => !value || verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={handleFieldChange('admission_setting.classes')}
+                />
+              </Col>
+              <Col xs={24} sm={24} md={12} lg={12} xl={12}>
+                <Form.TextArea
+                  field={'admission_setting.group_classes'}
+                  label={t('分组优先级')}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(GROUP_CLASSES_EXAMPLE, null, 2)
+                  }
+                  extraText={t('键为分组，值为优先级名称；令牌单独配置的优先级优先')}
+                  autosize={{ minRows: 4, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => !value || verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={handleFieldChange(
+                    'admission_setting.group_classes',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存请求排队设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
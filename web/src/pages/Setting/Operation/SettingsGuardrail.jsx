@@ -0,0 +1,310 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const GROUP_POLICIES_EXAMPLE = {
+  default: 'flag',
+  vip: 'log',
+  free: 'block',
+};
+
+export default function SettingsGuardrail(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'guardrail_setting.enabled': false,
+    'guardrail_setting.backend': 'moderation',
+    'guardrail_setting.moderation_model': 'omni-moderation-latest',
+    'guardrail_setting.moderation_group': 'default',
+    'guardrail_setting.webhook_url': '',
+    'guardrail_setting.webhook_secret': '',
+    'guardrail_setting.timeout_seconds': 10,
+    'guardrail_setting.fail_open_enabled': true,
+    'guardrail_setting.check_completion_enabled': false,
+    'guardrail_setting.default_action': 'flag',
+    'guardrail_setting.group_policies': '',
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  async function onSubmit() {
+    await refForm.current
+      .validate()
+      .then(() => {
+        const updateArray = compareObjects(inputs, inputsRow);
+        if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+        const requestQueue = updateArray.map((item) => {
+          return API.put('/api/option/', {
+            key: item.key,
+            value: String(inputs[item.key]),
+          });
+        });
+        setLoading(true);
+        Promise.all(requestQueue)
+          .then((res) => {
+            if (requestQueue.length === 1) {
+              if (res.includes(undefined)) return;
+            } else if (requestQueue.length > 1) {
+              if (res.includes(undefined))
+                return showError(t('部分保存失败，请重试'));
+            }
+            showSuccess(t('保存成功'));
+            props.refresh();
+          })
+          .catch(() => {
+            showError(t('保存失败，请重试'));
+          })
+          .finally(() => {
+            setLoading(false);
+          });
+      })
+      .catch(() => {
+        showError(t('请检查输入'));
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    refForm.current.setValues(currentInputs);
+  }, [props.options]);
+
+  const disabled = !inputs['guardrail_setting.enabled'];
+  const isWebhook = inputs['guardrail_setting.backend'] === 'webhook';
+  const actionOptions = [
+    { label: t('拦截'), value: 'block' },
+    { label: t('标记待复核'), value: 'flag' },
+    { label: t('仅记录'), value: 'log' },
+    { label: t('不审核'), value: 'off' },
+  ];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          values={inputs}
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('内容审核设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '在预扣费前调用外部审核服务审核用户输入，命中的请求按分组策略拦截或记录到审核日志',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'guardrail_setting.enabled'}
+                  label={t('启用内容审核')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('guardrail_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'guardrail_setting.check_completion_enabled'}
+                  label={t('审核模型输出')}
+                  extraText={t('请求完成后异步审核，命中时只记录不拦截')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'guardrail_setting.check_completion_enabled',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'guardrail_setting.fail_open_enabled'}
+                  label={t('审核服务不可用时放行')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'guardrail_setting.fail_open_enabled',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={'guardrail_setting.backend'}
+                  label={t('审核后端')}
+                  optionList={[
+                    {
+                      label: t('本站渠道 /v1/moderations'),
+                      value: 'moderation',
+                    },
+                    { label: 'Webhook', value: 'webhook' },
+                  ]}
+                  onChange={handleFieldChange('guardrail_setting.backend')}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={'guardrail_setting.default_action'}
+                  label={t('默认处理方式')}
+                  optionList={actionOptions}
+                  onChange={handleFieldChange(
+                    'guardrail_setting.default_action',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'guardrail_setting.timeout_seconds'}
+                  label={t('审核超时时间')}
+                  suffix={t('秒')}
+                  min={1}
+                  onChange={handleFieldChange(
+                    'guardrail_setting.timeout_seconds',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            {isWebhook ? (
+              <Row gutter={16}>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.Input
+                    field={'guardrail_setting.webhook_url'}
+                    label={t('Webhook 地址')}
+                    extraText={t(
+                      '请求体包含 stage、group、model、user_id、input，需返回 flagged 与 categories',
+                    )}
+                    onChange={handleFieldChange(
+                      'guardrail_setting.webhook_url',
+                    )}
+                    disabled={disabled}
+                  />
+                </Col>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.Input
+                    field={'guardrail_setting.webhook_secret'}
+                    label={t('Webhook 密钥')}
+                    type='password'
+                    placeholder={t('以 Bearer 方式发送，留空则不修改')}
+                    onChange={handleFieldChange(
+                      'guardrail_setting.webhook_secret',
+                    )}
+                    disabled={disabled}
+                  />
+                </Col>
+              </Row>
+            ) : (
+              <Row gutter={16}>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.Input
+                    field={'guardrail_setting.moderation_model'}
+                    label={t('审核模型')}
+                    onChange={handleFieldChange(
+                      'guardrail_setting.moderation_model',
+                    )}
+                    disabled={disabled}
+                  />
+                </Col>
+                <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                  <Form.Input
+                    field={'guardrail_setting.moderation_group'}
+                    label={t('审核渠道分组')}
+                    extraText={t(
+                      '从该分组中选择支持审核模型的 OpenAI 渠道，审核请求不计费',
+                    )}
+                    onChange={handleFieldChange(
+                      'guardrail_setting.moderation_group',
+                    )}
+                    disabled={disabled}
+                  />
+                </Col>
+              </Row>
+            )}
+            <Row>
+              <Col xs={24} sm={24} md={16} lg={16} xl={16}>
+                <Form.TextArea
+                  field={'guardrail_setting.group_policies'}
+                  label={t('分组处理方式')}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(GROUP_POLICIES_EXAMPLE, null, 2)
+                  }
+                  extraText={t(
+                    '可选值：block 拦截，flag 标记待复核，log 仅记录，off 不审核；未配置的分组使用默认处理方式',
+                  )}
+                  autosize={{ minRows: 4, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => !value || verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={handleFieldChange(
+                    'guardrail_setting.group_policies',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存内容审核设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
@@ -40,6 +40,10 @@ export default function SettingsMonitoring(props) {
     AutomaticDisableKeywords: '',
     'monitor_setting.auto_test_channel_enabled': false,
     'monitor_setting.auto_test_channel_minutes': 10,
+    'circuit_breaker_setting.enabled': false,
+    'circuit_breaker_setting.failure_threshold': 5,
+    'circuit_breaker_setting.cooldown_seconds': 60,
+    'circuit_breaker_setting.half_open_probes': 3,
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -221,6 +225,76 @@ export default function SettingsMonitoring(props) {
                 />
               </Col>
             </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'circuit_breaker_setting.enabled'}
+                  label={t('启用渠道熔断')}
+                  extraText={t(
+                    '渠道或多密钥中的单个密钥连续失败后暂停分配请求，冷却结束后放行少量探测请求，成功后恢复',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'circuit_breaker_setting.enabled': value,
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('熔断连续失败次数')}
+                  step={1}
+                  min={1}
+                  suffix={t('次')}
+                  field={'circuit_breaker_setting.failure_threshold'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'circuit_breaker_setting.failure_threshold':
+                        parseInt(value),
+                    })
+                  }
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('熔断冷却时间')}
+                  step={1}
+                  min={1}
+                  suffix={t('秒')}
+                  field={'circuit_breaker_setting.cooldown_seconds'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'circuit_breaker_setting.cooldown_seconds':
+                        parseInt(value),
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('半开状态探测请求数')}
+                  step={1}
+                  min={1}
+                  suffix={t('次')}
+                  field={'circuit_breaker_setting.half_open_probes'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      'circuit_breaker_setting.half_open_probes':
+                        parseInt(value),
+                    })
+                  }
+                />
+              </Col>
+            </Row>
             <Row>
               <Button size='default' onClick={onSubmit}>
                 {t('保存监控设置')}
@@ -0,0 +1,240 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+  verifyJSON,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+const CUSTOM_PATTERNS_EXAMPLE = {
+  employee_id: 'EMP-\\d{6}',
+  order_no: 'ORD\\d{10}',
+};
+
+// 分组与检测器在选项中以 JSON 数组保存
+const ARRAY_FIELDS = [
+  'pii_redaction_setting.groups',
+  'pii_redaction_setting.detectors',
+];
+
+function parseArray(value) {
+  if (Array.isArray(value)) return value;
+  try {
+    const parsed = JSON.parse(value || '[]');
+    return Array.isArray(parsed) ? parsed : [];
+  } catch (e) {
+    return [];
+  }
+}
+
+export default function SettingsPIIRedaction(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'pii_redaction_setting.enabled': false,
+    'pii_redaction_setting.groups': '[]',
+    'pii_redaction_setting.detectors':
+      '["email","phone","credit_card","iban","api_key"]',
+    'pii_redaction_setting.custom_patterns': '',
+    'pii_redaction_setting.restore_enabled': true,
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      if (ARRAY_FIELDS.includes(fieldName)) {
+        value = JSON.stringify(value || []);
+      }
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  async function onSubmit() {
+    await refForm.current
+      .validate()
+      .then(() => {
+        const updateArray = compareObjects(inputs, inputsRow);
+        if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+        const requestQueue = updateArray.map((item) => {
+          return API.put('/api/option/', {
+            key: item.key,
+            value: String(inputs[item.key]),
+          });
+        });
+        setLoading(true);
+        Promise.all(requestQueue)
+          .then((res) => {
+            if (requestQueue.length === 1) {
+              if (res.includes(undefined)) return;
+            } else if (requestQueue.length > 1) {
+              if (res.includes(undefined))
+                return showError(t('部分保存失败，请重试'));
+            }
+            showSuccess(t('保存成功'));
+            props.refresh();
+          })
+          .catch(() => {
+            showError(t('保存失败，请重试'));
+          })
+          .finally(() => {
+            setLoading(false);
+          });
+      })
+      .catch(() => {
+        showError(t('请检查输入'));
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    const formValues = { ...currentInputs };
+    ARRAY_FIELDS.forEach((key) => {
+      formValues[key] = parseArray(currentInputs[key]);
+    });
+    refForm.current.setValues(formValues);
+  }, [props.options]);
+
+  const disabled = !inputs['pii_redaction_setting.enabled'];
+  const detectorOptions = [
+    { label: t('邮箱'), value: 'email' },
+    { label: t('电话号码'), value: 'phone' },
+    { label: t('银行卡号'), value: 'credit_card' },
+    { label: 'IBAN', value: 'iban' },
+    { label: t('API 密钥'), value: 'api_key' },
+  ];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('隐私信息脱敏设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '发送给上游前将请求中的个人信息替换为占位符，如 [EMAIL_1]，相同内容使用相同占位符；渠道也可以在渠道设置中单独开启',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'pii_redaction_setting.enabled'}
+                  label={t('按分组启用脱敏')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('pii_redaction_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'pii_redaction_setting.restore_enabled'}
+                  label={t('在输出中还原占位符')}
+                  extraText={t(
+                    '支持对话补全、Claude 与 Gemini 格式，包括流式输出',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange(
+                    'pii_redaction_setting.restore_enabled',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.TagInput
+                  field={'pii_redaction_setting.groups'}
+                  label={t('脱敏分组')}
+                  placeholder={t('输入分组后回车，留空表示所有分组')}
+                  onChange={handleFieldChange('pii_redaction_setting.groups')}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={'pii_redaction_setting.detectors'}
+                  label={t('内置检测器')}
+                  multiple
+                  optionList={detectorOptions}
+                  onChange={handleFieldChange(
+                    'pii_redaction_setting.detectors',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Col xs={24} sm={24} md={16} lg={16} xl={16}>
+                <Form.TextArea
+                  field={'pii_redaction_setting.custom_patterns'}
+                  label={t('自定义正则')}
+                  placeholder={
+                    t('为一个 JSON 文本，例如：') +
+                    '\n' +
+                    JSON.stringify(CUSTOM_PATTERNS_EXAMPLE, null, 2)
+                  }
+                  extraText={t(
+                    '名称用于生成占位符，例如 employee_id 生成 [EMPLOYEE_ID_1]，无效的正则会被忽略',
+                  )}
+                  autosize={{ minRows: 4, maxRows: 12 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => !value || verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  onChange={handleFieldChange(
+                    'pii_redaction_setting.custom_patterns',
+                  )}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存脱敏设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
@@ -0,0 +1,244 @@
+/*
+Copyright (C) 2025 QuantumNous
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU Affero General Public License as
+published by the Free Software Foundation, either version 3 of the
+License, or (at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+GNU Affero General Public License for more details.
+
+You should have received a copy of the GNU Affero General Public License
+along with this program. If not, see <https://www.gnu.org/licenses/>.
+
+For commercial licensing, please contact support@quantumnous.com
+*/
+
+import React, { useEffect, useState, useRef } from 'react';
+import { Button, Col, Form, Row, Spin, Typography } from '@douyinfe/semi-ui';
+import {
+  compareObjects,
+  API,
+  showError,
+  showSuccess,
+  showWarning,
+} from '../../../helpers';
+import { useTranslation } from 'react-i18next';
+
+// 记录范围在选项中以 JSON 数组保存，用户与令牌 ID 为数字
+const ARRAY_FIELDS = [
+  'payload_log_setting.user_ids',
+  'payload_log_setting.token_ids',
+  'payload_log_setting.groups',
+];
+const NUMBER_ARRAY_FIELDS = [
+  'payload_log_setting.user_ids',
+  'payload_log_setting.token_ids',
+];
+
+function parseArray(value) {
+  if (Array.isArray(value)) return value;
+  try {
+    const parsed = JSON.parse(value || '[]');
+    return Array.isArray(parsed) ? parsed.map(String) : [];
+  } catch (e) {
+    return [];
+  }
+}
+
+export default function SettingsPayloadLog(props) {
+  const { t } = useTranslation();
+  const [loading, setLoading] = useState(false);
+  const [inputs, setInputs] = useState({
+    'payload_log_setting.enabled': false,
+    'payload_log_setting.user_ids': '[]',
+    'payload_log_setting.token_ids': '[]',
+    'payload_log_setting.groups': '[]',
+    'payload_log_setting.storage': 'database',
+    'payload_log_setting.retention_days': 7,
+    'payload_log_setting.max_body_size_kb': 1024,
+  });
+  const refForm = useRef();
+  const [inputsRow, setInputsRow] = useState(inputs);
+
+  function handleFieldChange(fieldName) {
+    return (value) => {
+      if (NUMBER_ARRAY_FIELDS.includes(fieldName)) {
+        value = JSON.stringify(
+          (value || []).map(Number).filter((id) => Number.isInteger(id)),
+        );
+      } else if (ARRAY_FIELDS.includes(fieldName)) {
+        value = JSON.stringify(value || []);
+      }
+      setInputs((inputs) => ({ ...inputs, [fieldName]: value }));
+    };
+  }
+
+  async function onSubmit() {
+    await refForm.current
+      .validate()
+      .then(() => {
+        const updateArray = compareObjects(inputs, inputsRow);
+        if (!updateArray.length) return showWarning(t('你似乎并没有修改什么'));
+        const requestQueue = updateArray.map((item) => {
+          return API.put('/api/option/', {
+            key: item.key,
+            value: String(inputs[item.key]),
+          });
+        });
+        setLoading(true);
+        Promise.all(requestQueue)
+          .then((res) => {
+            if (requestQueue.length === 1) {
+              if (res.includes(undefined)) return;
+            } else if (requestQueue.length > 1) {
+              if (res.includes(undefined))
+                return showError(t('部分保存失败，请重试'));
+            }
+            showSuccess(t('保存成功'));
+            props.refresh();
+          })
+          .catch(() => {
+            showError(t('保存失败，请重试'));
+          })
+          .finally(() => {
+            setLoading(false);
+          });
+      })
+      .catch(() => {
+        showError(t('请检查输入'));
+      });
+  }
+
+  useEffect(() => {
+    const currentInputs = {};
+    for (let key in props.options) {
+      if (Object.keys(inputs).includes(key)) {
+        currentInputs[key] = props.options[key];
+      }
+    }
+    setInputs(currentInputs);
+    setInputsRow(structuredClone(currentInputs));
+    const formValues = { ...currentInputs };
+    ARRAY_FIELDS.forEach((key) => {
+      formValues[key] = parseArray(currentInputs[key]);
+    });
+    refForm.current.setValues(formValues);
+  }, [props.options]);
+
+  const disabled = !inputs['payload_log_setting.enabled'];
+
+  return (
+    <>
+      <Spin spinning={loading}>
+        <Form
+          getFormApi={(formAPI) => (refForm.current = formAPI)}
+          style={{ marginBottom: 15 }}
+        >
+          <Form.Section text={t('完整内容记录设置')}>
+            <Typography.Text
+              type='tertiary'
+              style={{ marginBottom: 16, display: 'block' }}
+            >
+              {t(
+                '为指定的用户、令牌或分组记录完整的请求体、发送给上游的请求体与响应内容，压缩后保存，可在使用日志中查看',
+              )}
+            </Typography.Text>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'payload_log_setting.enabled'}
+                  label={t('启用完整内容记录')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={handleFieldChange('payload_log_setting.enabled')}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Select
+                  field={'payload_log_setting.storage'}
+                  label={t('保存位置')}
+                  optionList={[
+                    { label: t('日志数据库'), value: 'database' },
+                    { label: t('对象存储'), value: 'storage' },
+                  ]}
+                  extraText={t('未启用对象存储时保存在本地磁盘')}
+                  onChange={handleFieldChange('payload_log_setting.storage')}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.TagInput
+                  field={'payload_log_setting.user_ids'}
+                  label={t('记录的用户 ID')}
+                  placeholder={t('输入后回车')}
+                  onChange={handleFieldChange('payload_log_setting.user_ids')}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.TagInput
+                  field={'payload_log_setting.token_ids'}
+                  label={t('记录的令牌 ID')}
+                  placeholder={t('输入后回车')}
+                  onChange={handleFieldChange('payload_log_setting.token_ids')}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.TagInput
+                  field={'payload_log_setting.groups'}
+                  label={t('记录的分组')}
+                  placeholder={t('输入后回车')}
+                  onChange={handleFieldChange('payload_log_setting.groups')}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'payload_log_setting.retention_days'}
+                  label={t('保留天数')}
+                  extraText={t('0 表示永久保留')}
+                  suffix={t('天')}
+                  min={0}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.retention_days',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  field={'payload_log_setting.max_body_size_kb'}
+                  label={t('单项内容最大记录大小')}
+                  extraText={t(
+                    '请求体、上游请求体与响应分别计算，超出部分截断',
+                  )}
+                  suffix='KB'
+                  min={1}
+                  onChange={handleFieldChange(
+                    'payload_log_setting.max_body_size_kb',
+                  )}
+                  disabled={disabled}
+                />
+              </Col>
+            </Row>
+            <Row>
+              <Button size='default' onClick={onSubmit}>
+                {t('保存完整内容记录设置')}
+              </Button>
+            </Row>
+          </Form.Section>
+        </Form>
+      </Spin>
+    </>
+  );
+}
@@ -34,6 +34,9 @@ export default function SettingsSensitiveWords(props) {
   const [inputs, setInputs] = useState({
     CheckSensitiveEnabled: false,
     CheckSensitiveOnPromptEnabled: false,
+    CheckSensitiveOnCompletionEnabled: false,
+    StopOnSensitiveEnabled: true,
+    StreamCacheQueueLength: 0,
     SensitiveWords: '',
   });
   const refForm = useRef();
@@ -125,6 +128,59 @@ export default function SettingsSensitiveWords(props) {
                   }
                 />
               </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'CheckSensitiveOnCompletionEnabled'}
+                  label={t('启用输出检查')}
+                  extraText={t(
+                    '检查模型输出，流式输出会缓存少量文本用于匹配跨片段的屏蔽词',
+                  )}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      CheckSensitiveOnCompletionEnabled: value,
+                    })
+                  }
+                />
+              </Col>
+            </Row>
+            <Row gutter={16}>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.Switch
+                  field={'StopOnSensitiveEnabled'}
+                  label={t('输出命中屏蔽词时中断输出')}
+                  extraText={t('关闭时将命中的屏蔽词替换为 **###** 后继续输出')}
+                  size='default'
+                  checkedText='｜'
+                  uncheckedText='〇'
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      StopOnSensitiveEnabled: value,
+                    })
+                  }
+                />
+              </Col>
+              <Col xs={24} sm={12} md={8} lg={8} xl={8}>
+                <Form.InputNumber
+                  label={t('流式输出额外缓存的片段数')}
+                  step={1}
+                  min={0}
+                  extraText={t(
+                    '0 表示仅缓存匹配屏蔽词所需的最少文本，数值越大输出延迟越高',
+                  )}
+                  field={'StreamCacheQueueLength'}
+                  onChange={(value) =>
+                    setInputs({
+                      ...inputs,
+                      StreamCacheQueueLength: String(value),
+                    })
+                  }
+                />
+              </Col>
             </Row>
             <Row>
               <Col xs={24} sm={12} md={8} lg={8} xl={8}>
@@ -39,6 +39,7 @@ export default function RequestRateLimit(props) {
     ModelRequestRateLimitSuccessCount: 1000,
     ModelRequestRateLimitDurationMinutes: 1,
     ModelRequestRateLimitGroup: '',
+    GroupRelayLimit: '',
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -229,6 +230,32 @@ export default function RequestRateLimit(props) {
                 />
               </Col>
             </Row>
+            <Row>
+              <Col xs={24} sm={16}>
+                <Form.TextArea
+                  label={t('分组 TPM 与并发限制')}
+                  placeholder={t(
+                    '{\n  "default": {"tpm": 100000, "concurrency": 20}\n}',
+                  )}
+                  field={'GroupRelayLimit'}
+                  autosize={{ minRows: 5, maxRows: 15 }}
+                  trigger='blur'
+                  stopValidateWithError
+                  rules={[
+                    {
+                      validator: (rule, value) => verifyJSON(value),
+                      message: t('不是合法的 JSON 字符串'),
+                    },
+                  ]}
+                  extraText={t(
+                    '分组内所有用户共享的每分钟 token 数与并发请求数上限，0 表示不限制；令牌和用户的限制可在令牌与用户编辑页设置。该限制不受上方开关控制',
+                  )}
+                  onChange={(value) => {
+                    setInputs({ ...inputs, GroupRelayLimit: value });
+                  }}
+                />
+              </Col>
+            </Row>
             <Row>
               <Button size='default' onClick={onSubmit}>
                 {t('保存模型速率限制')}
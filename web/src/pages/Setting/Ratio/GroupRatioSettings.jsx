@@ -39,6 +39,7 @@ export default function GroupRatioSettings(props) {
     'group_ratio_setting.group_special_usable_group': '',
     AutoGroups: '',
     DefaultUseAutoGroup: false,
+    AdaptiveSelectGroups: '',
   });
   const refForm = useRef();
   const [inputsRow, setInputsRow] = useState(inputs);
@@ -261,6 +262,43 @@ export default function GroupRatioSettings(props) {
             />
           </Col>
         </Row>
+        <Row gutter={16}>
+          <Col xs={24} sm={16}>
+            <Form.TextArea
+              label={t('启用自适应渠道选择的分组')}
+              extraText={t(
+                '列出的分组在同一优先级内会根据渠道近期的成功率、首字时间与输出速度选择渠道，而不是仅按权重随机',
+              )}
+              placeholder={t('为一个 JSON 文本')}
+              field={'AdaptiveSelectGroups'}
+              autosize={{ minRows: 2, maxRows: 6 }}
+              trigger='blur'
+              stopValidateWithError
+              rules={[
+                {
+                  validator: (rule, value) => {
+                    if (!value || value.trim() === '') {
+                      return true;
+                    }
+                    try {
+                      const parsed = JSON.parse(value);
+                      return (
+                        Array.isArray(parsed) &&
+                        parsed.every((item) => typeof item === 'string')
+                      );
+                    } catch (error) {
+                      return false;
+                    }
+                  },
+                  message: t('必须是有效的 JSON 字符串数组，例如：["g1","g2"]'),
+                },
+              ]}
+              onChange={(value) =>
+                setInputs({ ...inputs, AdaptiveSelectGroups: value })
+              }
+            />
+          </Col>
+        </Row>
       </Form>
       <Button onClick={onSubmit}>{t('保存分组倍率设置')}</Button>
     </Spin>